@@ -1,13 +1,12 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"strings"
-	"time"
 
 	flags "github.com/jessevdk/go-flags"
-	"github.com/redis/go-redis/v9"
 
 	"github.com/thek4n/paste.thek4n.ru/internal/application/service"
 	"github.com/thek4n/paste.thek4n.ru/internal/domain/aggregate"
@@ -15,8 +14,10 @@ import (
 )
 
 type apikeysOptions struct {
-	DBPort int    `long:"dbport" default:"6379" description:"Database port"`
-	DBHost string `long:"dbhost" default:"localhost" description:"Database host"`
+	Storage    string `long:"storage" default:"redis" choice:"redis" choice:"sqlite" description:"Storage backend"`
+	SQLitePath string `long:"sqlitepath" default:"paste.db" description:"Path to sqlite database file for sqlite storage"`
+
+	RedisOptions `group:"Redis options"`
 }
 
 func apikeysCommand(args []string) {
@@ -33,12 +34,11 @@ func apikeysCommand(args []string) {
 		os.Exit(1)
 	}
 
-	client := newRedisClientAPIKeys(&opts)
-
-	s := service.NewAPIKeysService(
-		repository.NewRedisAPIKeyRORepository(client),
-		repository.NewRedisAPIKeyWORepository(client),
-	)
+	s, err := newAPIKeysService(&opts)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Fail to initialize storage: %s\n", err)
+		os.Exit(2)
+	}
 
 	switch args[0] {
 	case "list":
@@ -196,15 +196,25 @@ func columnT(input string) string {
 	return result.String()
 }
 
-func newRedisClientAPIKeys(opts *apikeysOptions) *redis.Client {
-	return redis.NewClient(&redis.Options{
-		Addr:         fmt.Sprintf("%s:%d", opts.DBHost, opts.DBPort),
-		PoolSize:     100,
-		Password:     "",
-		Username:     "",
-		DB:           2,
-		MaxRetries:   5,
-		DialTimeout:  10 * time.Second,
-		WriteTimeout: 5 * time.Second,
-	})
+func newAPIKeysService(opts *apikeysOptions) (*service.APIKeysService, error) {
+	if opts.Storage == "sqlite" {
+		db, err := repository.OpenSQLite(context.Background(), opts.SQLitePath)
+		if err != nil {
+			return nil, fmt.Errorf("fail to open sqlite storage: %w", err)
+		}
+
+		r := repository.NewSQLiteAPIKeyRepository(db)
+		return service.NewAPIKeysService(r, r), nil
+	}
+
+	keyspace := opts.apikeysKeyspace()
+	client, err := newRedisClient(&opts.RedisOptions, keyspace.db)
+	if err != nil {
+		return nil, fmt.Errorf("fail to create redis client: %w", err)
+	}
+
+	return service.NewAPIKeysService(
+		repository.NewRedisAPIKeyRORepository(client, keyspace.prefix),
+		repository.NewRedisAPIKeyWORepository(client, keyspace.prefix),
+	), nil
 }
@@ -10,6 +10,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 	"time"
@@ -31,6 +32,16 @@ func (c TestQuotaConfig) Quota() uint32 {
 	return math.MaxUint32
 }
 
+// testURLBlocklist hosts urls to which can not be shortened in tests.
+const testURLBlocklist = `# test blocklist
+blocked.example
+203.0.113.0/24
+`
+
+// testGeoIPDatabase countries of clicks in tests.
+const testGeoIPDatabase = `198.51.100.0/24,DE
+`
+
 type testServer struct {
 	*httptest.Server
 }
@@ -72,26 +83,45 @@ func setupTestServer(t *testing.T) *testServer {
 
 	opts := pasteOptions{
 		EnableHealthcheck: true,
-		DBHost:            getRedisHost(),
-		DBPort:            6379,
+		RedisOptions: RedisOptions{
+			DBHost:        getRedisHost(),
+			DBPort:        6379,
+			RecordsPrefix: "record:",
+			QuotasPrefix:  "quota:",
+			APIKeysPrefix: "apikey:",
+		},
+		URLBlocklist:  filepath.Join(t.TempDir(), "urlblocklist"),
+		GeoIPDatabase: filepath.Join(t.TempDir(), "geoip.csv"),
 	}
+	err := os.WriteFile(opts.URLBlocklist, []byte(testURLBlocklist), 0o600)
+	require.NoError(t, err)
+	err = os.WriteFile(opts.GeoIPDatabase, []byte(testGeoIPDatabase), 0o600)
+	require.NoError(t, err)
+
+	client, err := newRedisClient(&opts.RedisOptions, -1)
+	require.NoError(t, err)
+	client.FlushDB(context.Background())
+
+	repositories, err := newRedisStorage(&opts, TestQuotaConfig{})
+	require.NoError(t, err)
 
-	recordsClient := newRedisClient(&opts, 0)
-	quotaClient := newRedisClient(&opts, 1)
-	apikeyClient := newRedisClient(&opts, 2)
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
 
-	recordsClient.FlushDB(context.Background())
-	quotaClient.FlushDB(context.Background())
-	apikeyClient.FlushDB(context.Background())
+	urlPolicy, err := newURLPolicy(ctx, &opts, slog.Default())
+	require.NoError(t, err)
+
+	geoIP, err := newGeoIPRepository(&opts)
+	require.NoError(t, err)
 
 	handlers := handlersFactory(
-		recordsClient,
-		quotaClient,
-		apikeyClient,
+		repositories,
 		&opts,
 		slog.Default(),
 		event.NewPublisher(),
 		TestQuotaConfig{},
+		urlPolicy,
+		geoIP,
 	)
 
 	mux := http.NewServeMux()
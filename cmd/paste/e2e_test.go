@@ -3,10 +3,23 @@
 package main
 
 import (
+	"archive/tar"
+	"archive/zip"
 	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
 	"fmt"
+	"io"
+	"mime/multipart"
 	"net/http"
+	"net/url"
+	"path"
+	"regexp"
 	"strconv"
+	"strings"
 	"testing"
 	"time"
 
@@ -76,6 +89,53 @@ func TestCache(t *testing.T) {
 		)
 	})
 
+	t.Run("cache request words key style", func(t *testing.T) {
+		t.Parallel()
+
+		resp, err := ts.post("/?keystyle=words", "test body")
+		require.NoError(t, err)
+		require.Equal(t, http.StatusCreated, resp.StatusCode)
+
+		gotURL := mustReadBody(t, resp.Body)
+		assert.Regexp(t, `/[a-z]+-[a-z]+-[0-9]+/$`, gotURL)
+
+		resp, err = http.Get(gotURL)
+		require.NoError(t, err)
+		assert.Equal(t, "test body", mustReadBody(t, resp.Body))
+	})
+
+	t.Run("cache request words key style keeps requested length", func(t *testing.T) {
+		t.Parallel()
+
+		maxLength := int(config.DefaultCacheValidationConfig{}.MaxKeyLength())
+		resp, err := ts.post(fmt.Sprintf("/?keystyle=words&len=%d", maxLength), "test body")
+		require.NoError(t, err)
+		require.Equal(t, http.StatusCreated, resp.StatusCode)
+		assert.Equal(t, maxLength, getKeyLength(t, mustReadBody(t, resp.Body)))
+
+		resp, err = ts.post("/?keystyle=words&len=8", "test body")
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode, "words do not fit length")
+	})
+
+	t.Run("cache request unknown key style returns 400", func(t *testing.T) {
+		t.Parallel()
+
+		resp, err := ts.post("/?keystyle=unknown", "test body")
+		require.NoError(t, err)
+
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	})
+
+	t.Run("unprivileged cache request sequential key style returns 403", func(t *testing.T) {
+		t.Parallel()
+
+		resp, err := ts.post("/?keystyle=sequential", "test body")
+		require.NoError(t, err)
+
+		assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+	})
+
 	t.Run("unprivileged cache with big body returns 413", func(t *testing.T) {
 		t.Parallel()
 
@@ -89,6 +149,22 @@ func TestCache(t *testing.T) {
 			"should reject too large bodies",
 		)
 	})
+
+	t.Run("unprivileged cache with big chunked body returns 413", func(t *testing.T) {
+		t.Parallel()
+
+		largeBody := bytes.Repeat([]byte("a"), int(config.DefaultCacheValidationConfig{}.UnprivilegedMaxBodySize()+100))
+
+		// io.MultiReader hides body length, so request is sent chunked
+		// and limit is enforced while reading.
+		resp, err := http.Post(ts.URL+"/", "text/plain", io.MultiReader(bytes.NewReader(largeBody)))
+		require.NoError(t, err)
+
+		assert.Equal(t,
+			http.StatusRequestEntityTooLarge, resp.StatusCode,
+			"should reject too large chunked bodies",
+		)
+	})
 }
 
 func TestGet(t *testing.T) {
@@ -114,6 +190,314 @@ func TestGet(t *testing.T) {
 	})
 }
 
+func TestDelete(t *testing.T) {
+	ts := setupTestServer(t)
+
+	deleteKey := func(t *testing.T, url, token string) *http.Response {
+		t.Helper()
+
+		req, err := http.NewRequest(http.MethodDelete, url, nil)
+		require.NoError(t, err)
+		if token != "" {
+			req.Header.Set("X-Owner-Token", token)
+		}
+
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+
+		return resp
+	}
+
+	t.Run("delete with owner token removes key", func(t *testing.T) {
+		t.Parallel()
+
+		postResp, err := ts.post("/", "password")
+		require.NoError(t, err)
+		require.Equal(t, http.StatusCreated, postResp.StatusCode)
+		token := postResp.Header.Get("X-Owner-Token")
+		require.NotEmpty(t, token)
+		gotURL := mustReadBody(t, postResp.Body)
+
+		resp := deleteKey(t, gotURL, token)
+		assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+
+		getResp, err := http.Get(gotURL)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusNotFound, getResp.StatusCode)
+
+		resp = deleteKey(t, gotURL, token)
+		assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	})
+
+	t.Run("delete with owner token parameter removes key", func(t *testing.T) {
+		t.Parallel()
+
+		postResp, err := ts.post("/", "password")
+		require.NoError(t, err)
+		require.Equal(t, http.StatusCreated, postResp.StatusCode)
+		token := postResp.Header.Get("X-Owner-Token")
+		gotURL := mustReadBody(t, postResp.Body)
+
+		resp := deleteKey(t, gotURL+"?token="+token, "")
+		assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+	})
+
+	t.Run("delete with wrong or without owner token is rejected", func(t *testing.T) {
+		t.Parallel()
+
+		postResp, err := ts.post("/", "password")
+		require.NoError(t, err)
+		require.Equal(t, http.StatusCreated, postResp.StatusCode)
+		gotURL := mustReadBody(t, postResp.Body)
+
+		resp := deleteKey(t, gotURL, "wrong")
+		assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+		resp = deleteKey(t, gotURL, "")
+		assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+
+		getResp, err := http.Get(gotURL)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusOK, getResp.StatusCode)
+	})
+}
+
+func TestUpdate(t *testing.T) {
+	ts := setupTestServer(t)
+
+	patchKey := func(t *testing.T, url, token, body string) *http.Response {
+		t.Helper()
+
+		req, err := http.NewRequest(http.MethodPatch, url, strings.NewReader(body))
+		require.NoError(t, err)
+		if token != "" {
+			req.Header.Set("X-Owner-Token", token)
+		}
+
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+
+		return resp
+	}
+
+	t.Run("update with owner token replaces body keeping key", func(t *testing.T) {
+		t.Parallel()
+
+		postResp, err := ts.post("/?disposable=2", "password")
+		require.NoError(t, err)
+		require.Equal(t, http.StatusCreated, postResp.StatusCode)
+		token := postResp.Header.Get("X-Owner-Token")
+		gotURL := mustReadBody(t, postResp.Body)
+
+		getResp, err := http.Get(gotURL)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, getResp.StatusCode)
+
+		resp := patchKey(t, gotURL+"?disposable=1", token, "new password")
+		assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+
+		clicksResp, err := http.Get(gotURL + "clicks/")
+		require.NoError(t, err)
+		assert.Equal(t, "1", mustReadBody(t, clicksResp.Body))
+
+		getResp, err = http.Get(gotURL)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusOK, getResp.StatusCode)
+		assert.Equal(t, "new password", mustReadBody(t, getResp.Body))
+
+		getResp, err = http.Get(gotURL)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusNotFound, getResp.StatusCode)
+	})
+
+	t.Run("update switches url flag", func(t *testing.T) {
+		t.Parallel()
+
+		postResp, err := ts.post("/", "https://example.com/")
+		require.NoError(t, err)
+		require.Equal(t, http.StatusCreated, postResp.StatusCode)
+		token := postResp.Header.Get("X-Owner-Token")
+		gotURL := mustReadBody(t, postResp.Body)
+
+		resp := patchKey(t, gotURL+"?url=true", token, "")
+		assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+
+		client := &http.Client{
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		}
+		getResp, err := client.Get(gotURL)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusSeeOther, getResp.StatusCode)
+		assert.Equal(t, "https://example.com/", getResp.Header.Get("Location"))
+	})
+
+	t.Run("url can not be updated to url not allowed by policy", func(t *testing.T) {
+		t.Parallel()
+		postResp, err := ts.post("/?url=true", "https://example.com/")
+		require.NoError(t, err)
+		require.Equal(t, http.StatusCreated, postResp.StatusCode)
+		token := postResp.Header.Get("X-Owner-Token")
+		gotURL := mustReadBody(t, postResp.Body)
+
+		resp := patchKey(t, gotURL, token, "https://blocked.example/")
+		assert.Equal(t, http.StatusUnprocessableEntity, resp.StatusCode)
+
+		resp = patchKey(t, gotURL, token, gotURL)
+		assert.Equal(t, http.StatusUnprocessableEntity, resp.StatusCode)
+
+		resp, err = noRedirectGet(gotURL)
+		require.NoError(t, err)
+		assert.Equal(t, "https://example.com/", resp.Header.Get("Location"))
+	})
+
+	t.Run("update is validated and authorized", func(t *testing.T) {
+		t.Parallel()
+
+		postResp, err := ts.post("/", "password")
+		require.NoError(t, err)
+		require.Equal(t, http.StatusCreated, postResp.StatusCode)
+		token := postResp.Header.Get("X-Owner-Token")
+		gotURL := mustReadBody(t, postResp.Body)
+
+		resp := patchKey(t, gotURL+"?ttl=0", token, "")
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+		resp = patchKey(t, gotURL+"?url=true", token, "")
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+		resp = patchKey(t, gotURL+"?sliding=true", token, "")
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+		resp = patchKey(t, gotURL, "wrong", "new password")
+		assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+		resp = patchKey(t, gotURL, "", "new password")
+		assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+
+		getResp, err := http.Get(gotURL)
+		require.NoError(t, err)
+		assert.Equal(t, "password", mustReadBody(t, getResp.Body))
+	})
+}
+
+func TestGetContentType(t *testing.T) {
+	ts := setupTestServer(t)
+
+	t.Run("get returns content type declared by type parameter", func(t *testing.T) {
+		t.Parallel()
+
+		postResp, err := ts.post("/?type=application/yaml", "key: value")
+		require.NoError(t, err)
+		require.Equal(t, http.StatusCreated, postResp.StatusCode)
+
+		getResp, err := http.Get(mustReadBody(t, postResp.Body))
+		require.NoError(t, err)
+
+		assert.Equal(t, "application/yaml", getResp.Header.Get("Content-Type"))
+	})
+
+	t.Run("get returns content type declared by request header", func(t *testing.T) {
+		t.Parallel()
+
+		postResp, err := http.Post(ts.URL+"/", "application/json", strings.NewReader(`{"key": "value"}`))
+		require.NoError(t, err)
+		require.Equal(t, http.StatusCreated, postResp.StatusCode)
+
+		getResp, err := http.Get(mustReadBody(t, postResp.Body))
+		require.NoError(t, err)
+
+		assert.Equal(t, "application/json", getResp.Header.Get("Content-Type"))
+	})
+
+	t.Run("form content type of request is not declared", func(t *testing.T) {
+		t.Parallel()
+
+		postResp, err := http.Post(ts.URL+"/", "application/x-www-form-urlencoded", strings.NewReader("text"))
+		require.NoError(t, err)
+		require.Equal(t, http.StatusCreated, postResp.StatusCode)
+
+		getResp, err := http.Get(mustReadBody(t, postResp.Body))
+		require.NoError(t, err)
+
+		assert.Equal(t, "text/plain; charset=utf-8", getResp.Header.Get("Content-Type"))
+	})
+
+	t.Run("get returns filename and content type by its extension", func(t *testing.T) {
+		t.Parallel()
+
+		postResp, err := http.Post(ts.URL+"/?filename=data.json", "application/x-www-form-urlencoded", strings.NewReader("{}"))
+		require.NoError(t, err)
+		require.Equal(t, http.StatusCreated, postResp.StatusCode)
+
+		getResp, err := http.Get(mustReadBody(t, postResp.Body))
+		require.NoError(t, err)
+
+		assert.Equal(t, "application/json", getResp.Header.Get("Content-Type"))
+		assert.Equal(t, `inline; filename=data.json`, getResp.Header.Get("Content-Disposition"))
+	})
+
+	t.Run("cache with invalid type or filename returns 400", func(t *testing.T) {
+		t.Parallel()
+
+		for _, query := range []string{"type=json", "type=text/", "filename=../etc/passwd", "filename=.."} {
+			resp, err := ts.post("/?"+query, "body")
+			require.NoError(t, err)
+			assert.Equal(t, http.StatusBadRequest, resp.StatusCode, query)
+		}
+	})
+}
+
+func TestGetEncoding(t *testing.T) {
+	ts := setupTestServer(t)
+
+	largeBody := strings.Repeat("large body ", int(config.DefaultCachingConfig{}.CompressThresholdBytes()))
+
+	t.Run("get with accepted gzip returns stored gzip body as is", func(t *testing.T) {
+		t.Parallel()
+
+		postResp, err := ts.post("/", largeBody)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusCreated, postResp.StatusCode)
+		gotURL := mustReadBody(t, postResp.Body)
+
+		req, err := http.NewRequest(http.MethodGet, gotURL, nil)
+		require.NoError(t, err)
+		req.Header.Set("Accept-Encoding", "gzip")
+
+		// transport does not decompress response if request has own Accept-Encoding
+		getResp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer getResp.Body.Close()
+
+		assert.Equal(t, "gzip", getResp.Header.Get("Content-Encoding"))
+		assert.Equal(t, "text/plain; charset=utf-8", getResp.Header.Get("Content-Type"))
+
+		gz, err := gzip.NewReader(getResp.Body)
+		require.NoError(t, err)
+		assert.Equal(t, largeBody, mustReadBody(t, gz))
+	})
+
+	t.Run("get without accepted encoding returns decoded body", func(t *testing.T) {
+		t.Parallel()
+
+		postResp, err := ts.post("/", largeBody)
+		require.NoError(t, err)
+		gotURL := mustReadBody(t, postResp.Body)
+
+		req, err := http.NewRequest(http.MethodGet, gotURL, nil)
+		require.NoError(t, err)
+		req.Header.Set("Accept-Encoding", "identity")
+
+		getResp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+
+		assert.Empty(t, getResp.Header.Get("Content-Encoding"))
+		assert.Equal(t, largeBody, mustReadBody(t, getResp.Body))
+	})
+}
+
 func TestGetClicks(t *testing.T) {
 	ts := setupTestServer(t)
 
@@ -150,49 +534,1053 @@ func TestGetClicks(t *testing.T) {
 			strconv.Itoa(expectedRequests), mustReadBody(t, clicksResp.Body),
 		)
 	})
-}
-
-func TestCacheDisposable(t *testing.T) {
-	ts := setupTestServer(t)
 
-	t.Run("disposable record will be removed after expected number of get requests", func(t *testing.T) {
+	t.Run("get by bot is not counted in clicks", func(t *testing.T) {
 		t.Parallel()
-		const disposableCount = 3
-		postResp, err := ts.post(fmt.Sprintf("/?disposable=%d", disposableCount), "test body")
+		postResp, err := ts.post("/", "test body")
 		require.NoError(t, err)
 		gotURL := mustReadBody(t, postResp.Body)
 
-		for range disposableCount {
-			resp, err := http.Get(gotURL)
-			require.NoError(t, err)
-			require.Equal(t,
-				http.StatusOK, resp.StatusCode,
-				"shoudn`t be removed yet",
-			)
-		}
+		req, err := http.NewRequest(http.MethodGet, gotURL, nil)
+		require.NoError(t, err)
+		req.Header.Set("User-Agent", "Googlebot/2.1 (+http://www.google.com/bot.html)")
 
-		resp, err := http.Get(gotURL)
+		resp, err := http.DefaultClient.Do(req)
 		require.NoError(t, err)
-		assert.Equal(t,
-			http.StatusNotFound, resp.StatusCode,
-			"should be removed",
-		)
+		assert.Equal(t, "test body", mustReadBody(t, resp.Body))
+
+		clicksResp, err := http.Get(gotURL + "/clicks/")
+		require.NoError(t, err)
+		assert.Equal(t, "0", mustReadBody(t, clicksResp.Body))
 	})
 }
 
-func TestCacheURL(t *testing.T) {
+func TestView(t *testing.T) {
 	ts := setupTestServer(t)
 
-	t.Run("get cached url redirects to expected location", func(t *testing.T) {
+	t.Run("view renders highlighted html with line anchors", func(t *testing.T) {
 		t.Parallel()
-		expectedURL := "https://example.com"
-		postResp, err := ts.post("/?url=true", expectedURL)
+		postResp, err := ts.post("/?filename=main.go", "package main\n\nfunc main() { println(\"<b>\") }\n")
 		require.NoError(t, err)
 		gotURL := mustReadBody(t, postResp.Body)
 
-		resp, err := noRedirectGet(gotURL)
+		viewResp, err := http.Get(gotURL + "view/")
 		require.NoError(t, err)
-		assert.Equal(t, http.StatusSeeOther, resp.StatusCode)
-		assert.Equal(t, expectedURL, resp.Header.Get("Location"))
+		require.Equal(t, http.StatusOK, viewResp.StatusCode)
+		assert.Equal(t, "text/html; charset=utf-8", viewResp.Header.Get("Content-Type"))
+
+		body := mustReadBody(t, viewResp.Body)
+		assert.Contains(t, body, `id="L3"`)
+		assert.Contains(t, body, "&lt;b&gt;")
+		assert.NotContains(t, body, "<b>\"")
+		assert.Contains(t, body, ">Go<")
+		assert.Contains(t, body, ">Raw<")
+		assert.NotContains(t, body, "data:", "body is not embedded in page twice")
+
+		key := strings.Trim(strings.TrimPrefix(gotURL, ts.URL), "/")
+		downloadURL := "/" + key + "/?download=1"
+		assert.Contains(t, body, `href="`+downloadURL+`"`)
+
+		downloadResp, err := http.Get(ts.URL + downloadURL)
+		require.NoError(t, err)
+		assert.Equal(t, `attachment; filename=main.go`, downloadResp.Header.Get("Content-Disposition"))
+		assert.Equal(t, "package main\n\nfunc main() { println(\"<b>\") }\n", mustReadBody(t, downloadResp.Body))
+	})
+
+	t.Run("view consumes disposable record", func(t *testing.T) {
+		t.Parallel()
+		postResp, err := ts.post("/?disposable=1", "secret")
+		require.NoError(t, err)
+		gotURL := mustReadBody(t, postResp.Body)
+
+		viewResp, err := http.Get(gotURL + "view/")
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, viewResp.StatusCode)
+		viewBody := mustReadBody(t, viewResp.Body)
+		assert.NotContains(t, viewBody, ">Raw<", "raw link is useless for exhausted record")
+		assert.NotContains(t, viewBody, ">Download<")
+
+		getResp, err := http.Get(gotURL)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusNotFound, getResp.StatusCode)
+	})
+}
+
+func TestLinkPreview(t *testing.T) {
+	ts := setupTestServer(t)
+
+	previewGet := func(t *testing.T, url string) *http.Response {
+		t.Helper()
+		req, err := http.NewRequest(http.MethodGet, url, nil)
+		require.NoError(t, err)
+		req.Header.Set("User-Agent", "Slackbot-LinkExpanding 1.0 (+https://api.slack.com/robots)")
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		return resp
+	}
+
+	t.Run("preview bot and head request do not consume disposable record", func(t *testing.T) {
+		t.Parallel()
+		postResp, err := ts.post("/?disposable=1", "secret")
+		require.NoError(t, err)
+		gotURL := mustReadBody(t, postResp.Body)
+
+		previewResp := previewGet(t, gotURL)
+		require.Equal(t, http.StatusOK, previewResp.StatusCode)
+		assert.NotContains(t, mustReadBody(t, previewResp.Body), "secret")
+
+		headResp, err := http.Head(gotURL)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, headResp.StatusCode)
+
+		getResp, err := http.Get(gotURL)
+		require.NoError(t, err)
+		assert.Equal(t, "secret", mustReadBody(t, getResp.Body))
+	})
+
+	t.Run("preview bot gets not found for missing record", func(t *testing.T) {
+		t.Parallel()
+		previewResp := previewGet(t, ts.URL+"/missing-key/")
+		assert.Equal(t, http.StatusNotFound, previewResp.StatusCode)
+	})
+
+	t.Run("preview bot reads unlimited record", func(t *testing.T) {
+		t.Parallel()
+		postResp, err := ts.post("/", "public")
+		require.NoError(t, err)
+		gotURL := mustReadBody(t, postResp.Body)
+
+		previewResp := previewGet(t, gotURL)
+		assert.Equal(t, "public", mustReadBody(t, previewResp.Body))
+	})
+
+	t.Run("reveal record is consumed only by post request", func(t *testing.T) {
+		t.Parallel()
+		postResp, err := ts.post("/?reveal=true", "secret")
+		require.NoError(t, err)
+		gotURL := mustReadBody(t, postResp.Body)
+
+		getResp, err := http.Get(gotURL)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusPreconditionRequired, getResp.StatusCode)
+
+		req, err := http.NewRequest(http.MethodGet, gotURL, nil)
+		require.NoError(t, err)
+		req.Header.Set("Accept", "text/html")
+		htmlResp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, htmlResp.StatusCode)
+		body := mustReadBody(t, htmlResp.Body)
+		assert.Contains(t, body, `method="post"`)
+		assert.NotContains(t, body, "secret")
+
+		revealResp, err := http.Post(gotURL, "", nil)
+		require.NoError(t, err)
+		assert.Equal(t, "secret", mustReadBody(t, revealResp.Body))
+
+		infoResp, err := http.Get(gotURL + "info/")
+		require.NoError(t, err)
+		assert.Contains(t, mustReadBody(t, infoResp.Body), `"clicks":1`)
+	})
+}
+
+func TestCacheForm(t *testing.T) {
+	ts := setupTestServer(t)
+
+	t.Run("multipart file part is content with its filename", func(t *testing.T) {
+		t.Parallel()
+
+		var form bytes.Buffer
+		writer := multipart.NewWriter(&form)
+		require.NoError(t, writer.WriteField("disposable", "2"))
+		part, err := writer.CreateFormFile("file", "data.json")
+		require.NoError(t, err)
+		_, err = part.Write([]byte(`{"key": "value"}`))
+		require.NoError(t, err)
+		require.NoError(t, writer.Close())
+
+		postResp, err := http.Post(ts.URL+"/", writer.FormDataContentType(), &form)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusCreated, postResp.StatusCode)
+		gotURL := mustReadBody(t, postResp.Body)
+
+		getResp, err := http.Get(gotURL)
+		require.NoError(t, err)
+		assert.Equal(t, `{"key": "value"}`, mustReadBody(t, getResp.Body))
+		assert.Equal(t, "application/json", getResp.Header.Get("Content-Type"))
+		assert.Equal(t, "inline; filename=data.json", getResp.Header.Get("Content-Disposition"))
+		assert.Equal(t, "1", getResp.Header.Get("X-Remaining-Reads"))
+	})
+
+	t.Run("urlencoded content field overrides query options", func(t *testing.T) {
+		t.Parallel()
+
+		form := url.Values{}
+		form.Set("content", "Hello & bye")
+		form.Set("disposable", "1")
+
+		postResp, err := http.PostForm(ts.URL+"/?disposable=5", form)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusCreated, postResp.StatusCode)
+		gotURL := mustReadBody(t, postResp.Body)
+
+		getResp, err := http.Get(gotURL)
+		require.NoError(t, err)
+		assert.Equal(t, "Hello & bye", mustReadBody(t, getResp.Body))
+
+		getResp, err = http.Get(gotURL)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusNotFound, getResp.StatusCode)
+	})
+
+	t.Run("urlencoded body without content field is content", func(t *testing.T) {
+		t.Parallel()
+
+		postResp, err := http.Post(ts.URL+"/", "application/x-www-form-urlencoded", strings.NewReader("key=value"))
+		require.NoError(t, err)
+		require.Equal(t, http.StatusCreated, postResp.StatusCode)
+		gotURL := mustReadBody(t, postResp.Body)
+
+		getResp, err := http.Get(gotURL)
+		require.NoError(t, err)
+		assert.Equal(t, "key=value", mustReadBody(t, getResp.Body))
+	})
+
+	t.Run("password form field protects content", func(t *testing.T) {
+		t.Parallel()
+
+		urlencoded := url.Values{}
+		urlencoded.Set("content", "secret")
+		urlencoded.Set("password", "pw")
+
+		var multipartForm bytes.Buffer
+		writer := multipart.NewWriter(&multipartForm)
+		require.NoError(t, writer.WriteField("content", "secret"))
+		require.NoError(t, writer.WriteField("password", "pw"))
+		require.NoError(t, writer.Close())
+
+		for contentType, form := range map[string]io.Reader{
+			"application/x-www-form-urlencoded": strings.NewReader(urlencoded.Encode()),
+			writer.FormDataContentType():        &multipartForm,
+		} {
+			postResp, err := http.Post(ts.URL+"/", contentType, form)
+			require.NoError(t, err)
+			require.Equal(t, http.StatusCreated, postResp.StatusCode, contentType)
+			gotURL := mustReadBody(t, postResp.Body)
+
+			getResp, err := http.Get(gotURL)
+			require.NoError(t, err)
+			assert.Equal(t, http.StatusUnauthorized, getResp.StatusCode, contentType)
+			assert.NotContains(t, mustReadBody(t, getResp.Body), "secret", contentType)
+
+			req, err := http.NewRequest(http.MethodGet, gotURL, nil)
+			require.NoError(t, err)
+			req.Header.Set("X-Paste-Password", "pw")
+			getResp, err = http.DefaultClient.Do(req)
+			require.NoError(t, err)
+			assert.Equal(t, "secret", mustReadBody(t, getResp.Body), contentType)
+		}
+	})
+
+	t.Run("form without content returns 400", func(t *testing.T) {
+		t.Parallel()
+
+		var form bytes.Buffer
+		writer := multipart.NewWriter(&form)
+		require.NoError(t, writer.WriteField("ttl", "1h"))
+		require.NoError(t, writer.Close())
+
+		postResp, err := http.Post(ts.URL+"/", writer.FormDataContentType(), &form)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusBadRequest, postResp.StatusCode)
+	})
+
+	t.Run("urlencoded form larger than limit returns 413", func(t *testing.T) {
+		t.Parallel()
+
+		// content fits limit, but its encoding does not
+		form := url.Values{}
+		form.Set("content", strings.Repeat("%", int(config.DefaultCacheValidationConfig{}.UnprivilegedMaxBodySize())/2))
+
+		postResp, err := http.Post(ts.URL+"/", "application/x-www-form-urlencoded", strings.NewReader(form.Encode()))
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusRequestEntityTooLarge, postResp.StatusCode)
+	})
+
+	t.Run("browser form post gets page with link", func(t *testing.T) {
+		t.Parallel()
+
+		form := url.Values{}
+		form.Set("content", "Hello")
+		form.Set("disposable", "1")
+
+		req, err := http.NewRequest(http.MethodPost, ts.URL+"/", strings.NewReader(form.Encode()))
+		require.NoError(t, err)
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.Header.Set("Accept", "text/html")
+		postResp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusCreated, postResp.StatusCode)
+		assert.Contains(t, postResp.Header.Get("Content-Type"), "text/html")
+		page := mustReadBody(t, postResp.Body)
+		assert.Contains(t, page, ts.URL+"/")
+		ownerToken := postResp.Header.Get("X-Owner-Token")
+		require.NotEmpty(t, ownerToken)
+		assert.Contains(t, page, ownerToken)
+	})
+}
+
+func TestBundle(t *testing.T) {
+	ts := setupTestServer(t)
+
+	newBundleForm := func(t *testing.T, disposable string) (string, *bytes.Buffer) {
+		t.Helper()
+
+		var form bytes.Buffer
+		writer := multipart.NewWriter(&form)
+		if disposable != "" {
+			require.NoError(t, writer.WriteField("disposable", disposable))
+		}
+		part, err := writer.CreateFormFile("file", "config.yaml")
+		require.NoError(t, err)
+		_, err = part.Write([]byte("key: value"))
+		require.NoError(t, err)
+		part, err = writer.CreateFormFile("file", "app.log")
+		require.NoError(t, err)
+		_, err = part.Write([]byte("started"))
+		require.NoError(t, err)
+		require.NoError(t, writer.Close())
+
+		return writer.FormDataContentType(), &form
+	}
+
+	t.Run("multipart with several files is bundle", func(t *testing.T) {
+		t.Parallel()
+
+		contentType, form := newBundleForm(t, "")
+		postResp, err := http.Post(ts.URL+"/", contentType, form)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusCreated, postResp.StatusCode)
+		gotURL := mustReadBody(t, postResp.Body)
+
+		listResp, err := http.Get(gotURL)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, listResp.StatusCode)
+		assert.Equal(t, gotURL+"config.yaml\n"+gotURL+"app.log\n", mustReadBody(t, listResp.Body))
+
+		fileResp, err := http.Get(gotURL + "app.log")
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, fileResp.StatusCode)
+		assert.Equal(t, "started", mustReadBody(t, fileResp.Body))
+		assert.Equal(t, "inline; filename=app.log", fileResp.Header.Get("Content-Disposition"))
+
+		archiveResp, err := http.Get(gotURL + "archive.zip")
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, archiveResp.StatusCode)
+		assert.Equal(t, "application/zip", archiveResp.Header.Get("Content-Type"))
+
+		archive := []byte(mustReadBody(t, archiveResp.Body))
+		zr, err := zip.NewReader(bytes.NewReader(archive), int64(len(archive)))
+		require.NoError(t, err)
+		require.Len(t, zr.File, 2)
+		assert.Equal(t, "config.yaml", zr.File[0].Name)
+		assert.Equal(t, "app.log", zr.File[1].Name)
+	})
+
+	t.Run("disposable is counted for whole bundle", func(t *testing.T) {
+		t.Parallel()
+
+		contentType, form := newBundleForm(t, "2")
+		postResp, err := http.Post(ts.URL+"/", contentType, form)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusCreated, postResp.StatusCode)
+		gotURL := mustReadBody(t, postResp.Body)
+
+		listResp, err := http.Get(gotURL)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusOK, listResp.StatusCode)
+
+		fileResp, err := http.Get(gotURL + "config.yaml")
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusOK, fileResp.StatusCode)
+		assert.Equal(t, "1", fileResp.Header.Get("X-Remaining-Reads"))
+
+		archiveResp, err := http.Get(gotURL + "archive.zip")
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusOK, archiveResp.StatusCode)
+
+		fileResp, err = http.Get(gotURL + "app.log")
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusNotFound, fileResp.StatusCode)
+	})
+
+	t.Run("tar archive upload is bundle", func(t *testing.T) {
+		t.Parallel()
+
+		var archive bytes.Buffer
+		tw := tar.NewWriter(&archive)
+		content := []byte("package main")
+		require.NoError(t, tw.WriteHeader(&tar.Header{Name: "src/main.go", Typeflag: tar.TypeReg, Mode: 0o644, Size: int64(len(content))}))
+		_, err := tw.Write(content)
+		require.NoError(t, err)
+		require.NoError(t, tw.Close())
+
+		postResp, err := http.Post(ts.URL+"/?bundle=true", "application/x-tar", &archive)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusCreated, postResp.StatusCode)
+		gotURL := mustReadBody(t, postResp.Body)
+
+		fileResp, err := http.Get(gotURL + "src/main.go")
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, fileResp.StatusCode)
+		assert.Equal(t, "package main", mustReadBody(t, fileResp.Body))
+	})
+
+	t.Run("not archive bundle returns 400", func(t *testing.T) {
+		t.Parallel()
+
+		postResp, err := http.Post(ts.URL+"/?bundle=true", "text/plain", strings.NewReader("plain text"))
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusBadRequest, postResp.StatusCode)
+	})
+
+	t.Run("file of plain record returns 404", func(t *testing.T) {
+		t.Parallel()
+
+		postResp, err := http.Post(ts.URL+"/", "text/plain", strings.NewReader("plain text"))
+		require.NoError(t, err)
+		require.Equal(t, http.StatusCreated, postResp.StatusCode)
+		gotURL := mustReadBody(t, postResp.Body)
+
+		fileResp, err := http.Get(gotURL + "config.yaml")
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusNotFound, fileResp.StatusCode)
+
+		getResp, err := http.Get(gotURL)
+		require.NoError(t, err)
+		assert.Equal(t, "plain text", mustReadBody(t, getResp.Body))
+	})
+}
+
+func TestCacheJSON(t *testing.T) {
+	ts := setupTestServer(t)
+
+	t.Run("json client gets created record description", func(t *testing.T) {
+		t.Parallel()
+
+		req, err := http.NewRequest(http.MethodPost, ts.URL+"/?disposable=2&ttl=1h", strings.NewReader("Hello"))
+		require.NoError(t, err)
+		req.Header.Set("Accept", "application/json")
+
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusCreated, resp.StatusCode)
+		assert.Equal(t, "application/json", resp.Header.Get("Content-Type"))
+
+		var created struct {
+			Key            string    `json:"key"`
+			URL            string    `json:"url"`
+			ExpiresAt      time.Time `json:"expires_at"`
+			Eternal        bool      `json:"eternal"`
+			RemainingReads int       `json:"remaining_reads"`
+			KeyLength      int       `json:"key_length"`
+			Privileged     bool      `json:"privileged"`
+			OwnerToken     string    `json:"owner_token"`
+		}
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&created))
+
+		assert.Equal(t, ts.URL+"/"+created.Key+"/", created.URL)
+		assert.Len(t, created.Key, created.KeyLength)
+		assert.WithinDuration(t, time.Now().Add(time.Hour), created.ExpiresAt, time.Minute)
+		assert.False(t, created.Eternal)
+		assert.Equal(t, 2, created.RemainingReads)
+		assert.False(t, created.Privileged)
+		assert.Equal(t, resp.Header.Get("X-Owner-Token"), created.OwnerToken)
+
+		getResp, err := http.Get(created.URL)
+		require.NoError(t, err)
+		assert.Equal(t, "Hello", mustReadBody(t, getResp.Body))
+	})
+
+	t.Run("json client gets problem details on error", func(t *testing.T) {
+		t.Parallel()
+
+		req, err := http.NewRequest(http.MethodPost, ts.URL+"/?ttl=invalid", strings.NewReader("Hello"))
+		require.NoError(t, err)
+		req.Header.Set("Accept", "application/json")
+
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+		assert.Equal(t, "application/problem+json", resp.Header.Get("Content-Type"))
+
+		var problem struct {
+			Type   string `json:"type"`
+			Title  string `json:"title"`
+			Status int    `json:"status"`
+			Detail string `json:"detail"`
+			Code   string `json:"code"`
+		}
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&problem))
+
+		assert.Equal(t, "about:blank", problem.Type)
+		assert.Equal(t, "Bad Request", problem.Title)
+		assert.Equal(t, http.StatusBadRequest, problem.Status)
+		assert.Equal(t, "Invalid 'ttl' parameter", problem.Detail)
+		assert.Equal(t, "invalid_request", problem.Code)
+	})
+
+	t.Run("domain error has its code", func(t *testing.T) {
+		t.Parallel()
+
+		req, err := http.NewRequest(http.MethodPost, ts.URL+"/?apikey=nonexistent", strings.NewReader("Hello"))
+		require.NoError(t, err)
+		req.Header.Set("Accept", "application/problem+json")
+
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+
+		var problem struct {
+			Code string `json:"code"`
+		}
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&problem))
+		assert.Equal(t, "apikey_not_found", problem.Code)
+	})
+
+	t.Run("plain client gets plain error", func(t *testing.T) {
+		t.Parallel()
+
+		resp, err := http.Post(ts.URL+"/?ttl=invalid", "text/plain", strings.NewReader("Hello"))
+		require.NoError(t, err)
+		require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+		assert.Equal(t, "Invalid 'ttl' parameter", mustReadBody(t, resp.Body))
+	})
+}
+
+func TestConditionalGet(t *testing.T) {
+	ts := setupTestServer(t)
+
+	t.Run("record readable again has etag and serves ranges", func(t *testing.T) {
+		t.Parallel()
+
+		postResp, err := http.Post(ts.URL+"/?ttl=1h", "text/plain", strings.NewReader("0123456789"))
+		require.NoError(t, err)
+		require.Equal(t, http.StatusCreated, postResp.StatusCode)
+		gotURL := mustReadBody(t, postResp.Body)
+
+		getResp, err := http.Get(gotURL)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, getResp.StatusCode)
+		etag := getResp.Header.Get("ETag")
+		require.NotEmpty(t, etag)
+		assert.Equal(t, "bytes", getResp.Header.Get("Accept-Ranges"))
+		assert.Equal(t, "public, max-age=600, must-revalidate", getResp.Header.Get("Cache-Control"))
+		assert.Equal(t, "0123456789", mustReadBody(t, getResp.Body))
+
+		req, err := http.NewRequest(http.MethodGet, gotURL, nil)
+		require.NoError(t, err)
+		req.Header.Set("If-None-Match", etag)
+		notModifiedResp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusNotModified, notModifiedResp.StatusCode)
+
+		req, err = http.NewRequest(http.MethodGet, gotURL, nil)
+		require.NoError(t, err)
+		req.Header.Set("Range", "bytes=5-")
+		rangeResp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusPartialContent, rangeResp.StatusCode)
+		assert.Equal(t, "bytes 5-9/10", rangeResp.Header.Get("Content-Range"))
+		assert.Equal(t, "56789", mustReadBody(t, rangeResp.Body))
+	})
+
+	t.Run("disposable record is not cached and head does not consume it", func(t *testing.T) {
+		t.Parallel()
+
+		postResp, err := http.Post(ts.URL+"/?disposable=1", "text/plain", strings.NewReader("0123456789"))
+		require.NoError(t, err)
+		require.Equal(t, http.StatusCreated, postResp.StatusCode)
+		gotURL := mustReadBody(t, postResp.Body)
+
+		for range 2 {
+			headResp, err := http.Head(gotURL)
+			require.NoError(t, err)
+			assert.Equal(t, http.StatusOK, headResp.StatusCode)
+			assert.Equal(t, "1", headResp.Header.Get("X-Remaining-Reads"))
+			assert.Equal(t, "no-store", headResp.Header.Get("Cache-Control"))
+			assert.Equal(t, int64(10), headResp.ContentLength)
+		}
+
+		req, err := http.NewRequest(http.MethodGet, gotURL, nil)
+		require.NoError(t, err)
+		req.Header.Set("Range", "bytes=5-")
+		getResp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusOK, getResp.StatusCode)
+		assert.Empty(t, getResp.Header.Get("ETag"))
+		assert.Equal(t, "0123456789", mustReadBody(t, getResp.Body))
+
+		headResp, err := http.Head(gotURL)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusNotFound, headResp.StatusCode)
+	})
+
+	t.Run("head of bundle file does not consume bundle", func(t *testing.T) {
+		t.Parallel()
+
+		var form bytes.Buffer
+		writer := multipart.NewWriter(&form)
+		require.NoError(t, writer.WriteField("disposable", "1"))
+		for _, name := range []string{"a.txt", "b.txt"} {
+			part, err := writer.CreateFormFile("file", name)
+			require.NoError(t, err)
+			_, err = part.Write([]byte(name))
+			require.NoError(t, err)
+		}
+		require.NoError(t, writer.Close())
+
+		postResp, err := http.Post(ts.URL+"/", writer.FormDataContentType(), &form)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusCreated, postResp.StatusCode)
+		gotURL := mustReadBody(t, postResp.Body)
+
+		headResp, err := http.Head(gotURL + "b.txt")
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusOK, headResp.StatusCode)
+		assert.Equal(t, int64(len("b.txt")), headResp.ContentLength)
+
+		headResp, err = http.Head(gotURL + "archive.zip")
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusOK, headResp.StatusCode)
+
+		getResp, err := http.Get(gotURL + "b.txt")
+		require.NoError(t, err)
+		assert.Equal(t, "b.txt", mustReadBody(t, getResp.Body))
+	})
+}
+
+func TestSafeServing(t *testing.T) {
+	ts := setupTestServer(t)
+
+	t.Run("active content of unprivileged record is served as plain text", func(t *testing.T) {
+		t.Parallel()
+
+		bodies := map[string]string{
+			"/?type=text/html":             "<script>alert(1)</script>",
+			"/?filename=image.svg":         "<svg xmlns=\"http://www.w3.org/2000/svg\"><script>alert(1)</script></svg>",
+			"/?type=application/xml":       "<?xml version=\"1.0\"?><x/>",
+			"/?type=application/rss%2Bxml": "<rss/>",
+			"/":                            "<html><body><script>alert(1)</script></body></html>",
+		}
+
+		for path, body := range bodies {
+			// request without content type, so type is guessed by filename or content
+			postResp, err := http.DefaultClient.Do(&http.Request{
+				Method:        http.MethodPost,
+				URL:           mustParseURL(t, ts.URL+path),
+				Header:        http.Header{},
+				Body:          io.NopCloser(strings.NewReader(body)),
+				ContentLength: int64(len(body)),
+			})
+			require.NoError(t, err)
+			require.Equal(t, http.StatusCreated, postResp.StatusCode)
+			gotURL := mustReadBody(t, postResp.Body)
+
+			getResp, err := http.Get(gotURL)
+			require.NoError(t, err)
+			assert.Equal(t, "text/plain; charset=utf-8", getResp.Header.Get("Content-Type"), path)
+			assert.Equal(t, body, mustReadBody(t, getResp.Body))
+		}
+	})
+
+	t.Run("raw content has security headers", func(t *testing.T) {
+		t.Parallel()
+
+		postResp, err := http.Post(ts.URL+"/?type=application/json", "text/plain", strings.NewReader(`{"key": "value"}`))
+		require.NoError(t, err)
+		require.Equal(t, http.StatusCreated, postResp.StatusCode)
+		gotURL := mustReadBody(t, postResp.Body)
+
+		getResp, err := http.Get(gotURL)
+		require.NoError(t, err)
+		assert.Equal(t, "application/json", getResp.Header.Get("Content-Type"))
+		assert.Equal(t, "nosniff", getResp.Header.Get("X-Content-Type-Options"))
+		assert.Equal(t, "no-referrer", getResp.Header.Get("Referrer-Policy"))
+		assert.Contains(t, getResp.Header.Get("Content-Security-Policy"), "default-src 'none'")
+		assert.Contains(t, getResp.Header.Get("Content-Security-Policy"), "sandbox")
+	})
+
+	t.Run("pages rendered from record have page policy", func(t *testing.T) {
+		t.Parallel()
+
+		getPage := func(t *testing.T, url string) *http.Response {
+			t.Helper()
+
+			req, err := http.NewRequest(http.MethodGet, url, nil)
+			require.NoError(t, err)
+			req.Header.Set("Accept", "text/html")
+
+			resp, err := http.DefaultClient.Do(req)
+			require.NoError(t, err)
+			return resp
+		}
+
+		postResp, err := ts.post("/", "<b>text</b>")
+		require.NoError(t, err)
+		viewURL := mustReadBody(t, postResp.Body) + "view/"
+
+		postResp, err = ts.post("/?encrypted=true", "ciphertext of nonce and sealed text")
+		require.NoError(t, err)
+		decryptURL := mustReadBody(t, postResp.Body)
+
+		req, err := http.NewRequest(http.MethodPost, ts.URL+"/", strings.NewReader("secret"))
+		require.NoError(t, err)
+		req.Header.Set("X-Paste-Password", "pw")
+		postResp, err = http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		passwordURL := mustReadBody(t, postResp.Body)
+
+		postResp, err = ts.post("/?reveal=true", "secret")
+		require.NoError(t, err)
+		revealURL := mustReadBody(t, postResp.Body)
+
+		for _, pageURL := range []string{viewURL, decryptURL, passwordURL, revealURL} {
+			resp := getPage(t, pageURL)
+			body := mustReadBody(t, resp.Body)
+			assert.Equal(t, "text/html; charset=utf-8", resp.Header.Get("Content-Type"), pageURL)
+
+			policy := resp.Header.Get("Content-Security-Policy")
+			assert.Contains(t, policy, "default-src 'none'", pageURL)
+			assert.Contains(t, policy, "form-action 'self'", pageURL)
+			assert.Contains(t, policy, "script-src ", pageURL)
+
+			// inline scripts of page are allowed by their hashes
+			for _, script := range regexp.MustCompile(`(?s)<script>(.*?)</script>`).FindAllStringSubmatch(body, -1) {
+				sum := sha256.Sum256([]byte(script[1]))
+				assert.Contains(t, policy, "'sha256-"+base64.StdEncoding.EncodeToString(sum[:])+"'", pageURL)
+			}
+		}
+
+		assert.Contains(t, mustReadBody(t, getPage(t, decryptURL).Body), "<script>", "decrypt page script is checked")
+		assert.Contains(t, mustReadBody(t, getPage(t, revealURL).Body), "<script>", "reveal page script is checked")
+	})
+
+	t.Run("url redirect does not send referrer", func(t *testing.T) {
+		t.Parallel()
+
+		postResp, err := http.Post(ts.URL+"/?url=true", "text/plain", strings.NewReader("https://example.com/"))
+		require.NoError(t, err)
+		require.Equal(t, http.StatusCreated, postResp.StatusCode)
+		gotURL := mustReadBody(t, postResp.Body)
+
+		client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+		getResp, err := client.Get(gotURL)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusSeeOther, getResp.StatusCode)
+		assert.Equal(t, "no-referrer", getResp.Header.Get("Referrer-Policy"))
+	})
+}
+
+func mustParseURL(t *testing.T, rawURL string) *url.URL {
+	t.Helper()
+
+	u, err := url.Parse(rawURL)
+	require.NoError(t, err)
+	return u
+}
+
+func TestGetInfo(t *testing.T) {
+	ts := setupTestServer(t)
+
+	t.Run("info does not consume disposable record", func(t *testing.T) {
+		t.Parallel()
+		postResp, err := ts.post("/?disposable=1&ttl=1h", "test body")
+		require.NoError(t, err)
+		gotURL := mustReadBody(t, postResp.Body)
+
+		for range 3 {
+			infoResp, err := http.Get(gotURL + "/info/")
+			require.NoError(t, err)
+			require.Equal(t, http.StatusOK, infoResp.StatusCode)
+			assert.Equal(t, "application/json", infoResp.Header.Get("Content-Type"))
+
+			var info struct {
+				ExpiresAt      time.Time `json:"expires_at"`
+				TTLSeconds     int64     `json:"ttl_seconds"`
+				Eternal        bool      `json:"eternal"`
+				RemainingReads *uint8    `json:"remaining_reads"`
+				Clicks         uint32    `json:"clicks"`
+				BodySize       int64     `json:"body_size"`
+				URL            bool      `json:"url"`
+			}
+			require.NoError(t, json.NewDecoder(infoResp.Body).Decode(&info))
+			require.NoError(t, infoResp.Body.Close())
+
+			assert.False(t, info.Eternal)
+			assert.WithinDuration(t, time.Now().Add(time.Hour), info.ExpiresAt, time.Minute)
+			assert.InDelta(t, time.Hour.Seconds(), info.TTLSeconds, 60)
+			require.NotNil(t, info.RemainingReads)
+			assert.Equal(t, uint8(1), *info.RemainingReads)
+			assert.Equal(t, uint32(0), info.Clicks)
+			assert.Equal(t, int64(len("test body")), info.BodySize)
+			assert.False(t, info.URL)
+		}
+
+		getResp, err := http.Get(gotURL)
+		require.NoError(t, err)
+		assert.Equal(t, "test body", mustReadBody(t, getResp.Body))
+		assert.Equal(t, "0", getResp.Header.Get("X-Remaining-Reads"))
+		assert.NotEmpty(t, getResp.Header.Get("X-Expires-At"))
+
+		infoResp, err := http.Get(gotURL + "/info/")
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusNotFound, infoResp.StatusCode)
+	})
+
+	t.Run("info of unlimited record omits limits", func(t *testing.T) {
+		t.Parallel()
+		postResp, err := ts.post("/", "test body")
+		require.NoError(t, err)
+		gotURL := mustReadBody(t, postResp.Body)
+
+		getResp, err := http.Get(gotURL)
+		require.NoError(t, err)
+		assert.Empty(t, getResp.Header.Get("X-Remaining-Reads"))
+
+		infoResp, err := http.Get(gotURL + "/info/")
+		require.NoError(t, err)
+		body := mustReadBody(t, infoResp.Body)
+		assert.NotContains(t, body, "remaining_reads")
+		assert.Contains(t, body, `"clicks":1`)
+	})
+}
+
+func TestCacheDisposable(t *testing.T) {
+	ts := setupTestServer(t)
+
+	t.Run("disposable record will be removed after expected number of get requests", func(t *testing.T) {
+		t.Parallel()
+		const disposableCount = 3
+		postResp, err := ts.post(fmt.Sprintf("/?disposable=%d", disposableCount), "test body")
+		require.NoError(t, err)
+		gotURL := mustReadBody(t, postResp.Body)
+
+		for range disposableCount {
+			resp, err := http.Get(gotURL)
+			require.NoError(t, err)
+			require.Equal(t,
+				http.StatusOK, resp.StatusCode,
+				"shoudn`t be removed yet",
+			)
+		}
+
+		resp, err := http.Get(gotURL)
+		require.NoError(t, err)
+		assert.Equal(t,
+			http.StatusNotFound, resp.StatusCode,
+			"should be removed",
+		)
+	})
+}
+
+func TestCacheURL(t *testing.T) {
+	ts := setupTestServer(t)
+
+	t.Run("get cached url redirects to expected location", func(t *testing.T) {
+		t.Parallel()
+		expectedURL := "https://example.com"
+		postResp, err := ts.post("/?url=true", expectedURL)
+		require.NoError(t, err)
+		gotURL := mustReadBody(t, postResp.Body)
+
+		resp, err := noRedirectGet(gotURL)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusSeeOther, resp.StatusCode)
+		assert.Equal(t, expectedURL, resp.Header.Get("Location"))
+	})
+
+	t.Run("url redirects with chosen status forwarding query and path", func(t *testing.T) {
+		t.Parallel()
+		postResp, err := ts.post("/?url=true&redirect=301&forwardquery=true&forwardpath=true", "https://example.com/docs?lang=en")
+		require.NoError(t, err)
+		require.Equal(t, http.StatusCreated, postResp.StatusCode)
+		gotURL := mustReadBody(t, postResp.Body)
+
+		resp, err := noRedirectGet(gotURL + "guide/getting%20started?utm_source=mail")
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusMovedPermanently, resp.StatusCode)
+		assert.Equal(t, "https://example.com/docs/guide/getting%20started?lang=en&utm_source=mail", resp.Header.Get("Location"))
+
+		resp, err = noRedirectGet(gotURL)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusMovedPermanently, resp.StatusCode)
+		assert.Equal(t, "https://example.com/docs?lang=en", resp.Header.Get("Location"))
+
+		infoResp, err := http.Get(gotURL + "info/")
+		require.NoError(t, err)
+		var info struct {
+			Redirect struct {
+				Status       int  `json:"status"`
+				ForwardQuery bool `json:"forward_query"`
+				ForwardPath  bool `json:"forward_path"`
+			} `json:"redirect"`
+		}
+		require.NoError(t, json.NewDecoder(infoResp.Body).Decode(&info))
+		require.NoError(t, infoResp.Body.Close())
+		assert.Equal(t, http.StatusMovedPermanently, info.Redirect.Status)
+		assert.True(t, info.Redirect.ForwardQuery)
+		assert.True(t, info.Redirect.ForwardPath)
+	})
+
+	t.Run("url target template variables are replaced", func(t *testing.T) {
+		t.Parallel()
+		postResp, err := ts.post("/?url=true&redirect=308&forwardpath=true", "https://example.com/{path}/index.html?ref={key}&{query}")
+		require.NoError(t, err)
+		require.Equal(t, http.StatusCreated, postResp.StatusCode)
+		gotURL := mustReadBody(t, postResp.Body)
+		key := path.Base(gotURL)
+
+		resp, err := noRedirectGet(gotURL + "v2?utm_source=mail")
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusPermanentRedirect, resp.StatusCode)
+		assert.Equal(t, "https://example.com/v2/index.html?ref="+key+"&utm_source=mail", resp.Header.Get("Location"))
+	})
+
+	t.Run("path suffix of url not forwarding path is not found", func(t *testing.T) {
+		t.Parallel()
+		postResp, err := ts.post("/?url=true&forwardquery=true", "https://example.com/")
+		require.NoError(t, err)
+		gotURL := mustReadBody(t, postResp.Body)
+
+		resp, err := noRedirectGet(gotURL + "rest")
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+		resp, err = noRedirectGet(gotURL + "?utm_source=mail")
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusSeeOther, resp.StatusCode)
+		assert.Equal(t, "https://example.com/?utm_source=mail", resp.Header.Get("Location"))
+	})
+
+	t.Run("invalid redirect parameters are rejected", func(t *testing.T) {
+		t.Parallel()
+		for _, query := range []string{
+			"/?url=true&redirect=200",
+			"/?url=true&redirect=abc",
+			"/?url=true&forwardpath=yes",
+			"/?redirect=301",
+			"/?forwardquery=true",
+		} {
+			resp, err := ts.post(query, "https://example.com/")
+			require.NoError(t, err)
+			assert.Equal(t, http.StatusBadRequest, resp.StatusCode, query)
+		}
+	})
+
+	t.Run("urls not allowed by policy are rejected", func(t *testing.T) {
+		t.Parallel()
+		for _, target := range []string{
+			"https://blocked.example/",
+			"https://www.blocked.example/path",
+			"http://203.0.113.7/",
+			"ftp://example.com/file",
+			"gopher://example.com/",
+			ts.URL + "/another/",
+		} {
+			req, err := http.NewRequest(http.MethodPost, ts.URL+"/?url=true", strings.NewReader(target))
+			require.NoError(t, err)
+			req.Header.Set("Accept", "application/json")
+
+			resp, err := http.DefaultClient.Do(req)
+			require.NoError(t, err)
+			assert.Equal(t, http.StatusUnprocessableEntity, resp.StatusCode, target)
+
+			var problem struct {
+				Code string `json:"code"`
+			}
+			require.NoError(t, json.NewDecoder(resp.Body).Decode(&problem))
+			assert.Equal(t, "url_not_allowed", problem.Code, target)
+		}
+
+		resp, err := ts.post("/", "https://blocked.example/")
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusCreated, resp.StatusCode, "text record is not checked")
+	})
+}
+
+func TestGetStats(t *testing.T) {
+	ts := setupTestServer(t)
+
+	t.Run("stats require apikey that saved record", func(t *testing.T) {
+		t.Parallel()
+		postResp, err := ts.post("/", "test body")
+		require.NoError(t, err)
+		gotURL := mustReadBody(t, postResp.Body)
+
+		for query, expectedCode := range map[string]string{
+			"":                    "unauthorized",
+			"?apikey=nonexistent": "apikey_not_found",
+		} {
+			req, err := http.NewRequest(http.MethodGet, gotURL+"/stats/"+query, nil)
+			require.NoError(t, err)
+			req.Header.Set("Accept", "application/problem+json")
+
+			statsResp, err := http.DefaultClient.Do(req)
+			require.NoError(t, err)
+			assert.Equal(t, http.StatusUnauthorized, statsResp.StatusCode, query)
+
+			var problem struct {
+				Code string `json:"code"`
+			}
+			require.NoError(t, json.NewDecoder(statsResp.Body).Decode(&problem))
+			assert.Equal(t, expectedCode, problem.Code, query)
+		}
+	})
+
+	t.Run("stats of missing record is not found", func(t *testing.T) {
+		t.Parallel()
+		statsResp, err := http.Get(ts.URL + "/missingkey/stats/")
+		require.NoError(t, err)
+		require.NoError(t, statsResp.Body.Close())
+		assert.Equal(t, http.StatusUnauthorized, statsResp.StatusCode, "apikey is checked before record")
+	})
+
+	t.Run("getting stats does not consume record", func(t *testing.T) {
+		t.Parallel()
+		postResp, err := ts.post("/?disposable=1", "test body")
+		require.NoError(t, err)
+		gotURL := mustReadBody(t, postResp.Body)
+
+		statsResp, err := http.Get(gotURL + "/stats/")
+		require.NoError(t, err)
+		require.NoError(t, statsResp.Body.Close())
+
+		getResp, err := http.Get(gotURL)
+		require.NoError(t, err)
+		assert.Equal(t, "test body", mustReadBody(t, getResp.Body))
+	})
+}
+
+func TestMemoryStorageAPIKeys(t *testing.T) {
+	t.Run("memory storage accepts apikeys given by options", func(t *testing.T) {
+		t.Parallel()
+		ctx, cancel := context.WithCancel(context.Background())
+		t.Cleanup(cancel)
+
+		repositories := newMemoryStorage(ctx, &pasteOptions{APIKeys: []string{"devkey"}}, TestQuotaConfig{})
+
+		apikey, err := repositories.apikeys.GetByID(ctx, "devkey")
+		require.NoError(t, err)
+		assert.True(t, apikey.Valid())
+
+		_, err = repositories.apikeys.GetByID(ctx, "another")
+		require.Error(t, err)
+	})
+
+	t.Run("apikey option is rejected by another storage", func(t *testing.T) {
+		t.Parallel()
+		_, err := newStorage(context.Background(), &pasteOptions{Storage: "redis", APIKeys: []string{"devkey"}}, TestQuotaConfig{})
+		require.Error(t, err)
 	})
 }
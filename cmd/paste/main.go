@@ -11,6 +11,7 @@ var usageMessage = `usage: %s <command>
 Commands:
 	run       Run paste server.
 	apikeys   API keys management.
+	put       Upload text to paste service.
 	ping      Ping command. Can be used for check app health.
 `
 
@@ -29,6 +30,10 @@ func main() {
 		apikeysCommand(os.Args[2:])
 		fmt.Println("apikeys")
 
+	case "put":
+		putCommand(os.Args[2:])
+		os.Exit(0)
+
 	case "ping":
 		pingCommand(os.Args[2:])
 		os.Exit(0)
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"log/slog"
 	"net/http"
@@ -10,8 +11,8 @@ import (
 
 	flags "github.com/jessevdk/go-flags"
 	amqp "github.com/rabbitmq/amqp091-go"
-	"github.com/redis/go-redis/v9"
 
+	apprepository "github.com/thek4n/paste.thek4n.ru/internal/application/repository"
 	"github.com/thek4n/paste.thek4n.ru/internal/application/service"
 	"github.com/thek4n/paste.thek4n.ru/internal/domain/config"
 	"github.com/thek4n/paste.thek4n.ru/internal/domain/event"
@@ -24,19 +25,28 @@ import (
 var version = "built-from-source"
 
 type pasteOptions struct {
-	Port                  int    `short:"p" long:"port" default:"80" description:"Port to listen"`
-	Host                  string `long:"host" default:"localhost" description:"Host to listen"`
-	EnableHealthcheck     bool   `long:"health" description:"Enable health handler on /health/ URL"`
-	DBPort                int    `long:"dbport" default:"6379" description:"Database port"`
-	DBHost                string `long:"dbhost" default:"localhost" description:"Database host"`
-	ShowVersion           bool   `short:"v" long:"version" description:"Show version and exit"`
-	Logger                string `long:"logger" default:"plain" choice:"json" choice:"plain" description:"Choose type logger"`
-	LogLevel              string `long:"loglevel" default:"INFO" choice:"DEBUG" choice:"debug" choice:"INFO" choice:"info" choice:"WARN" choice:"warn" choice:"ERROR" choice:"error" choice:"TRACE" choice:"trace" description:"Logger level"`
-	BrokerHost            string `long:"brokerhost" default:"localhost" description:"AMQP broker host"`
-	BrokerPort            int    `long:"brokerport" default:"5672" description:"AMQP broker port"`
-	BrokerUser            string `long:"brokeruser" default:"guest" description:"AMQP broker user"`
-	BrokerPassword        string `long:"brokerpassword" default:"guest" description:"AMQP broker password"`
-	EnableInteractiveDocs bool   `long:"docs" description:"Enable interactive documentation"`
+	Port                  int      `short:"p" long:"port" default:"80" description:"Port to listen"`
+	Host                  string   `long:"host" default:"localhost" description:"Host to listen"`
+	EnableHealthcheck     bool     `long:"health" description:"Enable health handler on /health/ URL"`
+	ShowVersion           bool     `short:"v" long:"version" description:"Show version and exit"`
+	Logger                string   `long:"logger" default:"plain" choice:"json" choice:"plain" description:"Choose type logger"`
+	LogLevel              string   `long:"loglevel" default:"INFO" choice:"DEBUG" choice:"debug" choice:"INFO" choice:"info" choice:"WARN" choice:"warn" choice:"ERROR" choice:"error" choice:"TRACE" choice:"trace" description:"Logger level"`
+	BrokerHost            string   `long:"brokerhost" default:"localhost" description:"AMQP broker host"`
+	BrokerPort            int      `long:"brokerport" default:"5672" description:"AMQP broker port"`
+	BrokerUser            string   `long:"brokeruser" default:"guest" description:"AMQP broker user"`
+	BrokerPassword        string   `long:"brokerpassword" default:"guest" description:"AMQP broker password"`
+	EnableInteractiveDocs bool     `long:"docs" description:"Enable interactive documentation"`
+	Storage               string   `long:"storage" default:"redis" choice:"redis" choice:"memory" choice:"sqlite" description:"Storage backend. Memory storage loses all records on restart"`
+	SQLitePath            string   `long:"sqlitepath" default:"paste.db" description:"Path to sqlite database file for sqlite storage"`
+	APIKeys               []string `long:"apikey" env:"PASTE_APIKEYS" env-delim:"," description:"Apikey accepted by memory storage, e.g. for local development. Can be repeated"`
+	DisableBroker         bool     `long:"nobroker" description:"Do not send apikeys usage events to AMQP broker"`
+	Codec                 string   `long:"codec" default:"gzip" choice:"gzip" choice:"zstd" description:"Codec to compress large bodies. Already stored bodies keep their codec"`
+	UserContentHost       string   `long:"usercontenthost" description:"Host to serve raw content to browsers from, e.g. usercontent.example.com. Browsers are redirected to it"`
+	URLBlocklist          string   `long:"urlblocklist" description:"Path to file with domains, ips and cidrs urls to which can not be shortened, one per line. File is reloaded on change"`
+	ServiceHosts          []string `long:"servicehost" description:"Another host of service, urls to which can not be shortened. Can be repeated"`
+	GeoIPDatabase         string   `long:"geoipdb" description:"Path to CSV GeoIP database with 'start_ip,end_ip,country' or 'cidr,country' rows to count clicks by country"`
+
+	RedisOptions `group:"Redis options"`
 }
 
 const levelTrace = slog.Level(-8)
@@ -70,39 +80,58 @@ func runServer(args []string) {
 		opts.DBHost = redisHost
 	}
 
-	brokerConnectionURL := fmt.Sprintf(
-		"amqp://%s:%s@%s:%d/",
-		opts.BrokerUser,
-		opts.BrokerPassword,
-		getBrokerHost(&opts),
-		opts.BrokerPort,
-	)
+	eventPublisher := event.NewPublisher()
+
+	if !opts.DisableBroker {
+		brokerConnectionURL := fmt.Sprintf(
+			"amqp://%s:%s@%s:%d/",
+			opts.BrokerUser,
+			opts.BrokerPassword,
+			getBrokerHost(&opts),
+			opts.BrokerPort,
+		)
+
+		loggerb := logger.With("broker_host", getBrokerHost(&opts), "broker_port", opts.BrokerPort, "broker_user", opts.BrokerUser)
+		loggerb.Debug("Initializing amqp broker channel...")
+		brokerChannel, err := initBrokerChannel(brokerConnectionURL, loggerb)
+		if err != nil {
+			loggerb.Error("Failed to initialize amqp broker channel", "error", err)
+			os.Exit(1)
+		}
+		loggerb.Debug("Successfully initialized amqp broker channel")
+
+		rbmq := eventhandler.NewRabbitMQEventHandler(brokerChannel)
+		eventPublisher.Subscribe(rbmq, event.NewAPIKeyUsedEvent("", apikeys.UsageReason_CUSTOMKEY, ""))
+	}
+
+	quotaConfig := config.DefaultQuotaConfig{}
 
-	loggerb := logger.With("broker_host", getBrokerHost(&opts), "broker_port", opts.BrokerPort, "broker_user", opts.BrokerUser)
-	loggerb.Debug("Initializing amqp broker channel...")
-	brokerChannel, err := initBrokerChannel(brokerConnectionURL, loggerb)
+	repositories, err := newStorage(context.Background(), &opts, quotaConfig)
 	if err != nil {
-		loggerb.Error("Failed to initialize amqp broker channel", "error", err)
+		logger.Error("Failed to initialize storage", "storage", opts.Storage, "error", err)
 		os.Exit(1)
 	}
-	loggerb.Debug("Successfully initialized amqp broker channel")
 
-	eventPublisher := event.NewPublisher()
-	rbmq := eventhandler.NewRabbitMQEventHandler(brokerChannel)
-	eventPublisher.Subscribe(rbmq, event.NewAPIKeyUsedEvent("", apikeys.UsageReason_CUSTOMKEY, ""))
+	urlPolicy, err := newURLPolicy(context.Background(), &opts, logger)
+	if err != nil {
+		logger.Error("Failed to initialize url policy", "urlblocklist", opts.URLBlocklist, "error", err)
+		os.Exit(1)
+	}
 
-	recordsClient := newRedisClient(&opts, 0)
-	quotaClient := newRedisClient(&opts, 1)
-	apikeyClient := newRedisClient(&opts, 2)
+	geoIP, err := newGeoIPRepository(&opts)
+	if err != nil {
+		logger.Error("Failed to initialize geoip database", "geoipdb", opts.GeoIPDatabase, "error", err)
+		os.Exit(1)
+	}
 
 	handlers := handlersFactory(
-		recordsClient,
-		quotaClient,
-		apikeyClient,
+		repositories,
 		&opts,
 		logger,
 		eventPublisher,
-		config.DefaultQuotaConfig{},
+		quotaConfig,
+		urlPolicy,
+		geoIP,
 	)
 	addHandlers(mux, handlers, &opts)
 
@@ -137,63 +166,122 @@ func (o *pasteOptions) getLogLevel() slog.Level {
 }
 
 func handlersFactory(
-	recordsClient *redis.Client,
-	quotaClient *redis.Client,
-	apikeyClient *redis.Client,
+	repositories storage,
 	opts *pasteOptions,
 	logger *slog.Logger,
 	eventPublisher *event.Publisher,
 	quotaConfig config.QuotaConfig,
+	urlPolicy *service.URLPolicy,
+	geoIP apprepository.GeoIPRepository,
 ) *webhandlers.Handlers {
-	cachingConfig := config.DefaultCachingConfig{}
 	cacheValidationConfig := config.DefaultCacheValidationConfig{}
-
-	redisRecordRepository := repository.NewRedisRecordRepository(
-		recordsClient,
-		cachingConfig,
+	apikeyService := service.NewAPIKeyService(
+		repositories.apikeys,
 	)
-
-	redisAPIKeyRORepository := repository.NewRedisAPIKeyRORepository(
-		apikeyClient,
+	clickStatsService := service.NewClickStatsService(
+		repositories.records,
+		repositories.clickStats,
+		geoIP,
+		apikeyService,
+		config.DefaultClickStatsConfig{},
+		logger,
 	)
 
 	return webhandlers.NewHandlers(
 		cacheValidationConfig,
+		newServingConfig(opts),
 		version,
 		opts.EnableHealthcheck,
 		*logger,
 		service.NewGetService(
-			redisRecordRepository,
+			repositories.records,
+			repositories.passwordAttempts,
+			config.DefaultPasswordAttemptsConfig{},
+			clickStatsService,
 		),
 		service.NewCacheService(
-			redisRecordRepository,
-			repository.NewRedisQuotaRepository(
-				quotaClient,
-				quotaConfig,
-			),
-			redisAPIKeyRORepository,
-			service.NewAPIKeyService(
-				redisAPIKeyRORepository,
-			),
+			repositories.records,
+			repositories.quotas,
+			repositories.apikeys,
+			apikeyService,
 			eventPublisher,
 			cacheValidationConfig,
 			quotaConfig,
+			urlPolicy,
+			logger,
+		),
+		service.NewUpdateService(
+			repositories.records,
+			apikeyService,
+			cacheValidationConfig,
+			urlPolicy,
 			logger,
 		),
+		clickStatsService,
 	)
 }
 
-func newRedisClient(opts *pasteOptions, db int) *redis.Client {
-	return redis.NewClient(&redis.Options{
-		Addr:         fmt.Sprintf("%s:%d", opts.DBHost, opts.DBPort),
-		PoolSize:     100,
-		Password:     "",
-		Username:     "",
-		DB:           db,
-		MaxRetries:   5,
-		DialTimeout:  10 * time.Second,
-		WriteTimeout: 5 * time.Second,
-	})
+// servingConfig overrides default serving config values by options.
+type servingConfig struct {
+	config.DefaultServingConfig
+	userContentHost string
+}
+
+func newServingConfig(opts *pasteOptions) servingConfig {
+	return servingConfig{userContentHost: opts.UserContentHost}
+}
+
+// UserContentHost host chosen by option.
+func (c servingConfig) UserContentHost() string {
+	return c.userContentHost
+}
+
+// urlBlocklistReloadInterval how often url blocklist file is checked for changes.
+const urlBlocklistReloadInterval = 10 * time.Second
+
+func newURLPolicy(ctx context.Context, opts *pasteOptions, logger *slog.Logger) (*service.URLPolicy, error) {
+	cfg := urlPolicyConfig{serviceHosts: opts.ServiceHosts}
+	if opts.UserContentHost != "" {
+		cfg.serviceHosts = append(cfg.serviceHosts, opts.UserContentHost)
+	}
+
+	if opts.URLBlocklist == "" {
+		return service.NewURLPolicy(cfg, nil), nil
+	}
+
+	blocklist, err := repository.NewFileURLBlocklistRepository(opts.URLBlocklist)
+	if err != nil {
+		return nil, err
+	}
+	go blocklist.RunReloader(ctx, urlBlocklistReloadInterval, logger)
+
+	return service.NewURLPolicy(cfg, blocklist), nil
+}
+
+// urlPolicyConfig overrides default url policy config values by options.
+type urlPolicyConfig struct {
+	config.DefaultURLPolicyConfig
+	serviceHosts []string
+}
+
+// ServiceHosts hosts chosen by options.
+func (c urlPolicyConfig) ServiceHosts() []string {
+	return c.serviceHosts
+}
+
+// newGeoIPRepository returns GeoIP database chosen by option,
+// nil if countries of clicks are not detected.
+func newGeoIPRepository(opts *pasteOptions) (apprepository.GeoIPRepository, error) {
+	if opts.GeoIPDatabase == "" {
+		return nil, nil
+	}
+
+	geoIP, err := repository.NewFileGeoIPRepository(opts.GeoIPDatabase)
+	if err != nil {
+		return nil, err
+	}
+
+	return geoIP, nil
 }
 
 func getBrokerHost(opts *pasteOptions) string {
@@ -238,8 +326,20 @@ func newLoggerHandler(opts *pasteOptions) (slog.Handler, error) {
 }
 
 func addHandlers(mux *http.ServeMux, h *webhandlers.Handlers, opts *pasteOptions) {
-	mux.HandleFunc("GET /{key}/{$}", h.Get)
+	mux.HandleFunc("GET /{key}/{$}", withHead(h.Get, h.Head))
 	mux.HandleFunc("GET /{key}/clicks/{$}", h.GetClicks)
+	mux.HandleFunc("GET /{key}/info/{$}", h.GetInfo)
+	mux.HandleFunc("GET /{key}/stats/{$}", h.GetStats)
+	mux.HandleFunc("GET /{key}/view/{$}", withHead(h.View, h.Head))
+	mux.HandleFunc("POST /{key}/{$}", h.Get)
+	mux.HandleFunc("POST /{key}/view/{$}", h.View)
+	mux.HandleFunc("GET /{key}/archive.zip", withHead(h.GetArchive, h.Head))
+	mux.HandleFunc("POST /{key}/archive.zip", h.GetArchive)
+	// path after key is file of bundle or suffix forwarded by url record
+	mux.HandleFunc("GET /{key}/{path...}", withHead(h.Get, h.Head))
+	mux.HandleFunc("POST /{key}/{path...}", h.Get)
+	mux.HandleFunc("PATCH /{key}/{$}", h.Update)
+	mux.HandleFunc("DELETE /{key}/{$}", h.Delete)
 	mux.HandleFunc("POST /{$}", h.Cache)
 
 	if opts.EnableHealthcheck {
@@ -247,7 +347,20 @@ func addHandlers(mux *http.ServeMux, h *webhandlers.Handlers, opts *pasteOptions
 	}
 	if opts.EnableInteractiveDocs {
 		mux.HandleFunc("GET /docs/{$}", h.DocsHandler)
-		mux.Handle("/docs/static/", h.DocsStaticHandler())
+		mux.Handle("GET /docs/static/", h.DocsStaticHandler())
+	}
+}
+
+// withHead routes HEAD requests matched by GET pattern to head handler,
+// so they do not consume record. Own HEAD patterns of record paths would
+// conflict with GET patterns of other paths, e.g. /health/.
+func withHead(get, head http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodHead {
+			head(w, r)
+			return
+		}
+		get(w, r)
 	}
 }
 
@@ -0,0 +1,127 @@
+// Put tool to upload text to paste service
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	flags "github.com/jessevdk/go-flags"
+
+	"github.com/thek4n/paste.thek4n.ru/pkg/e2ecrypt"
+)
+
+type putOptions struct {
+	Server     string `long:"server" env:"PASTE_SERVER" default:"https://paste.thek4n.ru/" description:"Paste service URL"`
+	Encrypt    bool   `long:"encrypt" description:"Encrypt text before upload. Key is put in URL fragment and is not sent to server"`
+	TTL        string `long:"ttl" description:"Expiration time like 1h or 30m"`
+	Disposable int    `long:"disposable" description:"Number of reads before text is removed"`
+	Filename   string `long:"filename" description:"Filename of text"`
+	APIKey     string `long:"apikey" env:"PASTE_APIKEY" description:"API key"`
+}
+
+func putCommand(args []string) {
+	var opts putOptions
+
+	args, err := flags.NewParser(&opts, flags.Default).ParseArgs(args)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Parse params error: %s\n", err)
+		os.Exit(2)
+	}
+
+	body, err := readPutBody(args)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Fail to read text: %s\n", err)
+		os.Exit(2)
+	}
+
+	var key e2ecrypt.Key
+	if opts.Encrypt {
+		key, err = e2ecrypt.NewKey()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Fail to encrypt text: %s\n", err)
+			os.Exit(1)
+		}
+
+		body, err = key.Seal(body)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Fail to encrypt text: %s\n", err)
+			os.Exit(1)
+		}
+	}
+
+	recordURL, err := putRequest(&opts, body)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	if opts.Encrypt {
+		recordURL += "#" + key.String()
+	}
+
+	fmt.Println(recordURL)
+}
+
+// readPutBody reads file from first argument or stdin if there are no
+// arguments or argument is '-'.
+func readPutBody(args []string) ([]byte, error) {
+	if len(args) < 1 || args[0] == "-" {
+		return io.ReadAll(os.Stdin)
+	}
+
+	return os.ReadFile(args[0])
+}
+
+// putRequest uploads body and returns URL of created record.
+func putRequest(opts *putOptions, body []byte) (string, error) {
+	query := url.Values{}
+	if opts.Encrypt {
+		query.Set("encrypted", "true")
+	}
+	if opts.TTL != "" {
+		query.Set("ttl", opts.TTL)
+	}
+	if opts.Disposable != 0 {
+		query.Set("disposable", strconv.Itoa(opts.Disposable))
+	}
+	if opts.Filename != "" {
+		query.Set("filename", opts.Filename)
+	}
+	if opts.APIKey != "" {
+		query.Set("apikey", opts.APIKey)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	server := strings.TrimSuffix(opts.Server, "/") + "/?" + query.Encode()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, server, bytes.NewReader(body))
+	if err != nil {
+		return "", fmt.Errorf("cannot make request: %s", err)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("cannot make request: %s", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	answer, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("error reading server answer: %s", err)
+	}
+
+	if resp.StatusCode != http.StatusCreated {
+		return "", fmt.Errorf("server answered %d: %s", resp.StatusCode, strings.TrimSpace(string(answer)))
+	}
+
+	return strings.TrimSpace(string(answer)), nil
+}
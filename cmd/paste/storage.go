@@ -0,0 +1,177 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+
+	apprepository "github.com/thek4n/paste.thek4n.ru/internal/application/repository"
+	"github.com/thek4n/paste.thek4n.ru/internal/domain/aggregate"
+	"github.com/thek4n/paste.thek4n.ru/internal/domain/config"
+	"github.com/thek4n/paste.thek4n.ru/internal/domain/objectvalue"
+	"github.com/thek4n/paste.thek4n.ru/internal/infrastructure/repository"
+)
+
+// sweepPeriod period of removing expired records from memory and sqlite storages.
+const sweepPeriod = time.Minute
+
+// storage contains repositories of chosen storage backend.
+type storage struct {
+	records apprepository.RecordRepository
+	quotas  apprepository.QuotaRepository
+	apikeys apprepository.APIKeyRORepository
+	// passwordAttempts quotas of wrong password attempts per protected record.
+	passwordAttempts apprepository.QuotaRepository
+	// clickStats click analytics stored next to records.
+	clickStats apprepository.ClickStatsRepository
+}
+
+// cachingConfig overrides default caching config values by options.
+type cachingConfig struct {
+	config.DefaultCachingConfig
+	bodyCodec string
+}
+
+func newCachingConfig(opts *pasteOptions) cachingConfig {
+	return cachingConfig{bodyCodec: opts.Codec}
+}
+
+// BodyCodec codec chosen by option or default one.
+func (c cachingConfig) BodyCodec() string {
+	if c.bodyCodec == "" {
+		return c.DefaultCachingConfig.BodyCodec()
+	}
+	return c.bodyCodec
+}
+
+func newStorage(ctx context.Context, opts *pasteOptions, quotaConfig config.QuotaConfig) (storage, error) {
+	if len(opts.APIKeys) > 0 && opts.Storage != "memory" {
+		return storage{}, errors.New("apikey option is supported only by memory storage, use apikeys command instead")
+	}
+
+	switch opts.Storage {
+	case "memory":
+		return newMemoryStorage(ctx, opts, quotaConfig), nil
+	case "sqlite":
+		return newSQLiteStorage(ctx, opts, quotaConfig)
+	default:
+		return newRedisStorage(opts, quotaConfig)
+	}
+}
+
+// newRedisStorage returns storage in redis. Repositories share one client
+// unless legacy databases layout is used.
+func newRedisStorage(opts *pasteOptions, quotaConfig config.QuotaConfig) (storage, error) {
+	clients := make(map[int]redis.UniversalClient)
+	client := func(keyspace redisKeyspace) (redis.UniversalClient, error) {
+		if c, ok := clients[keyspace.db]; ok {
+			return c, nil
+		}
+
+		c, err := newRedisClient(&opts.RedisOptions, keyspace.db)
+		if err != nil {
+			return nil, fmt.Errorf("fail to create redis client: %w", err)
+		}
+		clients[keyspace.db] = c
+
+		return c, nil
+	}
+
+	recordsKeyspace := opts.recordsKeyspace()
+	recordsClient, err := client(recordsKeyspace)
+	if err != nil {
+		return storage{}, err
+	}
+
+	quotasKeyspace := opts.quotasKeyspace()
+	quotasClient, err := client(quotasKeyspace)
+	if err != nil {
+		return storage{}, err
+	}
+
+	apikeysKeyspace := opts.apikeysKeyspace()
+	apikeysClient, err := client(apikeysKeyspace)
+	if err != nil {
+		return storage{}, err
+	}
+
+	records := repository.NewRedisRecordRepository(
+		recordsClient,
+		newCachingConfig(opts),
+		recordsKeyspace.prefix,
+	)
+
+	return storage{
+		records: records,
+		quotas: repository.NewRedisQuotaRepository(
+			quotasClient,
+			quotaConfig,
+			quotasKeyspace.prefix,
+		),
+		apikeys: repository.NewRedisAPIKeyRORepository(
+			apikeysClient,
+			apikeysKeyspace.prefix,
+		),
+		passwordAttempts: repository.NewRedisQuotaRepository(
+			quotasClient,
+			config.DefaultPasswordAttemptsConfig{},
+			quotasKeyspace.prefix,
+		),
+		clickStats: records,
+	}, nil
+}
+
+// newMemoryStorage returns storage that lives in process memory with apikeys
+// given by options and starts sweepers of expired entries until ctx done.
+func newMemoryStorage(ctx context.Context, opts *pasteOptions, quotaConfig config.QuotaConfig) storage {
+	records := repository.NewMemoryRecordRepository(newCachingConfig(opts))
+	quotas := repository.NewMemoryQuotaRepository(quotaConfig)
+	passwordAttempts := repository.NewMemoryQuotaRepository(config.DefaultPasswordAttemptsConfig{})
+
+	go records.RunSweeper(ctx, sweepPeriod)
+	go quotas.RunSweeper(ctx, sweepPeriod)
+	go passwordAttempts.RunSweeper(ctx, sweepPeriod)
+
+	apikeys := repository.NewMemoryAPIKeyRepository()
+	for _, key := range opts.APIKeys {
+		apikey := aggregate.NewAPIKey(objectvalue.APIKeyID(uuid.New()), key, true)
+		// memory repository never fails
+		_ = apikeys.SetByID(ctx, key, apikey)
+	}
+
+	return storage{
+		records:          records,
+		quotas:           quotas,
+		apikeys:          apikeys,
+		passwordAttempts: passwordAttempts,
+		clickStats:       records,
+	}
+}
+
+// newSQLiteStorage returns storage in sqlite database file
+// and starts sweepers of expired rows until ctx done.
+func newSQLiteStorage(ctx context.Context, opts *pasteOptions, quotaConfig config.QuotaConfig) (storage, error) {
+	db, err := repository.OpenSQLite(ctx, opts.SQLitePath)
+	if err != nil {
+		return storage{}, fmt.Errorf("fail to open sqlite storage: %w", err)
+	}
+
+	records := repository.NewSQLiteRecordRepository(db, newCachingConfig(opts))
+	quotas := repository.NewSQLiteQuotaRepository(db, quotaConfig)
+
+	go records.RunSweeper(ctx, sweepPeriod)
+	go quotas.RunSweeper(ctx, sweepPeriod)
+
+	return storage{
+		records: records,
+		quotas:  quotas,
+		apikeys: repository.NewSQLiteAPIKeyRepository(db),
+		// shares quotas table, expired attempts are removed by quotas sweeper
+		passwordAttempts: repository.NewSQLiteQuotaRepository(db, config.DefaultPasswordAttemptsConfig{}),
+		clickStats:       records,
+	}, nil
+}
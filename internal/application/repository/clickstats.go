@@ -0,0 +1,21 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/thek4n/paste.thek4n.ru/internal/domain/aggregate"
+	"github.com/thek4n/paste.thek4n.ru/internal/domain/objectvalue"
+)
+
+// ClickStatsRepository domain interface. Click stats are stored next to
+// record and removed with it.
+type ClickStatsRepository interface {
+	// GetClickStats returns click stats of record. Returns ErrRecordNotFound
+	// if record does not exist.
+	GetClickStats(context.Context, objectvalue.RecordKey) (aggregate.ClickStats, error)
+
+	// UpdateClickStats atomically applies update to click stats of record.
+	// update may be called several times if stats are changed concurrently.
+	// Returns ErrRecordNotFound if record does not exist.
+	UpdateClickStats(ctx context.Context, key objectvalue.RecordKey, update func(*aggregate.ClickStats)) error
+}
@@ -0,0 +1,11 @@
+package repository
+
+import (
+	"context"
+)
+
+// GeoIPRepository readonly interface for countries of ips.
+type GeoIPRepository interface {
+	// Country returns ISO code of country of ip, empty if it is unknown.
+	Country(context.Context, string) (string, error)
+}
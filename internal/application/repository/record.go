@@ -2,6 +2,7 @@ package repository
 
 import (
 	"context"
+	"io"
 
 	"github.com/thek4n/paste.thek4n.ru/internal/domain/aggregate"
 	"github.com/thek4n/paste.thek4n.ru/internal/domain/objectvalue"
@@ -9,8 +10,45 @@ import (
 
 // RecordRepository domain interface.
 type RecordRepository interface {
-	GetByKey(context.Context, objectvalue.RecordKey) (aggregate.Record, error)
+	// GetByKey returns record without consuming it. If body is stored in
+	// one of acceptedEncodings it is returned as is, otherwise it is decoded.
+	GetByKey(ctx context.Context, key objectvalue.RecordKey, acceptedEncodings ...objectvalue.BodyEncoding) (aggregate.Record, error)
 	SetByKey(context.Context, objectvalue.RecordKey, aggregate.Record) error
+
+	// InfoByKey returns record without consuming it. Body is neither read nor
+	// decoded, so record has empty body. Returns ErrRecordNotFound if it does not exist.
+	InfoByKey(context.Context, objectvalue.RecordKey) (aggregate.Record, error)
+
+	// EncodeBody reads body up to limit and encodes it as it is stored, so
+	// large body is compressed while reading instead of being held whole.
+	// Returns ErrBodyTooLarge as soon as limit exceeded.
+	EncodeBody(r io.Reader, limit int64) (objectvalue.EncodedBody, error)
+
+	// ConsumeByKey atomically checks record disposable counter, decreases it,
+	// increases clicks counter if countClick and removes exhausted record.
+	// Returns record state after consuming. If body is stored in one of
+	// acceptedEncodings it is returned as is, otherwise it is decoded.
+	ConsumeByKey(ctx context.Context, key objectvalue.RecordKey, countClick bool, acceptedEncodings ...objectvalue.BodyEncoding) (aggregate.Record, error)
 	Exists(context.Context, objectvalue.RecordKey) (bool, error)
-	GenerateUniqueKey(ctx context.Context, minLength uint8, maxLength uint8) (objectvalue.RecordKey, error)
+
+	// AccessByKey returns what reader must present to consume record without
+	// reading its body. Returns ErrRecordNotFound if it does not exist.
+	AccessByKey(context.Context, objectvalue.RecordKey) (objectvalue.RecordAccess, error)
+
+	// UpdateByKey atomically rewrites body, body size, bundle files, password hash, encrypted
+	// flag, expiration date, disposable counter and url flag of existing record. Clicks, metadata
+	// and owner token hash are kept. Returns ErrRecordNotFound if it does not exist.
+	UpdateByKey(context.Context, objectvalue.RecordKey, aggregate.Record) error
+
+	// DeleteByKey removes record. Returns ErrRecordNotFound if it does not exist.
+	DeleteByKey(context.Context, objectvalue.RecordKey) error
+
+	// ReserveKey atomically reserves key if it does not exist and returns false otherwise.
+	// Reserved key exists but is not readable until SetByKey fills it.
+	// Reservation expires if key is not filled.
+	ReserveKey(context.Context, objectvalue.RecordKey) (bool, error)
+
+	// GenerateUniqueKey generates and reserves unique key of style. Returns
+	// ErrInvalidRequestedKeyLength if key of style can not be minLength long.
+	GenerateUniqueKey(ctx context.Context, style objectvalue.KeyStyle, minLength uint8, maxLength uint8) (objectvalue.RecordKey, error)
 }
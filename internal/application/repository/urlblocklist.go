@@ -0,0 +1,11 @@
+package repository
+
+import (
+	"context"
+)
+
+// URLBlocklistRepository readonly interface for blocked hosts of urls.
+type URLBlocklistRepository interface {
+	// Blocked returns is host blocked. Host is lowercase domain or ip.
+	Blocked(context.Context, string) (bool, error)
+}
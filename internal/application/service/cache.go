@@ -2,8 +2,12 @@ package service
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"fmt"
+	"io"
+	"slices"
 	"strings"
 	"time"
 
@@ -15,6 +19,7 @@ import (
 	"github.com/thek4n/paste.thek4n.ru/internal/domain/logger"
 	"github.com/thek4n/paste.thek4n.ru/internal/domain/objectvalue"
 	"github.com/thek4n/paste.thek4n.ru/pkg/apikeys"
+	"github.com/thek4n/paste.thek4n.ru/pkg/passwordcrypt"
 )
 
 // CacheService application service.
@@ -26,10 +31,11 @@ type CacheService struct {
 	eventPublisher   *event.Publisher
 	validationConfig config.CacheValidationConfig
 	quotaConfig      config.QuotaConfig
+	urlPolicy        *URLPolicy
 	logger           logger.Logger
 }
 
-// NewCacheService constructor.
+// NewCacheService constructor. Urls of url records are checked by urlPolicy.
 func NewCacheService(
 	recordRepository repository.RecordRepository,
 	quotaRepository repository.QuotaRepository,
@@ -38,6 +44,7 @@ func NewCacheService(
 	eventPublisher *event.Publisher,
 	cfg config.CacheValidationConfig,
 	quotacfg config.QuotaConfig,
+	urlPolicy *URLPolicy,
 	lgr logger.Logger,
 ) *CacheService {
 	return &CacheService{
@@ -48,12 +55,28 @@ func NewCacheService(
 		eventPublisher:   eventPublisher,
 		validationConfig: cfg,
 		quotaConfig:      quotacfg,
+		urlPolicy:        urlPolicy,
 		logger:           lgr,
 	}
 }
 
+// CacheAnswer CacheService result.
+type CacheAnswer struct {
+	Key objectvalue.RecordKey
+	// OwnerToken lets creator manage record. It is not stored, so it
+	// can not be got again.
+	OwnerToken objectvalue.OwnerToken
+	// ExpiresAt is zero if record is eternal.
+	ExpiresAt time.Time
+	// RemainingReads is meaningful only if ReadsLimited.
+	RemainingReads uint8
+	ReadsLimited   bool
+	// Privileged is true if record was cached with valid apikey.
+	Privileged bool
+}
+
 // Serve service method that serve cache request.
-func (s *CacheService) Serve(params objectvalue.CacheRequestParams) (objectvalue.RecordKey, error) {
+func (s *CacheService) Serve(params objectvalue.CacheRequestParams) (CacheAnswer, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
@@ -62,9 +85,9 @@ func (s *CacheService) Serve(params objectvalue.CacheRequestParams) (objectvalue
 	var err error
 
 	if params.APIKey != "" {
-		privileged, apikeyID, err = s.checkAPIKey(ctx, params)
+		privileged, apikeyID, err = s.checkAPIKey(ctx, params.APIKey)
 		if err != nil {
-			return objectvalue.RecordKey(""), err
+			return CacheAnswer{}, err
 		}
 	}
 
@@ -72,65 +95,186 @@ func (s *CacheService) Serve(params objectvalue.CacheRequestParams) (objectvalue
 		s.logger.Info("Authorize APIKey", "apikey", apikeyID)
 	}
 
+	if params.BodyEncoding != objectvalue.BodyEncodingIdentity && (params.IsURL || params.Password != "") {
+		return CacheAnswer{}, errors.New("url and password protected bodies must not be encoded")
+	}
+
+	if params.IsURL {
+		if err := checkURL(ctx, s.urlPolicy, s.logger, string(params.Body), params.Host); err != nil {
+			return CacheAnswer{}, err
+		}
+	}
+
+	ownerToken := objectvalue.NewOwnerToken()
+	var key objectvalue.RecordKey
+
 	if privileged {
 		err := s.validatePrivielegedRequestParams(params)
 		if err != nil {
-			return objectvalue.RecordKey(""), err
+			return CacheAnswer{}, err
 		}
 
 		s.logAPIKeyUsage(apikeyID, params)
-		return s.servePrivileged(ctx, params)
+		record, err := newRecord(params, apikeyID, ownerToken)
+		if err != nil {
+			return CacheAnswer{}, err
+		}
+
+		key, err = s.servePrivileged(ctx, params, record)
+		if err != nil {
+			return CacheAnswer{}, err
+		}
+
+		return newCacheAnswer(key, ownerToken, record, true), nil
 	}
 
 	err = s.validateUnprivilegedRequestParams(params)
 	if err != nil {
-		return objectvalue.RecordKey(""), err
+		return CacheAnswer{}, err
+	}
+
+	record, err := newRecord(params, "", ownerToken)
+	if err != nil {
+		return CacheAnswer{}, err
 	}
 
-	return s.serveUnprivileged(ctx, params)
+	key, err = s.serveUnprivileged(ctx, params, record)
+	if err != nil {
+		return CacheAnswer{}, err
+	}
+
+	return newCacheAnswer(key, ownerToken, record, false), nil
+}
+
+func newCacheAnswer(key objectvalue.RecordKey, ownerToken objectvalue.OwnerToken, record aggregate.Record, privileged bool) CacheAnswer {
+	return CacheAnswer{
+		Key:            key,
+		OwnerToken:     ownerToken,
+		ExpiresAt:      record.ExpirationDate().Date(),
+		RemainingReads: record.DisposableCounter(),
+		ReadsLimited:   !record.DisposableCounterEternal(),
+		Privileged:     privileged,
+	}
 }
 
-func (s *CacheService) checkAPIKey(ctx context.Context, params objectvalue.CacheRequestParams) (bool, string, error) {
-	apikeyExists, err := s.apikeyService.Exists(ctx, params.APIKey)
+// Delete removes record if ownerToken returned on caching or apikey
+// that created record is presented.
+func (s *CacheService) Delete(key objectvalue.RecordKey, ownerToken objectvalue.OwnerToken, apikey string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	if ownerToken == "" && apikey == "" {
+		return domainerrors.ErrNonAuthorized
+	}
+
+	apikeyID := ""
+	if apikey != "" {
+		var err error
+		_, apikeyID, err = s.checkAPIKey(ctx, apikey)
+		if err != nil {
+			return err
+		}
+	}
+
+	record, err := s.recordRepository.GetByKey(ctx, key)
+	if errors.Is(err, domainerrors.ErrRecordNotFound) {
+		return domainerrors.ErrRecordNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("fail to get record: %w", err)
+	}
+
+	if !record.OwnedBy(ownerToken, apikeyID) {
+		s.logger.Warn("Deleting not owned record", "key", string(key), "apikey", apikeyID)
+		return domainerrors.ErrNotOwner
+	}
+
+	err = s.recordRepository.DeleteByKey(ctx, key)
+	if errors.Is(err, domainerrors.ErrRecordNotFound) {
+		return domainerrors.ErrRecordNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("fail to delete record: %w", err)
+	}
+
+	s.logger.Info("Delete record", "key", string(key), "apikey", apikeyID)
+
+	return nil
+}
+
+// MaxBodySize returns body size limit for request authorized with apikey.
+// Empty apikey means unprivileged request. It lets caller enforce limit
+// while reading body instead of buffering it first.
+func (s *CacheService) MaxBodySize(apikey string) (int64, error) {
+	if apikey == "" {
+		return s.validationConfig.UnprivilegedMaxBodySize(), nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	if _, _, err := s.checkAPIKey(ctx, apikey); err != nil {
+		return 0, err
+	}
+
+	return s.validationConfig.PrivilegedMaxBodySize(), nil
+}
+
+func (s *CacheService) checkAPIKey(ctx context.Context, apikey string) (bool, string, error) {
+	return checkAPIKey(ctx, s.apikeyService, s.logger, apikey)
+}
+
+// ReadBody reads body up to limit got by MaxBodySize. Body is compressed
+// while reading as records repository stores it, so large body is never
+// held whole. Url and password protected bodies must be read plain.
+func (s *CacheService) ReadBody(body io.Reader, limit int64) (objectvalue.EncodedBody, error) {
+	encoded, err := s.recordRepository.EncodeBody(body, limit)
+	if errors.Is(err, domainerrors.ErrBodyTooLarge) {
+		return objectvalue.EncodedBody{}, domainerrors.ErrBodyTooLarge
+	}
+	if err != nil {
+		return objectvalue.EncodedBody{}, fmt.Errorf("fail to read body: %w", err)
+	}
+
+	return encoded, nil
+}
+
+// checkAPIKey returns is apikey valid and its ID. Returns error if apikey
+// does not exist or invalid.
+func checkAPIKey(ctx context.Context, apikeyService IAPIKeyService, lgr logger.Logger, apikey string) (bool, string, error) {
+	apikeyExists, err := apikeyService.Exists(ctx, apikey)
 	if err != nil {
 		return false, "", fmt.Errorf("fail to check apikey existing: %w", err)
 	}
 
 	if !apikeyExists {
-		s.logger.Warn("Using non existing apikey")
+		lgr.Warn("Using non existing apikey")
 		return false, "", domainerrors.ErrAPIKeyNotFound
 	}
 
-	apikeyID, err := s.apikeyService.GetID(ctx, params.APIKey)
+	apikeyID, err := apikeyService.GetID(ctx, apikey)
 	if err != nil {
 		return false, "", fmt.Errorf("fail to get apikey ID: %w", err)
 	}
 
-	apikeyValid, err := s.apikeyService.CheckValid(ctx, params.APIKey)
+	apikeyValid, err := apikeyService.CheckValid(ctx, apikey)
 	if err != nil {
 		return false, "", fmt.Errorf("fail to check apikey validity: %w", err)
 	}
 
 	if !apikeyValid {
-		s.logger.Warn("Using invalid apikey", "apikey", apikeyID)
+		lgr.Warn("Using invalid apikey", "apikey", apikeyID)
 		return false, "", domainerrors.ErrAPIKeyInvalid
 	}
 
 	return apikeyValid, apikeyID, nil
 }
 
-func (s *CacheService) servePrivileged(ctx context.Context, params objectvalue.CacheRequestParams) (objectvalue.RecordKey, error) {
-	expirationDate := objectvalue.NewExpirationDateFromTTL(params.TTL)
-	newRecord := aggregate.NewRecord(
-		"",
-		expirationDate,
-		params.Disposable,
-		params.Disposable == 0,
-		0,
-		params.Body,
-		params.IsURL,
-	)
-
+func (s *CacheService) servePrivileged(
+	ctx context.Context,
+	params objectvalue.CacheRequestParams,
+	newRecord aggregate.Record,
+) (objectvalue.RecordKey, error) {
 	newRecordKey, err := s.getRecordKey(ctx, params)
 	if err != nil {
 		return newRecordKey, err
@@ -145,12 +289,12 @@ func (s *CacheService) servePrivileged(ctx context.Context, params objectvalue.C
 
 func (s *CacheService) getRecordKey(ctx context.Context, params objectvalue.CacheRequestParams) (objectvalue.RecordKey, error) {
 	if params.RequestedKey != "" {
-		requestedRecordKeyExists, err := s.recordRepository.Exists(ctx, objectvalue.RecordKey(params.RequestedKey))
+		reserved, err := s.recordRepository.ReserveKey(ctx, objectvalue.RecordKey(params.RequestedKey))
 		if err != nil {
-			return objectvalue.RecordKey(""), fmt.Errorf("fail to check requested key existing: %w", err)
+			return objectvalue.RecordKey(""), fmt.Errorf("fail to reserve requested key: %w", err)
 		}
 
-		if requestedRecordKeyExists {
+		if !reserved {
 			return objectvalue.RecordKey(""), domainerrors.ErrRequestedKeyExists
 		}
 
@@ -165,7 +309,10 @@ func (s *CacheService) getRecordKey(ctx context.Context, params objectvalue.Cach
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
-	newRecordKey, err := s.recordRepository.GenerateUniqueKey(ctx, keyLength, s.validationConfig.MaxKeyLength())
+	newRecordKey, err := s.recordRepository.GenerateUniqueKey(ctx, s.keyStyle(params), keyLength, s.validationConfig.MaxKeyLength())
+	if errors.Is(err, domainerrors.ErrInvalidRequestedKeyLength) {
+		return newRecordKey, domainerrors.ErrInvalidRequestedKeyLength
+	}
 	if err != nil {
 		return newRecordKey, fmt.Errorf("fail to generate unique key: %w", err)
 	}
@@ -173,26 +320,23 @@ func (s *CacheService) getRecordKey(ctx context.Context, params objectvalue.Cach
 	return newRecordKey, nil
 }
 
-func (s *CacheService) serveUnprivileged(ctx context.Context, params objectvalue.CacheRequestParams) (objectvalue.RecordKey, error) {
-	expirationDate := objectvalue.NewExpirationDateFromTTL(params.TTL)
-	newRecord := aggregate.NewRecord(
-		"",
-		expirationDate,
-		params.Disposable,
-		params.Disposable == 0,
-		0,
-		params.Body,
-		params.IsURL,
-	)
-
+func (s *CacheService) serveUnprivileged(
+	ctx context.Context,
+	params objectvalue.CacheRequestParams,
+	newRecord aggregate.Record,
+) (objectvalue.RecordKey, error) {
 	var newRecordKey objectvalue.RecordKey
+	var err error
 
 	keyLength := s.validationConfig.DefaultKeyLength()
 	if params.RequestedKeyLength != 0 {
 		keyLength = params.RequestedKeyLength
 	}
 
-	newRecordKey, err := s.recordRepository.GenerateUniqueKey(ctx, keyLength, s.validationConfig.MaxKeyLength())
+	newRecordKey, err = s.recordRepository.GenerateUniqueKey(ctx, s.keyStyle(params), keyLength, s.validationConfig.MaxKeyLength())
+	if errors.Is(err, domainerrors.ErrInvalidRequestedKeyLength) {
+		return newRecordKey, domainerrors.ErrInvalidRequestedKeyLength
+	}
 	if err != nil {
 		return newRecordKey, fmt.Errorf("fail to generate unique key: %w", err)
 	}
@@ -209,6 +353,85 @@ func (s *CacheService) serveUnprivileged(ctx context.Context, params objectvalue
 	return newRecordKey, nil
 }
 
+// keyStyle returns requested key style or default one.
+func (s *CacheService) keyStyle(params objectvalue.CacheRequestParams) objectvalue.KeyStyle {
+	if params.KeyStyle == "" {
+		return s.validationConfig.DefaultKeyStyle()
+	}
+	return params.KeyStyle
+}
+
+// newRecord returns record of params. Body of record protected by
+// password is encrypted.
+func newRecord(params objectvalue.CacheRequestParams, apikeyID string, ownerToken objectvalue.OwnerToken) (aggregate.Record, error) {
+	record := aggregate.NewRecord(
+		"",
+		newExpirationDate(params.TTL, params.Sliding),
+		params.Disposable,
+		params.Disposable == 0,
+		0,
+		params.Body,
+		params.IsURL,
+	)
+	record.SetMetadata(newRecordMetadata(params, apikeyID))
+	record.SetOwnerTokenHash(ownerToken.Hash())
+	record.SetBodySize(params.BodyLen)
+	record.SetEncrypted(params.Encrypted)
+	record.SetReveal(params.Reveal)
+	record.SetFiles(params.Files)
+	record.SetRedirect(params.Redirect)
+
+	if params.BodyEncoding != objectvalue.BodyEncodingIdentity {
+		record.SetEncodedBody(params.Body, params.BodyEncoding)
+	}
+
+	if params.Password != "" {
+		if err := sealBody(&record, params.Password, params.Body); err != nil {
+			return aggregate.Record{}, err
+		}
+	}
+
+	return record, nil
+}
+
+// sealBody encrypts body with password and sets it to record with password hash.
+func sealBody(record *aggregate.Record, password string, body []byte) error {
+	hash, sealed, err := passwordcrypt.Seal(password, body)
+	if err != nil {
+		return fmt.Errorf("fail to encrypt body: %w", err)
+	}
+
+	record.SetEncodedBody(sealed, objectvalue.BodyEncodingIdentity)
+	record.SetPasswordHash(hash)
+
+	return nil
+}
+
+func newRecordMetadata(params objectvalue.CacheRequestParams, apikeyID string) objectvalue.RecordMetadata {
+	return objectvalue.NewRecordMetadata(
+		time.Now(),
+		params.ContentType,
+		params.Filename,
+		hashSourceIP(params.SourceIP),
+		apikeyID,
+	)
+}
+
+// hashSourceIP returns hex encoded sha256 of ip, so records of one
+// creator can be found without storing ip itself.
+func hashSourceIP(ip string) string {
+	sum := sha256.Sum256([]byte(ip))
+	return hex.EncodeToString(sum[:])
+}
+
+func newExpirationDate(ttl time.Duration, sliding bool) objectvalue.ExpirationDate {
+	if sliding {
+		return objectvalue.NewSlidingExpirationDateFromTTL(ttl)
+	}
+
+	return objectvalue.NewExpirationDateFromTTL(ttl)
+}
+
 func (s *CacheService) manageQuota(ctx context.Context, sourceIP objectvalue.QuotaSourceIP) error {
 	quota, err := s.quotaRepository.GetByID(ctx, sourceIP)
 	if err != nil {
@@ -251,18 +474,22 @@ func (s *CacheService) validatePrivielegedRequestParams(params objectvalue.Cache
 		}
 	}
 
-	if params.BodyLen > s.validationConfig.PrivilegedMaxBodySize() {
-		return domainerrors.ErrBodyTooLarge
+	if err := validateBodyLen(s.validationConfig, true, params.BodyLen); err != nil {
+		return err
 	}
 
-	if params.TTL > s.validationConfig.PrivilegedMaxTTL() {
-		return domainerrors.ErrInvalidTTL
+	if err := validateTTL(s.validationConfig, true, params.TTL); err != nil {
+		return err
 	}
 
 	if params.RequestedKeyLength < s.validationConfig.PrivilegedMinKeyLength() {
 		return domainerrors.ErrInvalidRequestedKeyLength
 	}
 
+	if params.KeyStyle != "" && !slices.Contains(s.validationConfig.PrivilegedKeyStyles(), params.KeyStyle) {
+		return domainerrors.ErrKeyStyleNotAllowed
+	}
+
 	return s.validateCommonRequestParams(params)
 }
 
@@ -271,22 +498,22 @@ func (s *CacheService) validateUnprivilegedRequestParams(params objectvalue.Cach
 		return domainerrors.ErrNonAuthorized
 	}
 
-	if params.BodyLen > s.validationConfig.UnprivilegedMaxBodySize() {
-		return domainerrors.ErrBodyTooLarge
+	if err := validateBodyLen(s.validationConfig, false, params.BodyLen); err != nil {
+		return err
 	}
 
-	if params.TTL < s.validationConfig.MinTTL() {
-		return domainerrors.ErrInvalidTTL
-	}
-
-	if params.TTL > s.validationConfig.UnprivilegedMaxTTL() {
-		return domainerrors.ErrInvalidTTL
+	if err := validateTTL(s.validationConfig, false, params.TTL); err != nil {
+		return err
 	}
 
 	if params.RequestedKeyLength < s.validationConfig.UnprivilegedMinKeyLength() {
 		return domainerrors.ErrInvalidRequestedKeyLength
 	}
 
+	if params.KeyStyle != "" && !slices.Contains(s.validationConfig.UnprivilegedKeyStyles(), params.KeyStyle) {
+		return domainerrors.ErrKeyStyleNotAllowed
+	}
+
 	return s.validateCommonRequestParams(params)
 }
 
@@ -295,6 +522,75 @@ func (s *CacheService) validateCommonRequestParams(params objectvalue.CacheReque
 		return domainerrors.ErrInvalidRequestedKeyLength
 	}
 
+	return s.validateBundle(params)
+}
+
+// validateBundle checks that files of bundle have unique names and
+// cover its body. Url and encrypted records can not be bundles.
+func (s *CacheService) validateBundle(params objectvalue.CacheRequestParams) error {
+	if len(params.Files) == 0 {
+		return nil
+	}
+
+	if params.IsURL || params.Encrypted {
+		return domainerrors.ErrInvalidBundle
+	}
+
+	if len(params.Files) > s.validationConfig.MaxBundleFiles() {
+		return domainerrors.ErrInvalidBundle
+	}
+
+	if params.Files.Size() != int64(len(params.Body)) {
+		return domainerrors.ErrInvalidBundle
+	}
+
+	names := make(map[string]struct{}, len(params.Files))
+	for _, file := range params.Files {
+		if file.Name() == "" || file.Name() == objectvalue.BundleArchiveName {
+			return domainerrors.ErrInvalidBundle
+		}
+
+		if _, ok := names[file.Name()]; ok {
+			return domainerrors.ErrInvalidBundle
+		}
+		names[file.Name()] = struct{}{}
+	}
+
+	return nil
+}
+
+// validateBodyLen checks body size against privileged or unprivileged limit.
+func validateBodyLen(cfg config.CacheValidationConfig, privileged bool, bodyLen int64) error {
+	maxBodySize := cfg.UnprivilegedMaxBodySize()
+	if privileged {
+		maxBodySize = cfg.PrivilegedMaxBodySize()
+	}
+
+	if bodyLen > maxBodySize {
+		return domainerrors.ErrBodyTooLarge
+	}
+
+	return nil
+}
+
+// validateTTL checks ttl against privileged or unprivileged bounds.
+// Zero ttl means eternal record, so it is allowed only for privileged.
+func validateTTL(cfg config.CacheValidationConfig, privileged bool, ttl time.Duration) error {
+	if privileged {
+		if ttl > cfg.PrivilegedMaxTTL() {
+			return domainerrors.ErrInvalidTTL
+		}
+		return nil
+	}
+
+	if ttl < cfg.MinTTL() {
+		return domainerrors.ErrInvalidTTL
+	}
+
+	if ttl > cfg.UnprivilegedMaxTTL() {
+		return domainerrors.ErrInvalidTTL
+	}
+
 	return nil
 }
 
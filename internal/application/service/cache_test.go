@@ -1,19 +1,20 @@
-//go:build integration
+//go:build unit
 
 package service
 
 import (
 	"context"
-	"fmt"
-	"os"
+	"strconv"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
-	"github.com/redis/go-redis/v9"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
 	"github.com/thek4n/paste.thek4n.ru/internal/domain/config"
+	"github.com/thek4n/paste.thek4n.ru/internal/domain/domainerrors"
 	"github.com/thek4n/paste.thek4n.ru/internal/domain/event"
 	"github.com/thek4n/paste.thek4n.ru/internal/domain/objectvalue"
 	"github.com/thek4n/paste.thek4n.ru/internal/infrastructure/repository"
@@ -24,9 +25,9 @@ func TestCacheService_Serve(t *testing.T) {
 
 	publisher := event.NewPublisher()
 
-	recordRepo := repository.NewRedisRecordRepository(newRedisClient(0), config.DefaultCachingConfig{})
-	quotaRepo := repository.NewRedisQuotaRepository(newRedisClient(1), config.DefaultQuotaConfig{})
-	apikeyRepo := repository.NewRedisAPIKeyRORepository(newRedisClient(2))
+	recordRepo := repository.NewMemoryRecordRepository(config.DefaultCachingConfig{})
+	quotaRepo := repository.NewMemoryQuotaRepository(config.DefaultQuotaConfig{})
+	apikeyRepo := repository.NewMemoryAPIKeyRepository()
 
 	apikeyService := TrueAPIKeyService{}
 
@@ -41,6 +42,7 @@ func TestCacheService_Serve(t *testing.T) {
 		publisher,
 		cacheValidationCfg,
 		config.DefaultQuotaConfig{},
+		NewURLPolicy(config.DefaultURLPolicyConfig{}, nil),
 		MuteLogger{},
 	)
 
@@ -56,10 +58,15 @@ func TestCacheService_Serve(t *testing.T) {
 			Disposable:         1,
 			IsURL:              false,
 		}
-		key, err := svc.Serve(params)
+		answer, err := svc.Serve(params)
+		key := answer.Key
 		require.NoError(t, err)
 
 		assert.NotEmpty(t, key)
+		assert.False(t, answer.Privileged)
+		assert.True(t, answer.ReadsLimited)
+		assert.Equal(t, uint8(1), answer.RemainingReads)
+		assert.WithinDuration(t, time.Now().Add(cacheValidationCfg.DefaultTTL()), answer.ExpiresAt, time.Minute)
 	})
 
 	t.Run("service returns correct requested key with apikey mock", func(t *testing.T) {
@@ -74,34 +81,661 @@ func TestCacheService_Serve(t *testing.T) {
 			Disposable:         1,
 			IsURL:              false,
 		}
-		key, err := svc.Serve(params)
+		answer, err := svc.Serve(params)
+		key := answer.Key
 		require.NoError(t, err)
 
 		assert.Equal(t, "key", string(key))
+		assert.True(t, answer.Privileged)
+	})
+
+	t.Run("record metadata is stored", func(t *testing.T) {
+		params := objectvalue.CacheRequestParams{
+			APIKey:             "non-empty",
+			SourceIP:           "127.0.0.1",
+			Body:               []byte("{}"),
+			TTL:                cacheValidationCfg.DefaultTTL(),
+			BodyLen:            2,
+			RequestedKeyLength: cacheValidationCfg.DefaultKeyLength(),
+			ContentType:        "application/json",
+			Filename:           "data.json",
+		}
+		answer, err := svc.Serve(params)
+		key := answer.Key
+		require.NoError(t, err)
+
+		record, err := recordRepo.GetByKey(context.Background(), key)
+		require.NoError(t, err)
+
+		metadata := record.Metadata()
+		assert.WithinDuration(t, time.Now(), metadata.CreatedAt(), time.Minute)
+		assert.Equal(t, "application/json", metadata.ContentType())
+		assert.Equal(t, "data.json", metadata.Filename())
+		assert.Equal(t, hashSourceIP("127.0.0.1"), metadata.SourceIPHash())
+		assert.NotContains(t, metadata.SourceIPHash(), "127.0.0.1")
+		assert.Equal(t, "apikey-id", metadata.APIKeyID())
+	})
+
+	t.Run("encrypted flag is stored", func(t *testing.T) {
+		answer, err := svc.Serve(objectvalue.CacheRequestParams{
+			SourceIP:           "127.0.0.1",
+			Body:               []byte("ciphertext"),
+			TTL:                cacheValidationCfg.DefaultTTL(),
+			BodyLen:            10,
+			RequestedKeyLength: cacheValidationCfg.DefaultKeyLength(),
+			Encrypted:          true,
+		})
+		require.NoError(t, err)
+
+		record, err := recordRepo.GetByKey(context.Background(), answer.Key)
+		require.NoError(t, err)
+		assert.True(t, record.Encrypted())
+		assert.Equal(t, []byte("ciphertext"), record.RGetBody())
+	})
+
+	t.Run("generated key has requested style", func(t *testing.T) {
+		params := objectvalue.CacheRequestParams{
+			SourceIP:           "127.0.0.1",
+			Body:               []byte("test"),
+			TTL:                cacheValidationCfg.DefaultTTL(),
+			BodyLen:            4,
+			RequestedKeyLength: cacheValidationCfg.DefaultKeyLength(),
+			KeyStyle:           objectvalue.KeyStyleWords,
+		}
+		answer, err := svc.Serve(params)
+		key := answer.Key
+		require.NoError(t, err)
+
+		assert.Regexp(t, `^[a-z]+-[a-z]+-[0-9]+$`, string(key))
+		assert.Len(t, key, int(cacheValidationCfg.DefaultKeyLength()))
+	})
+
+	t.Run("words key style shorter than shortest words is invalid length", func(t *testing.T) {
+		params := objectvalue.CacheRequestParams{
+			SourceIP:           "127.0.0.1",
+			Body:               []byte("test"),
+			TTL:                cacheValidationCfg.DefaultTTL(),
+			BodyLen:            4,
+			RequestedKeyLength: cacheValidationCfg.UnprivilegedMinKeyLength(),
+			KeyStyle:           objectvalue.KeyStyleWords,
+		}
+		_, err := svc.Serve(params)
+
+		assert.ErrorIs(t, err, domainerrors.ErrInvalidRequestedKeyLength)
+	})
+
+	t.Run("sequential key style is not allowed for unprivileged", func(t *testing.T) {
+		params := objectvalue.CacheRequestParams{
+			SourceIP:           "127.0.0.1",
+			Body:               []byte("test"),
+			TTL:                cacheValidationCfg.DefaultTTL(),
+			BodyLen:            4,
+			RequestedKeyLength: cacheValidationCfg.DefaultKeyLength(),
+			KeyStyle:           objectvalue.KeyStyleSequential,
+		}
+		_, err := svc.Serve(params)
+
+		assert.ErrorIs(t, err, domainerrors.ErrKeyStyleNotAllowed)
+	})
+
+	t.Run("sequential key style is allowed for privileged", func(t *testing.T) {
+		params := objectvalue.CacheRequestParams{
+			APIKey:             "non-empty",
+			SourceIP:           "127.0.0.1",
+			Body:               []byte("test"),
+			TTL:                cacheValidationCfg.DefaultTTL(),
+			BodyLen:            4,
+			RequestedKeyLength: cacheValidationCfg.PrivilegedMinKeyLength(),
+			KeyStyle:           objectvalue.KeyStyleSequential,
+		}
+		answer, err := svc.Serve(params)
+		key := answer.Key
+		require.NoError(t, err)
+
+		assert.Len(t, key, int(cacheValidationCfg.PrivilegedMinKeyLength()))
 	})
 }
 
-func newRedisClient(db int) *redis.Client {
-	host := getRedisHost()
-	port := 6379
-	return redis.NewClient(&redis.Options{
-		Addr:         fmt.Sprintf("%s:%d", host, port),
-		PoolSize:     100,
-		Password:     "",
-		Username:     "",
-		DB:           db,
-		MaxRetries:   5,
-		DialTimeout:  10 * time.Second,
-		WriteTimeout: 5 * time.Second,
+func TestCacheService_Delete(t *testing.T) {
+	t.Parallel()
+
+	cacheValidationCfg := config.DefaultCacheValidationConfig{}
+	recordRepo := repository.NewMemoryRecordRepository(config.DefaultCachingConfig{})
+	svc := NewCacheService(
+		recordRepo,
+		repository.NewMemoryQuotaRepository(config.DefaultQuotaConfig{}),
+		repository.NewMemoryAPIKeyRepository(),
+		TrueAPIKeyService{},
+		event.NewPublisher(),
+		cacheValidationCfg,
+		config.DefaultQuotaConfig{},
+		NewURLPolicy(config.DefaultURLPolicyConfig{}, nil),
+		MuteLogger{},
+	)
+
+	serve := func(t *testing.T, apikey string) CacheAnswer {
+		t.Helper()
+
+		answer, err := svc.Serve(objectvalue.CacheRequestParams{
+			APIKey:             apikey,
+			SourceIP:           "127.0.0.1",
+			Body:               []byte("secret"),
+			TTL:                cacheValidationCfg.DefaultTTL(),
+			BodyLen:            6,
+			RequestedKeyLength: cacheValidationCfg.DefaultKeyLength(),
+		})
+		require.NoError(t, err)
+		require.NotEmpty(t, answer.OwnerToken)
+
+		return answer
+	}
+
+	t.Run("owner token removes record", func(t *testing.T) {
+		t.Parallel()
+
+		answer := serve(t, "")
+
+		require.NoError(t, svc.Delete(answer.Key, answer.OwnerToken, ""))
+
+		_, err := recordRepo.GetByKey(context.Background(), answer.Key)
+		assert.ErrorIs(t, err, domainerrors.ErrRecordNotFound)
+
+		err = svc.Delete(answer.Key, answer.OwnerToken, "")
+		assert.ErrorIs(t, err, domainerrors.ErrRecordNotFound)
+	})
+
+	t.Run("creator apikey removes record", func(t *testing.T) {
+		t.Parallel()
+
+		answer := serve(t, "non-empty")
+
+		require.NoError(t, svc.Delete(answer.Key, "", "non-empty"))
+	})
+
+	t.Run("apikey does not remove record created without it", func(t *testing.T) {
+		t.Parallel()
+
+		answer := serve(t, "")
+
+		err := svc.Delete(answer.Key, "", "non-empty")
+		assert.ErrorIs(t, err, domainerrors.ErrNotOwner)
+	})
+
+	t.Run("wrong owner token does not remove record", func(t *testing.T) {
+		t.Parallel()
+
+		answer := serve(t, "")
+
+		err := svc.Delete(answer.Key, objectvalue.NewOwnerToken(), "")
+		assert.ErrorIs(t, err, domainerrors.ErrNotOwner)
+
+		exists, err := recordRepo.Exists(context.Background(), answer.Key)
+		require.NoError(t, err)
+		assert.True(t, exists)
+	})
+
+	t.Run("delete without credentials is not authorized", func(t *testing.T) {
+		t.Parallel()
+
+		answer := serve(t, "")
+
+		err := svc.Delete(answer.Key, "", "")
+		assert.ErrorIs(t, err, domainerrors.ErrNonAuthorized)
 	})
 }
 
-func getRedisHost() string {
-	redisHost := os.Getenv("REDIS_HOST")
-	if redisHost == "" {
-		return "localhost"
+func TestCacheService_ServeRequestedKeyConcurrently(t *testing.T) {
+	t.Parallel()
+
+	const writers = 50
+
+	cacheValidationCfg := config.DefaultCacheValidationConfig{}
+	svc := NewCacheService(
+		repository.NewMemoryRecordRepository(config.DefaultCachingConfig{}),
+		repository.NewMemoryQuotaRepository(config.DefaultQuotaConfig{}),
+		repository.NewMemoryAPIKeyRepository(),
+		TrueAPIKeyService{},
+		event.NewPublisher(),
+		cacheValidationCfg,
+		config.DefaultQuotaConfig{},
+		NewURLPolicy(config.DefaultURLPolicyConfig{}, nil),
+		MuteLogger{},
+	)
+
+	var created atomic.Int32
+	var wg sync.WaitGroup
+	for i := range writers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			_, err := svc.Serve(objectvalue.CacheRequestParams{
+				APIKey:             "non-empty",
+				RequestedKey:       "racy",
+				SourceIP:           "127.0.0.1",
+				Body:               []byte(strconv.Itoa(i)),
+				TTL:                cacheValidationCfg.DefaultTTL(),
+				BodyLen:            1,
+				RequestedKeyLength: cacheValidationCfg.DefaultKeyLength(),
+			})
+			if err == nil {
+				created.Add(1)
+				return
+			}
+			assert.ErrorIs(t, err, domainerrors.ErrRequestedKeyExists)
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, int32(1), created.Load())
+}
+
+func TestCacheService_MaxBodySize(t *testing.T) {
+	t.Parallel()
+
+	cacheValidationCfg := config.DefaultCacheValidationConfig{}
+	newService := func(apikeyService IAPIKeyService) *CacheService {
+		return NewCacheService(
+			repository.NewMemoryRecordRepository(config.DefaultCachingConfig{}),
+			repository.NewMemoryQuotaRepository(config.DefaultQuotaConfig{}),
+			repository.NewMemoryAPIKeyRepository(),
+			apikeyService,
+			event.NewPublisher(),
+			cacheValidationCfg,
+			config.DefaultQuotaConfig{},
+			NewURLPolicy(config.DefaultURLPolicyConfig{}, nil),
+			MuteLogger{},
+		)
 	}
-	return redisHost
+
+	t.Run("without apikey returns unprivileged limit", func(t *testing.T) {
+		t.Parallel()
+
+		limit, err := newService(FalseAPIKeyService{}).MaxBodySize("")
+		require.NoError(t, err)
+		assert.Equal(t, cacheValidationCfg.UnprivilegedMaxBodySize(), limit)
+	})
+
+	t.Run("with valid apikey returns privileged limit", func(t *testing.T) {
+		t.Parallel()
+
+		limit, err := newService(TrueAPIKeyService{}).MaxBodySize("non-empty")
+		require.NoError(t, err)
+		assert.Equal(t, cacheValidationCfg.PrivilegedMaxBodySize(), limit)
+	})
+
+	t.Run("with non existing apikey returns error", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := newService(FalseAPIKeyService{}).MaxBodySize("non-empty")
+		assert.ErrorIs(t, err, domainerrors.ErrAPIKeyNotFound)
+	})
+}
+
+func TestCacheService_ServePasswordProtected(t *testing.T) {
+	t.Parallel()
+
+	cacheValidationCfg := config.DefaultCacheValidationConfig{}
+	passwordAttemptsCfg := config.DefaultPasswordAttemptsConfig{}
+	recordRepo := repository.NewMemoryRecordRepository(config.DefaultCachingConfig{})
+	cacheSvc := NewCacheService(
+		recordRepo,
+		repository.NewMemoryQuotaRepository(config.DefaultQuotaConfig{}),
+		repository.NewMemoryAPIKeyRepository(),
+		TrueAPIKeyService{},
+		event.NewPublisher(),
+		cacheValidationCfg,
+		config.DefaultQuotaConfig{},
+		NewURLPolicy(config.DefaultURLPolicyConfig{}, nil),
+		MuteLogger{},
+	)
+	getSvc := NewGetService(recordRepo, repository.NewMemoryQuotaRepository(passwordAttemptsCfg), passwordAttemptsCfg, nil)
+
+	serve := func(t *testing.T) objectvalue.RecordKey {
+		t.Helper()
+
+		answer, err := cacheSvc.Serve(objectvalue.CacheRequestParams{
+			SourceIP:           "127.0.0.1",
+			Body:               []byte("secret"),
+			TTL:                cacheValidationCfg.DefaultTTL(),
+			BodyLen:            6,
+			RequestedKeyLength: cacheValidationCfg.DefaultKeyLength(),
+			Disposable:         1,
+			Password:           "password",
+		})
+		require.NoError(t, err)
+
+		return answer.Key
+	}
+
+	t.Run("body is stored encrypted", func(t *testing.T) {
+		t.Parallel()
+
+		key := serve(t)
+
+		record, err := recordRepo.GetByKey(context.Background(), key)
+		require.NoError(t, err)
+		assert.True(t, record.Protected())
+		assert.NotContains(t, string(record.RGetBody()), "secret")
+	})
+
+	t.Run("info gives size of plain body", func(t *testing.T) {
+		t.Parallel()
+
+		key := serve(t)
+
+		info, err := getSvc.GetInfo(key)
+		require.NoError(t, err)
+		assert.Equal(t, int64(len("secret")), info.BodySize)
+	})
+
+	t.Run("wrong password does not consume disposable record", func(t *testing.T) {
+		t.Parallel()
+
+		key := serve(t)
+
+		_, err := getSvc.GetBody(key, objectvalue.GetRequestParams{})
+		assert.ErrorIs(t, err, domainerrors.ErrWrongPassword)
+
+		_, err = getSvc.GetBody(key, objectvalue.GetRequestParams{Password: "wrong"})
+		assert.ErrorIs(t, err, domainerrors.ErrWrongPassword)
+
+		answer, err := getSvc.GetBody(key, objectvalue.GetRequestParams{Password: "password"})
+		require.NoError(t, err)
+		assert.Equal(t, []byte("secret"), answer.Body)
+
+		_, err = getSvc.GetBody(key, objectvalue.GetRequestParams{Password: "password"})
+		assert.ErrorIs(t, err, domainerrors.ErrRecordNotFound)
+	})
+
+	t.Run("password attempts are limited", func(t *testing.T) {
+		t.Parallel()
+
+		key := serve(t)
+
+		for range passwordAttemptsCfg.Quota() {
+			_, err := getSvc.GetBody(key, objectvalue.GetRequestParams{Password: "wrong"})
+			require.ErrorIs(t, err, domainerrors.ErrWrongPassword)
+		}
+
+		_, err := getSvc.GetBody(key, objectvalue.GetRequestParams{Password: "password"})
+		assert.ErrorIs(t, err, domainerrors.ErrPasswordAttemptsExhausted)
+	})
+}
+
+func TestCacheService_ServeReveal(t *testing.T) {
+	t.Parallel()
+
+	cacheValidationCfg := config.DefaultCacheValidationConfig{}
+	passwordAttemptsCfg := config.DefaultPasswordAttemptsConfig{}
+	recordRepo := repository.NewMemoryRecordRepository(config.DefaultCachingConfig{})
+	cacheSvc := NewCacheService(
+		recordRepo,
+		repository.NewMemoryQuotaRepository(config.DefaultQuotaConfig{}),
+		repository.NewMemoryAPIKeyRepository(),
+		TrueAPIKeyService{},
+		event.NewPublisher(),
+		cacheValidationCfg,
+		config.DefaultQuotaConfig{},
+		NewURLPolicy(config.DefaultURLPolicyConfig{}, nil),
+		MuteLogger{},
+	)
+	getSvc := NewGetService(recordRepo, repository.NewMemoryQuotaRepository(passwordAttemptsCfg), passwordAttemptsCfg, nil)
+
+	answer, err := cacheSvc.Serve(objectvalue.CacheRequestParams{
+		SourceIP:           "127.0.0.1",
+		Body:               []byte("secret"),
+		TTL:                cacheValidationCfg.DefaultTTL(),
+		BodyLen:            6,
+		RequestedKeyLength: cacheValidationCfg.DefaultKeyLength(),
+		Disposable:         1,
+		Reveal:             true,
+	})
+	require.NoError(t, err)
+
+	_, err = getSvc.GetBody(answer.Key, objectvalue.GetRequestParams{})
+	assert.ErrorIs(t, err, domainerrors.ErrRevealNotConfirmed)
+
+	info, err := getSvc.GetInfo(answer.Key)
+	require.NoError(t, err)
+	assert.True(t, info.Reveal)
+	assert.Equal(t, uint8(1), info.RemainingReads, "unconfirmed reading does not consume record")
+
+	got, err := getSvc.GetBody(answer.Key, objectvalue.GetRequestParams{Confirmed: true})
+	require.NoError(t, err)
+	assert.Equal(t, []byte("secret"), got.Body)
+}
+
+func TestCacheService_ServePrivilegedMark(t *testing.T) {
+	t.Parallel()
+
+	cacheValidationCfg := config.DefaultCacheValidationConfig{}
+	passwordAttemptsCfg := config.DefaultPasswordAttemptsConfig{}
+	recordRepo := repository.NewMemoryRecordRepository(config.DefaultCachingConfig{})
+	cacheSvc := NewCacheService(
+		recordRepo,
+		repository.NewMemoryQuotaRepository(config.DefaultQuotaConfig{}),
+		repository.NewMemoryAPIKeyRepository(),
+		TrueAPIKeyService{},
+		event.NewPublisher(),
+		cacheValidationCfg,
+		config.DefaultQuotaConfig{},
+		NewURLPolicy(config.DefaultURLPolicyConfig{}, nil),
+		MuteLogger{},
+	)
+	getSvc := NewGetService(recordRepo, repository.NewMemoryQuotaRepository(passwordAttemptsCfg), passwordAttemptsCfg, nil)
+
+	for _, apikey := range []string{"", "apikey"} {
+		answer, err := cacheSvc.Serve(objectvalue.CacheRequestParams{
+			APIKey:             apikey,
+			SourceIP:           "127.0.0.1",
+			Body:               []byte("<script></script>"),
+			TTL:                cacheValidationCfg.DefaultTTL(),
+			BodyLen:            17,
+			RequestedKeyLength: cacheValidationCfg.DefaultKeyLength(),
+			ContentType:        "text/html",
+		})
+		require.NoError(t, err)
+
+		got, err := getSvc.GetBody(answer.Key, objectvalue.GetRequestParams{})
+		require.NoError(t, err)
+		assert.Equal(t, apikey != "", got.Privileged)
+		assert.Equal(t, "text/html", got.ContentType)
+	}
+}
+
+func TestCacheService_ServeRedirect(t *testing.T) {
+	t.Parallel()
+
+	cacheValidationCfg := config.DefaultCacheValidationConfig{}
+	passwordAttemptsCfg := config.DefaultPasswordAttemptsConfig{}
+	recordRepo := repository.NewMemoryRecordRepository(config.DefaultCachingConfig{})
+	cacheSvc := NewCacheService(
+		recordRepo,
+		repository.NewMemoryQuotaRepository(config.DefaultQuotaConfig{}),
+		repository.NewMemoryAPIKeyRepository(),
+		TrueAPIKeyService{},
+		event.NewPublisher(),
+		cacheValidationCfg,
+		config.DefaultQuotaConfig{},
+		NewURLPolicy(config.DefaultURLPolicyConfig{}, nil),
+		MuteLogger{},
+	)
+	getSvc := NewGetService(recordRepo, repository.NewMemoryQuotaRepository(passwordAttemptsCfg), passwordAttemptsCfg, nil)
+
+	serve := func(t *testing.T, redirect objectvalue.Redirect) objectvalue.RecordKey {
+		t.Helper()
+
+		answer, err := cacheSvc.Serve(objectvalue.CacheRequestParams{
+			SourceIP:           "127.0.0.1",
+			Body:               []byte("https://example.com/"),
+			TTL:                cacheValidationCfg.DefaultTTL(),
+			BodyLen:            20,
+			RequestedKeyLength: cacheValidationCfg.DefaultKeyLength(),
+			IsURL:              true,
+			Redirect:           redirect,
+		})
+		require.NoError(t, err)
+		return answer.Key
+	}
+
+	t.Run("redirect is stored and path suffix is accepted", func(t *testing.T) {
+		t.Parallel()
+
+		redirect := objectvalue.NewRedirect(objectvalue.RedirectMovedPermanently, true, true)
+		key := serve(t, redirect)
+
+		got, err := getSvc.GetBody(key, objectvalue.GetRequestParams{File: "guide/intro"})
+		require.NoError(t, err)
+		assert.True(t, got.IsURL)
+		assert.Equal(t, redirect, got.Redirect)
+		assert.Equal(t, []byte("https://example.com/"), got.Body)
+
+		head, err := getSvc.Head(key, objectvalue.GetRequestParams{File: "guide/intro"})
+		require.NoError(t, err)
+		assert.Equal(t, redirect, head.Redirect)
+	})
+
+	t.Run("path suffix of url not forwarding path is not found", func(t *testing.T) {
+		t.Parallel()
+
+		key := serve(t, objectvalue.NewRedirect(0, true, false))
+
+		_, err := getSvc.GetBody(key, objectvalue.GetRequestParams{File: "guide/intro"})
+		require.ErrorIs(t, err, domainerrors.ErrRecordNotFound)
+
+		got, err := getSvc.GetBody(key, objectvalue.GetRequestParams{})
+		require.NoError(t, err)
+		assert.Equal(t, objectvalue.RedirectSeeOther, got.Redirect.Status())
+	})
+}
+
+func TestCacheService_ServeBundle(t *testing.T) {
+	t.Parallel()
+
+	cacheValidationCfg := config.DefaultCacheValidationConfig{}
+	passwordAttemptsCfg := config.DefaultPasswordAttemptsConfig{}
+	recordRepo := repository.NewMemoryRecordRepository(config.DefaultCachingConfig{})
+	cacheSvc := NewCacheService(
+		recordRepo,
+		repository.NewMemoryQuotaRepository(config.DefaultQuotaConfig{}),
+		repository.NewMemoryAPIKeyRepository(),
+		TrueAPIKeyService{},
+		event.NewPublisher(),
+		cacheValidationCfg,
+		config.DefaultQuotaConfig{},
+		NewURLPolicy(config.DefaultURLPolicyConfig{}, nil),
+		MuteLogger{},
+	)
+	getSvc := NewGetService(recordRepo, repository.NewMemoryQuotaRepository(passwordAttemptsCfg), passwordAttemptsCfg, nil)
+
+	serve := func(t *testing.T, disposable uint8, password string, files objectvalue.BundleFiles) (CacheAnswer, error) {
+		t.Helper()
+
+		body := []byte("key: valuestarted")
+		return cacheSvc.Serve(objectvalue.CacheRequestParams{
+			SourceIP:           "127.0.0.1",
+			Body:               body,
+			TTL:                cacheValidationCfg.DefaultTTL(),
+			BodyLen:            int64(len(body)),
+			RequestedKeyLength: cacheValidationCfg.DefaultKeyLength(),
+			Disposable:         disposable,
+			Password:           password,
+			Files:              files,
+		})
+	}
+
+	files := objectvalue.BundleFiles{
+		objectvalue.NewBundleFile("config.yaml", "application/yaml", 10),
+		objectvalue.NewBundleFile("logs/app.log", "", 7),
+	}
+
+	t.Run("listing does not consume bundle and files share disposable counter", func(t *testing.T) {
+		t.Parallel()
+
+		answer, err := serve(t, 2, "", files)
+		require.NoError(t, err)
+
+		listing, err := getSvc.GetBody(answer.Key, objectvalue.GetRequestParams{})
+		require.NoError(t, err)
+		assert.Equal(t, files, listing.Files)
+		assert.Empty(t, listing.Body)
+
+		got, err := getSvc.GetBody(answer.Key, objectvalue.GetRequestParams{File: "logs/app.log"})
+		require.NoError(t, err)
+		assert.Equal(t, []byte("started"), got.Body)
+		assert.Equal(t, "app.log", got.Filename)
+		assert.Empty(t, got.Files)
+
+		got, err = getSvc.GetBody(answer.Key, objectvalue.GetRequestParams{Archive: true})
+		require.NoError(t, err)
+		assert.Equal(t, []byte("key: valuestarted"), got.Body)
+		assert.Equal(t, files, got.Files)
+
+		_, err = getSvc.GetBody(answer.Key, objectvalue.GetRequestParams{File: "config.yaml"})
+		assert.ErrorIs(t, err, domainerrors.ErrRecordNotFound)
+	})
+
+	t.Run("missing file is not found and does not consume bundle", func(t *testing.T) {
+		t.Parallel()
+
+		answer, err := serve(t, 1, "", files)
+		require.NoError(t, err)
+
+		_, err = getSvc.GetBody(answer.Key, objectvalue.GetRequestParams{File: "missing.txt"})
+		assert.ErrorIs(t, err, domainerrors.ErrRecordNotFound)
+
+		got, err := getSvc.GetBody(answer.Key, objectvalue.GetRequestParams{File: "config.yaml"})
+		require.NoError(t, err)
+		assert.Equal(t, []byte("key: value"), got.Body)
+		assert.Equal(t, "application/yaml", got.ContentType)
+	})
+
+	t.Run("protected bundle requires password for listing and files", func(t *testing.T) {
+		t.Parallel()
+
+		answer, err := serve(t, 0, "secret", files)
+		require.NoError(t, err)
+
+		_, err = getSvc.GetBody(answer.Key, objectvalue.GetRequestParams{})
+		assert.ErrorIs(t, err, domainerrors.ErrWrongPassword)
+
+		got, err := getSvc.GetBody(answer.Key, objectvalue.GetRequestParams{Password: "secret", File: "logs/app.log"})
+		require.NoError(t, err)
+		assert.Equal(t, []byte("started"), got.Body)
+	})
+
+	t.Run("plain record has no files", func(t *testing.T) {
+		t.Parallel()
+
+		answer, err := serve(t, 0, "", nil)
+		require.NoError(t, err)
+
+		_, err = getSvc.GetBody(answer.Key, objectvalue.GetRequestParams{File: "config.yaml"})
+		assert.ErrorIs(t, err, domainerrors.ErrRecordNotFound)
+	})
+
+	t.Run("invalid bundles are rejected", func(t *testing.T) {
+		t.Parallel()
+
+		invalid := map[string]objectvalue.BundleFiles{
+			"duplicate names": {
+				objectvalue.NewBundleFile("a.txt", "", 10),
+				objectvalue.NewBundleFile("a.txt", "", 7),
+			},
+			"reserved name": {
+				objectvalue.NewBundleFile(objectvalue.BundleArchiveName, "", 17),
+			},
+			"sizes do not match body": {
+				objectvalue.NewBundleFile("a.txt", "", 1),
+			},
+		}
+
+		for name, files := range invalid {
+			_, err := serve(t, 0, "", files)
+			assert.ErrorIs(t, err, domainerrors.ErrInvalidBundle, name)
+		}
+	})
 }
 
 type MuteLogger struct{}
@@ -122,7 +756,7 @@ func (s TrueAPIKeyService) CheckValid(context.Context, string) (bool, error) {
 }
 
 func (s TrueAPIKeyService) GetID(context.Context, string) (string, error) {
-	return "", nil
+	return "apikey-id", nil
 }
 
 type FalseAPIKeyService struct{}
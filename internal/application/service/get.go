@@ -2,58 +2,345 @@ package service
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"path"
 	"time"
 
 	"github.com/thek4n/paste.thek4n.ru/internal/application/repository"
 	"github.com/thek4n/paste.thek4n.ru/internal/domain/aggregate"
+	"github.com/thek4n/paste.thek4n.ru/internal/domain/config"
+	"github.com/thek4n/paste.thek4n.ru/internal/domain/domainerrors"
 	"github.com/thek4n/paste.thek4n.ru/internal/domain/objectvalue"
+	"github.com/thek4n/paste.thek4n.ru/pkg/passwordcrypt"
 )
 
+// passwordAttemptsPrefix separates password attempts of records from quotas of ips.
+const passwordAttemptsPrefix = "password:"
+
 // GetService application service for getting records.
 type GetService struct {
-	recordRepository repository.RecordRepository
+	recordRepository           repository.RecordRepository
+	passwordAttemptsRepository repository.QuotaRepository
+	passwordAttemptsConfig     config.QuotaConfig
+	clickStatsService          *ClickStatsService
 }
 
-// NewGetService constructor.
-func NewGetService(recordRepository repository.RecordRepository) *GetService {
+// NewGetService constructor. Wrong password attempts of protected records
+// are limited by quotas of passwordAttemptsRepository. Clicks on records
+// are recorded by clickStatsService, nil records no clicks.
+func NewGetService(
+	recordRepository repository.RecordRepository,
+	passwordAttemptsRepository repository.QuotaRepository,
+	passwordAttemptsConfig config.QuotaConfig,
+	clickStatsService *ClickStatsService,
+) *GetService {
 	return &GetService{
-		recordRepository: recordRepository,
+		recordRepository:           recordRepository,
+		passwordAttemptsRepository: passwordAttemptsRepository,
+		passwordAttemptsConfig:     passwordAttemptsConfig,
+		clickStatsService:          clickStatsService,
 	}
 }
 
 // GetBodyAnswer GetService result.
 type GetBodyAnswer struct {
-	Body  []byte
-	IsURL bool
+	Body []byte
+	// BodyEncoding is not empty if Body is returned encoded.
+	BodyEncoding objectvalue.BodyEncoding
+	IsURL        bool
+	// ContentType is empty if not declared on caching.
+	ContentType string
+	Filename    string
+	// ExpiresAt is zero if record is eternal.
+	ExpiresAt time.Time
+	// RemainingReads is meaningful only if ReadsLimited.
+	RemainingReads uint8
+	ReadsLimited   bool
+	Protected      bool
+	// Encrypted is true if Body is ciphertext which only client can decrypt.
+	Encrypted bool
+	Reveal    bool
+	// Files of bundle record. Body is empty if only listing of bundle is got.
+	Files objectvalue.BundleFiles
+	// Privileged is true if record was cached with apikey.
+	Privileged bool
+	// Redirect of url record.
+	Redirect objectvalue.Redirect
+}
+
+// GetInfoAnswer GetService result describing record without consuming it.
+type GetInfoAnswer struct {
+	GetBodyAnswer
+	Clicks   uint32
+	BodySize int64
 }
 
-// GetBody returns GetBodyAnswer. If not exists returns ErrRecordNotFound as error.
-func (h *GetService) GetBody(key objectvalue.RecordKey) (GetBodyAnswer, error) {
+// GetBody consumes record and returns GetBodyAnswer. If not exists returns ErrRecordNotFound as error.
+// Body stored in one of accepted encodings is returned without decoding.
+// Record requiring reveal is consumed only if reading is confirmed,
+// otherwise ErrRevealNotConfirmed is returned. Protected record is consumed
+// and decrypted only if password is right, otherwise ErrWrongPassword is returned.
+// Bundle is consumed as a whole by getting its file or archive of all files,
+// its listing is returned without consuming. Url record forwarding path is
+// also got with path suffix in File. Consuming by bot is not counted in
+// clicks. Consuming of record cached with apikey is recorded in background
+// as click described by params.Click.
+func (h *GetService) GetBody(key objectvalue.RecordKey, params objectvalue.GetRequestParams) (GetBodyAnswer, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
-	record, err := h.get(ctx, key)
+	access, err := h.recordRepository.AccessByKey(ctx, key)
 	if err != nil {
+		return GetBodyAnswer{}, fmt.Errorf("fail to get record access: %w", err)
+	}
+
+	files := access.Files()
+	listing := len(files) > 0 && params.File == "" && !params.Archive
+	if err := checkPathParams(files, access.ForwardPath(), params); err != nil {
 		return GetBodyAnswer{}, err
 	}
 
-	body, err := record.GetBody()
+	if access.Reveal() && !params.Confirmed && !listing {
+		return GetBodyAnswer{}, domainerrors.ErrRevealNotConfirmed
+	}
+
+	password := params.Password
+	checked, err := h.checkPassword(ctx, key, access.PasswordHash(), password)
 	if err != nil {
-		return GetBodyAnswer{}, fmt.Errorf("fail to read record body: %w", err)
+		return GetBodyAnswer{}, err
+	}
+
+	if listing {
+		return GetBodyAnswer{
+			Protected: access.PasswordHash() != "",
+			Reveal:    access.Reveal(),
+			Files:     files,
+		}, nil
+	}
+
+	acceptedEncodings := params.AcceptedEncodings
+	if checked.hash != "" || len(files) > 0 {
+		// encrypted body can not be served encoded, files are cut from
+		// decoded body of bundle
+		acceptedEncodings = nil
 	}
 
-	err = h.recordRepository.SetByKey(ctx, key, record)
+	countClick := objectvalue.ClassifyUserAgent(params.Click.UserAgent) != objectvalue.UserAgentBot
+	record, err := h.recordRepository.ConsumeByKey(ctx, key, countClick, acceptedEncodings...)
 	if err != nil {
-		return GetBodyAnswer{}, fmt.Errorf("fail to write record: %w", err)
+		return GetBodyAnswer{}, fmt.Errorf("fail to consume record: %w", err)
 	}
 
-	return GetBodyAnswer{
-		Body:  body,
-		IsURL: record.URL(),
+	if record.Protected() {
+		body, err := checked.open(record, password)
+		if errors.Is(err, passwordcrypt.ErrWrongPassword) {
+			return GetBodyAnswer{}, domainerrors.ErrWrongPassword
+		}
+		if err != nil {
+			return GetBodyAnswer{}, fmt.Errorf("fail to decrypt record: %w", err)
+		}
+		record.SetEncodedBody(body, objectvalue.BodyEncodingIdentity)
+	}
+
+	if h.clickStatsService != nil && record.Metadata().APIKeyID() != "" {
+		h.clickStatsService.AddClick(key, params.Click)
+	}
+
+	return newFileGetBodyAnswer(record, params.File)
+}
+
+// Head returns record like GetBody but without consuming it, so clients
+// can check record before reading. Password and reveal are not required,
+// so body of protected record and listing of bundle are not returned.
+func (h *GetService) Head(key objectvalue.RecordKey, params objectvalue.GetRequestParams) (GetBodyAnswer, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	acceptedEncodings := params.AcceptedEncodings
+	if params.File != "" || params.Archive {
+		acceptedEncodings = nil
+	}
+
+	record, err := h.recordRepository.GetByKey(ctx, key, acceptedEncodings...)
+	if err != nil {
+		return GetBodyAnswer{}, fmt.Errorf("fail to get record: %w", err)
+	}
+
+	if record.CounterExhausted() {
+		return GetBodyAnswer{}, domainerrors.ErrRecordCounterExhausted
+	}
+
+	if record.ExpirationDate().Expired() {
+		return GetBodyAnswer{}, domainerrors.ErrRecordExpired
+	}
+
+	if err := checkPathParams(record.Files(), record.URL() && record.Redirect().ForwardPath(), params); err != nil {
+		return GetBodyAnswer{}, err
+	}
+
+	if record.Protected() || (record.Bundle() && params.File == "") {
+		answer := newGetBodyAnswer(record)
+		answer.Body = nil
+		return answer, nil
+	}
+
+	return newFileGetBodyAnswer(record, params.File)
+}
+
+// checkPathParams returns ErrRecordNotFound if file or archive is
+// requested for record which is not bundle or file is not in bundle.
+// Path suffix is accepted by url record forwarding path.
+func checkPathParams(files objectvalue.BundleFiles, forwardPath bool, params objectvalue.GetRequestParams) error {
+	if params.File == "" && !params.Archive {
+		return nil
+	}
+
+	if len(files) == 0 {
+		if forwardPath && !params.Archive {
+			return nil
+		}
+		return domainerrors.ErrRecordNotFound
+	}
+
+	if _, _, ok := files.Find(params.File); params.File != "" && !ok {
+		return domainerrors.ErrRecordNotFound
+	}
+
+	return nil
+}
+
+// newFileGetBodyAnswer returns answer with body of record or with content
+// of bundle file if it is not empty. Body of bundle must be decoded.
+func newFileGetBodyAnswer(record aggregate.Record, name string) (GetBodyAnswer, error) {
+	answer := newGetBodyAnswer(record)
+
+	if name != "" && record.Bundle() {
+		file, body, ok := record.BundleFile(name)
+		if !ok {
+			return GetBodyAnswer{}, fmt.Errorf("bundle file '%s' is out of body", name)
+		}
+
+		answer.Body = body
+		answer.ContentType = file.ContentType()
+		answer.Filename = path.Base(file.Name())
+		answer.Files = nil
+	}
+
+	return answer, nil
+}
+
+// checkedPassword password hash of record and key derived from right password.
+// Hash is empty if record is not protected.
+type checkedPassword struct {
+	hash string
+	key  passwordcrypt.Key
+}
+
+// open decrypts body of record. Password is checked again only if it was
+// changed after checking.
+func (c checkedPassword) open(record aggregate.Record, password string) ([]byte, error) {
+	if record.PasswordHash() == c.hash {
+		return c.key.Open(record.RGetBody())
+	}
+
+	return passwordcrypt.Open(record.PasswordHash(), password, record.RGetBody())
+}
+
+// checkPassword returns ErrWrongPassword if record is protected and
+// password is missing or wrong. Wrong attempts are counted per key and
+// ErrPasswordAttemptsExhausted is returned if too many were made.
+func (h *GetService) checkPassword(ctx context.Context, key objectvalue.RecordKey, hash, password string) (checkedPassword, error) {
+	if hash == "" {
+		return checkedPassword{}, nil
+	}
+
+	if password == "" {
+		return checkedPassword{}, domainerrors.ErrWrongPassword
+	}
+
+	attemptsID := objectvalue.QuotaSourceIP(passwordAttemptsPrefix + string(key))
+	attempts, err := h.passwordAttemptsRepository.GetByID(ctx, attemptsID)
+	if errors.Is(err, domainerrors.ErrQuotaNotFound) {
+		attempts = aggregate.NewQuota(attemptsID, h.passwordAttemptsConfig.Quota())
+	} else if err != nil {
+		return checkedPassword{}, fmt.Errorf("fail to get password attempts: %w", err)
+	}
+
+	if attempts.Exhausted() {
+		return checkedPassword{}, domainerrors.ErrPasswordAttemptsExhausted
+	}
+
+	passwordKey, err := passwordcrypt.Verify(hash, password)
+	if errors.Is(err, passwordcrypt.ErrWrongPassword) {
+		attempts.Sub()
+		if err := h.passwordAttemptsRepository.SetByID(ctx, attemptsID, attempts); err != nil {
+			return checkedPassword{}, fmt.Errorf("fail to write password attempts: %w", err)
+		}
+		return checkedPassword{}, domainerrors.ErrWrongPassword
+	}
+	if err != nil {
+		return checkedPassword{}, fmt.Errorf("fail to verify password: %w", err)
+	}
+
+	return checkedPassword{hash: hash, key: passwordKey}, nil
+}
+
+// GetInfo returns record description without consuming it and without
+// reading its body. BodySize is size of plain body given on caching.
+// If not exists returns ErrRecordNotFound as error.
+func (h *GetService) GetInfo(key objectvalue.RecordKey) (GetInfoAnswer, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	record, err := h.recordRepository.InfoByKey(ctx, key)
+	if err != nil {
+		return GetInfoAnswer{}, fmt.Errorf("fail to get record info from repository: %w", err)
+	}
+
+	if record.CounterExhausted() {
+		return GetInfoAnswer{}, domainerrors.ErrRecordCounterExhausted
+	}
+
+	if record.ExpirationDate().Expired() {
+		return GetInfoAnswer{}, domainerrors.ErrRecordExpired
+	}
+
+	// records cached before body size was stored have no size, so it is
+	// measured by stored body
+	bodySize := record.BodySize()
+	if bodySize == 0 {
+		stored, err := h.get(ctx, key)
+		if err != nil {
+			return GetInfoAnswer{}, err
+		}
+		bodySize = int64(len(stored.RGetBody()))
+	}
+
+	return GetInfoAnswer{
+		GetBodyAnswer: newGetBodyAnswer(record),
+		Clicks:        record.Clicks(),
+		BodySize:      bodySize,
 	}, nil
 }
 
+// GetAccess returns what reader must present to consume record. Body of
+// record is not loaded. If not exists returns ErrRecordNotFound as error.
+func (h *GetService) GetAccess(key objectvalue.RecordKey) (objectvalue.RecordAccess, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	access, err := h.recordRepository.AccessByKey(ctx, key)
+	if errors.Is(err, domainerrors.ErrRecordNotFound) {
+		return objectvalue.RecordAccess{}, domainerrors.ErrRecordNotFound
+	}
+	if err != nil {
+		return objectvalue.RecordAccess{}, fmt.Errorf("fail to get record access: %w", err)
+	}
+
+	return access, nil
+}
+
 // GetClicks returns clicks number. If not exists returns ErrRecordNotFound as error.
 func (h *GetService) GetClicks(key objectvalue.RecordKey) (uint32, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
@@ -67,6 +354,25 @@ func (h *GetService) GetClicks(key objectvalue.RecordKey) (uint32, error) {
 	return record.Clicks(), nil
 }
 
+func newGetBodyAnswer(record aggregate.Record) GetBodyAnswer {
+	return GetBodyAnswer{
+		Body:           record.RGetBody(),
+		BodyEncoding:   record.BodyEncoding(),
+		IsURL:          record.URL(),
+		ContentType:    record.Metadata().ContentType(),
+		Filename:       record.Metadata().Filename(),
+		ExpiresAt:      record.ExpirationDate().Date(),
+		RemainingReads: record.DisposableCounter(),
+		ReadsLimited:   !record.DisposableCounterEternal(),
+		Protected:      record.Protected(),
+		Encrypted:      record.Encrypted(),
+		Reveal:         record.Reveal(),
+		Files:          record.Files(),
+		Privileged:     record.Metadata().APIKeyID() != "",
+		Redirect:       record.Redirect(),
+	}
+}
+
 func (h *GetService) get(ctx context.Context, key objectvalue.RecordKey) (aggregate.Record, error) {
 	record, err := h.recordRepository.GetByKey(ctx, key)
 	if err != nil {
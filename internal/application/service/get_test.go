@@ -0,0 +1,308 @@
+//go:build integration
+
+package service
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/thek4n/paste.thek4n.ru/internal/domain/aggregate"
+	"github.com/thek4n/paste.thek4n.ru/internal/domain/config"
+	"github.com/thek4n/paste.thek4n.ru/internal/domain/domainerrors"
+	"github.com/thek4n/paste.thek4n.ru/internal/domain/objectvalue"
+	"github.com/thek4n/paste.thek4n.ru/internal/infrastructure/repository"
+	"github.com/thek4n/paste.thek4n.ru/pkg/bodycodec"
+)
+
+func TestGetService_GetBody(t *testing.T) {
+	t.Parallel()
+
+	recordsClient := newRedisClient(0)
+	recordRepo := repository.NewRedisRecordRepository(recordsClient, config.DefaultCachingConfig{}, "")
+	svc := NewGetService(recordRepo, repository.NewMemoryQuotaRepository(config.DefaultPasswordAttemptsConfig{}), config.DefaultPasswordAttemptsConfig{}, nil)
+
+	t.Run("concurrent reads of disposable record serve body exactly disposable times", func(t *testing.T) {
+		const disposable = 5
+		const readers = 100
+
+		key := objectvalue.RecordKey("concurrent-disposable-key")
+		record := aggregate.NewRecord(
+			string(key),
+			objectvalue.NewExpirationDateFromTTL(time.Minute),
+			disposable,
+			false,
+			0,
+			[]byte("secret"),
+			false,
+		)
+		require.NoError(t, recordRepo.SetByKey(context.Background(), key, record))
+
+		var served atomic.Int32
+		var wg sync.WaitGroup
+		start := make(chan struct{})
+
+		for range readers {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				<-start
+
+				answer, err := svc.GetBody(key, objectvalue.GetRequestParams{})
+				if err == nil {
+					assert.Equal(t, []byte("secret"), answer.Body)
+					served.Add(1)
+				}
+			}()
+		}
+
+		close(start)
+		wg.Wait()
+
+		assert.Equal(t, int32(disposable), served.Load())
+
+		exists, err := recordRepo.Exists(context.Background(), key)
+		require.NoError(t, err)
+		assert.False(t, exists, "exhausted record should be removed")
+	})
+
+	t.Run("eternal record is not removed and clicks are counted", func(t *testing.T) {
+		const readers = 50
+
+		key := objectvalue.RecordKey("concurrent-eternal-key")
+		record := aggregate.NewRecord(
+			string(key),
+			objectvalue.NewExpirationDateFromTTL(time.Minute),
+			0,
+			true,
+			0,
+			[]byte("body"),
+			false,
+		)
+		require.NoError(t, recordRepo.SetByKey(context.Background(), key, record))
+
+		var wg sync.WaitGroup
+		for range readers {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				_, err := svc.GetBody(key, objectvalue.GetRequestParams{})
+				assert.NoError(t, err)
+			}()
+		}
+		wg.Wait()
+
+		clicks, err := svc.GetClicks(key)
+		require.NoError(t, err)
+		assert.Equal(t, uint32(readers), clicks)
+	})
+
+	t.Run("reading record does not prolong its expiration date", func(t *testing.T) {
+		key := objectvalue.RecordKey("absolute-expiration-key")
+		expirationDate := objectvalue.NewExpirationDateFromTTL(time.Hour)
+		record := aggregate.NewRecord(string(key), expirationDate, 0, true, 0, []byte("body"), false)
+		require.NoError(t, recordRepo.SetByKey(context.Background(), key, record))
+
+		for range 3 {
+			_, err := svc.GetBody(key, objectvalue.GetRequestParams{})
+			require.NoError(t, err)
+		}
+
+		got, err := recordRepo.GetByKey(context.Background(), key)
+		require.NoError(t, err)
+		assert.Equal(t, expirationDate.Date().UnixMilli(), got.ExpirationDate().Date().UnixMilli())
+
+		pttl, err := recordsClient.PTTL(context.Background(), string(key)).Result()
+		require.NoError(t, err)
+		assert.LessOrEqual(t, pttl, time.Hour)
+	})
+
+	t.Run("legacy record with relative ttl migrates to absolute expiration date", func(t *testing.T) {
+		ctx := context.Background()
+		key := "legacy-ttl-key"
+
+		require.NoError(t, recordsClient.HSet(ctx, key,
+			"body", "legacy",
+			"ttl", int64(time.Hour),
+			"clicks", 0,
+			"countdown", 0,
+			"eternal", true,
+			"url", false,
+		).Err())
+		require.NoError(t, recordsClient.Expire(ctx, key, 30*time.Minute).Err())
+
+		answer, err := svc.GetBody(objectvalue.RecordKey(key), objectvalue.GetRequestParams{})
+		require.NoError(t, err)
+		assert.Equal(t, []byte("legacy"), answer.Body)
+
+		got, err := recordRepo.GetByKey(ctx, objectvalue.RecordKey(key))
+		require.NoError(t, err)
+		assert.False(t, got.ExpirationDateEternal())
+		assert.InDelta(t, (30 * time.Minute).Seconds(), got.TTL().Seconds(), 5)
+
+		ttlExists, err := recordsClient.HExists(ctx, key, "ttl").Result()
+		require.NoError(t, err)
+		assert.False(t, ttlExists)
+	})
+
+	t.Run("legacy record without encoding field is decoded by gzip magic bytes", func(t *testing.T) {
+		ctx := context.Background()
+		key := "legacy-gzip-key"
+		body := bytes.Repeat([]byte("legacy "), 1000)
+
+		var compressed bytes.Buffer
+		gz := gzip.NewWriter(&compressed)
+		_, err := gz.Write(body)
+		require.NoError(t, err)
+		require.NoError(t, gz.Close())
+
+		require.NoError(t, recordsClient.HSet(ctx, key,
+			"body", compressed.Bytes(),
+			"clicks", 0,
+			"countdown", 0,
+			"eternal", true,
+			"url", false,
+		).Err())
+
+		answer, err := svc.GetBody(objectvalue.RecordKey(key), objectvalue.GetRequestParams{})
+		require.NoError(t, err)
+		assert.Equal(t, body, answer.Body)
+
+		answer, err = svc.GetBody(objectvalue.RecordKey(key), objectvalue.GetRequestParams{AcceptedEncodings: []objectvalue.BodyEncoding{bodycodec.Gzip}})
+		require.NoError(t, err)
+		assert.Equal(t, objectvalue.BodyEncoding(bodycodec.Gzip), answer.BodyEncoding)
+		assert.Equal(t, compressed.Bytes(), answer.Body)
+	})
+}
+
+func TestGetService_GetInfo(t *testing.T) {
+	t.Parallel()
+
+	recordsClient := newRedisClient(0)
+	recordRepo := repository.NewRedisRecordRepository(recordsClient, config.DefaultCachingConfig{}, "")
+	svc := NewGetService(recordRepo, repository.NewMemoryQuotaRepository(config.DefaultPasswordAttemptsConfig{}), config.DefaultPasswordAttemptsConfig{}, nil)
+
+	t.Run("getting info does not consume disposable record", func(t *testing.T) {
+		key := objectvalue.RecordKey("info-disposable-key")
+		expirationDate := objectvalue.NewExpirationDateFromTTL(time.Hour)
+		record := aggregate.NewRecord(string(key), expirationDate, 1, false, 0, []byte("secret"), false)
+		require.NoError(t, recordRepo.SetByKey(context.Background(), key, record))
+
+		for range 3 {
+			info, err := svc.GetInfo(key)
+			require.NoError(t, err)
+			assert.True(t, info.ReadsLimited)
+			assert.Equal(t, uint8(1), info.RemainingReads)
+			assert.Equal(t, uint32(0), info.Clicks)
+			assert.Equal(t, int64(len("secret")), info.BodySize)
+			assert.Equal(t, expirationDate.Date().UnixMilli(), info.ExpiresAt.UnixMilli())
+		}
+
+		answer, err := svc.GetBody(key, objectvalue.GetRequestParams{})
+		require.NoError(t, err)
+		assert.Equal(t, uint8(0), answer.RemainingReads)
+
+		_, err = svc.GetInfo(key)
+		assert.ErrorIs(t, err, domainerrors.ErrRecordNotFound)
+	})
+}
+
+func TestGetService_Head(t *testing.T) {
+	t.Parallel()
+
+	recordsClient := newRedisClient(0)
+	recordRepo := repository.NewRedisRecordRepository(recordsClient, config.DefaultCachingConfig{}, "")
+	svc := NewGetService(recordRepo, repository.NewMemoryQuotaRepository(config.DefaultPasswordAttemptsConfig{}), config.DefaultPasswordAttemptsConfig{}, nil)
+
+	t.Run("head does not consume disposable record", func(t *testing.T) {
+		key := objectvalue.RecordKey("head-disposable-key")
+		record := aggregate.NewRecord(string(key), objectvalue.NewExpirationDateFromTTL(time.Hour), 1, false, 0, []byte("secret"), false)
+		require.NoError(t, recordRepo.SetByKey(context.Background(), key, record))
+
+		for range 3 {
+			answer, err := svc.Head(key, objectvalue.GetRequestParams{})
+			require.NoError(t, err)
+			assert.Equal(t, []byte("secret"), answer.Body)
+			assert.True(t, answer.ReadsLimited)
+			assert.Equal(t, uint8(1), answer.RemainingReads)
+		}
+
+		clicks, err := svc.GetClicks(key)
+		require.NoError(t, err)
+		assert.Equal(t, uint32(0), clicks)
+
+		_, err = svc.GetBody(key, objectvalue.GetRequestParams{})
+		require.NoError(t, err)
+
+		_, err = svc.Head(key, objectvalue.GetRequestParams{})
+		assert.ErrorIs(t, err, domainerrors.ErrRecordNotFound)
+	})
+
+	t.Run("head returns body in accepted encoding like get", func(t *testing.T) {
+		key := objectvalue.RecordKey("head-encoded-key")
+		body := bytes.Repeat([]byte("a"), 8192)
+		record := aggregate.NewRecord(string(key), objectvalue.NewExpirationDateFromTTL(time.Hour), 0, true, 0, body, false)
+		require.NoError(t, recordRepo.SetByKey(context.Background(), key, record))
+
+		params := objectvalue.GetRequestParams{AcceptedEncodings: []objectvalue.BodyEncoding{bodycodec.Gzip}}
+
+		head, err := svc.Head(key, params)
+		require.NoError(t, err)
+		got, err := svc.GetBody(key, params)
+		require.NoError(t, err)
+
+		assert.Equal(t, objectvalue.BodyEncoding(bodycodec.Gzip), head.BodyEncoding)
+		assert.Equal(t, got.BodyEncoding, head.BodyEncoding)
+		assert.Equal(t, got.Body, head.Body)
+
+		head, err = svc.Head(key, objectvalue.GetRequestParams{})
+		require.NoError(t, err)
+		assert.Equal(t, objectvalue.BodyEncodingIdentity, head.BodyEncoding)
+		assert.Equal(t, body, head.Body)
+	})
+
+	t.Run("head of protected record has no body", func(t *testing.T) {
+		key := objectvalue.RecordKey("head-protected-key")
+		record := aggregate.NewRecord(string(key), objectvalue.NewExpirationDateFromTTL(time.Hour), 0, true, 0, []byte("sealed"), false)
+		record.SetPasswordHash("hash")
+		require.NoError(t, recordRepo.SetByKey(context.Background(), key, record))
+
+		answer, err := svc.Head(key, objectvalue.GetRequestParams{})
+		require.NoError(t, err)
+		assert.True(t, answer.Protected)
+		assert.Nil(t, answer.Body)
+	})
+}
+
+func newRedisClient(db int) *redis.Client {
+	host := getRedisHost()
+	port := 6379
+	return redis.NewClient(&redis.Options{
+		Addr:         fmt.Sprintf("%s:%d", host, port),
+		PoolSize:     100,
+		Password:     "",
+		Username:     "",
+		DB:           db,
+		MaxRetries:   5,
+		DialTimeout:  10 * time.Second,
+		WriteTimeout: 5 * time.Second,
+	})
+}
+
+func getRedisHost() string {
+	redisHost := os.Getenv("REDIS_HOST")
+	if redisHost == "" {
+		return "localhost"
+	}
+	return redisHost
+}
@@ -0,0 +1,158 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/thek4n/paste.thek4n.ru/internal/application/repository"
+	"github.com/thek4n/paste.thek4n.ru/internal/domain/aggregate"
+	"github.com/thek4n/paste.thek4n.ru/internal/domain/config"
+	"github.com/thek4n/paste.thek4n.ru/internal/domain/domainerrors"
+	"github.com/thek4n/paste.thek4n.ru/internal/domain/logger"
+	"github.com/thek4n/paste.thek4n.ru/internal/domain/objectvalue"
+)
+
+// clickTimeout bounds recording of one click.
+const clickTimeout = 3 * time.Second
+
+// maxPendingClicks limits clicks recorded at once, extra clicks are dropped.
+const maxPendingClicks = 1024
+
+// ClickStatsService application service for click analytics of records.
+// Only apikey cached record can get its stats, so clicks are recorded
+// only for records cached with apikey.
+type ClickStatsService struct {
+	recordRepository     repository.RecordRepository
+	clickStatsRepository repository.ClickStatsRepository
+	geoIPRepository      repository.GeoIPRepository
+	apikeyService        IAPIKeyService
+	config               config.ClickStatsConfig
+	logger               logger.Logger
+	pending              chan struct{}
+}
+
+// NewClickStatsService constructor. Nil geoIPRepository leaves countries unknown.
+func NewClickStatsService(
+	recordRepository repository.RecordRepository,
+	clickStatsRepository repository.ClickStatsRepository,
+	geoIPRepository repository.GeoIPRepository,
+	apikeyService IAPIKeyService,
+	cfg config.ClickStatsConfig,
+	lgr logger.Logger,
+) *ClickStatsService {
+	return &ClickStatsService{
+		recordRepository:     recordRepository,
+		clickStatsRepository: clickStatsRepository,
+		geoIPRepository:      geoIPRepository,
+		apikeyService:        apikeyService,
+		config:               cfg,
+		logger:               lgr,
+		pending:              make(chan struct{}, maxPendingClicks),
+	}
+}
+
+// ClickStatsAnswer ClickStatsService result.
+type ClickStatsAnswer struct {
+	Stats aggregate.ClickStats
+	// BucketSize time span of one bucket of Stats.
+	BucketSize time.Duration
+}
+
+// AddClick records click on consumed record in background, so reading of
+// record does not wait for stats storage. Click is dropped if too many
+// clicks are pending. Failure is only logged.
+func (s *ClickStatsService) AddClick(key objectvalue.RecordKey, params objectvalue.ClickRequestParams) {
+	at := time.Now()
+
+	select {
+	case s.pending <- struct{}{}:
+	default:
+		s.logger.Warn("Too many pending clicks, click is dropped", "key", string(key))
+		return
+	}
+
+	go func() {
+		defer func() { <-s.pending }()
+
+		ctx, cancel := context.WithTimeout(context.Background(), clickTimeout)
+		defer cancel()
+
+		s.addClick(ctx, key, at, params)
+	}()
+}
+
+func (s *ClickStatsService) addClick(ctx context.Context, key objectvalue.RecordKey, at time.Time, params objectvalue.ClickRequestParams) {
+	country := ""
+	if s.geoIPRepository != nil && params.SourceIP != "" {
+		var err error
+		country, err = s.geoIPRepository.Country(ctx, params.SourceIP)
+		if err != nil {
+			s.logger.Error("Fail to get country of click", "key", string(key), "error", err)
+		}
+	}
+
+	// visitor is identified by ip and user agent, only sketch of them is stored
+	click := objectvalue.NewClick(at, params.Referrer, params.UserAgent, country, params.SourceIP+"\n"+params.UserAgent)
+
+	err := s.clickStatsRepository.UpdateClickStats(ctx, key, func(stats *aggregate.ClickStats) {
+		stats.AddClick(click, s.config)
+	})
+	// record with exhausted counter is removed on consuming
+	if errors.Is(err, domainerrors.ErrRecordNotFound) {
+		return
+	}
+	if err != nil {
+		s.logger.Error("Fail to add click", "key", string(key), "error", err)
+	}
+}
+
+// GetStats returns click stats of record if apikey cached it.
+func (s *ClickStatsService) GetStats(key objectvalue.RecordKey, apikey string) (ClickStatsAnswer, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	if apikey == "" {
+		return ClickStatsAnswer{}, domainerrors.ErrNonAuthorized
+	}
+
+	_, apikeyID, err := checkAPIKey(ctx, s.apikeyService, s.logger, apikey)
+	if err != nil {
+		return ClickStatsAnswer{}, err
+	}
+
+	record, err := s.recordRepository.GetByKey(ctx, key)
+	if errors.Is(err, domainerrors.ErrRecordNotFound) {
+		return ClickStatsAnswer{}, domainerrors.ErrRecordNotFound
+	}
+	if err != nil {
+		return ClickStatsAnswer{}, fmt.Errorf("fail to get record: %w", err)
+	}
+
+	if record.CounterExhausted() {
+		return ClickStatsAnswer{}, domainerrors.ErrRecordCounterExhausted
+	}
+
+	if record.ExpirationDate().Expired() {
+		return ClickStatsAnswer{}, domainerrors.ErrRecordExpired
+	}
+
+	if record.Metadata().APIKeyID() != apikeyID {
+		s.logger.Warn("Getting stats of not owned record", "key", string(key), "apikey", apikeyID)
+		return ClickStatsAnswer{}, domainerrors.ErrNotOwner
+	}
+
+	stats, err := s.clickStatsRepository.GetClickStats(ctx, key)
+	if errors.Is(err, domainerrors.ErrRecordNotFound) {
+		return ClickStatsAnswer{}, domainerrors.ErrRecordNotFound
+	}
+	if err != nil {
+		return ClickStatsAnswer{}, fmt.Errorf("fail to get click stats: %w", err)
+	}
+
+	return ClickStatsAnswer{
+		Stats:      stats,
+		BucketSize: s.config.ClickStatsBucketSize(),
+	}, nil
+}
@@ -0,0 +1,160 @@
+//go:build unit
+
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/thek4n/paste.thek4n.ru/internal/domain/config"
+	"github.com/thek4n/paste.thek4n.ru/internal/domain/domainerrors"
+	"github.com/thek4n/paste.thek4n.ru/internal/domain/event"
+	"github.com/thek4n/paste.thek4n.ru/internal/domain/objectvalue"
+	"github.com/thek4n/paste.thek4n.ru/internal/infrastructure/repository"
+)
+
+type ipsGeoIP map[string]string
+
+func (g ipsGeoIP) Country(_ context.Context, ip string) (string, error) {
+	return g[ip], nil
+}
+
+type AnotherAPIKeyService struct {
+	TrueAPIKeyService
+}
+
+func (s AnotherAPIKeyService) GetID(context.Context, string) (string, error) {
+	return "another-apikey-id", nil
+}
+
+func TestClickStatsService(t *testing.T) {
+	t.Parallel()
+
+	cacheValidationCfg := config.DefaultCacheValidationConfig{}
+	passwordAttemptsCfg := config.DefaultPasswordAttemptsConfig{}
+	recordRepo := repository.NewMemoryRecordRepository(config.DefaultCachingConfig{})
+	cacheSvc := NewCacheService(
+		recordRepo,
+		repository.NewMemoryQuotaRepository(config.DefaultQuotaConfig{}),
+		repository.NewMemoryAPIKeyRepository(),
+		TrueAPIKeyService{},
+		event.NewPublisher(),
+		cacheValidationCfg,
+		config.DefaultQuotaConfig{},
+		NewURLPolicy(config.DefaultURLPolicyConfig{}, nil),
+		MuteLogger{},
+	)
+	newStatsSvc := func(apikeyService IAPIKeyService) *ClickStatsService {
+		return NewClickStatsService(
+			recordRepo,
+			recordRepo,
+			ipsGeoIP{"198.51.100.1": "de"},
+			apikeyService,
+			config.DefaultClickStatsConfig{},
+			MuteLogger{},
+		)
+	}
+	statsSvc := newStatsSvc(TrueAPIKeyService{})
+	getSvc := NewGetService(recordRepo, repository.NewMemoryQuotaRepository(passwordAttemptsCfg), passwordAttemptsCfg, statsSvc)
+
+	serve := func(t *testing.T, apikey string) objectvalue.RecordKey {
+		t.Helper()
+
+		answer, err := cacheSvc.Serve(objectvalue.CacheRequestParams{
+			APIKey:             apikey,
+			SourceIP:           "127.0.0.1",
+			Body:               []byte("body"),
+			TTL:                cacheValidationCfg.DefaultTTL(),
+			BodyLen:            4,
+			RequestedKeyLength: cacheValidationCfg.DefaultKeyLength(),
+		})
+		require.NoError(t, err)
+
+		return answer.Key
+	}
+
+	click := func(t *testing.T, key objectvalue.RecordKey, params objectvalue.ClickRequestParams) {
+		t.Helper()
+
+		_, err := getSvc.GetBody(key, objectvalue.GetRequestParams{Click: params})
+		require.NoError(t, err)
+	}
+
+	t.Run("clicks are counted by owner apikey", func(t *testing.T) {
+		t.Parallel()
+
+		key := serve(t, "non-empty")
+
+		browser := "Mozilla/5.0 (X11; Linux x86_64) Firefox/128.0"
+		click(t, key, objectvalue.ClickRequestParams{SourceIP: "198.51.100.1", Referrer: "https://example.com/post", UserAgent: browser})
+		click(t, key, objectvalue.ClickRequestParams{SourceIP: "198.51.100.1", Referrer: "https://example.com/", UserAgent: browser})
+		click(t, key, objectvalue.ClickRequestParams{SourceIP: "192.0.2.1", UserAgent: "curl/8.5.0"})
+		click(t, key, objectvalue.ClickRequestParams{SourceIP: "192.0.2.2", UserAgent: "Googlebot/2.1"})
+
+		var answer ClickStatsAnswer
+		require.Eventually(t, func() bool {
+			var err error
+			answer, err = statsSvc.GetStats(key, "non-empty")
+			require.NoError(t, err)
+			return answer.Stats.Clicks()+answer.Stats.BotClicks() == 4
+		}, time.Second, 10*time.Millisecond)
+
+		stats := answer.Stats
+		assert.Equal(t, config.DefaultClickStatsConfig{}.ClickStatsBucketSize(), answer.BucketSize)
+		assert.Equal(t, uint32(3), stats.Clicks())
+		assert.Equal(t, uint32(1), stats.BotClicks())
+		assert.Equal(t, uint64(2), stats.UniqueVisitors())
+		assert.Equal(t, map[string]uint32{"example.com": 2, objectvalue.ReferrerDirect: 1}, stats.Referrers())
+		assert.Equal(t, map[string]uint32{"DE": 2}, stats.Countries())
+		assert.Equal(t, map[objectvalue.UserAgentClass]uint32{
+			objectvalue.UserAgentBrowser: 2,
+			objectvalue.UserAgentCLI:     1,
+			objectvalue.UserAgentBot:     1,
+		}, stats.UserAgents())
+		require.Len(t, stats.Buckets(), 1)
+		assert.Equal(t, uint32(3), stats.Buckets()[0].Clicks())
+		assert.Equal(t, uint32(1), stats.Buckets()[0].BotClicks())
+	})
+
+	t.Run("head request is not counted", func(t *testing.T) {
+		t.Parallel()
+
+		key := serve(t, "non-empty")
+
+		_, err := getSvc.Head(key, objectvalue.GetRequestParams{
+			Click: objectvalue.ClickRequestParams{SourceIP: "192.0.2.1", UserAgent: "curl/8.5.0"},
+		})
+		require.NoError(t, err)
+
+		answer, err := statsSvc.GetStats(key, "non-empty")
+		require.NoError(t, err)
+		assert.Equal(t, uint32(0), answer.Stats.Clicks())
+		assert.Empty(t, answer.Stats.Buckets())
+	})
+
+	t.Run("stats require owner apikey", func(t *testing.T) {
+		t.Parallel()
+
+		key := serve(t, "non-empty")
+
+		_, err := statsSvc.GetStats(key, "")
+		require.ErrorIs(t, err, domainerrors.ErrNonAuthorized)
+
+		_, err = newStatsSvc(AnotherAPIKeyService{}).GetStats(key, "another")
+		require.ErrorIs(t, err, domainerrors.ErrNotOwner)
+
+		_, err = newStatsSvc(FalseAPIKeyService{}).GetStats(key, "nonexistent")
+		require.ErrorIs(t, err, domainerrors.ErrAPIKeyNotFound)
+
+		anonymousKey := serve(t, "")
+		_, err = statsSvc.GetStats(anonymousKey, "non-empty")
+		require.ErrorIs(t, err, domainerrors.ErrNotOwner)
+
+		_, err = statsSvc.GetStats("missing", "non-empty")
+		require.ErrorIs(t, err, domainerrors.ErrRecordNotFound)
+	})
+}
@@ -0,0 +1,236 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/thek4n/paste.thek4n.ru/internal/application/repository"
+	"github.com/thek4n/paste.thek4n.ru/internal/domain/aggregate"
+	"github.com/thek4n/paste.thek4n.ru/internal/domain/config"
+	"github.com/thek4n/paste.thek4n.ru/internal/domain/domainerrors"
+	"github.com/thek4n/paste.thek4n.ru/internal/domain/logger"
+	"github.com/thek4n/paste.thek4n.ru/internal/domain/objectvalue"
+	"github.com/thek4n/paste.thek4n.ru/pkg/passwordcrypt"
+)
+
+// UpdateService application service changing existing records.
+type UpdateService struct {
+	recordRepository repository.RecordRepository
+	apikeyService    IAPIKeyService
+	validationConfig config.CacheValidationConfig
+	urlPolicy        *URLPolicy
+	logger           logger.Logger
+}
+
+// NewUpdateService constructor. Urls of url records are checked by urlPolicy.
+func NewUpdateService(
+	recordRepository repository.RecordRepository,
+	apikeyService IAPIKeyService,
+	cfg config.CacheValidationConfig,
+	urlPolicy *URLPolicy,
+	lgr logger.Logger,
+) *UpdateService {
+	return &UpdateService{
+		recordRepository: recordRepository,
+		apikeyService:    apikeyService,
+		validationConfig: cfg,
+		urlPolicy:        urlPolicy,
+		logger:           lgr,
+	}
+}
+
+// Update changes body and settings of record keeping its key and clicks.
+// Owner is authorized like in CacheService.Delete. Params are validated
+// with privileged limits if valid apikey presented.
+func (s *UpdateService) Update(key objectvalue.RecordKey, params objectvalue.UpdateRequestParams) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	if params.OwnerToken == "" && params.APIKey == "" {
+		return domainerrors.ErrNonAuthorized
+	}
+
+	privileged := false
+	apikeyID := ""
+	if params.APIKey != "" {
+		var err error
+		privileged, apikeyID, err = checkAPIKey(ctx, s.apikeyService, s.logger, params.APIKey)
+		if err != nil {
+			return err
+		}
+	}
+
+	if err := s.validateRequestParams(params, privileged); err != nil {
+		return err
+	}
+
+	record, err := s.recordRepository.GetByKey(ctx, key)
+	if errors.Is(err, domainerrors.ErrRecordNotFound) {
+		return domainerrors.ErrRecordNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("fail to get record: %w", err)
+	}
+
+	if !record.OwnedBy(params.OwnerToken, apikeyID) {
+		s.logger.Warn("Updating not owned record", "key", string(key), "apikey", apikeyID)
+		return domainerrors.ErrNotOwner
+	}
+
+	// url of protected record is encrypted, so switching url flag requires
+	// new body to check it
+	if params.IsURL != nil && *params.IsURL != record.URL() && record.Protected() && params.Body == nil {
+		return domainerrors.ErrInvalidURL
+	}
+
+	updated := updateRecord(record, params)
+	if updated.URL() && updated.Encrypted() {
+		return domainerrors.ErrInvalidURL
+	}
+
+	if updated.URL() && !updated.Protected() && !validURL(string(updated.RGetBody())) {
+		return domainerrors.ErrInvalidURL
+	}
+
+	if params.Body != nil && (record.Protected() || params.Password != "") {
+		if updated.URL() && !validURL(string(params.Body)) {
+			return domainerrors.ErrInvalidURL
+		}
+	}
+
+	// body of protected record is checked before it is encrypted
+	if target := plainBody(record, params); updated.URL() && target != nil {
+		if err := checkURL(ctx, s.urlPolicy, s.logger, string(target), params.Host); err != nil {
+			return err
+		}
+	}
+
+	if params.Body != nil && (record.Protected() || params.Password != "") {
+		if err := resealBody(&updated, record.PasswordHash(), params.Password, params.Body); err != nil {
+			return err
+		}
+	}
+
+	err = s.recordRepository.UpdateByKey(ctx, key, updated)
+	if errors.Is(err, domainerrors.ErrRecordNotFound) {
+		return domainerrors.ErrRecordNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("fail to update record: %w", err)
+	}
+
+	s.logger.Info("Update record", "key", string(key), "apikey", apikeyID)
+
+	return nil
+}
+
+func (s *UpdateService) validateRequestParams(params objectvalue.UpdateRequestParams, privileged bool) error {
+	if params.Body != nil {
+		if err := validateBodyLen(s.validationConfig, privileged, params.BodyLen); err != nil {
+			return err
+		}
+	}
+
+	if params.TTL != nil {
+		if err := validateTTL(s.validationConfig, privileged, *params.TTL); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// plainBody returns not encrypted body record will have after update.
+// Returns nil if body of protected record is not changed.
+func plainBody(record aggregate.Record, params objectvalue.UpdateRequestParams) []byte {
+	if params.Body != nil {
+		return params.Body
+	}
+
+	if record.Protected() {
+		return nil
+	}
+
+	return record.RGetBody()
+}
+
+// updateRecord returns copy of record with changed fields presented in params.
+// New body replaces encrypted flag with params one.
+func updateRecord(record aggregate.Record, params objectvalue.UpdateRequestParams) aggregate.Record {
+	expirationDate := record.ExpirationDate()
+	if params.TTL != nil {
+		expirationDate = newExpirationDate(*params.TTL, params.Sliding)
+	}
+
+	disposable := record.DisposableCounter()
+	eternal := record.DisposableCounterEternal()
+	if params.Disposable != nil {
+		disposable = *params.Disposable
+		eternal = disposable == 0
+	}
+
+	// new body replaces bundle with plain record
+	body := record.RGetBody()
+	bodySize := record.BodySize()
+	files := record.Files()
+	encrypted := record.Encrypted()
+	if params.Body != nil {
+		body = params.Body
+		bodySize = params.BodyLen
+		files = nil
+		encrypted = params.Encrypted
+	}
+
+	isURL := record.URL()
+	if params.IsURL != nil {
+		isURL = *params.IsURL
+	}
+
+	updated := aggregate.NewRecord(
+		string(record.Key()),
+		expirationDate,
+		disposable,
+		eternal,
+		record.Clicks(),
+		body,
+		isURL,
+	)
+	updated.SetMetadata(record.Metadata())
+	updated.SetOwnerTokenHash(record.OwnerTokenHash())
+	updated.SetPasswordHash(record.PasswordHash())
+	updated.SetBodySize(bodySize)
+	updated.SetEncrypted(encrypted)
+	updated.SetReveal(record.Reveal())
+	updated.SetFiles(files)
+	updated.SetRedirect(record.Redirect())
+
+	return updated
+}
+
+// resealBody encrypts new body of record with password. Password must match
+// hash of already protected record.
+func resealBody(record *aggregate.Record, hash, password string, body []byte) error {
+	if password == "" {
+		return domainerrors.ErrWrongPassword
+	}
+
+	if hash != "" {
+		_, err := passwordcrypt.Verify(hash, password)
+		if errors.Is(err, passwordcrypt.ErrWrongPassword) {
+			return domainerrors.ErrWrongPassword
+		}
+		if err != nil {
+			return fmt.Errorf("fail to verify password: %w", err)
+		}
+	}
+
+	return sealBody(record, password, body)
+}
+
+func validURL(str string) bool {
+	u, err := url.Parse(str)
+	return err == nil && u.Scheme != "" && u.Host != ""
+}
@@ -0,0 +1,251 @@
+//go:build unit
+
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/thek4n/paste.thek4n.ru/internal/domain/config"
+	"github.com/thek4n/paste.thek4n.ru/internal/domain/domainerrors"
+	"github.com/thek4n/paste.thek4n.ru/internal/domain/event"
+	"github.com/thek4n/paste.thek4n.ru/internal/domain/objectvalue"
+	"github.com/thek4n/paste.thek4n.ru/internal/infrastructure/repository"
+)
+
+func TestUpdateService_Update(t *testing.T) {
+	t.Parallel()
+
+	cacheValidationCfg := config.DefaultCacheValidationConfig{}
+	recordRepo := repository.NewMemoryRecordRepository(config.DefaultCachingConfig{})
+	cacheSvc := NewCacheService(
+		recordRepo,
+		repository.NewMemoryQuotaRepository(config.DefaultQuotaConfig{}),
+		repository.NewMemoryAPIKeyRepository(),
+		TrueAPIKeyService{},
+		event.NewPublisher(),
+		cacheValidationCfg,
+		config.DefaultQuotaConfig{},
+		NewURLPolicy(config.DefaultURLPolicyConfig{}, nil),
+		MuteLogger{},
+	)
+	svc := NewUpdateService(recordRepo, TrueAPIKeyService{}, cacheValidationCfg, NewURLPolicy(config.DefaultURLPolicyConfig{}, nil), MuteLogger{})
+
+	serve := func(t *testing.T, apikey string) CacheAnswer {
+		t.Helper()
+
+		answer, err := cacheSvc.Serve(objectvalue.CacheRequestParams{
+			APIKey:             apikey,
+			SourceIP:           "127.0.0.1",
+			Body:               []byte("secret"),
+			TTL:                cacheValidationCfg.DefaultTTL(),
+			BodyLen:            6,
+			RequestedKeyLength: cacheValidationCfg.DefaultKeyLength(),
+			Disposable:         3,
+			ContentType:        "text/plain",
+		})
+		require.NoError(t, err)
+
+		return answer
+	}
+
+	t.Run("owner token replaces body keeping key and clicks", func(t *testing.T) {
+		t.Parallel()
+
+		answer := serve(t, "")
+		_, err := recordRepo.ConsumeByKey(context.Background(), answer.Key, true)
+		require.NoError(t, err)
+
+		err = svc.Update(answer.Key, objectvalue.UpdateRequestParams{
+			OwnerToken: answer.OwnerToken,
+			Body:       []byte("new secret"),
+			BodyLen:    10,
+		})
+		require.NoError(t, err)
+
+		record, err := recordRepo.GetByKey(context.Background(), answer.Key)
+		require.NoError(t, err)
+		assert.Equal(t, []byte("new secret"), record.RGetBody())
+		assert.Equal(t, uint32(1), record.Clicks())
+		assert.Equal(t, uint8(2), record.DisposableCounter())
+		assert.Equal(t, "text/plain", record.Metadata().ContentType())
+		assert.True(t, record.OwnedBy(answer.OwnerToken, ""))
+	})
+
+	t.Run("ttl and disposable counter are reset", func(t *testing.T) {
+		t.Parallel()
+
+		answer := serve(t, "")
+		ttl := time.Hour
+		disposable := uint8(0)
+
+		err := svc.Update(answer.Key, objectvalue.UpdateRequestParams{
+			OwnerToken: answer.OwnerToken,
+			TTL:        &ttl,
+			Disposable: &disposable,
+			Sliding:    true,
+		})
+		require.NoError(t, err)
+
+		record, err := recordRepo.GetByKey(context.Background(), answer.Key)
+		require.NoError(t, err)
+		assert.True(t, record.DisposableCounterEternal())
+		assert.Equal(t, ttl, record.ExpirationDate().SlidingTTL())
+		assert.WithinDuration(t, time.Now().Add(ttl), record.ExpirationDate().Date(), time.Minute)
+		assert.Equal(t, []byte("secret"), record.RGetBody())
+	})
+
+	t.Run("creator apikey makes record eternal", func(t *testing.T) {
+		t.Parallel()
+
+		answer := serve(t, "non-empty")
+		ttl := time.Duration(0)
+
+		require.NoError(t, svc.Update(answer.Key, objectvalue.UpdateRequestParams{APIKey: "non-empty", TTL: &ttl}))
+
+		record, err := recordRepo.GetByKey(context.Background(), answer.Key)
+		require.NoError(t, err)
+		assert.True(t, record.ExpirationDateEternal())
+	})
+
+	t.Run("unprivileged limits are applied to owner token", func(t *testing.T) {
+		t.Parallel()
+
+		answer := serve(t, "")
+		ttl := time.Duration(0)
+
+		err := svc.Update(answer.Key, objectvalue.UpdateRequestParams{OwnerToken: answer.OwnerToken, TTL: &ttl})
+		assert.ErrorIs(t, err, domainerrors.ErrInvalidTTL)
+
+		bodyLen := cacheValidationCfg.UnprivilegedMaxBodySize() + 1
+		err = svc.Update(answer.Key, objectvalue.UpdateRequestParams{
+			OwnerToken: answer.OwnerToken,
+			Body:       make([]byte, bodyLen),
+			BodyLen:    bodyLen,
+		})
+		assert.ErrorIs(t, err, domainerrors.ErrBodyTooLarge)
+	})
+
+	t.Run("url flag requires url body", func(t *testing.T) {
+		t.Parallel()
+
+		answer := serve(t, "")
+		isURL := true
+
+		err := svc.Update(answer.Key, objectvalue.UpdateRequestParams{OwnerToken: answer.OwnerToken, IsURL: &isURL})
+		assert.ErrorIs(t, err, domainerrors.ErrInvalidURL)
+
+		err = svc.Update(answer.Key, objectvalue.UpdateRequestParams{
+			OwnerToken: answer.OwnerToken,
+			Body:       []byte("https://example.com/"),
+			BodyLen:    20,
+			IsURL:      &isURL,
+		})
+		require.NoError(t, err)
+
+		record, err := recordRepo.GetByKey(context.Background(), answer.Key)
+		require.NoError(t, err)
+		assert.True(t, record.URL())
+	})
+
+	t.Run("url flag of protected record requires checked body and password", func(t *testing.T) {
+		t.Parallel()
+
+		answer, err := cacheSvc.Serve(objectvalue.CacheRequestParams{
+			SourceIP:           "127.0.0.1",
+			Body:               []byte("secret"),
+			TTL:                cacheValidationCfg.DefaultTTL(),
+			BodyLen:            6,
+			RequestedKeyLength: cacheValidationCfg.DefaultKeyLength(),
+			Password:           "pw",
+		})
+		require.NoError(t, err)
+		isURL := true
+
+		err = svc.Update(answer.Key, objectvalue.UpdateRequestParams{OwnerToken: answer.OwnerToken, Password: "pw", IsURL: &isURL})
+		assert.ErrorIs(t, err, domainerrors.ErrInvalidURL, "ciphertext is not url")
+
+		err = svc.Update(answer.Key, objectvalue.UpdateRequestParams{
+			OwnerToken: answer.OwnerToken,
+			Password:   "pw",
+			Body:       []byte("ftp://example.com/"),
+			BodyLen:    18,
+			IsURL:      &isURL,
+		})
+		assert.ErrorIs(t, err, domainerrors.ErrURLNotAllowed)
+
+		err = svc.Update(answer.Key, objectvalue.UpdateRequestParams{
+			OwnerToken: answer.OwnerToken,
+			Body:       []byte("https://example.com/"),
+			BodyLen:    20,
+			IsURL:      &isURL,
+		})
+		assert.ErrorIs(t, err, domainerrors.ErrWrongPassword)
+
+		err = svc.Update(answer.Key, objectvalue.UpdateRequestParams{
+			OwnerToken: answer.OwnerToken,
+			Password:   "pw",
+			Body:       []byte("https://example.com/"),
+			BodyLen:    20,
+			IsURL:      &isURL,
+		})
+		require.NoError(t, err)
+
+		record, err := recordRepo.GetByKey(context.Background(), answer.Key)
+		require.NoError(t, err)
+		assert.True(t, record.URL())
+		assert.True(t, record.Protected())
+	})
+
+	t.Run("new body replaces encrypted flag", func(t *testing.T) {
+		t.Parallel()
+
+		answer, err := cacheSvc.Serve(objectvalue.CacheRequestParams{
+			SourceIP:           "127.0.0.1",
+			Body:               []byte("ciphertext"),
+			TTL:                cacheValidationCfg.DefaultTTL(),
+			BodyLen:            10,
+			RequestedKeyLength: cacheValidationCfg.DefaultKeyLength(),
+			Encrypted:          true,
+		})
+		require.NoError(t, err)
+
+		err = svc.Update(answer.Key, objectvalue.UpdateRequestParams{OwnerToken: answer.OwnerToken, Body: []byte("new ciphertext"), BodyLen: 14, Encrypted: true})
+		require.NoError(t, err)
+		record, err := recordRepo.GetByKey(context.Background(), answer.Key)
+		require.NoError(t, err)
+		assert.True(t, record.Encrypted())
+
+		isURL := true
+		err = svc.Update(answer.Key, objectvalue.UpdateRequestParams{OwnerToken: answer.OwnerToken, IsURL: &isURL})
+		assert.ErrorIs(t, err, domainerrors.ErrInvalidURL, "encrypted url is not supported")
+
+		err = svc.Update(answer.Key, objectvalue.UpdateRequestParams{OwnerToken: answer.OwnerToken, Body: []byte("plain"), BodyLen: 5})
+		require.NoError(t, err)
+		record, err = recordRepo.GetByKey(context.Background(), answer.Key)
+		require.NoError(t, err)
+		assert.False(t, record.Encrypted())
+	})
+
+	t.Run("not owner can not update record", func(t *testing.T) {
+		t.Parallel()
+
+		answer := serve(t, "")
+
+		err := svc.Update(answer.Key, objectvalue.UpdateRequestParams{OwnerToken: objectvalue.NewOwnerToken(), Body: []byte("x"), BodyLen: 1})
+		assert.ErrorIs(t, err, domainerrors.ErrNotOwner)
+
+		err = svc.Update(answer.Key, objectvalue.UpdateRequestParams{APIKey: "non-empty"})
+		assert.ErrorIs(t, err, domainerrors.ErrNotOwner)
+
+		err = svc.Update(answer.Key, objectvalue.UpdateRequestParams{})
+		assert.ErrorIs(t, err, domainerrors.ErrNonAuthorized)
+
+		err = svc.Update("missing", objectvalue.UpdateRequestParams{OwnerToken: answer.OwnerToken})
+		assert.ErrorIs(t, err, domainerrors.ErrRecordNotFound)
+	})
+}
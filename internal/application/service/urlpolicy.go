@@ -0,0 +1,104 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"slices"
+	"strings"
+
+	"github.com/thek4n/paste.thek4n.ru/internal/application/repository"
+	"github.com/thek4n/paste.thek4n.ru/internal/domain/config"
+	"github.com/thek4n/paste.thek4n.ru/internal/domain/domainerrors"
+	"github.com/thek4n/paste.thek4n.ru/internal/domain/logger"
+)
+
+// URLPolicy decides which urls can be shortened. Url must have allowed
+// scheme, must not point to service itself, so short links do not redirect
+// back to it in chains or loops, and its host must not be blocked.
+type URLPolicy struct {
+	config    config.URLPolicyConfig
+	blocklist repository.URLBlocklistRepository
+}
+
+// NewURLPolicy constructor. Nil blocklist blocks nothing.
+func NewURLPolicy(cfg config.URLPolicyConfig, blocklist repository.URLBlocklistRepository) *URLPolicy {
+	return &URLPolicy{
+		config:    cfg,
+		blocklist: blocklist,
+	}
+}
+
+// Check returns ErrURLNotAllowed wrapped with reason if url must not be
+// shortened. serviceHost is host request was sent to, it may contain port.
+func (p *URLPolicy) Check(ctx context.Context, rawURL string, serviceHost string) error {
+	u, err := url.Parse(strings.TrimSpace(rawURL))
+	if err != nil {
+		return domainerrors.ErrInvalidURL
+	}
+
+	if !slices.Contains(p.config.AllowedURLSchemes(), u.Scheme) {
+		return fmt.Errorf("%w: scheme '%s' is not allowed", domainerrors.ErrURLNotAllowed, u.Scheme)
+	}
+
+	host := normalizeHost(u.Hostname())
+	if host == "" {
+		return domainerrors.ErrInvalidURL
+	}
+
+	if p.serviceHost(host, serviceHost) {
+		return fmt.Errorf("%w: host '%s' is service host", domainerrors.ErrURLNotAllowed, host)
+	}
+
+	if p.blocklist == nil {
+		return nil
+	}
+
+	blocked, err := p.blocklist.Blocked(ctx, host)
+	if err != nil {
+		return fmt.Errorf("fail to check url blocklist: %w", err)
+	}
+
+	if blocked {
+		return fmt.Errorf("%w: host '%s' is blocked", domainerrors.ErrURLNotAllowed, host)
+	}
+
+	return nil
+}
+
+// serviceHost returns is host requested one or one of configured service hosts.
+func (p *URLPolicy) serviceHost(host, requestedHost string) bool {
+	hosts := append([]string{requestedHost}, p.config.ServiceHosts()...)
+
+	return slices.ContainsFunc(hosts, func(serviceHost string) bool {
+		return serviceHost != "" && host == normalizeHost(hostWithoutPort(serviceHost))
+	})
+}
+
+// normalizeHost returns lowercase host without trailing dot of fully
+// qualified domain, so one host is not written different ways.
+func normalizeHost(host string) string {
+	return strings.TrimSuffix(strings.ToLower(host), ".")
+}
+
+func hostWithoutPort(hostport string) string {
+	host, _, err := net.SplitHostPort(hostport)
+	if err != nil {
+		return hostport
+	}
+	return host
+}
+
+// checkURL checks url by policy. Rejected url is logged with reason and
+// ErrURLNotAllowed is returned.
+func checkURL(ctx context.Context, policy *URLPolicy, lgr logger.Logger, rawURL, serviceHost string) error {
+	err := policy.Check(ctx, rawURL, serviceHost)
+	if errors.Is(err, domainerrors.ErrURLNotAllowed) {
+		lgr.Warn("Rejecting url by policy", "reason", err.Error())
+		return domainerrors.ErrURLNotAllowed
+	}
+
+	return err
+}
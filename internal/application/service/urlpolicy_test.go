@@ -0,0 +1,162 @@
+//go:build unit
+
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/thek4n/paste.thek4n.ru/internal/domain/config"
+	"github.com/thek4n/paste.thek4n.ru/internal/domain/domainerrors"
+	"github.com/thek4n/paste.thek4n.ru/internal/domain/event"
+	"github.com/thek4n/paste.thek4n.ru/internal/domain/objectvalue"
+	"github.com/thek4n/paste.thek4n.ru/internal/infrastructure/repository"
+)
+
+type hostsBlocklist map[string]bool
+
+func (b hostsBlocklist) Blocked(_ context.Context, host string) (bool, error) {
+	if host == "broken.example" {
+		return false, errors.New("broken blocklist")
+	}
+	return b[host], nil
+}
+
+type serviceHostsConfig struct {
+	config.DefaultURLPolicyConfig
+}
+
+func (serviceHostsConfig) ServiceHosts() []string {
+	return []string{"usercontent.example:8443"}
+}
+
+func TestURLPolicy_Check(t *testing.T) {
+	t.Parallel()
+
+	policy := NewURLPolicy(serviceHostsConfig{}, hostsBlocklist{"evil.example": true})
+	ctx := context.Background()
+
+	tests := []struct {
+		name string
+		url  string
+		err  error
+	}{
+		{name: "allowed url", url: "https://example.com/path?q=1"},
+		{name: "uppercase scheme", url: "HTTP://example.com/"},
+		{name: "disallowed scheme", url: "ftp://example.com/file", err: domainerrors.ErrURLNotAllowed},
+		{name: "javascript scheme", url: "javascript:alert(1)", err: domainerrors.ErrURLNotAllowed},
+		{name: "requested host", url: "https://paste.example/abc/", err: domainerrors.ErrURLNotAllowed},
+		{name: "requested host with fqdn dot", url: "https://PASTE.example./abc/", err: domainerrors.ErrURLNotAllowed},
+		{name: "configured service host", url: "http://usercontent.example/abc/", err: domainerrors.ErrURLNotAllowed},
+		{name: "blocked host", url: "https://evil.example/", err: domainerrors.ErrURLNotAllowed},
+		{name: "url without host", url: "https:///path", err: domainerrors.ErrInvalidURL},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			err := policy.Check(ctx, tt.url, "paste.example:8080")
+			if tt.err == nil {
+				assert.NoError(t, err)
+				return
+			}
+			assert.ErrorIs(t, err, tt.err)
+		})
+	}
+
+	t.Run("blocklist error is not rejection", func(t *testing.T) {
+		t.Parallel()
+
+		err := policy.Check(ctx, "https://broken.example/", "paste.example")
+		require.Error(t, err)
+		assert.NotErrorIs(t, err, domainerrors.ErrURLNotAllowed)
+	})
+
+	t.Run("nil blocklist blocks nothing", func(t *testing.T) {
+		t.Parallel()
+
+		err := NewURLPolicy(config.DefaultURLPolicyConfig{}, nil).Check(ctx, "https://evil.example/", "paste.example")
+		assert.NoError(t, err)
+	})
+}
+
+func TestURLPolicy_CacheAndUpdate(t *testing.T) {
+	t.Parallel()
+
+	cacheValidationCfg := config.DefaultCacheValidationConfig{}
+	recordRepo := repository.NewMemoryRecordRepository(config.DefaultCachingConfig{})
+	policy := NewURLPolicy(config.DefaultURLPolicyConfig{}, hostsBlocklist{"evil.example": true})
+	cacheSvc := NewCacheService(
+		recordRepo,
+		repository.NewMemoryQuotaRepository(config.DefaultQuotaConfig{}),
+		repository.NewMemoryAPIKeyRepository(),
+		TrueAPIKeyService{},
+		event.NewPublisher(),
+		cacheValidationCfg,
+		config.DefaultQuotaConfig{},
+		policy,
+		MuteLogger{},
+	)
+	updateSvc := NewUpdateService(recordRepo, TrueAPIKeyService{}, cacheValidationCfg, policy, MuteLogger{})
+
+	serve := func(body string, isURL bool) (CacheAnswer, error) {
+		return cacheSvc.Serve(objectvalue.CacheRequestParams{
+			Body:               []byte(body),
+			BodyLen:            int64(len(body)),
+			TTL:                cacheValidationCfg.DefaultTTL(),
+			RequestedKeyLength: cacheValidationCfg.DefaultKeyLength(),
+			SourceIP:           "127.0.0.1",
+			IsURL:              isURL,
+			Host:               "paste.example",
+		})
+	}
+
+	t.Run("blocked url is not cached", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := serve("https://sub.evil.example/", false)
+		require.NoError(t, err, "body of not url record is not checked")
+
+		_, err = serve("https://evil.example/", true)
+		assert.ErrorIs(t, err, domainerrors.ErrURLNotAllowed)
+
+		_, err = serve("https://paste.example/abc/", true)
+		assert.ErrorIs(t, err, domainerrors.ErrURLNotAllowed)
+	})
+
+	t.Run("url record can not be updated to blocked url", func(t *testing.T) {
+		t.Parallel()
+
+		answer, err := serve("https://example.com/", true)
+		require.NoError(t, err)
+
+		body := []byte("https://evil.example/")
+		err = updateSvc.Update(answer.Key, objectvalue.UpdateRequestParams{
+			OwnerToken: answer.OwnerToken,
+			Body:       body,
+			BodyLen:    int64(len(body)),
+			Host:       "paste.example",
+		})
+		assert.ErrorIs(t, err, domainerrors.ErrURLNotAllowed)
+	})
+
+	t.Run("text record with blocked url can not become url record", func(t *testing.T) {
+		t.Parallel()
+
+		answer, err := serve("https://evil.example/", false)
+		require.NoError(t, err)
+
+		isURL := true
+		err = updateSvc.Update(answer.Key, objectvalue.UpdateRequestParams{
+			OwnerToken: answer.OwnerToken,
+			IsURL:      &isURL,
+			Host:       "paste.example",
+		})
+		assert.ErrorIs(t, err, domainerrors.ErrURLNotAllowed)
+	})
+}
@@ -0,0 +1,154 @@
+package aggregate
+
+import (
+	"fmt"
+	"maps"
+	"time"
+
+	"github.com/thek4n/paste.thek4n.ru/internal/domain/config"
+	"github.com/thek4n/paste.thek4n.ru/internal/domain/objectvalue"
+	"github.com/thek4n/paste.thek4n.ru/pkg/hyperloglog"
+)
+
+// ClickStats aggregate contains click analytics of record: time series of
+// clicks in bounded time buckets, totals and breakdowns of human clicks.
+// Bot clicks are counted apart and are not in breakdowns.
+type ClickStats struct {
+	buckets   []objectvalue.ClickBucket
+	clicks    uint32
+	botClicks uint32
+	referrers map[string]uint32
+	agents    map[objectvalue.UserAgentClass]uint32
+	countries map[string]uint32
+	visitors  *hyperloglog.Sketch
+}
+
+// NewClickStats constructor. Buckets must be ordered by start. visitors is
+// serialized sketch of unique visitors, empty if there were no clicks.
+func NewClickStats(
+	buckets []objectvalue.ClickBucket,
+	clicks, botClicks uint32,
+	referrers map[string]uint32,
+	agents map[objectvalue.UserAgentClass]uint32,
+	countries map[string]uint32,
+	visitors []byte,
+) (ClickStats, error) {
+	sketch, err := hyperloglog.FromBytes(visitors)
+	if err != nil {
+		return ClickStats{}, fmt.Errorf("fail to restore visitors: %w", err)
+	}
+
+	return ClickStats{
+		buckets:   buckets,
+		clicks:    clicks,
+		botClicks: botClicks,
+		referrers: orEmpty(referrers),
+		agents:    orEmpty(agents),
+		countries: orEmpty(countries),
+		visitors:  sketch,
+	}, nil
+}
+
+// NewEmptyClickStats returns stats of record without clicks.
+func NewEmptyClickStats() ClickStats {
+	stats, _ := NewClickStats(nil, 0, 0, nil, nil, nil, nil)
+	return stats
+}
+
+func orEmpty[K comparable](m map[K]uint32) map[K]uint32 {
+	if m == nil {
+		return make(map[K]uint32)
+	}
+	return m
+}
+
+// AddClick counts click in bucket of its time. Buckets older than
+// configured number are dropped. Referrers and countries out of breakdown
+// limit are counted as objectvalue.BreakdownOther.
+func (s *ClickStats) AddClick(click objectvalue.Click, cfg config.ClickStatsConfig) {
+	clicks, botClicks := uint32(1), uint32(0)
+	if click.Bot() {
+		clicks, botClicks = 0, 1
+	}
+	s.addToBucket(click.At().UTC().Truncate(cfg.ClickStatsBucketSize()), clicks, botClicks, cfg.ClickStatsMaxBuckets())
+
+	s.agents[click.UserAgentClass()]++
+	if click.Bot() {
+		s.botClicks++
+		return
+	}
+
+	s.clicks++
+	addToBreakdown(s.referrers, click.ReferrerHost(), cfg.ClickStatsMaxBreakdown())
+	if click.Country() != "" {
+		addToBreakdown(s.countries, click.Country(), cfg.ClickStatsMaxBreakdown())
+	}
+	if click.VisitorID() != "" {
+		s.visitors.Add([]byte(click.VisitorID()))
+	}
+}
+
+// addToBucket adds clicks to bucket starting at start. Click earlier than
+// latest bucket, e.g. because of clock skew, is counted in latest bucket.
+func (s *ClickStats) addToBucket(start time.Time, clicks, botClicks uint32, maxBuckets int) {
+	last := len(s.buckets) - 1
+	if last >= 0 && !start.After(s.buckets[last].Start()) {
+		b := s.buckets[last]
+		s.buckets[last] = objectvalue.NewClickBucket(b.Start(), b.Clicks()+clicks, b.BotClicks()+botClicks)
+		return
+	}
+
+	s.buckets = append(s.buckets, objectvalue.NewClickBucket(start, clicks, botClicks))
+	if len(s.buckets) > maxBuckets {
+		s.buckets = s.buckets[len(s.buckets)-maxBuckets:]
+	}
+}
+
+func addToBreakdown(breakdown map[string]uint32, name string, limit int) {
+	if _, exists := breakdown[name]; !exists && len(breakdown) >= limit {
+		name = objectvalue.BreakdownOther
+	}
+	breakdown[name]++
+}
+
+// Buckets getter. Buckets are ordered by start, buckets without clicks
+// are omitted.
+func (s ClickStats) Buckets() []objectvalue.ClickBucket {
+	return append([]objectvalue.ClickBucket(nil), s.buckets...)
+}
+
+// Clicks returns number of human clicks.
+func (s ClickStats) Clicks() uint32 {
+	return s.clicks
+}
+
+// BotClicks returns number of clicks made by bots.
+func (s ClickStats) BotClicks() uint32 {
+	return s.botClicks
+}
+
+// Referrers returns human clicks by referrer host.
+func (s ClickStats) Referrers() map[string]uint32 {
+	return maps.Clone(s.referrers)
+}
+
+// UserAgents returns clicks by user agent class including bots.
+func (s ClickStats) UserAgents() map[objectvalue.UserAgentClass]uint32 {
+	return maps.Clone(s.agents)
+}
+
+// Countries returns human clicks by country. Clicks from unknown country
+// are not counted.
+func (s ClickStats) Countries() map[string]uint32 {
+	return maps.Clone(s.countries)
+}
+
+// UniqueVisitors returns estimated number of unique human visitors.
+func (s ClickStats) UniqueVisitors() uint64 {
+	return s.visitors.Estimate()
+}
+
+// Visitors returns serialized sketch of unique visitors.
+func (s ClickStats) Visitors() []byte {
+	return s.visitors.Bytes()
+}
@@ -0,0 +1,93 @@
+//go:build unit
+
+package aggregate
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/thek4n/paste.thek4n.ru/internal/domain/objectvalue"
+)
+
+type smallClickStatsConfig struct{}
+
+func (smallClickStatsConfig) ClickStatsBucketSize() time.Duration { return time.Hour }
+func (smallClickStatsConfig) ClickStatsMaxBuckets() int           { return 2 }
+func (smallClickStatsConfig) ClickStatsMaxBreakdown() int         { return 2 }
+
+func TestClickStats_AddClick(t *testing.T) {
+	const browser = "Mozilla/5.0 (X11; Linux x86_64) Firefox/130.0"
+	start := time.Date(2026, 1, 2, 15, 0, 0, 0, time.UTC)
+
+	t.Run("human and bot clicks are counted apart", func(t *testing.T) {
+		t.Parallel()
+
+		stats := NewEmptyClickStats()
+		stats.AddClick(objectvalue.NewClick(start.Add(time.Minute), "https://example.com/page", browser, "ru", "a"), smallClickStatsConfig{})
+		stats.AddClick(objectvalue.NewClick(start.Add(2*time.Minute), "", "curl/8.0", "", "b"), smallClickStatsConfig{})
+		stats.AddClick(objectvalue.NewClick(start.Add(3*time.Minute), "https://example.com/", "Googlebot/2.1", "US", "c"), smallClickStatsConfig{})
+
+		assert.Equal(t, uint32(2), stats.Clicks())
+		assert.Equal(t, uint32(1), stats.BotClicks())
+		assert.Equal(t, []objectvalue.ClickBucket{objectvalue.NewClickBucket(start, 2, 1)}, stats.Buckets())
+		assert.Equal(t, map[string]uint32{"example.com": 1, objectvalue.ReferrerDirect: 1}, stats.Referrers())
+		assert.Equal(t, map[string]uint32{"RU": 1}, stats.Countries())
+		assert.Equal(t, map[objectvalue.UserAgentClass]uint32{
+			objectvalue.UserAgentBrowser: 1,
+			objectvalue.UserAgentCLI:     1,
+			objectvalue.UserAgentBot:     1,
+		}, stats.UserAgents())
+		assert.Equal(t, uint64(2), stats.UniqueVisitors())
+	})
+
+	t.Run("old buckets are dropped", func(t *testing.T) {
+		t.Parallel()
+
+		stats := NewEmptyClickStats()
+		for i := range 3 {
+			stats.AddClick(objectvalue.NewClick(start.Add(time.Duration(i)*time.Hour), "", browser, "", "a"), smallClickStatsConfig{})
+		}
+		// late click is counted in latest bucket
+		stats.AddClick(objectvalue.NewClick(start, "", browser, "", "a"), smallClickStatsConfig{})
+
+		assert.Equal(t, []objectvalue.ClickBucket{
+			objectvalue.NewClickBucket(start.Add(time.Hour), 1, 0),
+			objectvalue.NewClickBucket(start.Add(2*time.Hour), 2, 0),
+		}, stats.Buckets())
+		assert.Equal(t, uint32(4), stats.Clicks())
+		assert.Equal(t, uint64(1), stats.UniqueVisitors())
+	})
+
+	t.Run("breakdown is limited", func(t *testing.T) {
+		t.Parallel()
+
+		stats := NewEmptyClickStats()
+		for _, referrer := range []string{"https://a.example/", "https://b.example/", "https://c.example/", "https://a.example/x"} {
+			stats.AddClick(objectvalue.NewClick(start, referrer, browser, "", ""), smallClickStatsConfig{})
+		}
+
+		assert.Equal(t, map[string]uint32{"a.example": 2, "b.example": 1, objectvalue.BreakdownOther: 1}, stats.Referrers())
+		assert.Equal(t, uint64(0), stats.UniqueVisitors())
+	})
+
+	t.Run("restored stats keep counting", func(t *testing.T) {
+		t.Parallel()
+
+		stats := NewEmptyClickStats()
+		stats.AddClick(objectvalue.NewClick(start, "", browser, "", "a"), smallClickStatsConfig{})
+
+		restored, err := NewClickStats(stats.Buckets(), stats.Clicks(), stats.BotClicks(), stats.Referrers(), stats.UserAgents(), stats.Countries(), stats.Visitors())
+		require.NoError(t, err)
+		restored.AddClick(objectvalue.NewClick(start, "", browser, "", "b"), smallClickStatsConfig{})
+
+		assert.Equal(t, uint32(1), stats.Clicks(), "restored stats do not share state")
+		assert.Equal(t, uint32(2), restored.Clicks())
+		assert.Equal(t, uint64(2), restored.UniqueVisitors())
+
+		_, err = NewClickStats(nil, 0, 0, nil, nil, nil, []byte{1})
+		assert.Error(t, err)
+	})
+}
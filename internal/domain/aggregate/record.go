@@ -2,6 +2,7 @@
 package aggregate
 
 import (
+	"crypto/subtle"
 	"time"
 
 	"github.com/thek4n/paste.thek4n.ru/internal/domain/domainerrors"
@@ -15,7 +16,16 @@ type Record struct {
 	disposableCounter objectvalue.DisposableCounter
 	clicks            objectvalue.ClicksCounter
 	body              []byte
+	bodyEncoding      objectvalue.BodyEncoding
+	bodySize          int64
 	url               bool
+	metadata          objectvalue.RecordMetadata
+	ownerTokenHash    string
+	passwordHash      string
+	encrypted         bool
+	reveal            bool
+	files             objectvalue.BundleFiles
+	redirect          objectvalue.Redirect
 }
 
 // NewRecord creates Record with initialized params.
@@ -66,8 +76,14 @@ func (r Record) ExpirationDateEternal() bool {
 	return r.expirationDate.Eternal()
 }
 
+// ExpirationDate record expiration date getter.
+func (r Record) ExpirationDate() objectvalue.ExpirationDate {
+	return r.expirationDate
+}
+
 // GetBody checks is record counter exhausted, is record expired.
-// Then decreases disposable counter, increases clicks counter and returns body.
+// Then decreases disposable counter, increases clicks counter,
+// prolongs sliding expiration date and returns body.
 func (r *Record) GetBody() ([]byte, error) {
 	if r.CounterExhausted() {
 		return nil, domainerrors.ErrRecordCounterExhausted
@@ -81,6 +97,8 @@ func (r *Record) GetBody() ([]byte, error) {
 
 	r.increaseClicksCounter()
 
+	r.expirationDate = r.expirationDate.Prolong()
+
 	return r.body, nil
 }
 
@@ -89,11 +107,138 @@ func (r Record) RGetBody() []byte {
 	return r.body
 }
 
+// BodyEncoding returns encoding of body returned by RGetBody and GetBody.
+func (r Record) BodyEncoding() objectvalue.BodyEncoding {
+	return r.bodyEncoding
+}
+
+// SetEncodedBody replaces body with its encoded representation.
+func (r *Record) SetEncodedBody(body []byte, encoding objectvalue.BodyEncoding) {
+	r.body = body
+	r.bodyEncoding = encoding
+}
+
+// BodySize returns size of plain body given on caching, before it is
+// encrypted with password and compressed. Zero if record was cached
+// before body size was stored.
+func (r Record) BodySize() int64 {
+	return r.bodySize
+}
+
+// SetBodySize setter.
+func (r *Record) SetBodySize(size int64) {
+	r.bodySize = size
+}
+
+// Metadata getter.
+func (r Record) Metadata() objectvalue.RecordMetadata {
+	return r.metadata
+}
+
+// SetMetadata setter.
+func (r *Record) SetMetadata(metadata objectvalue.RecordMetadata) {
+	r.metadata = metadata
+}
+
+// OwnerTokenHash getter.
+func (r Record) OwnerTokenHash() string {
+	return r.ownerTokenHash
+}
+
+// SetOwnerTokenHash setter.
+func (r *Record) SetOwnerTokenHash(hash string) {
+	r.ownerTokenHash = hash
+}
+
+// OwnedBy returns is record owned by owner of token or apikey with apikeyID.
+// Empty token and apikeyID own nothing.
+func (r Record) OwnedBy(token objectvalue.OwnerToken, apikeyID string) bool {
+	if token != "" && r.ownerTokenHash != "" &&
+		subtle.ConstantTimeCompare([]byte(token.Hash()), []byte(r.ownerTokenHash)) == 1 {
+		return true
+	}
+
+	return apikeyID != "" && apikeyID == r.metadata.APIKeyID()
+}
+
+// PasswordHash getter. Empty if record is not protected by password.
+func (r Record) PasswordHash() string {
+	return r.passwordHash
+}
+
+// SetPasswordHash setter. Body of protected record is encrypted with
+// key derived from password.
+func (r *Record) SetPasswordHash(hash string) {
+	r.passwordHash = hash
+}
+
+// Protected returns is record protected by password.
+func (r Record) Protected() bool {
+	return r.passwordHash != ""
+}
+
+// Encrypted getter. Body of encrypted record is ciphertext opaque to
+// server, it is decrypted by client with key not known to server.
+func (r Record) Encrypted() bool {
+	return r.encrypted
+}
+
+// SetEncrypted setter.
+func (r *Record) SetEncrypted(encrypted bool) {
+	r.encrypted = encrypted
+}
+
+// Reveal getter. Reading of revealed record must be explicitly confirmed,
+// so it is not consumed by link previews.
+func (r Record) Reveal() bool {
+	return r.reveal
+}
+
+// SetReveal setter.
+func (r *Record) SetReveal(reveal bool) {
+	r.reveal = reveal
+}
+
+// Files getter. Empty if record is not bundle.
+func (r Record) Files() objectvalue.BundleFiles {
+	return r.files
+}
+
+// SetFiles setter. Body of bundle is concatenation of files contents.
+func (r *Record) SetFiles(files objectvalue.BundleFiles) {
+	r.files = files
+}
+
+// Bundle returns is record bundle of files.
+func (r Record) Bundle() bool {
+	return len(r.files) > 0
+}
+
+// BundleFile returns file of bundle and its content. Body must be decoded.
+func (r Record) BundleFile(name string) (objectvalue.BundleFile, []byte, bool) {
+	file, offset, ok := r.files.Find(name)
+	if !ok || offset+file.Size() > int64(len(r.body)) {
+		return objectvalue.BundleFile{}, nil, false
+	}
+
+	return file, r.body[offset : offset+file.Size()], true
+}
+
 // URL getter.
 func (r Record) URL() bool {
 	return r.url
 }
 
+// Redirect getter. Meaningful only for url record.
+func (r Record) Redirect() objectvalue.Redirect {
+	return r.redirect
+}
+
+// SetRedirect setter.
+func (r *Record) SetRedirect(redirect objectvalue.Redirect) {
+	r.redirect = redirect
+}
+
 // TTL returns duration until record expiration date.
 func (r *Record) TTL() time.Duration {
 	return r.expirationDate.Until()
@@ -31,6 +31,36 @@ func TestNewRecord(t *testing.T) {
 	})
 }
 
+func TestRecord_OwnedBy(t *testing.T) {
+	token := objectvalue.NewOwnerToken()
+	record := NewRecord("key", objectvalue.NewExpirationDateFromTTL(time.Minute), 0, true, 0, nil, false)
+	record.SetOwnerTokenHash(token.Hash())
+	record.SetMetadata(objectvalue.NewRecordMetadata(time.Now(), "", "", "", "apikey-id"))
+
+	t.Run("record is owned by owner token", func(t *testing.T) {
+		t.Parallel()
+
+		assert.True(t, record.OwnedBy(token, ""))
+		assert.False(t, record.OwnedBy(objectvalue.NewOwnerToken(), ""))
+	})
+
+	t.Run("record is owned by creator apikey", func(t *testing.T) {
+		t.Parallel()
+
+		assert.True(t, record.OwnedBy("", "apikey-id"))
+		assert.False(t, record.OwnedBy("", "other-apikey-id"))
+	})
+
+	t.Run("record without owner is not owned by empty token and apikey", func(t *testing.T) {
+		t.Parallel()
+
+		legacy := NewRecord("key", objectvalue.NewExpirationDateFromTTL(time.Minute), 0, true, 0, nil, false)
+
+		assert.False(t, legacy.OwnedBy("", ""))
+		assert.False(t, legacy.OwnedBy(token, ""))
+	})
+}
+
 func TestRecord_GetBody(t *testing.T) {
 	t.Run("get body returns body when record is valid", func(t *testing.T) {
 		t.Parallel()
@@ -93,6 +123,32 @@ func TestRecord_GetBody(t *testing.T) {
 	})
 }
 
+func TestRecord_GetBodyExpiration(t *testing.T) {
+	t.Run("get body does not prolong expiration date", func(t *testing.T) {
+		t.Parallel()
+
+		expirationDate := objectvalue.NewExpirationDateFromTTL(5 * time.Minute)
+		record := NewRecord("key12", expirationDate, 0, true, 0, []byte("body"), false)
+
+		_, err := record.GetBody()
+		require.NoError(t, err)
+
+		assert.Equal(t, expirationDate.Date(), record.ExpirationDate().Date())
+	})
+
+	t.Run("get body prolongs sliding expiration date", func(t *testing.T) {
+		t.Parallel()
+
+		expirationDate := objectvalue.NewExpirationDate(time.Now().Add(time.Minute), time.Hour)
+		record := NewRecord("key13", expirationDate, 0, true, 0, []byte("body"), false)
+
+		_, err := record.GetBody()
+		require.NoError(t, err)
+
+		assert.Greater(t, record.TTL(), 59*time.Minute)
+	})
+}
+
 func TestRecord_RGetBody(t *testing.T) {
 	t.Run("rget body returns body without checks", func(t *testing.T) {
 		t.Parallel()
@@ -3,6 +3,8 @@ package config
 
 import (
 	"time"
+
+	"github.com/thek4n/paste.thek4n.ru/internal/domain/objectvalue"
 )
 
 // CacheValidationConfig contains getters for validation values.
@@ -21,6 +23,12 @@ type CacheValidationConfig interface {
 	PrivilegedMinKeyLength() uint8
 
 	AllowedKeyChars() string
+
+	DefaultKeyStyle() objectvalue.KeyStyle
+	UnprivilegedKeyStyles() []objectvalue.KeyStyle
+	PrivilegedKeyStyles() []objectvalue.KeyStyle
+
+	MaxBundleFiles() int
 }
 
 // QuotaConfig contains getters for quota config values.
@@ -32,9 +40,46 @@ type QuotaConfig interface {
 // CachingConfig contains getters for caching config values.
 type CachingConfig interface {
 	CompressThresholdBytes() uint16
+	BodyCodec() string
 	MaxBodySize() int64
 	AttemptsToIncreaseKeyMinLength() uint8
 	KeysCharset() string
+	KeyReservationTTL() time.Duration
+}
+
+// ServingConfig contains getters for policy of serving untrusted record content.
+type ServingConfig interface {
+	// ActiveContentTypes media types rendered or executed by browsers.
+	ActiveContentTypes() []string
+	ContentSecurityPolicy() string
+	// PageContentSecurityPolicy policy of HTML pages rendering record
+	// content. It must not contain script-src, inline scripts of pages
+	// are allowed by their hashes.
+	PageContentSecurityPolicy() string
+	ReferrerPolicy() string
+	// UserContentHost host serving raw content to browsers. Empty means
+	// content is served from requested host.
+	UserContentHost() string
+}
+
+// URLPolicyConfig contains getters for policy of urls allowed to shorten.
+type URLPolicyConfig interface {
+	// AllowedURLSchemes lowercase schemes of urls allowed to shorten.
+	AllowedURLSchemes() []string
+	// ServiceHosts hosts of service besides requested one. Urls to them
+	// are rejected, so short links do not redirect back to service.
+	ServiceHosts() []string
+}
+
+// ClickStatsConfig contains getters for bounds of click analytics of record.
+type ClickStatsConfig interface {
+	// ClickStatsBucketSize time span of one bucket of clicks time series.
+	ClickStatsBucketSize() time.Duration
+	// ClickStatsMaxBuckets number of latest buckets kept, older are dropped.
+	ClickStatsMaxBuckets() int
+	// ClickStatsMaxBreakdown number of distinct referrers and countries
+	// counted, others are counted together.
+	ClickStatsMaxBreakdown() int
 }
 
 // DefaultCacheValidationConfig contains default values for cache validataion.
@@ -95,6 +140,36 @@ func (c DefaultCacheValidationConfig) AllowedKeyChars() string {
 	return "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
 }
 
+// DefaultKeyStyle key style of generated keys if not requested.
+func (c DefaultCacheValidationConfig) DefaultKeyStyle() objectvalue.KeyStyle {
+	return objectvalue.KeyStyleRandom
+}
+
+// UnprivilegedKeyStyles key styles allowed for unprivileged.
+func (c DefaultCacheValidationConfig) UnprivilegedKeyStyles() []objectvalue.KeyStyle {
+	return []objectvalue.KeyStyle{
+		objectvalue.KeyStyleRandom,
+		objectvalue.KeyStyleWords,
+		objectvalue.KeyStylePronounceable,
+	}
+}
+
+// PrivilegedKeyStyles key styles allowed for privileged. Sequential keys
+// are short and enumerable, so they are not allowed for unprivileged.
+func (c DefaultCacheValidationConfig) PrivilegedKeyStyles() []objectvalue.KeyStyle {
+	return []objectvalue.KeyStyle{
+		objectvalue.KeyStyleRandom,
+		objectvalue.KeyStyleWords,
+		objectvalue.KeyStylePronounceable,
+		objectvalue.KeyStyleSequential,
+	}
+}
+
+// MaxBundleFiles max number of files in bundle.
+func (c DefaultCacheValidationConfig) MaxBundleFiles() int {
+	return 100
+}
+
 // DefaultQuotaConfig contains getters for defaults quota config.
 type DefaultQuotaConfig struct{}
 
@@ -108,6 +183,20 @@ func (c DefaultQuotaConfig) Quota() uint32 {
 	return 50
 }
 
+// DefaultPasswordAttemptsConfig contains getters for defaults limit of
+// wrong password attempts per protected record.
+type DefaultPasswordAttemptsConfig struct{}
+
+// QuotaResetPeriod period after last wrong attempt when attempts are reset.
+func (c DefaultPasswordAttemptsConfig) QuotaResetPeriod() time.Duration {
+	return 15 * time.Minute
+}
+
+// Quota number of wrong attempts allowed for reset period.
+func (c DefaultPasswordAttemptsConfig) Quota() uint32 {
+	return 10
+}
+
 // DefaultCachingConfig contains getters for defaults caching config.
 type DefaultCachingConfig struct{}
 
@@ -116,6 +205,11 @@ func (c DefaultCachingConfig) CompressThresholdBytes() uint16 {
 	return 4096
 }
 
+// BodyCodec codec name for compressing bodies larger than CompressThresholdBytes.
+func (c DefaultCachingConfig) BodyCodec() string {
+	return "gzip"
+}
+
 // MaxBodySize max body size.
 func (c DefaultCachingConfig) MaxBodySize() int64 {
 	return 100 * oneMebibyte
@@ -131,6 +225,84 @@ func (c DefaultCachingConfig) KeysCharset() string {
 	return "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
 }
 
+// KeyReservationTTL how long reserved key waits for record to be written.
+func (c DefaultCachingConfig) KeyReservationTTL() time.Duration {
+	return time.Minute
+}
+
+// DefaultServingConfig contains getters for default serving policy.
+type DefaultServingConfig struct{}
+
+// ActiveContentTypes served as plain text unless declared by privileged
+// creator. Types with +xml suffix are active too.
+func (c DefaultServingConfig) ActiveContentTypes() []string {
+	return []string{
+		"text/html",
+		"application/xhtml+xml",
+		"image/svg+xml",
+		"text/xml",
+		"application/xml",
+		"text/javascript",
+		"application/javascript",
+		"application/x-javascript",
+		"text/ecmascript",
+		"application/ecmascript",
+		"text/xsl",
+	}
+}
+
+// ContentSecurityPolicy forbids scripts, plugins and requests of raw content.
+func (c DefaultServingConfig) ContentSecurityPolicy() string {
+	return "default-src 'none'; style-src 'unsafe-inline'; sandbox"
+}
+
+// PageContentSecurityPolicy forbids requests, plugins and framing of pages
+// and lets their forms post only to service.
+func (c DefaultServingConfig) PageContentSecurityPolicy() string {
+	return "default-src 'none'; style-src 'unsafe-inline'; form-action 'self'; base-uri 'none'; frame-ancestors 'none'"
+}
+
+// ReferrerPolicy hides record URL from sites linked by record.
+func (c DefaultServingConfig) ReferrerPolicy() string {
+	return "no-referrer"
+}
+
+// UserContentHost is empty, content is served from requested host.
+func (c DefaultServingConfig) UserContentHost() string {
+	return ""
+}
+
+// DefaultURLPolicyConfig contains getters for default url policy.
+type DefaultURLPolicyConfig struct{}
+
+// AllowedURLSchemes allows only web urls.
+func (c DefaultURLPolicyConfig) AllowedURLSchemes() []string {
+	return []string{"http", "https"}
+}
+
+// ServiceHosts is empty, only requested host is service host.
+func (c DefaultURLPolicyConfig) ServiceHosts() []string {
+	return nil
+}
+
+// DefaultClickStatsConfig contains getters for default bounds of click analytics.
+type DefaultClickStatsConfig struct{}
+
+// ClickStatsBucketSize counts clicks per hour.
+func (c DefaultClickStatsConfig) ClickStatsBucketSize() time.Duration {
+	return time.Hour
+}
+
+// ClickStatsMaxBuckets keeps clicks of last week.
+func (c DefaultClickStatsConfig) ClickStatsMaxBuckets() int {
+	return 7 * hoursInDay
+}
+
+// ClickStatsMaxBreakdown number of distinct referrers and countries.
+func (c DefaultClickStatsConfig) ClickStatsMaxBreakdown() int {
+	return 50
+}
+
 // Body size.
 const (
 	oneMebibyte int64 = 1048576
@@ -23,9 +23,34 @@ var ErrInvalidRequestedKeyLength = errors.New("invalid requested key length")
 // ErrInvalidRequestedKey .
 var ErrInvalidRequestedKey = errors.New("invalid requested key")
 
+// ErrKeyStyleNotAllowed error type to point that requested key style is not allowed.
+var ErrKeyStyleNotAllowed = errors.New("key style is not allowed")
+
+// ErrInvalidURL error type to point that body of url record is not valid url.
+var ErrInvalidURL = errors.New("invalid url")
+
+// ErrURLNotAllowed error type to point that url is valid but is not allowed
+// to shorten by url policy.
+var ErrURLNotAllowed = errors.New("url not allowed")
+
+// ErrInvalidBundle error type to point that bundle files are invalid.
+var ErrInvalidBundle = errors.New("invalid bundle")
+
 // ErrNonAuthorized .
 var ErrNonAuthorized = errors.New("non authorized")
 
+// ErrNotOwner error type to point that record is not owned by requester.
+var ErrNotOwner = errors.New("not owner")
+
+// ErrWrongPassword error type to point that password of protected record is missing or wrong.
+var ErrWrongPassword = errors.New("wrong or missing password")
+
+// ErrPasswordAttemptsExhausted error type to point that too many wrong passwords were tried for record.
+var ErrPasswordAttemptsExhausted = errors.New("password attempts exhausted")
+
+// ErrRevealNotConfirmed error type to point that reading of record must be confirmed explicitly.
+var ErrRevealNotConfirmed = errors.New("reveal not confirmed")
+
 // ErrRecordNotFound .
 var ErrRecordNotFound = errors.New("record not found")
 
@@ -0,0 +1,62 @@
+package objectvalue
+
+// BundleArchiveName name of bundle file path reserved for archive of all
+// bundle files.
+const BundleArchiveName = "archive.zip"
+
+// BundleFile entry of bundle record.
+type BundleFile struct {
+	name        string
+	contentType string
+	size        int64
+}
+
+// NewBundleFile constructor.
+func NewBundleFile(name, contentType string, size int64) BundleFile {
+	return BundleFile{
+		name:        name,
+		contentType: contentType,
+		size:        size,
+	}
+}
+
+// Name path of file inside bundle, e.g. "logs/app.log".
+func (f BundleFile) Name() string {
+	return f.name
+}
+
+// ContentType declared media type of file. Empty if not declared.
+func (f BundleFile) ContentType() string {
+	return f.contentType
+}
+
+// Size of file content in bytes.
+func (f BundleFile) Size() int64 {
+	return f.size
+}
+
+// BundleFiles entries of bundle record. Body of bundle is concatenation
+// of its files contents in order of entries.
+type BundleFiles []BundleFile
+
+// Size returns total size of files contents.
+func (f BundleFiles) Size() int64 {
+	var size int64
+	for _, file := range f {
+		size += file.size
+	}
+	return size
+}
+
+// Find returns file by name and offset of its content in bundle body.
+func (f BundleFiles) Find(name string) (BundleFile, int64, bool) {
+	var offset int64
+	for _, file := range f {
+		if file.name == name {
+			return file, offset, true
+		}
+		offset += file.size
+	}
+
+	return BundleFile{}, 0, false
+}
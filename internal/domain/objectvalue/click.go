@@ -0,0 +1,156 @@
+package objectvalue
+
+import (
+	"net/url"
+	"strings"
+	"time"
+)
+
+// UserAgentClass kind of client clicked record.
+type UserAgentClass string
+
+// User agent classes.
+const (
+	UserAgentBrowser UserAgentClass = "browser"
+	UserAgentBot     UserAgentClass = "bot"
+	UserAgentCLI     UserAgentClass = "cli"
+	UserAgentOther   UserAgentClass = "other"
+)
+
+// ReferrerDirect referrer host of click without referrer.
+const ReferrerDirect = "direct"
+
+// BreakdownOther name counting values out of breakdown limit.
+const BreakdownOther = "other"
+
+// botUserAgentMarkers substrings of lowercase user agents of crawlers,
+// link previews and monitoring.
+var botUserAgentMarkers = []string{
+	"bot", "crawl", "spider", "slurp", "preview", "facebookexternalhit",
+	"embedly", "monitor", "uptime", "headless", "lighthouse", "scan",
+}
+
+// cliUserAgentPrefixes prefixes of lowercase user agents of command line
+// tools and http libraries.
+var cliUserAgentPrefixes = []string{
+	"curl/", "wget/", "httpie/", "xh/", "aria2/", "powershell/",
+	"python-requests/", "python-urllib/", "python-httpx/", "go-http-client/",
+	"okhttp/", "libwww-perl/", "java/", "node-fetch/", "axios/", "ruby",
+}
+
+// ClassifyUserAgent returns class of client by its user agent.
+func ClassifyUserAgent(userAgent string) UserAgentClass {
+	ua := strings.ToLower(strings.TrimSpace(userAgent))
+	if ua == "" {
+		return UserAgentOther
+	}
+
+	for _, marker := range botUserAgentMarkers {
+		if strings.Contains(ua, marker) {
+			return UserAgentBot
+		}
+	}
+
+	for _, prefix := range cliUserAgentPrefixes {
+		if strings.HasPrefix(ua, prefix) {
+			return UserAgentCLI
+		}
+	}
+
+	if strings.HasPrefix(ua, "mozilla/") || strings.HasPrefix(ua, "opera/") {
+		return UserAgentBrowser
+	}
+
+	return UserAgentOther
+}
+
+// Click consuming of record counted by click analytics.
+type Click struct {
+	at           time.Time
+	referrerHost string
+	agent        UserAgentClass
+	country      string
+	visitorID    string
+}
+
+// NewClick constructor. Only host of referrer is kept. Country is ISO code,
+// empty if unknown. visitorID identifies visitor to estimate unique ones.
+func NewClick(at time.Time, referrer, userAgent, country, visitorID string) Click {
+	return Click{
+		at:           at,
+		referrerHost: referrerHost(referrer),
+		agent:        ClassifyUserAgent(userAgent),
+		country:      strings.ToUpper(country),
+		visitorID:    visitorID,
+	}
+}
+
+func referrerHost(referrer string) string {
+	u, err := url.Parse(strings.TrimSpace(referrer))
+	if err != nil || u.Hostname() == "" {
+		return ReferrerDirect
+	}
+
+	return strings.TrimSuffix(strings.ToLower(u.Hostname()), ".")
+}
+
+// At getter.
+func (c Click) At() time.Time {
+	return c.at
+}
+
+// ReferrerHost getter. ReferrerDirect if click has no referrer.
+func (c Click) ReferrerHost() string {
+	return c.referrerHost
+}
+
+// UserAgentClass getter.
+func (c Click) UserAgentClass() UserAgentClass {
+	return c.agent
+}
+
+// Country getter.
+func (c Click) Country() string {
+	return c.country
+}
+
+// VisitorID getter.
+func (c Click) VisitorID() string {
+	return c.visitorID
+}
+
+// Bot returns is click made by bot, so it is not human click.
+func (c Click) Bot() bool {
+	return c.agent == UserAgentBot
+}
+
+// ClickBucket clicks counted in time span starting at Start.
+type ClickBucket struct {
+	start     time.Time
+	clicks    uint32
+	botClicks uint32
+}
+
+// NewClickBucket constructor.
+func NewClickBucket(start time.Time, clicks, botClicks uint32) ClickBucket {
+	return ClickBucket{
+		start:     start,
+		clicks:    clicks,
+		botClicks: botClicks,
+	}
+}
+
+// Start getter.
+func (b ClickBucket) Start() time.Time {
+	return b.start
+}
+
+// Clicks getter. Human clicks only.
+func (b ClickBucket) Clicks() uint32 {
+	return b.clicks
+}
+
+// BotClicks getter.
+func (b ClickBucket) BotClicks() uint32 {
+	return b.botClicks
+}
@@ -0,0 +1,44 @@
+//go:build unit
+
+package objectvalue
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClassifyUserAgent(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]UserAgentClass{
+		"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 Chrome/128.0 Safari/537.36": UserAgentBrowser,
+		"Mozilla/5.0 (compatible; Googlebot/2.1; +http://www.google.com/bot.html)":                UserAgentBot,
+		"TelegramBot (like TwitterBot)": UserAgentBot,
+		"facebookexternalhit/1.1":       UserAgentBot,
+		"curl/8.5.0":                    UserAgentCLI,
+		"Wget/1.21.4":                   UserAgentCLI,
+		"python-requests/2.32.3":        UserAgentCLI,
+		"Go-http-client/1.1":            UserAgentCLI,
+		"":                              UserAgentOther,
+		"SomeApp/1.0":                   UserAgentOther,
+	}
+
+	for userAgent, expected := range tests {
+		assert.Equal(t, expected, ClassifyUserAgent(userAgent), userAgent)
+	}
+}
+
+func TestNewClick(t *testing.T) {
+	t.Parallel()
+
+	click := NewClick(time.Now(), "https://News.Example.COM./article?id=1", "curl/8.5.0", "de", "visitor")
+	assert.Equal(t, "news.example.com", click.ReferrerHost())
+	assert.Equal(t, "DE", click.Country())
+	assert.False(t, click.Bot())
+
+	click = NewClick(time.Now(), "not a url", "Googlebot/2.1", "", "")
+	assert.Equal(t, ReferrerDirect, click.ReferrerHost())
+	assert.True(t, click.Bot())
+}
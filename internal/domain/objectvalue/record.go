@@ -1,4 +1,181 @@
 package objectvalue
 
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"time"
+)
+
 // RecordKey objectvalue.
 type RecordKey string
+
+// KeyStyle style of generated record key.
+type KeyStyle string
+
+// Key styles.
+const (
+	// KeyStyleRandom random characters of keys charset, e.g. "8fYfLk34Y1H3UQ".
+	KeyStyleRandom KeyStyle = "random"
+	// KeyStyleWords hyphenated words with number, e.g. "brave-otter-42".
+	KeyStyleWords KeyStyle = "words"
+	// KeyStylePronounceable alternating consonants and vowels, e.g. "dakolimu".
+	KeyStylePronounceable KeyStyle = "pronounceable"
+	// KeyStyleSequential base62 encoded sequence number, e.g. "1c".
+	KeyStyleSequential KeyStyle = "sequential"
+)
+
+// Valid returns is key style known.
+func (s KeyStyle) Valid() bool {
+	switch s {
+	case KeyStyleRandom, KeyStyleWords, KeyStylePronounceable, KeyStyleSequential:
+		return true
+	}
+	return false
+}
+
+// OwnerToken secret returned to record creator to manage record.
+type OwnerToken string
+
+// NewOwnerToken generates random owner token.
+func NewOwnerToken() OwnerToken {
+	return OwnerToken(rand.Text())
+}
+
+// Hash returns hex encoded sha256 of token. Only hash is stored,
+// so token can not be taken from storage.
+func (t OwnerToken) Hash() string {
+	sum := sha256.Sum256([]byte(t))
+	return hex.EncodeToString(sum[:])
+}
+
+// RecordMetadata describes record content and its creation.
+// Zero fields are unknown, e.g. for records written by previous versions.
+type RecordMetadata struct {
+	createdAt    time.Time
+	contentType  string
+	filename     string
+	sourceIPHash string
+	apikeyID     string
+}
+
+// NewRecordMetadata constructor.
+func NewRecordMetadata(createdAt time.Time, contentType, filename, sourceIPHash, apikeyID string) RecordMetadata {
+	return RecordMetadata{
+		createdAt:    createdAt,
+		contentType:  contentType,
+		filename:     filename,
+		sourceIPHash: sourceIPHash,
+		apikeyID:     apikeyID,
+	}
+}
+
+// CreatedAt getter.
+func (m RecordMetadata) CreatedAt() time.Time {
+	return m.createdAt
+}
+
+// ContentType declared media type of body.
+func (m RecordMetadata) ContentType() string {
+	return m.contentType
+}
+
+// Filename getter.
+func (m RecordMetadata) Filename() string {
+	return m.filename
+}
+
+// SourceIPHash hash of creator ip.
+func (m RecordMetadata) SourceIPHash() string {
+	return m.sourceIPHash
+}
+
+// APIKeyID public id of creator apikey. Empty for unprivileged records.
+func (m RecordMetadata) APIKeyID() string {
+	return m.apikeyID
+}
+
+// RecordAccess describes what reader must present to consume record.
+type RecordAccess struct {
+	passwordHash string
+	reveal       bool
+	files        BundleFiles
+	forwardPath  bool
+	readsLimited bool
+}
+
+// NewRecordAccess constructor. Files are empty if record is not bundle.
+// forwardPath is true if url record accepts path suffix after key.
+// readsLimited is true if record is disposable.
+func NewRecordAccess(passwordHash string, reveal bool, files BundleFiles, forwardPath, readsLimited bool) RecordAccess {
+	return RecordAccess{
+		passwordHash: passwordHash,
+		reveal:       reveal,
+		files:        files,
+		forwardPath:  forwardPath,
+		readsLimited: readsLimited,
+	}
+}
+
+// PasswordHash getter. Empty if record is not protected by password.
+func (a RecordAccess) PasswordHash() string {
+	return a.passwordHash
+}
+
+// Reveal returns is reading of record must be explicitly confirmed.
+func (a RecordAccess) Reveal() bool {
+	return a.reveal
+}
+
+// Files getter. Empty if record is not bundle.
+func (a RecordAccess) Files() BundleFiles {
+	return a.files
+}
+
+// ForwardPath returns is path suffix after key forwarded by url record.
+func (a RecordAccess) ForwardPath() bool {
+	return a.forwardPath
+}
+
+// ReadsLimited returns is number of reads of record limited.
+func (a RecordAccess) ReadsLimited() bool {
+	return a.readsLimited
+}
+
+// BodyEncoding content coding of stored record body, e.g. "gzip".
+// Empty value means body is not encoded.
+type BodyEncoding string
+
+// BodyEncodingIdentity not encoded body.
+const BodyEncodingIdentity BodyEncoding = ""
+
+// EncodedBody record body encoded as it is stored.
+type EncodedBody struct {
+	data     []byte
+	encoding BodyEncoding
+	size     int64
+}
+
+// NewEncodedBody constructor. Size is length of decoded body.
+func NewEncodedBody(data []byte, encoding BodyEncoding, size int64) EncodedBody {
+	return EncodedBody{
+		data:     data,
+		encoding: encoding,
+		size:     size,
+	}
+}
+
+// Data getter.
+func (b EncodedBody) Data() []byte {
+	return b.data
+}
+
+// Encoding getter.
+func (b EncodedBody) Encoding() BodyEncoding {
+	return b.encoding
+}
+
+// Size returns length of decoded body.
+func (b EncodedBody) Size() int64 {
+	return b.size
+}
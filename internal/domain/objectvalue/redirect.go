@@ -0,0 +1,119 @@
+package objectvalue
+
+import "strings"
+
+// RedirectStatus HTTP status code of redirect to url of record.
+type RedirectStatus int
+
+// Redirect statuses.
+const (
+	// RedirectMovedPermanently 301, may be cached by browsers and changes POST to GET.
+	RedirectMovedPermanently RedirectStatus = 301
+	// RedirectFound 302, temporary and changes POST to GET.
+	RedirectFound RedirectStatus = 302
+	// RedirectSeeOther 303, temporary and always gets target with GET.
+	RedirectSeeOther RedirectStatus = 303
+	// RedirectTemporary 307, temporary and keeps method and body.
+	RedirectTemporary RedirectStatus = 307
+	// RedirectPermanent 308, may be cached by browsers and keeps method and body.
+	RedirectPermanent RedirectStatus = 308
+)
+
+// DefaultRedirectStatus status of records which did not choose one.
+const DefaultRedirectStatus = RedirectSeeOther
+
+// Valid returns is redirect status supported.
+func (s RedirectStatus) Valid() bool {
+	switch s {
+	case RedirectMovedPermanently, RedirectFound, RedirectSeeOther, RedirectTemporary, RedirectPermanent:
+		return true
+	}
+	return false
+}
+
+// Template variables of redirect target.
+const (
+	// RedirectVarKey replaced with key of record.
+	RedirectVarKey = "{key}"
+	// RedirectVarPath replaced with path suffix after key, without leading slash.
+	RedirectVarPath = "{path}"
+	// RedirectVarQuery replaced with query string of request, without '?'.
+	RedirectVarQuery = "{query}"
+)
+
+// Redirect describes how url record redirects to its target.
+// Zero value redirects with DefaultRedirectStatus and forwards nothing.
+type Redirect struct {
+	status       RedirectStatus
+	forwardQuery bool
+	forwardPath  bool
+}
+
+// NewRedirect constructor. Zero status means DefaultRedirectStatus.
+func NewRedirect(status RedirectStatus, forwardQuery, forwardPath bool) Redirect {
+	return Redirect{
+		status:       status,
+		forwardQuery: forwardQuery,
+		forwardPath:  forwardPath,
+	}
+}
+
+// Status getter.
+func (r Redirect) Status() RedirectStatus {
+	if r.status == 0 {
+		return DefaultRedirectStatus
+	}
+	return r.status
+}
+
+// ForwardQuery returns is query string of request appended to target.
+func (r Redirect) ForwardQuery() bool {
+	return r.forwardQuery
+}
+
+// ForwardPath returns is path suffix after key appended to target.
+// Record not forwarding path is not found by path with suffix.
+func (r Redirect) ForwardPath() bool {
+	return r.forwardPath
+}
+
+// Target returns url to redirect to. Template variables of target are
+// replaced, then path suffix and query are appended if they are forwarded
+// and target does not place them by variables. Path must be escaped.
+func (r Redirect) Target(target string, key RecordKey, path, rawQuery string) string {
+	target = strings.TrimSpace(target)
+	placesPath := strings.Contains(target, RedirectVarPath)
+	placesQuery := strings.Contains(target, RedirectVarQuery)
+
+	target = strings.NewReplacer(
+		RedirectVarKey, string(key),
+		RedirectVarPath, path,
+		RedirectVarQuery, rawQuery,
+	).Replace(target)
+
+	if r.forwardPath && !placesPath && path != "" {
+		end := len(target)
+		if i := strings.IndexAny(target, "?#"); i >= 0 {
+			end = i
+		}
+		target = strings.TrimSuffix(target[:end], "/") + "/" + path + target[end:]
+	}
+
+	if r.forwardQuery && !placesQuery && rawQuery != "" {
+		base, fragment, hasFragment := strings.Cut(target, "#")
+
+		switch {
+		case !strings.Contains(base, "?"):
+			base += "?"
+		case !strings.HasSuffix(base, "?") && !strings.HasSuffix(base, "&"):
+			base += "&"
+		}
+		target = base + rawQuery
+
+		if hasFragment {
+			target += "#" + fragment
+		}
+	}
+
+	return target
+}
@@ -0,0 +1,94 @@
+//go:build unit
+
+package objectvalue
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRedirect_Status(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, RedirectSeeOther, Redirect{}.Status(), "zero redirect should use default status")
+	assert.Equal(t, RedirectPermanent, NewRedirect(RedirectPermanent, false, false).Status())
+
+	assert.True(t, RedirectMovedPermanently.Valid())
+	assert.True(t, RedirectTemporary.Valid())
+	assert.False(t, RedirectStatus(200).Valid())
+	assert.False(t, RedirectStatus(304).Valid())
+}
+
+func TestRedirect_Target(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		redirect Redirect
+		target   string
+		path     string
+		query    string
+		want     string
+	}{
+		{
+			name:     "nothing forwarded",
+			redirect: NewRedirect(0, false, false),
+			target:   " https://example.com/page\n",
+			path:     "docs",
+			query:    "utm_source=mail",
+			want:     "https://example.com/page",
+		},
+		{
+			name:     "query appended",
+			redirect: NewRedirect(0, true, false),
+			target:   "https://example.com/page",
+			query:    "utm_source=mail&utm_medium=email",
+			want:     "https://example.com/page?utm_source=mail&utm_medium=email",
+		},
+		{
+			name:     "query merged with target query",
+			redirect: NewRedirect(0, true, false),
+			target:   "https://example.com/page?lang=en#top",
+			query:    "utm_source=mail",
+			want:     "https://example.com/page?lang=en&utm_source=mail#top",
+		},
+		{
+			name:     "empty query keeps target",
+			redirect: NewRedirect(0, true, true),
+			target:   "https://example.com/page",
+			want:     "https://example.com/page",
+		},
+		{
+			name:     "path appended before query",
+			redirect: NewRedirect(0, false, true),
+			target:   "https://example.com/docs/?lang=en",
+			path:     "guide/intro",
+			want:     "https://example.com/docs/guide/intro?lang=en",
+		},
+		{
+			name:     "path appended to host",
+			redirect: NewRedirect(0, true, true),
+			target:   "https://example.com",
+			path:     "a%20b",
+			query:    "q=1",
+			want:     "https://example.com/a%20b?q=1",
+		},
+		{
+			name:     "variables placed by template",
+			redirect: NewRedirect(0, true, true),
+			target:   "https://example.com/{path}/index.html?ref={key}&{query}",
+			path:     "v2",
+			query:    "utm_source=mail",
+			want:     "https://example.com/v2/index.html?ref=abc&utm_source=mail",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, tt.want, tt.redirect.Target(tt.target, "abc", tt.path, tt.query))
+		})
+	}
+}
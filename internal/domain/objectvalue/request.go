@@ -12,8 +12,9 @@ type CacheRequestID uuid.UUID
 
 // ExpirationDate timer to expirate record.
 type ExpirationDate struct {
-	date    time.Time
-	eternal bool
+	date       time.Time
+	slidingTTL time.Duration
+	eternal    bool
 }
 
 // NewExpirationDateFromTTL constructor.
@@ -25,6 +26,29 @@ func NewExpirationDateFromTTL(t time.Duration) ExpirationDate {
 	}
 }
 
+// NewSlidingExpirationDateFromTTL constructor of expiration date
+// that prolongs by ttl on every Prolong call.
+func NewSlidingExpirationDateFromTTL(t time.Duration) ExpirationDate {
+	e := NewExpirationDateFromTTL(t)
+	if !e.eternal {
+		e.slidingTTL = t
+	}
+	return e
+}
+
+// NewExpirationDate constructor from absolute date. Zero date means eternal.
+// Non zero slidingTTL makes expiration date sliding.
+func NewExpirationDate(date time.Time, slidingTTL time.Duration) ExpirationDate {
+	if date.IsZero() {
+		return ExpirationDate{eternal: true}
+	}
+
+	return ExpirationDate{
+		date:       date,
+		slidingTTL: slidingTTL,
+	}
+}
+
 // Expired returns is date after then now.
 func (e ExpirationDate) Expired() bool {
 	if e.eternal {
@@ -38,6 +62,35 @@ func (e ExpirationDate) Eternal() bool {
 	return e.eternal
 }
 
+// Date returns absolute expiration date. Returns zero time if eternal.
+func (e ExpirationDate) Date() time.Time {
+	if e.eternal {
+		return time.Time{}
+	}
+	return e.date
+}
+
+// Sliding returns is expiration date prolongs on Prolong.
+func (e ExpirationDate) Sliding() bool {
+	return e.slidingTTL != 0
+}
+
+// SlidingTTL getter. Returns zero if expiration date is not sliding.
+func (e ExpirationDate) SlidingTTL() time.Duration {
+	return e.slidingTTL
+}
+
+// Prolong moves sliding expiration date to sliding ttl from now.
+// Does nothing with not sliding or eternal expiration date.
+func (e ExpirationDate) Prolong() ExpirationDate {
+	if e.eternal || !e.Sliding() {
+		return e
+	}
+
+	e.date = time.Now().Add(e.slidingTTL)
+	return e
+}
+
 // Until returns duration until e.
 func (e ExpirationDate) Until() time.Duration {
 	if e.eternal {
@@ -124,6 +177,70 @@ type CacheRequestParams struct {
 	TTL                time.Duration
 	BodyLen            int64
 	RequestedKeyLength uint8
-	Disposable         uint8
-	IsURL              bool
+	KeyStyle           KeyStyle
+	ContentType        string
+	Filename           string
+	// BodyEncoding of Body, BodyLen is length of decoded body then.
+	// Url and password protected bodies are never encoded.
+	BodyEncoding BodyEncoding
+	// Password protects record if not empty.
+	Password   string
+	Disposable uint8
+	IsURL      bool
+	Sliding    bool
+	// Encrypted marks body as ciphertext encrypted by client.
+	Encrypted bool
+	// Reveal requires explicit confirmation before every read.
+	Reveal bool
+	// Files makes bundle record of Body concatenating files contents.
+	Files BundleFiles
+	// Redirect of url record.
+	Redirect Redirect
+	// Host request was sent to. Url record can not point to it.
+	Host string
+}
+
+// GetRequestParams represents get request params.
+type GetRequestParams struct {
+	// Password of protected record.
+	Password string
+	// Confirmed is true if reader explicitly confirmed reading.
+	Confirmed bool
+	// AcceptedEncodings body stored in one of these encodings is returned as is.
+	AcceptedEncodings []BodyEncoding
+	// File name of bundle file to get. Bundle without File and Archive is
+	// listed without consuming it. For url record forwarding path it is
+	// path suffix after key.
+	File string
+	// Archive gets all files of bundle.
+	Archive bool
+	// Click describes reader for click analytics.
+	Click ClickRequestParams
+}
+
+// ClickRequestParams describes reader consuming record.
+type ClickRequestParams struct {
+	SourceIP  string
+	Referrer  string
+	UserAgent string
+}
+
+// UpdateRequestParams represents update request params. Nil fields are
+// left unchanged.
+type UpdateRequestParams struct {
+	APIKey     string
+	OwnerToken OwnerToken
+	// Password of protected record. Required to change its body.
+	Password   string
+	Body       []byte
+	BodyLen    int64
+	TTL        *time.Duration
+	Disposable *uint8
+	IsURL      *bool
+	Sliding    bool
+	// Encrypted marks new body as ciphertext encrypted by client. New body
+	// without it is plain, so encrypted flag of record is always replaced.
+	Encrypted bool
+	// Host request was sent to. Url record can not point to it.
+	Host string
 }
@@ -49,6 +49,61 @@ func TestExpirationDate(t *testing.T) {
 	})
 }
 
+func TestExpirationDate_Absolute(t *testing.T) {
+	t.Run("new expiration date keeps absolute date", func(t *testing.T) {
+		t.Parallel()
+
+		date := time.Now().Add(time.Hour).Truncate(time.Millisecond)
+		exp := NewExpirationDate(date, 0)
+
+		assert.Equal(t, date, exp.Date())
+		assert.False(t, exp.Eternal())
+		assert.False(t, exp.Sliding())
+	})
+
+	t.Run("new expiration date with zero date is eternal", func(t *testing.T) {
+		t.Parallel()
+
+		exp := NewExpirationDate(time.Time{}, 0)
+
+		assert.True(t, exp.Eternal())
+		assert.True(t, exp.Date().IsZero())
+		assert.False(t, exp.Expired())
+	})
+
+	t.Run("prolong does not change not sliding expiration date", func(t *testing.T) {
+		t.Parallel()
+
+		exp := NewExpirationDateFromTTL(time.Hour)
+		date := exp.Date()
+
+		exp = exp.Prolong()
+
+		assert.Equal(t, date, exp.Date())
+	})
+
+	t.Run("prolong moves sliding expiration date to ttl from now", func(t *testing.T) {
+		t.Parallel()
+
+		date := time.Now().Add(time.Minute)
+		exp := NewExpirationDate(date, time.Hour)
+
+		exp = exp.Prolong()
+
+		assert.True(t, exp.Sliding())
+		assert.Greater(t, exp.Until(), 59*time.Minute)
+	})
+
+	t.Run("sliding expiration date with zero ttl is eternal and not sliding", func(t *testing.T) {
+		t.Parallel()
+
+		exp := NewSlidingExpirationDateFromTTL(0)
+
+		assert.True(t, exp.Eternal())
+		assert.False(t, exp.Sliding())
+	})
+}
+
 func TestDisposableCounter(t *testing.T) {
 	t.Run("new disposable counter with valid value returns counter", func(t *testing.T) {
 		t.Parallel()
@@ -3,6 +3,8 @@ package repository
 import (
 	"context"
 	"fmt"
+	"strings"
+	"sync"
 
 	"github.com/redis/go-redis/v9"
 
@@ -17,13 +19,15 @@ type redisAPIKeyRecord struct {
 
 // RedisAPIKeyRORepository redis implementation domain interface.
 type RedisAPIKeyRORepository struct {
-	client *redis.Client
+	client    redis.UniversalClient
+	keyPrefix string
 }
 
-// NewRedisAPIKeyRORepository constructor.
-func NewRedisAPIKeyRORepository(c *redis.Client) *RedisAPIKeyRORepository {
+// NewRedisAPIKeyRORepository constructor. APIKey keys are prefixed with keyPrefix.
+func NewRedisAPIKeyRORepository(c redis.UniversalClient, keyPrefix string) *RedisAPIKeyRORepository {
 	return &RedisAPIKeyRORepository{
-		client: c,
+		client:    c,
+		keyPrefix: keyPrefix,
 	}
 }
 
@@ -31,7 +35,7 @@ func NewRedisAPIKeyRORepository(c *redis.Client) *RedisAPIKeyRORepository {
 func (r *RedisAPIKeyRORepository) GetByID(ctx context.Context, key string) (aggregate.APIKey, error) {
 	var record redisAPIKeyRecord
 
-	err := r.client.HGetAll(ctx, key).Scan(&record)
+	err := r.client.HGetAll(ctx, r.keyPrefix+key).Scan(&record)
 	if err != nil {
 		return aggregate.APIKey{}, fmt.Errorf("failure get record for key '%s': %w", key, err)
 	}
@@ -47,35 +51,29 @@ func (r *RedisAPIKeyRORepository) GetByID(ctx context.Context, key string) (aggr
 // GetAll fetch all APIKeys from redis db.
 func (r *RedisAPIKeyRORepository) GetAll(ctx context.Context) ([]aggregate.APIKey, error) {
 	var apikeys []aggregate.APIKey
+	var mu sync.Mutex
 
-	var cursor uint64
-	for {
-		var keys []string
-		var err error
-		keys, cursor, err = r.client.Scan(ctx, cursor, "*", 100).Result()
+	err := scanKeys(ctx, r.client, escapeGlob(r.keyPrefix)+"*", func(ctx context.Context, client redis.Cmdable, key string) error {
+		var record redisAPIKeyRecord
+		err := client.HGetAll(ctx, key).Scan(&record)
 		if err != nil {
-			return nil, fmt.Errorf("fail to scan for apikeys: %w", err)
+			return fmt.Errorf("fail to scan apikey record: %w", err)
 		}
 
-		for _, key := range keys {
-			var record redisAPIKeyRecord
-			err := r.client.HGetAll(ctx, key).Scan(&record)
-			if err != nil {
-				return nil, fmt.Errorf("fail to scan apikey record: %w", err)
-			}
-
-			rid, err := objectvalue.NewAPIKeyID(record.ID)
-			if err != nil {
-				return nil, fmt.Errorf("fail to parse apikey record id for key: %w", err)
-			}
-			rapikey := aggregate.NewAPIKey(rid, key, record.Valid)
-
-			apikeys = append(apikeys, rapikey)
+		rid, err := objectvalue.NewAPIKeyID(record.ID)
+		if err != nil {
+			return fmt.Errorf("fail to parse apikey record id for key: %w", err)
 		}
+		rapikey := aggregate.NewAPIKey(rid, strings.TrimPrefix(key, r.keyPrefix), record.Valid)
 
-		if cursor == 0 {
-			break
-		}
+		mu.Lock()
+		apikeys = append(apikeys, rapikey)
+		mu.Unlock()
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
 
 	return apikeys, nil
@@ -83,7 +81,7 @@ func (r *RedisAPIKeyRORepository) GetAll(ctx context.Context) ([]aggregate.APIKe
 
 // Exists checks is key exists.
 func (r *RedisAPIKeyRORepository) Exists(ctx context.Context, key string) (bool, error) {
-	keysNumber, err := r.client.Exists(ctx, key).Uint64()
+	keysNumber, err := r.client.Exists(ctx, r.keyPrefix+key).Uint64()
 	if err != nil {
 		return false, fmt.Errorf("failure checking is key exists: %w", err)
 	}
@@ -93,13 +91,15 @@ func (r *RedisAPIKeyRORepository) Exists(ctx context.Context, key string) (bool,
 
 // RedisAPIKeyWORepository redis implementation of domain interface.
 type RedisAPIKeyWORepository struct {
-	client *redis.Client
+	client    redis.UniversalClient
+	keyPrefix string
 }
 
-// NewRedisAPIKeyWORepository constructor.
-func NewRedisAPIKeyWORepository(c *redis.Client) *RedisAPIKeyWORepository {
+// NewRedisAPIKeyWORepository constructor. APIKey keys are prefixed with keyPrefix.
+func NewRedisAPIKeyWORepository(c redis.UniversalClient, keyPrefix string) *RedisAPIKeyWORepository {
 	return &RedisAPIKeyWORepository{
-		client: c,
+		client:    c,
+		keyPrefix: keyPrefix,
 	}
 }
 
@@ -110,7 +110,7 @@ func (r *RedisAPIKeyWORepository) SetByID(ctx context.Context, key string, apike
 		Valid: apikey.Valid(),
 	}
 
-	err := r.client.HSet(ctx, key, record).Err()
+	err := r.client.HSet(ctx, r.keyPrefix+key, record).Err()
 	if err != nil {
 		return fmt.Errorf("failure set apikey for key '%s': %w", key, err)
 	}
@@ -120,10 +120,61 @@ func (r *RedisAPIKeyWORepository) SetByID(ctx context.Context, key string, apike
 
 // RemoveByID write apikey to redis.
 func (r *RedisAPIKeyWORepository) RemoveByID(ctx context.Context, key string) error {
-	err := r.client.Del(ctx, key).Err()
+	err := r.client.Del(ctx, r.keyPrefix+key).Err()
 	if err != nil {
 		return fmt.Errorf("failure remove apikey by key '%s': %w", key, err)
 	}
 
 	return nil
 }
+
+// scanKeys calls fn for every key matching pattern. Cluster keyspace is
+// split between masters, so each of them is scanned and fn may be called
+// concurrently with client of the node holding key.
+func scanKeys(
+	ctx context.Context,
+	client redis.UniversalClient,
+	pattern string,
+	fn func(ctx context.Context, client redis.Cmdable, key string) error,
+) error {
+	if cluster, ok := client.(*redis.ClusterClient); ok {
+		return cluster.ForEachMaster(ctx, func(ctx context.Context, master *redis.Client) error {
+			return scanNodeKeys(ctx, master, pattern, fn)
+		})
+	}
+
+	return scanNodeKeys(ctx, client, pattern, fn)
+}
+
+func scanNodeKeys(
+	ctx context.Context,
+	client redis.Cmdable,
+	pattern string,
+	fn func(ctx context.Context, client redis.Cmdable, key string) error,
+) error {
+	iter := client.Scan(ctx, 0, pattern, 100).Iterator()
+	for iter.Next(ctx) {
+		if err := fn(ctx, client, iter.Val()); err != nil {
+			return err
+		}
+	}
+
+	if err := iter.Err(); err != nil {
+		return fmt.Errorf("fail to scan keys: %w", err)
+	}
+
+	return nil
+}
+
+// escapeGlob escapes glob special chars of SCAN MATCH pattern.
+func escapeGlob(s string) string {
+	var b strings.Builder
+	for _, c := range s {
+		if strings.ContainsRune(`*?[]\`, c) {
+			b.WriteRune('\\')
+		}
+		b.WriteRune(c)
+	}
+
+	return b.String()
+}
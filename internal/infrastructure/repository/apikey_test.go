@@ -0,0 +1,60 @@
+//go:build integration
+
+package repository
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/thek4n/paste.thek4n.ru/internal/domain/aggregate"
+	"github.com/thek4n/paste.thek4n.ru/internal/domain/config"
+	"github.com/thek4n/paste.thek4n.ru/internal/domain/objectvalue"
+)
+
+func TestRedisRepositoriesSharedKeyspace(t *testing.T) {
+	ctx := context.Background()
+	client := redis.NewClient(&redis.Options{
+		Addr: fmt.Sprintf("%s:%d", getRedisHost(), 6379),
+		DB:   3,
+	})
+	require.NoError(t, client.FlushDB(ctx).Err())
+
+	records := NewRedisRecordRepository(client, config.DefaultCachingConfig{}, "record:")
+	apikeysRO := NewRedisAPIKeyRORepository(client, "apikey:")
+	apikeysWO := NewRedisAPIKeyWORepository(client, "apikey:")
+
+	t.Run("get all returns only apikeys without prefix", func(t *testing.T) {
+		record := aggregate.NewRecord("same", objectvalue.NewExpirationDateFromTTL(time.Hour), 0, true, 0, []byte("body"), false)
+		require.NoError(t, records.SetByKey(ctx, "same", record))
+
+		require.NoError(t, apikeysWO.SetByID(ctx, "same", aggregate.NewAPIKey(objectvalue.NilAPIKeyID, "same", true)))
+
+		all, err := apikeysRO.GetAll(ctx)
+		require.NoError(t, err)
+		require.Len(t, all, 1)
+		assert.Equal(t, "same", all[0].Key())
+
+		exists, err := client.Exists(ctx, "record:same", "apikey:same").Result()
+		require.NoError(t, err)
+		assert.Equal(t, int64(2), exists)
+
+		got, err := records.GetByKey(ctx, "same")
+		require.NoError(t, err)
+		assert.Equal(t, []byte("body"), got.RGetBody())
+	})
+}
+
+func getRedisHost() string {
+	redisHost := os.Getenv("REDIS_HOST")
+	if redisHost == "" {
+		return "localhost"
+	}
+	return redisHost
+}
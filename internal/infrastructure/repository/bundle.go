@@ -0,0 +1,58 @@
+package repository
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/thek4n/paste.thek4n.ru/internal/domain/objectvalue"
+)
+
+// storedBundleFile stored entry of bundle record.
+type storedBundleFile struct {
+	Name        string `json:"name"`
+	ContentType string `json:"content_type,omitempty"`
+	Size        int64  `json:"size"`
+}
+
+// encodeBundleFiles serializes files to JSON. Files of not bundle record
+// are stored as empty string.
+func encodeBundleFiles(files objectvalue.BundleFiles) (string, error) {
+	if len(files) == 0 {
+		return "", nil
+	}
+
+	stored := make([]storedBundleFile, 0, len(files))
+	for _, file := range files {
+		stored = append(stored, storedBundleFile{
+			Name:        file.Name(),
+			ContentType: file.ContentType(),
+			Size:        file.Size(),
+		})
+	}
+
+	data, err := json.Marshal(stored)
+	if err != nil {
+		return "", fmt.Errorf("fail to encode bundle files: %w", err)
+	}
+
+	return string(data), nil
+}
+
+// decodeBundleFiles deserializes files encoded by encodeBundleFiles.
+func decodeBundleFiles(data string) (objectvalue.BundleFiles, error) {
+	if data == "" {
+		return nil, nil
+	}
+
+	var stored []storedBundleFile
+	if err := json.Unmarshal([]byte(data), &stored); err != nil {
+		return nil, fmt.Errorf("fail to decode bundle files: %w", err)
+	}
+
+	files := make(objectvalue.BundleFiles, 0, len(stored))
+	for _, file := range stored {
+		files = append(files, objectvalue.NewBundleFile(file.Name, file.ContentType, file.Size))
+	}
+
+	return files, nil
+}
@@ -0,0 +1,114 @@
+package repository
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/thek4n/paste.thek4n.ru/internal/domain/aggregate"
+	"github.com/thek4n/paste.thek4n.ru/internal/domain/objectvalue"
+)
+
+// clickStatsUpdateAttempts number of attempts to update click stats
+// changed concurrently.
+const clickStatsUpdateAttempts = 10
+
+// storedClickStats stored click stats of record.
+type storedClickStats struct {
+	Buckets   []storedClickBucket `json:"buckets,omitempty"`
+	Clicks    uint32              `json:"clicks"`
+	BotClicks uint32              `json:"bot_clicks"`
+	Referrers map[string]uint32   `json:"referrers,omitempty"`
+	Agents    map[string]uint32   `json:"agents,omitempty"`
+	Countries map[string]uint32   `json:"countries,omitempty"`
+	// Visitors serialized sketch of unique visitors.
+	Visitors []byte `json:"visitors,omitempty"`
+}
+
+// storedClickBucket bucket of clicks starting at unix seconds.
+type storedClickBucket struct {
+	Start     int64  `json:"start"`
+	Clicks    uint32 `json:"clicks"`
+	BotClicks uint32 `json:"bot_clicks,omitempty"`
+}
+
+// encodeClickStats serializes stats to JSON.
+func encodeClickStats(stats aggregate.ClickStats) (string, error) {
+	stored := storedClickStats{
+		Clicks:    stats.Clicks(),
+		BotClicks: stats.BotClicks(),
+		Referrers: stats.Referrers(),
+		Agents:    make(map[string]uint32),
+		Countries: stats.Countries(),
+		Visitors:  stats.Visitors(),
+	}
+
+	for _, bucket := range stats.Buckets() {
+		stored.Buckets = append(stored.Buckets, storedClickBucket{
+			Start:     bucket.Start().Unix(),
+			Clicks:    bucket.Clicks(),
+			BotClicks: bucket.BotClicks(),
+		})
+	}
+
+	for agent, clicks := range stats.UserAgents() {
+		stored.Agents[string(agent)] = clicks
+	}
+
+	data, err := json.Marshal(stored)
+	if err != nil {
+		return "", fmt.Errorf("fail to encode click stats: %w", err)
+	}
+
+	return string(data), nil
+}
+
+// decodeClickStats deserializes stats encoded by encodeClickStats. Record
+// without clicks has empty stats stored as empty string.
+func decodeClickStats(data string) (aggregate.ClickStats, error) {
+	if data == "" {
+		return aggregate.NewEmptyClickStats(), nil
+	}
+
+	var stored storedClickStats
+	if err := json.Unmarshal([]byte(data), &stored); err != nil {
+		return aggregate.ClickStats{}, fmt.Errorf("fail to decode click stats: %w", err)
+	}
+
+	buckets := make([]objectvalue.ClickBucket, 0, len(stored.Buckets))
+	for _, bucket := range stored.Buckets {
+		buckets = append(buckets, objectvalue.NewClickBucket(time.Unix(bucket.Start, 0).UTC(), bucket.Clicks, bucket.BotClicks))
+	}
+
+	agents := make(map[objectvalue.UserAgentClass]uint32, len(stored.Agents))
+	for agent, clicks := range stored.Agents {
+		agents[objectvalue.UserAgentClass(agent)] = clicks
+	}
+
+	stats, err := aggregate.NewClickStats(
+		buckets,
+		stored.Clicks,
+		stored.BotClicks,
+		stored.Referrers,
+		agents,
+		stored.Countries,
+		stored.Visitors,
+	)
+	if err != nil {
+		return aggregate.ClickStats{}, fmt.Errorf("fail to decode click stats: %w", err)
+	}
+
+	return stats, nil
+}
+
+// updateEncodedClickStats applies update to encoded stats and returns them encoded.
+func updateEncodedClickStats(data string, update func(*aggregate.ClickStats)) (string, error) {
+	stats, err := decodeClickStats(data)
+	if err != nil {
+		return "", err
+	}
+
+	update(&stats)
+
+	return encodeClickStats(stats)
+}
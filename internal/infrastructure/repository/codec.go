@@ -0,0 +1,119 @@
+package repository
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+
+	"github.com/thek4n/paste.thek4n.ru/internal/domain/config"
+	"github.com/thek4n/paste.thek4n.ru/internal/domain/domainerrors"
+	"github.com/thek4n/paste.thek4n.ru/internal/domain/objectvalue"
+	"github.com/thek4n/paste.thek4n.ru/pkg/bodycodec"
+)
+
+// encodeBody compresses body with configured codec if body is larger than
+// threshold. Body already encoded by readEncodedBody is returned as is.
+func encodeBody(cfg config.CachingConfig, body []byte, encoding objectvalue.BodyEncoding) ([]byte, objectvalue.BodyEncoding, error) {
+	if encoding != objectvalue.BodyEncodingIdentity {
+		return body, encoding, nil
+	}
+
+	if len(body) <= int(cfg.CompressThresholdBytes()) {
+		return body, objectvalue.BodyEncodingIdentity, nil
+	}
+
+	codec, err := bodycodec.Get(cfg.BodyCodec())
+	if err != nil {
+		return nil, objectvalue.BodyEncodingIdentity, err
+	}
+
+	encodedBody, err := codec.Encode(body)
+	if err != nil {
+		return nil, objectvalue.BodyEncodingIdentity, fmt.Errorf("failed to compress: %w", err)
+	}
+
+	return encodedBody, objectvalue.BodyEncoding(codec.Name()), nil
+}
+
+// readEncodedBody reads body up to limit like encodeBody encodes it. Body
+// larger than threshold is compressed while reading, so only compressed
+// body is held in memory.
+func readEncodedBody(cfg config.CachingConfig, r io.Reader, limit int64) (objectvalue.EncodedBody, error) {
+	r = io.LimitReader(r, limit+1)
+
+	head := make([]byte, min(int64(cfg.CompressThresholdBytes()), limit)+1)
+	n, err := io.ReadFull(r, head)
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return objectvalue.NewEncodedBody(head[:n], objectvalue.BodyEncodingIdentity, int64(n)), nil
+	}
+	if err != nil {
+		return objectvalue.EncodedBody{}, fmt.Errorf("failed to read body: %w", err)
+	}
+	if int64(n) > limit {
+		return objectvalue.EncodedBody{}, domainerrors.ErrBodyTooLarge
+	}
+
+	codec, err := bodycodec.Get(cfg.BodyCodec())
+	if err != nil {
+		return objectvalue.EncodedBody{}, err
+	}
+
+	var buf bytes.Buffer
+	w, err := codec.NewWriter(&buf)
+	if err != nil {
+		return objectvalue.EncodedBody{}, fmt.Errorf("failed to create %s writer: %w", codec.Name(), err)
+	}
+
+	if _, err := w.Write(head); err != nil {
+		return objectvalue.EncodedBody{}, fmt.Errorf("failed to compress: %w", err)
+	}
+
+	size, err := io.Copy(w, r)
+	if err != nil {
+		return objectvalue.EncodedBody{}, fmt.Errorf("failed to read body: %w", err)
+	}
+
+	size += int64(n)
+	if size > limit {
+		return objectvalue.EncodedBody{}, domainerrors.ErrBodyTooLarge
+	}
+
+	if err := w.Close(); err != nil {
+		return objectvalue.EncodedBody{}, fmt.Errorf("failed to compress: %w", err)
+	}
+
+	return objectvalue.NewEncodedBody(buf.Bytes(), objectvalue.BodyEncoding(codec.Name()), size), nil
+}
+
+// decodeBody returns body as is if it is not encoded or its encoding is
+// accepted, otherwise decompresses it.
+func decodeBody(
+	cfg config.CachingConfig,
+	body []byte,
+	encoding objectvalue.BodyEncoding,
+	acceptedEncodings []objectvalue.BodyEncoding,
+) ([]byte, objectvalue.BodyEncoding, error) {
+	if encoding == objectvalue.BodyEncodingIdentity || slices.Contains(acceptedEncodings, encoding) {
+		return body, encoding, nil
+	}
+
+	codec, err := bodycodec.Get(string(encoding))
+	if err != nil {
+		return nil, encoding, err
+	}
+
+	decodedBody, err := bodycodec.Decode(codec, body, cfg.MaxBodySize())
+	if err != nil {
+		return nil, encoding, fmt.Errorf("fail to decompress compressed body: %w", err)
+	}
+
+	return decodedBody, objectvalue.BodyEncodingIdentity, nil
+}
+
+// isGzip detects gzip by magic bytes. Used only for records written before
+// body encoding was stored alongside body.
+func isGzip(data []byte) bool {
+	return len(data) >= 2 && data[0] == 0x1f && data[1] == 0x8b
+}
@@ -0,0 +1,148 @@
+package repository
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"net/netip"
+	"os"
+	"slices"
+	"strings"
+)
+
+// geoIPRange ips from start to end inclusive located in country.
+type geoIPRange struct {
+	start   netip.Addr
+	end     netip.Addr
+	country string
+}
+
+// FileGeoIPRepository local GeoIP database file implementation of domain
+// interface of GeoIP repository. File is CSV with "start_ip,end_ip,country"
+// rows like free DB-IP country database or "cidr,country" rows. Lines
+// starting with '#' are ignored. Ranges must not overlap.
+type FileGeoIPRepository struct {
+	ranges []geoIPRange
+}
+
+// NewFileGeoIPRepository constructor. Database is read once.
+func NewFileGeoIPRepository(path string) (*FileGeoIPRepository, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("fail to open geoip database: %w", err)
+	}
+	defer file.Close()
+
+	reader := csv.NewReader(file)
+	reader.Comment = '#'
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	var ranges []geoIPRange
+	for {
+		row, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("fail to read geoip database: %w", err)
+		}
+
+		r, err := parseGeoIPRange(row)
+		if err != nil {
+			line, _ := reader.FieldPos(0)
+			return nil, fmt.Errorf("invalid geoip database row at line %d: %w", line, err)
+		}
+		ranges = append(ranges, r)
+	}
+
+	slices.SortFunc(ranges, func(a, b geoIPRange) int {
+		return a.start.Compare(b.start)
+	})
+
+	return &FileGeoIPRepository{ranges: ranges}, nil
+}
+
+func parseGeoIPRange(row []string) (geoIPRange, error) {
+	switch len(row) {
+	case 2:
+		prefix, err := netip.ParsePrefix(row[0])
+		if err != nil {
+			return geoIPRange{}, err
+		}
+		prefix = prefix.Masked()
+
+		return newGeoIPRange(prefix.Addr(), lastAddr(prefix), row[1])
+	case 3:
+		start, err := netip.ParseAddr(row[0])
+		if err != nil {
+			return geoIPRange{}, err
+		}
+
+		end, err := netip.ParseAddr(row[1])
+		if err != nil {
+			return geoIPRange{}, err
+		}
+
+		return newGeoIPRange(start, end, row[2])
+	default:
+		return geoIPRange{}, fmt.Errorf("expected 2 or 3 fields, got %d", len(row))
+	}
+}
+
+func newGeoIPRange(start, end netip.Addr, country string) (geoIPRange, error) {
+	start, end = start.Unmap(), end.Unmap()
+	if start.Is4() != end.Is4() || end.Less(start) {
+		return geoIPRange{}, fmt.Errorf("invalid range from %s to %s", start, end)
+	}
+
+	country = strings.ToUpper(strings.TrimSpace(country))
+	if len(country) != 2 {
+		return geoIPRange{}, fmt.Errorf("invalid country code '%s'", country)
+	}
+
+	return geoIPRange{start: start, end: end, country: country}, nil
+}
+
+// lastAddr returns last address of masked prefix.
+func lastAddr(prefix netip.Prefix) netip.Addr {
+	addr := prefix.Addr().Unmap()
+	bytes := addr.AsSlice()
+	bits := prefix.Bits()
+	if addr.Is4() && prefix.Addr().Is4In6() {
+		bits -= 96
+	}
+
+	for i := range bytes {
+		hostBits := max(0, min(8, (i+1)*8-bits))
+		bytes[i] |= byte(1<<hostBits - 1)
+	}
+
+	last, _ := netip.AddrFromSlice(bytes)
+	return last
+}
+
+// Country returns ISO code of country of ip, empty if ip is not in database.
+func (r *FileGeoIPRepository) Country(_ context.Context, ip string) (string, error) {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return "", nil
+	}
+	addr = addr.Unmap().WithZone("")
+
+	// last range starting not after addr
+	i, found := slices.BinarySearchFunc(r.ranges, addr, func(r geoIPRange, addr netip.Addr) int {
+		return r.start.Compare(addr)
+	})
+	if !found {
+		i--
+	}
+
+	if i < 0 || r.ranges[i].start.Is4() != addr.Is4() || r.ranges[i].end.Less(addr) {
+		return "", nil
+	}
+
+	return r.ranges[i].country, nil
+}
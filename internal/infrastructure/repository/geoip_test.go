@@ -0,0 +1,66 @@
+//go:build unit
+
+package repository
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileGeoIPRepository(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	t.Run("country is found by ranges and cidrs", func(t *testing.T) {
+		t.Parallel()
+
+		path := filepath.Join(t.TempDir(), "geoip.csv")
+		content := "# test database\n1.0.0.0,1.0.0.255,AU\n5.0.0.0/8,ru\n2001:db8::/32,DE\n10.0.0.0,10.0.0.10,US\n"
+		require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+
+		geoip, err := NewFileGeoIPRepository(path)
+		require.NoError(t, err)
+
+		tests := map[string]string{
+			"1.0.0.1":        "AU",
+			"1.0.1.0":        "",
+			"5.255.255.255":  "RU",
+			"::ffff:5.1.2.3": "RU",
+			"10.0.0.10":      "US",
+			"10.0.0.11":      "",
+			"2001:db8::1":    "DE",
+			"2001:db9::1":    "",
+			"0.0.0.1":        "",
+			"not an ip":      "",
+			"ffff::1":        "",
+		}
+		for ip, expected := range tests {
+			country, err := geoip.Country(ctx, ip)
+			require.NoError(t, err)
+			assert.Equal(t, expected, country, ip)
+		}
+	})
+
+	t.Run("invalid database is error", func(t *testing.T) {
+		t.Parallel()
+
+		for _, content := range []string{
+			"1.0.0.0,AU\n",
+			"1.0.0.9,1.0.0.0,AU\n",
+			"1.0.0.0/8,Australia\n",
+			"1.0.0.0,1.0.0.1,AU,extra\n",
+		} {
+			path := filepath.Join(t.TempDir(), "geoip.csv")
+			require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+
+			_, err := NewFileGeoIPRepository(path)
+			assert.Error(t, err, content)
+		}
+	})
+}
@@ -0,0 +1,257 @@
+package repository
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/thek4n/paste.thek4n.ru/internal/domain/config"
+	"github.com/thek4n/paste.thek4n.ru/internal/domain/domainerrors"
+	"github.com/thek4n/paste.thek4n.ru/internal/domain/objectvalue"
+)
+
+// KeyGenerator generates record key candidates. Candidate may be already
+// taken, so uniqueness is provided by reserving it.
+type KeyGenerator interface {
+	// GenerateKey generates key. Meaning of length depends on key style,
+	// but greater length always gives more possible keys.
+	GenerateKey(ctx context.Context, length uint8) (objectvalue.RecordKey, error)
+}
+
+// sequenceFunc returns next value of persistent atomic counter.
+type sequenceFunc func(ctx context.Context) (uint64, error)
+
+// newKeyGenerators returns generators of all key styles.
+// Sequential keys are numbered by next.
+func newKeyGenerators(cfg config.CachingConfig, next sequenceFunc) map[objectvalue.KeyStyle]KeyGenerator {
+	return map[objectvalue.KeyStyle]KeyGenerator{
+		objectvalue.KeyStyleRandom:        randomKeyGenerator{charset: cfg.KeysCharset()},
+		objectvalue.KeyStyleWords:         wordsKeyGenerator{},
+		objectvalue.KeyStylePronounceable: pronounceableKeyGenerator{},
+		objectvalue.KeyStyleSequential:    sequentialKeyGenerator{next: next},
+	}
+}
+
+// randomKeyGenerator generates length random chars of charset.
+type randomKeyGenerator struct {
+	charset string
+}
+
+func (g randomKeyGenerator) GenerateKey(_ context.Context, length uint8) (objectvalue.RecordKey, error) {
+	key, err := randomString(length, g.charset)
+	if err != nil {
+		return "", err
+	}
+	return objectvalue.RecordKey(key), nil
+}
+
+// wordsKeyGenerator generates adjective and noun followed by number
+// of length chars, e.g. "brave-otter-427". Words are picked to leave at
+// least two digits of number. Shorter length than wordsKeyMinLength is
+// ErrInvalidRequestedKeyLength.
+type wordsKeyGenerator struct{}
+
+// wordsKeyMinDigits min number of digits of words key number.
+const wordsKeyMinDigits = 2
+
+// wordsKeyMinLength length of words key of shortest words: two words,
+// two separators and number.
+var wordsKeyMinLength = shortestWordLength(keyAdjectives) + shortestWordLength(keyNouns) + 2 + wordsKeyMinDigits
+
+func (wordsKeyGenerator) GenerateKey(_ context.Context, length uint8) (objectvalue.RecordKey, error) {
+	if int(length) < wordsKeyMinLength {
+		return "", domainerrors.ErrInvalidRequestedKeyLength
+	}
+
+	// length left for words without separators and number
+	wordsLength := int(length) - 2 - wordsKeyMinDigits
+
+	adjective, err := randomWord(keyAdjectives, wordsLength-shortestWordLength(keyNouns))
+	if err != nil {
+		return "", err
+	}
+
+	noun, err := randomWord(keyNouns, wordsLength-len(adjective))
+	if err != nil {
+		return "", err
+	}
+
+	number, err := randomString(length-uint8(len(adjective)+len(noun)+2), "0123456789")
+	if err != nil {
+		return "", err
+	}
+
+	return objectvalue.RecordKey(adjective + "-" + noun + "-" + number), nil
+}
+
+// randomWord returns random word not longer than maxLength.
+func randomWord(words []string, maxLength int) (string, error) {
+	fitting := make([]string, 0, len(words))
+	for _, word := range words {
+		if len(word) <= maxLength {
+			fitting = append(fitting, word)
+		}
+	}
+
+	n, err := randomInt(len(fitting))
+	if err != nil {
+		return "", err
+	}
+
+	return fitting[n], nil
+}
+
+// shortestWordLength returns length of shortest word.
+func shortestWordLength(words []string) int {
+	shortest := len(words[0])
+	for _, word := range words[1:] {
+		shortest = min(shortest, len(word))
+	}
+	return shortest
+}
+
+// pronounceableKeyGenerator generates length lowercase chars alternating
+// consonants and vowels, e.g. "dakolimu". Consonants that sound alike
+// or may be spelled differently are excluded.
+type pronounceableKeyGenerator struct{}
+
+const (
+	pronounceableConsonants = "bdfghjklmnprstvz"
+	pronounceableVowels     = "aeiou"
+)
+
+func (pronounceableKeyGenerator) GenerateKey(_ context.Context, length uint8) (objectvalue.RecordKey, error) {
+	var b strings.Builder
+	b.Grow(int(length))
+
+	for i := range length {
+		charset := pronounceableConsonants
+		if i%2 == 1 {
+			charset = pronounceableVowels
+		}
+
+		n, err := randomInt(len(charset))
+		if err != nil {
+			return "", err
+		}
+		b.WriteByte(charset[n])
+	}
+
+	return objectvalue.RecordKey(b.String()), nil
+}
+
+// sequentialKeyGenerator encodes next sequence number in base62 padded
+// with leading zeros to length. Number that does not fit length makes
+// longer key.
+type sequentialKeyGenerator struct {
+	next sequenceFunc
+}
+
+const base62Charset = "0123456789abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ"
+
+func (g sequentialKeyGenerator) GenerateKey(ctx context.Context, length uint8) (objectvalue.RecordKey, error) {
+	n, err := g.next(ctx)
+	if err != nil {
+		return "", fmt.Errorf("fail to get next sequence number: %w", err)
+	}
+
+	key := encodeNumber(n, base62Charset)
+	if len(key) < int(length) {
+		key = strings.Repeat(base62Charset[:1], int(length)-len(key)) + key
+	}
+
+	return objectvalue.RecordKey(key), nil
+}
+
+// encodeNumber encodes n in positional numeral system with charset as digits.
+func encodeNumber(n uint64, charset string) string {
+	base := uint64(len(charset))
+	if n == 0 {
+		return charset[:1]
+	}
+
+	var digits []byte
+	for ; n > 0; n /= base {
+		digits = append(digits, charset[n%base])
+	}
+
+	for i, j := 0, len(digits)-1; i < j; i, j = i+1, j-1 {
+		digits[i], digits[j] = digits[j], digits[i]
+	}
+
+	return string(digits)
+}
+
+// generateUniqueKey generates key of style until reserve func succeeds.
+// See RedisRecordRepository.GenerateUniqueKey.
+func generateUniqueKey(
+	ctx context.Context,
+	cfg config.CachingConfig,
+	generators map[objectvalue.KeyStyle]KeyGenerator,
+	style objectvalue.KeyStyle,
+	minLength, maxLength uint8,
+	reserve func(context.Context, objectvalue.RecordKey) (bool, error),
+) (objectvalue.RecordKey, error) {
+	generator, ok := generators[style]
+	if !ok {
+		return "", fmt.Errorf("unknown key style '%s'", style)
+	}
+
+	currentAttemptsCountdown := cfg.AttemptsToIncreaseKeyMinLength()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return "", fmt.Errorf("timeout")
+		default:
+		}
+
+		key, err := generator.GenerateKey(ctx, minLength)
+		if err != nil {
+			return "", fmt.Errorf("fail generate key: %w", err)
+		}
+		reserved, err := reserve(ctx, key)
+		if err != nil {
+			return "", fmt.Errorf("fail to reserve key '%s': %w", key, err)
+		}
+		if reserved {
+			return key, nil
+		}
+		currentAttemptsCountdown--
+
+		if currentAttemptsCountdown < 1 {
+			minLength++
+			currentAttemptsCountdown = cfg.AttemptsToIncreaseKeyMinLength()
+		}
+
+		if minLength > maxLength {
+			return "", fmt.Errorf("max key length reached")
+		}
+	}
+}
+
+// randomString generate new random string with specified length using charset.
+func randomString(length uint8, charset string) (string, error) {
+	result := make([]byte, length)
+
+	for i := range length {
+		n, err := randomInt(len(charset))
+		if err != nil {
+			return "", err
+		}
+		result[i] = charset[n]
+	}
+
+	return string(result), nil
+}
+
+// randomInt returns uniform random number in [0, n).
+func randomInt(n int) (int, error) {
+	nBig, err := rand.Int(rand.Reader, big.NewInt(int64(n)))
+	if err != nil {
+		return 0, fmt.Errorf("failure generate random number: %w", err)
+	}
+	return int(nBig.Int64()), nil
+}
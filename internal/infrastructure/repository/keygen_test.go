@@ -0,0 +1,101 @@
+//go:build unit
+
+package repository
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/thek4n/paste.thek4n.ru/internal/domain/config"
+	"github.com/thek4n/paste.thek4n.ru/internal/domain/domainerrors"
+	"github.com/thek4n/paste.thek4n.ru/internal/domain/objectvalue"
+)
+
+func TestKeyGenerators(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("words key consists of adjective, noun and number", func(t *testing.T) {
+		t.Parallel()
+
+		key, err := wordsKeyGenerator{}.GenerateKey(ctx, 14)
+		require.NoError(t, err)
+		assert.Regexp(t, `^[a-z]+-[a-z]+-[0-9]{2,}$`, string(key))
+	})
+
+	t.Run("words key has requested length up to max key length", func(t *testing.T) {
+		t.Parallel()
+
+		maxLength := config.DefaultCacheValidationConfig{}.MaxKeyLength()
+		for length := uint8(wordsKeyMinLength); length <= maxLength; length++ {
+			for range 20 {
+				key, err := wordsKeyGenerator{}.GenerateKey(ctx, length)
+				require.NoError(t, err)
+				assert.Len(t, key, int(length))
+				assert.Regexp(t, `^[a-z]+-[a-z]+-[0-9]{2,}$`, string(key))
+			}
+		}
+	})
+
+	t.Run("words key shorter than shortest words is not generated", func(t *testing.T) {
+		t.Parallel()
+
+		assert.Equal(t, 10, wordsKeyMinLength)
+
+		_, err := wordsKeyGenerator{}.GenerateKey(ctx, uint8(wordsKeyMinLength-1))
+		assert.ErrorIs(t, err, domainerrors.ErrInvalidRequestedKeyLength)
+	})
+
+	t.Run("pronounceable key alternates consonants and vowels", func(t *testing.T) {
+		t.Parallel()
+
+		key, err := pronounceableKeyGenerator{}.GenerateKey(ctx, 9)
+		require.NoError(t, err)
+		assert.Regexp(t, `^([bdfghjklmnprstvz][aeiou]){4}[bdfghjklmnprstvz]$`, string(key))
+	})
+
+	t.Run("sequential keys are base62 encoded sequence numbers padded to length", func(t *testing.T) {
+		t.Parallel()
+
+		var n uint64 = 60
+		generator := sequentialKeyGenerator{
+			next: func(context.Context) (uint64, error) {
+				n++
+				return n, nil
+			},
+		}
+
+		var keys []objectvalue.RecordKey
+		for range 3 {
+			key, err := generator.GenerateKey(ctx, 3)
+			require.NoError(t, err)
+			keys = append(keys, key)
+		}
+		assert.Equal(t, []objectvalue.RecordKey{"00Z", "010", "011"}, keys)
+
+		key, err := generator.GenerateKey(ctx, 1)
+		require.NoError(t, err)
+		assert.Equal(t, objectvalue.RecordKey("12"), key, "number longer than length is not cut")
+	})
+
+	t.Run("unique key of unknown style is not generated", func(t *testing.T) {
+		t.Parallel()
+
+		repo := NewMemoryRecordRepository(config.DefaultCachingConfig{})
+
+		_, err := repo.GenerateUniqueKey(ctx, "unknown", 8, 20)
+		assert.Error(t, err)
+	})
+}
+
+func TestEncodeNumber(t *testing.T) {
+	t.Run("number is encoded with charset as digits", func(t *testing.T) {
+		t.Parallel()
+
+		assert.Equal(t, "0", encodeNumber(0, "0123456789"))
+		assert.Equal(t, "42", encodeNumber(42, "0123456789"))
+		assert.Equal(t, "101010", encodeNumber(42, "01"))
+	})
+}
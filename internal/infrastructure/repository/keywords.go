@@ -0,0 +1,42 @@
+package repository
+
+// keyAdjectives and keyNouns are words of keys generated in words style.
+// Words are short, common and have no homophones, so key can be dictated.
+var keyAdjectives = []string{
+	"able", "acid", "agile", "amber", "ample", "angry", "azure", "bald",
+	"basic", "bold", "brave", "brief", "bright", "brisk", "busy", "calm",
+	"cheap", "civil", "clean", "clear", "clever", "cold", "cool", "cosy",
+	"crazy", "crisp", "cute", "daily", "damp", "dark", "deep", "dense",
+	"dizzy", "dry", "dusty", "eager", "early", "easy", "empty", "epic",
+	"equal", "even", "exact", "extra", "faint", "fancy", "fast", "fierce",
+	"final", "firm", "flat", "fluffy", "fond", "frank", "free", "fresh",
+	"funny", "fuzzy", "gentle", "giant", "glad", "gold", "good", "grand",
+	"green", "happy", "hardy", "heavy", "honest", "huge", "humble", "icy",
+	"ideal", "jolly", "juicy", "keen", "kind", "large", "lazy", "light",
+	"little", "lively", "loud", "lucky", "magic", "major", "merry", "mighty",
+	"mild", "modern", "narrow", "neat", "nice", "noble", "odd", "open",
+	"polite", "proud", "pure", "quick", "quiet", "rapid", "rare", "ready",
+	"rich", "rough", "round", "royal", "rusty", "safe", "sharp", "shiny",
+	"silent", "silly", "simple", "sleepy", "slow", "smart", "smooth", "soft",
+	"solid", "spicy", "steady", "sunny", "super", "sweet", "swift", "tall",
+	"tame", "tidy", "tiny", "tough", "true", "urban", "vast", "vivid",
+	"warm", "wild", "wise", "witty", "young", "zany", "zesty",
+}
+
+var keyNouns = []string{
+	"apple", "arrow", "badger", "banana", "beaver", "berry", "bison", "bridge",
+	"brook", "camel", "candle", "canyon", "carrot", "castle", "cedar", "cherry",
+	"cliff", "cloud", "clover", "cobra", "comet", "coral", "cougar", "crane",
+	"cricket", "dolphin", "donkey", "dragon", "eagle", "ember", "falcon", "ferret",
+	"field", "finch", "forest", "fox", "frog", "garden", "gecko", "ginger",
+	"glacier", "goose", "grape", "harbor", "hawk", "hedgehog", "heron", "hippo",
+	"island", "jaguar", "jungle", "kettle", "kitten", "koala", "ladder", "lagoon",
+	"lemon", "lemur", "lion", "lizard", "llama", "lobster", "lotus", "mango",
+	"maple", "meadow", "melon", "meteor", "monkey", "moose", "mountain", "nebula",
+	"ocean", "olive", "orange", "otter", "owl", "panda", "panther", "parrot",
+	"peach", "pebble", "pelican", "pepper", "piano", "pigeon", "planet", "pony",
+	"potato", "puffin", "pumpkin", "rabbit", "raccoon", "radish", "river", "robin",
+	"rocket", "salmon", "shark", "sparrow", "spider", "squid", "star", "stone",
+	"summit", "sunset", "swan", "tiger", "tomato", "tulip", "turtle", "valley",
+	"violin", "walrus", "whale", "willow", "wizard", "wolf", "zebra",
+}
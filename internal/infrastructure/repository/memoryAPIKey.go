@@ -0,0 +1,63 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/thek4n/paste.thek4n.ru/internal/domain/aggregate"
+	"github.com/thek4n/paste.thek4n.ru/internal/domain/domainerrors"
+)
+
+// MemoryAPIKeyRepository in-memory implementation of both readonly
+// and write domain interfaces for apikeys.
+type MemoryAPIKeyRepository struct {
+	store *memoryStore[string, aggregate.APIKey]
+}
+
+// NewMemoryAPIKeyRepository constructor.
+func NewMemoryAPIKeyRepository() *MemoryAPIKeyRepository {
+	return &MemoryAPIKeyRepository{
+		store: newMemoryStore[string, aggregate.APIKey](),
+	}
+}
+
+// GetByID fetch APIKey from memory.
+func (r *MemoryAPIKeyRepository) GetByID(_ context.Context, key string) (aggregate.APIKey, error) {
+	apikey, exists := r.store.get(key)
+	if !exists {
+		return aggregate.APIKey{}, fmt.Errorf("failure get record for key '%s': %w", key, domainerrors.ErrAPIKeyNotFound)
+	}
+
+	return apikey, nil
+}
+
+// GetAll fetch all APIKeys from memory.
+func (r *MemoryAPIKeyRepository) GetAll(_ context.Context) ([]aggregate.APIKey, error) {
+	values := r.store.values()
+
+	apikeys := make([]aggregate.APIKey, 0, len(values))
+	for _, apikey := range values {
+		apikeys = append(apikeys, apikey)
+	}
+
+	return apikeys, nil
+}
+
+// Exists checks is key exists.
+func (r *MemoryAPIKeyRepository) Exists(_ context.Context, key string) (bool, error) {
+	_, exists := r.store.get(key)
+	return exists, nil
+}
+
+// SetByID write apikey to memory.
+func (r *MemoryAPIKeyRepository) SetByID(_ context.Context, key string, apikey aggregate.APIKey) error {
+	r.store.set(key, aggregate.NewAPIKey(apikey.PublicID(), key, apikey.Valid()), time.Time{})
+	return nil
+}
+
+// RemoveByID removes apikey from memory.
+func (r *MemoryAPIKeyRepository) RemoveByID(_ context.Context, key string) error {
+	r.store.delete(key)
+	return nil
+}
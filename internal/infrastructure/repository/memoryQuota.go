@@ -0,0 +1,46 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/thek4n/paste.thek4n.ru/internal/domain/aggregate"
+	"github.com/thek4n/paste.thek4n.ru/internal/domain/config"
+	"github.com/thek4n/paste.thek4n.ru/internal/domain/domainerrors"
+	"github.com/thek4n/paste.thek4n.ru/internal/domain/objectvalue"
+)
+
+// MemoryQuotaRepository in-memory implementation of domain interface of quota repository.
+type MemoryQuotaRepository struct {
+	store  *memoryStore[objectvalue.QuotaSourceIP, uint32]
+	config config.QuotaConfig
+}
+
+// NewMemoryQuotaRepository constructor.
+func NewMemoryQuotaRepository(cfg config.QuotaConfig) *MemoryQuotaRepository {
+	return &MemoryQuotaRepository{
+		store:  newMemoryStore[objectvalue.QuotaSourceIP, uint32](),
+		config: cfg,
+	}
+}
+
+// GetByID get Quota aggregate from memory.
+func (r *MemoryQuotaRepository) GetByID(_ context.Context, id objectvalue.QuotaSourceIP) (aggregate.Quota, error) {
+	value, exists := r.store.get(id)
+	if !exists {
+		return aggregate.Quota{}, domainerrors.ErrQuotaNotFound
+	}
+
+	return aggregate.NewQuota(id, value), nil
+}
+
+// SetByID write quota to memory.
+func (r *MemoryQuotaRepository) SetByID(_ context.Context, id objectvalue.QuotaSourceIP, q aggregate.Quota) error {
+	r.store.set(id, q.Value(), time.Now().Add(r.config.QuotaResetPeriod()))
+	return nil
+}
+
+// RunSweeper periodically removes expired quotas until ctx done.
+func (r *MemoryQuotaRepository) RunSweeper(ctx context.Context, period time.Duration) {
+	r.store.runSweeper(ctx, period)
+}
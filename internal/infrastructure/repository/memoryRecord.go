@@ -0,0 +1,357 @@
+package repository
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync/atomic"
+	"time"
+
+	"github.com/thek4n/paste.thek4n.ru/internal/domain/aggregate"
+	"github.com/thek4n/paste.thek4n.ru/internal/domain/config"
+	"github.com/thek4n/paste.thek4n.ru/internal/domain/domainerrors"
+	"github.com/thek4n/paste.thek4n.ru/internal/domain/objectvalue"
+)
+
+type memoryKeyRecord struct {
+	body       []byte
+	encoding   objectvalue.BodyEncoding
+	bodySize   int64
+	slidingTTL time.Duration
+	clicks     uint32
+	countdown  uint8
+	eternal    bool
+	url        bool
+	metadata   objectvalue.RecordMetadata
+	// ownerTokenHash see aggregate.Record.OwnerTokenHash.
+	ownerTokenHash string
+	passwordHash   string
+	encrypted      bool
+	reveal         bool
+	files          objectvalue.BundleFiles
+	redirect       objectvalue.Redirect
+	// clickStats encoded by encodeClickStats.
+	clickStats string
+	// reserved is placeholder of key reserved by ReserveKey.
+	reserved bool
+}
+
+// MemoryRecordRepository in-memory implementation of domain interface.
+type MemoryRecordRepository struct {
+	store         *memoryStore[objectvalue.RecordKey, memoryKeyRecord]
+	config        config.CachingConfig
+	sequence      atomic.Uint64
+	keyGenerators map[objectvalue.KeyStyle]KeyGenerator
+}
+
+// NewMemoryRecordRepository constructor.
+func NewMemoryRecordRepository(cfg config.CachingConfig) *MemoryRecordRepository {
+	r := &MemoryRecordRepository{
+		store:  newMemoryStore[objectvalue.RecordKey, memoryKeyRecord](),
+		config: cfg,
+	}
+	r.keyGenerators = newKeyGenerators(cfg, r.nextSequence)
+
+	return r
+}
+
+// GetByKey fetch Record from memory.
+func (r *MemoryRecordRepository) GetByKey(
+	_ context.Context,
+	key objectvalue.RecordKey,
+	acceptedEncodings ...objectvalue.BodyEncoding,
+) (aggregate.Record, error) {
+	entry, found := r.store.lookup(key)
+	if !found || entry.value.reserved {
+		return aggregate.Record{}, domainerrors.ErrRecordNotFound
+	}
+
+	return r.toRecord(key, entry.value, entry.expiresAt, acceptedEncodings)
+}
+
+// InfoByKey returns record without consuming it and without body.
+func (r *MemoryRecordRepository) InfoByKey(_ context.Context, key objectvalue.RecordKey) (aggregate.Record, error) {
+	entry, found := r.store.lookup(key)
+	if !found || entry.value.reserved {
+		return aggregate.Record{}, domainerrors.ErrRecordNotFound
+	}
+
+	rec := entry.value
+	rec.body = nil
+	rec.encoding = objectvalue.BodyEncodingIdentity
+
+	return r.toRecord(key, rec, entry.expiresAt, nil)
+}
+
+// EncodeBody reads body up to limit compressing it like SetByKey does.
+func (r *MemoryRecordRepository) EncodeBody(body io.Reader, limit int64) (objectvalue.EncodedBody, error) {
+	return readEncodedBody(r.config, body, limit)
+}
+
+// SetByKey writes Record to memory.
+func (r *MemoryRecordRepository) SetByKey(_ context.Context, key objectvalue.RecordKey, record aggregate.Record) error {
+	expirationDate := record.ExpirationDate()
+	rec := memoryKeyRecord{
+		url:        record.URL(),
+		bodySize:   record.BodySize(),
+		clicks:     record.Clicks(),
+		countdown:  record.DisposableCounter(),
+		eternal:    record.DisposableCounterEternal(),
+		slidingTTL: expirationDate.SlidingTTL(),
+		metadata:   record.Metadata(),
+
+		ownerTokenHash: record.OwnerTokenHash(),
+		passwordHash:   record.PasswordHash(),
+		encrypted:      record.Encrypted(),
+		reveal:         record.Reveal(),
+		files:          record.Files(),
+		redirect:       record.Redirect(),
+	}
+
+	body, encoding, err := encodeBody(r.config, record.RGetBody(), record.BodyEncoding())
+	if err != nil {
+		return err
+	}
+
+	if encoding == objectvalue.BodyEncodingIdentity {
+		body = bytes.Clone(body)
+	}
+	rec.body = body
+	rec.encoding = encoding
+
+	r.store.set(key, rec, expirationDate.Date())
+
+	return nil
+}
+
+// ConsumeByKey atomically decreases disposable counter, increases clicks
+// if countClick and removes record if its counter exhausted.
+func (r *MemoryRecordRepository) ConsumeByKey(
+	_ context.Context,
+	key objectvalue.RecordKey,
+	countClick bool,
+	acceptedEncodings ...objectvalue.BodyEncoding,
+) (aggregate.Record, error) {
+	var record aggregate.Record
+	var consumed memoryKeyRecord
+	var err error
+
+	found := r.store.update(key, func(v memoryKeyRecord, e time.Time) (memoryKeyRecord, time.Time, bool) {
+		if v.reserved {
+			err = domainerrors.ErrRecordNotFound
+			return v, e, true
+		}
+
+		record = aggregate.NewRecord(
+			string(key),
+			objectvalue.NewExpirationDate(e, v.slidingTTL),
+			v.countdown,
+			v.eternal,
+			v.clicks,
+			v.body,
+			v.url,
+		)
+
+		if _, err = record.GetBody(); err != nil {
+			return v, e, true
+		}
+
+		v.countdown = record.DisposableCounter()
+		if countClick {
+			v.clicks = record.Clicks()
+		}
+		consumed = v
+
+		return v, record.ExpirationDate().Date(), !record.CounterExhausted()
+	})
+	if !found {
+		return aggregate.Record{}, domainerrors.ErrRecordNotFound
+	}
+	if errors.Is(err, domainerrors.ErrRecordNotFound) {
+		return aggregate.Record{}, err
+	}
+	if err != nil {
+		return aggregate.Record{}, fmt.Errorf("fail to consume record by key '%s': %w", key, err)
+	}
+
+	return r.toRecord(key, consumed, record.ExpirationDate().Date(), acceptedEncodings)
+}
+
+// AccessByKey returns password hash, reveal flag, bundle files, path
+// forwarding and reads limit of record. Reserved key is not found.
+func (r *MemoryRecordRepository) AccessByKey(_ context.Context, key objectvalue.RecordKey) (objectvalue.RecordAccess, error) {
+	entry, found := r.store.lookup(key)
+	if !found || entry.value.reserved {
+		return objectvalue.RecordAccess{}, domainerrors.ErrRecordNotFound
+	}
+
+	rec := entry.value
+	return objectvalue.NewRecordAccess(rec.passwordHash, rec.reveal, rec.files, rec.url && rec.redirect.ForwardPath(), !rec.eternal), nil
+}
+
+// Exists returns is record with this key exists.
+func (r *MemoryRecordRepository) Exists(_ context.Context, key objectvalue.RecordKey) (bool, error) {
+	_, exists := r.store.get(key)
+	return exists, nil
+}
+
+// UpdateByKey rewrites body, bundle files, password hash and settings of
+// record keeping its clicks. Reserved key is not updated.
+func (r *MemoryRecordRepository) UpdateByKey(_ context.Context, key objectvalue.RecordKey, record aggregate.Record) error {
+	expirationDate := record.ExpirationDate()
+
+	body, encoding, err := encodeBody(r.config, record.RGetBody(), record.BodyEncoding())
+	if err != nil {
+		return err
+	}
+
+	if encoding == objectvalue.BodyEncodingIdentity {
+		body = bytes.Clone(body)
+	}
+
+	reserved := false
+	found := r.store.update(key, func(v memoryKeyRecord, e time.Time) (memoryKeyRecord, time.Time, bool) {
+		reserved = v.reserved
+		if reserved {
+			return v, e, true
+		}
+
+		v.body = body
+		v.encoding = encoding
+		v.bodySize = record.BodySize()
+		v.slidingTTL = expirationDate.SlidingTTL()
+		v.countdown = record.DisposableCounter()
+		v.eternal = record.DisposableCounterEternal()
+		v.url = record.URL()
+		v.passwordHash = record.PasswordHash()
+		v.encrypted = record.Encrypted()
+		v.files = record.Files()
+
+		return v, expirationDate.Date(), true
+	})
+	if !found || reserved {
+		return domainerrors.ErrRecordNotFound
+	}
+
+	return nil
+}
+
+// DeleteByKey removes record. Reserved key is not removed.
+func (r *MemoryRecordRepository) DeleteByKey(_ context.Context, key objectvalue.RecordKey) error {
+	reserved := false
+	found := r.store.update(key, func(v memoryKeyRecord, e time.Time) (memoryKeyRecord, time.Time, bool) {
+		reserved = v.reserved
+		return v, e, reserved
+	})
+	if !found || reserved {
+		return domainerrors.ErrRecordNotFound
+	}
+
+	return nil
+}
+
+// GetClickStats returns click stats of record. Reserved key is not found.
+func (r *MemoryRecordRepository) GetClickStats(_ context.Context, key objectvalue.RecordKey) (aggregate.ClickStats, error) {
+	entry, found := r.store.lookup(key)
+	if !found || entry.value.reserved {
+		return aggregate.ClickStats{}, domainerrors.ErrRecordNotFound
+	}
+
+	return decodeClickStats(entry.value.clickStats)
+}
+
+// UpdateClickStats atomically applies update to click stats of record.
+// Reserved key is not updated.
+func (r *MemoryRecordRepository) UpdateClickStats(
+	_ context.Context,
+	key objectvalue.RecordKey,
+	update func(*aggregate.ClickStats),
+) error {
+	var err error
+	found := r.store.update(key, func(v memoryKeyRecord, e time.Time) (memoryKeyRecord, time.Time, bool) {
+		if v.reserved {
+			err = domainerrors.ErrRecordNotFound
+			return v, e, true
+		}
+
+		var updated string
+		updated, err = updateEncodedClickStats(v.clickStats, update)
+		if err == nil {
+			v.clickStats = updated
+		}
+
+		return v, e, true
+	})
+	if !found {
+		return domainerrors.ErrRecordNotFound
+	}
+	if errors.Is(err, domainerrors.ErrRecordNotFound) {
+		return err
+	}
+	if err != nil {
+		return fmt.Errorf("fail to update click stats by key '%s': %w", key, err)
+	}
+
+	return nil
+}
+
+// ReserveKey atomically reserves key if it does not exist.
+func (r *MemoryRecordRepository) ReserveKey(_ context.Context, key objectvalue.RecordKey) (bool, error) {
+	reserved := r.store.setIfAbsent(key, memoryKeyRecord{reserved: true}, time.Now().Add(r.config.KeyReservationTTL()))
+	return reserved, nil
+}
+
+// GenerateUniqueKey Generates and reserves unique key.
+// See RedisRecordRepository.GenerateUniqueKey.
+func (r *MemoryRecordRepository) GenerateUniqueKey(
+	ctx context.Context,
+	style objectvalue.KeyStyle,
+	minLength, maxLength uint8,
+) (objectvalue.RecordKey, error) {
+	return generateUniqueKey(ctx, r.config, r.keyGenerators, style, minLength, maxLength, r.ReserveKey)
+}
+
+// nextSequence increments sequence of sequential keys.
+func (r *MemoryRecordRepository) nextSequence(context.Context) (uint64, error) {
+	return r.sequence.Add(1), nil
+}
+
+// RunSweeper periodically removes expired records until ctx done.
+func (r *MemoryRecordRepository) RunSweeper(ctx context.Context, period time.Duration) {
+	r.store.runSweeper(ctx, period)
+}
+
+func (r *MemoryRecordRepository) toRecord(
+	key objectvalue.RecordKey,
+	rec memoryKeyRecord,
+	expiresAt time.Time,
+	acceptedEncodings []objectvalue.BodyEncoding,
+) (aggregate.Record, error) {
+	body, encoding, err := decodeBody(r.config, rec.body, rec.encoding, acceptedEncodings)
+	if err != nil {
+		return aggregate.Record{}, err
+	}
+
+	record := aggregate.NewRecord(
+		string(key),
+		objectvalue.NewExpirationDate(expiresAt, rec.slidingTTL),
+		rec.countdown,
+		rec.eternal,
+		rec.clicks,
+		nil,
+		rec.url,
+	)
+	record.SetEncodedBody(body, encoding)
+	record.SetBodySize(rec.bodySize)
+	record.SetMetadata(rec.metadata)
+	record.SetOwnerTokenHash(rec.ownerTokenHash)
+	record.SetPasswordHash(rec.passwordHash)
+	record.SetEncrypted(rec.encrypted)
+	record.SetReveal(rec.reveal)
+	record.SetFiles(rec.files)
+	record.SetRedirect(rec.redirect)
+
+	return record, nil
+}
@@ -0,0 +1,513 @@
+//go:build unit
+
+package repository
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/thek4n/paste.thek4n.ru/internal/domain/aggregate"
+	"github.com/thek4n/paste.thek4n.ru/internal/domain/config"
+	"github.com/thek4n/paste.thek4n.ru/internal/domain/domainerrors"
+	"github.com/thek4n/paste.thek4n.ru/internal/domain/objectvalue"
+	"github.com/thek4n/paste.thek4n.ru/pkg/bodycodec"
+)
+
+func TestMemoryRecordRepository(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("set and get returns same record", func(t *testing.T) {
+		t.Parallel()
+
+		repo := NewMemoryRecordRepository(config.DefaultCachingConfig{})
+		expirationDate := objectvalue.NewExpirationDateFromTTL(time.Hour)
+		record := aggregate.NewRecord("key", expirationDate, 3, false, 2, []byte("body"), true)
+
+		require.NoError(t, repo.SetByKey(ctx, "key", record))
+
+		got, err := repo.GetByKey(ctx, "key")
+		require.NoError(t, err)
+		assert.Equal(t, []byte("body"), got.RGetBody())
+		assert.Equal(t, uint8(3), got.DisposableCounter())
+		assert.Equal(t, uint32(2), got.Clicks())
+		assert.True(t, got.URL())
+		assert.Equal(t, expirationDate.Date(), got.ExpirationDate().Date())
+	})
+
+	t.Run("record metadata is stored", func(t *testing.T) {
+		t.Parallel()
+
+		repo := NewMemoryRecordRepository(config.DefaultCachingConfig{})
+		createdAt := time.UnixMilli(time.Now().UnixMilli())
+		record := aggregate.NewRecord("meta", objectvalue.NewExpirationDateFromTTL(time.Hour), 0, true, 0, []byte("{}"), false)
+		record.SetMetadata(objectvalue.NewRecordMetadata(createdAt, "application/json", "data.json", "hash", "apikey-id"))
+
+		require.NoError(t, repo.SetByKey(ctx, "meta", record))
+
+		got, err := repo.ConsumeByKey(ctx, "meta", true)
+		require.NoError(t, err)
+		assert.Equal(t, record.Metadata(), got.Metadata())
+	})
+
+	t.Run("info is got without body and with stored body size", func(t *testing.T) {
+		t.Parallel()
+
+		repo := NewMemoryRecordRepository(config.DefaultCachingConfig{})
+
+		record := aggregate.NewRecord("info", objectvalue.NewExpirationDateFromTTL(time.Hour), 2, false, 0, bytes.Repeat([]byte("a"), 4096), false)
+		record.SetBodySize(4096)
+		record.SetMetadata(objectvalue.NewRecordMetadata(time.Now(), "text/plain", "", "", ""))
+
+		require.NoError(t, repo.SetByKey(ctx, "info", record))
+
+		got, err := repo.InfoByKey(ctx, "info")
+		require.NoError(t, err)
+		assert.Empty(t, got.RGetBody())
+		assert.Equal(t, int64(4096), got.BodySize())
+		assert.Equal(t, uint8(2), got.DisposableCounter())
+		assert.Equal(t, "text/plain", got.Metadata().ContentType())
+
+		updated := aggregate.NewRecord("info", objectvalue.NewExpirationDateFromTTL(time.Hour), 2, false, 0, []byte("new body"), false)
+		updated.SetBodySize(8)
+		require.NoError(t, repo.UpdateByKey(ctx, "info", updated))
+
+		got, err = repo.GetByKey(ctx, "info")
+		require.NoError(t, err)
+		assert.Equal(t, int64(8), got.BodySize())
+
+		_, err = repo.InfoByKey(ctx, "missing")
+		assert.ErrorIs(t, err, domainerrors.ErrRecordNotFound)
+	})
+
+	t.Run("encrypted flag is stored and rewritten on update", func(t *testing.T) {
+		t.Parallel()
+
+		repo := NewMemoryRecordRepository(config.DefaultCachingConfig{})
+		record := aggregate.NewRecord("encrypted", objectvalue.NewExpirationDateFromTTL(time.Hour), 0, true, 0, []byte("ciphertext"), false)
+		record.SetEncrypted(true)
+
+		require.NoError(t, repo.SetByKey(ctx, "encrypted", record))
+
+		got, err := repo.GetByKey(ctx, "encrypted")
+		require.NoError(t, err)
+		assert.True(t, got.Encrypted())
+
+		updated := aggregate.NewRecord("encrypted", objectvalue.NewExpirationDateFromTTL(time.Hour), 0, true, 0, []byte("plain"), false)
+		require.NoError(t, repo.UpdateByKey(ctx, "encrypted", updated))
+
+		got, err = repo.ConsumeByKey(ctx, "encrypted", true)
+		require.NoError(t, err)
+		assert.False(t, got.Encrypted())
+		assert.Equal(t, []byte("plain"), got.RGetBody())
+	})
+
+	t.Run("bundle files are stored and given by access", func(t *testing.T) {
+		t.Parallel()
+
+		repo := NewMemoryRecordRepository(config.DefaultCachingConfig{})
+		files := objectvalue.BundleFiles{
+			objectvalue.NewBundleFile("config.yaml", "application/yaml", 4),
+			objectvalue.NewBundleFile("logs/app.log", "", 3),
+		}
+		record := aggregate.NewRecord("bundle", objectvalue.NewExpirationDateFromTTL(time.Hour), 0, true, 0, []byte("confapp"), false)
+		record.SetFiles(files)
+
+		require.NoError(t, repo.SetByKey(ctx, "bundle", record))
+
+		access, err := repo.AccessByKey(ctx, "bundle")
+		require.NoError(t, err)
+		assert.Equal(t, files, access.Files())
+
+		got, err := repo.ConsumeByKey(ctx, "bundle", true)
+		require.NoError(t, err)
+		assert.Equal(t, files, got.Files())
+
+		file, content, ok := got.BundleFile("logs/app.log")
+		require.True(t, ok)
+		assert.Equal(t, "logs/app.log", file.Name())
+		assert.Equal(t, []byte("app"), content)
+
+		plain := aggregate.NewRecord("bundle", objectvalue.NewExpirationDateFromTTL(time.Hour), 0, true, 0, []byte("plain"), false)
+		require.NoError(t, repo.UpdateByKey(ctx, "bundle", plain))
+
+		access, err = repo.AccessByKey(ctx, "bundle")
+		require.NoError(t, err)
+		assert.Empty(t, access.Files())
+	})
+
+	t.Run("redirect is stored and path forwarding given by access", func(t *testing.T) {
+		t.Parallel()
+
+		repo := NewMemoryRecordRepository(config.DefaultCachingConfig{})
+		redirect := objectvalue.NewRedirect(objectvalue.RedirectMovedPermanently, true, true)
+		record := aggregate.NewRecord("short", objectvalue.NewExpirationDateFromTTL(time.Hour), 0, true, 0, []byte("https://example.com/"), true)
+		record.SetRedirect(redirect)
+
+		require.NoError(t, repo.SetByKey(ctx, "short", record))
+
+		access, err := repo.AccessByKey(ctx, "short")
+		require.NoError(t, err)
+		assert.True(t, access.ForwardPath())
+
+		got, err := repo.GetByKey(ctx, "short")
+		require.NoError(t, err)
+		assert.Equal(t, redirect, got.Redirect())
+
+		plain := aggregate.NewRecord("short", objectvalue.NewExpirationDateFromTTL(time.Hour), 0, true, 0, []byte("plain"), false)
+		require.NoError(t, repo.UpdateByKey(ctx, "short", plain))
+
+		access, err = repo.AccessByKey(ctx, "short")
+		require.NoError(t, err)
+		assert.False(t, access.ForwardPath(), "record which is not url should not forward path")
+	})
+
+	t.Run("reads limit is given by access", func(t *testing.T) {
+		t.Parallel()
+
+		repo := NewMemoryRecordRepository(config.DefaultCachingConfig{})
+		disposable := aggregate.NewRecord("disposable", objectvalue.NewExpirationDateFromTTL(time.Hour), 2, false, 0, []byte("body"), false)
+		eternal := aggregate.NewRecord("eternal", objectvalue.NewExpirationDateFromTTL(time.Hour), 0, true, 0, []byte("body"), false)
+		require.NoError(t, repo.SetByKey(ctx, "disposable", disposable))
+		require.NoError(t, repo.SetByKey(ctx, "eternal", eternal))
+
+		access, err := repo.AccessByKey(ctx, "disposable")
+		require.NoError(t, err)
+		assert.True(t, access.ReadsLimited())
+
+		access, err = repo.AccessByKey(ctx, "eternal")
+		require.NoError(t, err)
+		assert.False(t, access.ReadsLimited())
+	})
+
+	t.Run("click stats are updated concurrently and kept on update", func(t *testing.T) {
+		t.Parallel()
+
+		repo := NewMemoryRecordRepository(config.DefaultCachingConfig{})
+		record := aggregate.NewRecord("stats", objectvalue.NewExpirationDateFromTTL(time.Hour), 0, true, 0, []byte("https://example.com/"), true)
+		require.NoError(t, repo.SetByKey(ctx, "stats", record))
+
+		stats, err := repo.GetClickStats(ctx, "stats")
+		require.NoError(t, err)
+		assert.Equal(t, uint32(0), stats.Clicks())
+
+		var wg sync.WaitGroup
+		for i := range 8 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				click := objectvalue.NewClick(time.Now(), "https://example.com/", "curl/8.0", "", strconv.Itoa(i))
+				assert.NoError(t, repo.UpdateClickStats(ctx, "stats", func(stats *aggregate.ClickStats) {
+					stats.AddClick(click, config.DefaultClickStatsConfig{})
+				}))
+			}()
+		}
+		wg.Wait()
+
+		require.NoError(t, repo.UpdateByKey(ctx, "stats", record))
+
+		stats, err = repo.GetClickStats(ctx, "stats")
+		require.NoError(t, err)
+		assert.Equal(t, uint32(8), stats.Clicks())
+		assert.Equal(t, map[string]uint32{"example.com": 8}, stats.Referrers())
+		assert.Equal(t, uint64(8), stats.UniqueVisitors())
+
+		got, err := repo.GetByKey(ctx, "stats")
+		require.NoError(t, err)
+		assert.Equal(t, []byte("https://example.com/"), got.RGetBody())
+
+		reserved, err := repo.ReserveKey(ctx, "reserved-stats")
+		require.NoError(t, err)
+		require.True(t, reserved)
+		_, err = repo.GetClickStats(ctx, "reserved-stats")
+		assert.ErrorIs(t, err, domainerrors.ErrRecordNotFound)
+		err = repo.UpdateClickStats(ctx, "missing", func(*aggregate.ClickStats) {})
+		assert.ErrorIs(t, err, domainerrors.ErrRecordNotFound)
+	})
+
+	t.Run("update rewrites record keeping clicks and metadata", func(t *testing.T) {
+		t.Parallel()
+
+		repo := NewMemoryRecordRepository(config.DefaultCachingConfig{})
+		record := aggregate.NewRecord("updated", objectvalue.NewExpirationDateFromTTL(time.Hour), 3, false, 0, []byte("body"), false)
+		record.SetMetadata(objectvalue.NewRecordMetadata(time.UnixMilli(time.Now().UnixMilli()), "text/plain", "", "hash", ""))
+		require.NoError(t, repo.SetByKey(ctx, "updated", record))
+
+		_, err := repo.ConsumeByKey(ctx, "updated", true)
+		require.NoError(t, err)
+
+		update := aggregate.NewRecord("updated", objectvalue.NewExpirationDateFromTTL(0), 0, true, 0, []byte("https://example.com/"), true)
+		require.NoError(t, repo.UpdateByKey(ctx, "updated", update))
+
+		got, err := repo.GetByKey(ctx, "updated")
+		require.NoError(t, err)
+		assert.Equal(t, []byte("https://example.com/"), got.RGetBody())
+		assert.True(t, got.URL())
+		assert.True(t, got.DisposableCounterEternal())
+		assert.True(t, got.ExpirationDateEternal())
+		assert.Equal(t, uint32(1), got.Clicks())
+		assert.Equal(t, record.Metadata(), got.Metadata())
+
+		assert.ErrorIs(t, repo.UpdateByKey(ctx, "missing", update), domainerrors.ErrRecordNotFound)
+
+		reserved, err := repo.ReserveKey(ctx, "reserved-update")
+		require.NoError(t, err)
+		require.True(t, reserved)
+		assert.ErrorIs(t, repo.UpdateByKey(ctx, "reserved-update", update), domainerrors.ErrRecordNotFound)
+	})
+
+	t.Run("delete removes record but not reservation", func(t *testing.T) {
+		t.Parallel()
+
+		repo := NewMemoryRecordRepository(config.DefaultCachingConfig{})
+		record := aggregate.NewRecord("deleted", objectvalue.NewExpirationDateFromTTL(time.Hour), 0, true, 0, []byte("body"), false)
+		require.NoError(t, repo.SetByKey(ctx, "deleted", record))
+
+		require.NoError(t, repo.DeleteByKey(ctx, "deleted"))
+		assert.ErrorIs(t, repo.DeleteByKey(ctx, "deleted"), domainerrors.ErrRecordNotFound)
+
+		reserved, err := repo.ReserveKey(ctx, "reserved")
+		require.NoError(t, err)
+		require.True(t, reserved)
+		assert.ErrorIs(t, repo.DeleteByKey(ctx, "reserved"), domainerrors.ErrRecordNotFound)
+
+		exists, err := repo.Exists(ctx, "reserved")
+		require.NoError(t, err)
+		assert.True(t, exists)
+	})
+
+	t.Run("large body is stored compressed and returned intact", func(t *testing.T) {
+		t.Parallel()
+
+		cfg := config.DefaultCachingConfig{}
+		repo := NewMemoryRecordRepository(cfg)
+		body := bytes.Repeat([]byte("a"), int(cfg.CompressThresholdBytes())*2)
+		record := aggregate.NewRecord("big", objectvalue.NewExpirationDateFromTTL(time.Hour), 0, true, 0, body, false)
+
+		require.NoError(t, repo.SetByKey(ctx, "big", record))
+
+		stored, ok := repo.store.get("big")
+		require.True(t, ok)
+		assert.Equal(t, objectvalue.BodyEncoding(bodycodec.Gzip), stored.encoding)
+
+		got, err := repo.GetByKey(ctx, "big")
+		require.NoError(t, err)
+		assert.Equal(t, body, got.RGetBody())
+	})
+
+	t.Run("body encoded while reading is stored as is", func(t *testing.T) {
+		t.Parallel()
+
+		cfg := zstdCachingConfig{}
+		repo := NewMemoryRecordRepository(cfg)
+		body := bytes.Repeat([]byte("a"), int(cfg.CompressThresholdBytes())*2)
+
+		small, err := repo.EncodeBody(bytes.NewReader([]byte("body")), int64(len(body)))
+		require.NoError(t, err)
+		assert.Equal(t, objectvalue.BodyEncodingIdentity, small.Encoding())
+		assert.Equal(t, []byte("body"), small.Data())
+		assert.Equal(t, int64(4), small.Size())
+
+		encoded, err := repo.EncodeBody(bytes.NewReader(body), int64(len(body)))
+		require.NoError(t, err)
+		assert.Equal(t, objectvalue.BodyEncoding(bodycodec.Zstd), encoded.Encoding())
+		assert.Less(t, len(encoded.Data()), len(body))
+		assert.Equal(t, int64(len(body)), encoded.Size())
+
+		record := aggregate.NewRecord("streamed", objectvalue.NewExpirationDateFromTTL(time.Hour), 0, true, 0, nil, false)
+		record.SetEncodedBody(encoded.Data(), encoded.Encoding())
+		require.NoError(t, repo.SetByKey(ctx, "streamed", record))
+
+		stored, ok := repo.store.get("streamed")
+		require.True(t, ok)
+		assert.Equal(t, encoded.Data(), stored.body, "encoded body is not compressed twice")
+
+		got, err := repo.GetByKey(ctx, "streamed")
+		require.NoError(t, err)
+		assert.Equal(t, body, got.RGetBody())
+
+		_, err = repo.EncodeBody(bytes.NewReader(body), int64(len(body))-1)
+		require.ErrorIs(t, err, domainerrors.ErrBodyTooLarge)
+
+		_, err = repo.EncodeBody(bytes.NewReader([]byte("body")), 3)
+		require.ErrorIs(t, err, domainerrors.ErrBodyTooLarge)
+	})
+
+	t.Run("consume returns encoded body if its encoding accepted", func(t *testing.T) {
+		t.Parallel()
+
+		cfg := zstdCachingConfig{}
+		repo := NewMemoryRecordRepository(cfg)
+		body := bytes.Repeat([]byte("a"), int(cfg.CompressThresholdBytes())*2)
+		record := aggregate.NewRecord("encoded", objectvalue.NewExpirationDateFromTTL(time.Hour), 0, true, 0, body, false)
+
+		require.NoError(t, repo.SetByKey(ctx, "encoded", record))
+
+		got, err := repo.ConsumeByKey(ctx, "encoded", true, bodycodec.Gzip, bodycodec.Zstd)
+		require.NoError(t, err)
+		assert.Equal(t, objectvalue.BodyEncoding(bodycodec.Zstd), got.BodyEncoding())
+
+		codec, err := bodycodec.Get(bodycodec.Zstd)
+		require.NoError(t, err)
+		decoded, err := bodycodec.Decode(codec, got.RGetBody(), cfg.MaxBodySize())
+		require.NoError(t, err)
+		assert.Equal(t, body, decoded)
+
+		got, err = repo.ConsumeByKey(ctx, "encoded", true, bodycodec.Gzip)
+		require.NoError(t, err)
+		assert.Equal(t, objectvalue.BodyEncodingIdentity, got.BodyEncoding())
+		assert.Equal(t, body, got.RGetBody())
+	})
+
+	t.Run("expired record is not found", func(t *testing.T) {
+		t.Parallel()
+
+		repo := NewMemoryRecordRepository(config.DefaultCachingConfig{})
+		record := aggregate.NewRecord("expired", objectvalue.NewExpirationDateFromTTL(-time.Second), 0, true, 0, []byte("body"), false)
+
+		require.NoError(t, repo.SetByKey(ctx, "expired", record))
+
+		_, err := repo.GetByKey(ctx, "expired")
+		assert.True(t, errors.Is(err, domainerrors.ErrRecordNotFound))
+
+		exists, err := repo.Exists(ctx, "expired")
+		require.NoError(t, err)
+		assert.False(t, exists)
+	})
+
+	t.Run("concurrent consume serves disposable record exactly disposable times", func(t *testing.T) {
+		t.Parallel()
+
+		const disposable = 5
+		const readers = 100
+
+		repo := NewMemoryRecordRepository(config.DefaultCachingConfig{})
+		record := aggregate.NewRecord("disposable", objectvalue.NewExpirationDateFromTTL(time.Hour), disposable, false, 0, []byte("body"), false)
+		require.NoError(t, repo.SetByKey(ctx, "disposable", record))
+
+		var served atomic.Int32
+		var wg sync.WaitGroup
+		for range readers {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if _, err := repo.ConsumeByKey(ctx, "disposable", true); err == nil {
+					served.Add(1)
+				}
+			}()
+		}
+		wg.Wait()
+
+		assert.Equal(t, int32(disposable), served.Load())
+
+		exists, err := repo.Exists(ctx, "disposable")
+		require.NoError(t, err)
+		assert.False(t, exists)
+	})
+
+	t.Run("reserved key exists, is not readable and can not be reserved twice", func(t *testing.T) {
+		t.Parallel()
+
+		repo := NewMemoryRecordRepository(config.DefaultCachingConfig{})
+
+		reserved, err := repo.ReserveKey(ctx, "reserved")
+		require.NoError(t, err)
+		assert.True(t, reserved)
+
+		reserved, err = repo.ReserveKey(ctx, "reserved")
+		require.NoError(t, err)
+		assert.False(t, reserved)
+
+		exists, err := repo.Exists(ctx, "reserved")
+		require.NoError(t, err)
+		assert.True(t, exists)
+
+		_, err = repo.GetByKey(ctx, "reserved")
+		assert.ErrorIs(t, err, domainerrors.ErrRecordNotFound)
+
+		_, err = repo.ConsumeByKey(ctx, "reserved", true)
+		assert.ErrorIs(t, err, domainerrors.ErrRecordNotFound)
+
+		record := aggregate.NewRecord("reserved", objectvalue.NewExpirationDateFromTTL(time.Hour), 0, true, 0, []byte("body"), false)
+		require.NoError(t, repo.SetByKey(ctx, "reserved", record))
+
+		got, err := repo.GetByKey(ctx, "reserved")
+		require.NoError(t, err)
+		assert.Equal(t, []byte("body"), got.RGetBody())
+	})
+
+	t.Run("generate unique key returns key of requested length", func(t *testing.T) {
+		t.Parallel()
+
+		repo := NewMemoryRecordRepository(config.DefaultCachingConfig{})
+
+		key, err := repo.GenerateUniqueKey(ctx, objectvalue.KeyStyleRandom, 8, 20)
+		require.NoError(t, err)
+		assert.Len(t, string(key), 8)
+	})
+}
+
+type zstdCachingConfig struct {
+	config.DefaultCachingConfig
+}
+
+func (c zstdCachingConfig) BodyCodec() string {
+	return bodycodec.Zstd
+}
+
+func TestMemoryQuotaRepository(t *testing.T) {
+	t.Run("quota not found before set", func(t *testing.T) {
+		t.Parallel()
+
+		repo := NewMemoryQuotaRepository(config.DefaultQuotaConfig{})
+
+		_, err := repo.GetByID(context.Background(), "127.0.0.1")
+		assert.True(t, errors.Is(err, domainerrors.ErrQuotaNotFound))
+	})
+
+	t.Run("set quota can be got", func(t *testing.T) {
+		t.Parallel()
+
+		repo := NewMemoryQuotaRepository(config.DefaultQuotaConfig{})
+		require.NoError(t, repo.SetByID(context.Background(), "127.0.0.1", aggregate.NewQuota("127.0.0.1", 7)))
+
+		quota, err := repo.GetByID(context.Background(), "127.0.0.1")
+		require.NoError(t, err)
+		assert.Equal(t, uint32(7), quota.Value())
+	})
+}
+
+func TestMemoryAPIKeyRepository(t *testing.T) {
+	t.Run("set, get and remove apikey", func(t *testing.T) {
+		t.Parallel()
+
+		ctx := context.Background()
+		repo := NewMemoryAPIKeyRepository()
+		apikey := aggregate.NewAPIKey(objectvalue.NilAPIKeyID, "secret", true)
+
+		require.NoError(t, repo.SetByID(ctx, "secret", apikey))
+
+		got, err := repo.GetByID(ctx, "secret")
+		require.NoError(t, err)
+		assert.True(t, got.Valid())
+		assert.Equal(t, "secret", got.Key())
+
+		all, err := repo.GetAll(ctx)
+		require.NoError(t, err)
+		assert.Len(t, all, 1)
+
+		require.NoError(t, repo.RemoveByID(ctx, "secret"))
+
+		exists, err := repo.Exists(ctx, "secret")
+		require.NoError(t, err)
+		assert.False(t, exists)
+	})
+}
@@ -0,0 +1,149 @@
+package repository
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+type memoryEntry[V any] struct {
+	value     V
+	expiresAt time.Time
+}
+
+func (e memoryEntry[V]) expired(now time.Time) bool {
+	return !e.expiresAt.IsZero() && !now.Before(e.expiresAt)
+}
+
+// memoryStore thread safe in-memory key-value storage with entries expiration.
+// Zero expiresAt means entry never expires.
+type memoryStore[K comparable, V any] struct {
+	mu      sync.Mutex
+	entries map[K]memoryEntry[V]
+}
+
+func newMemoryStore[K comparable, V any]() *memoryStore[K, V] {
+	return &memoryStore[K, V]{
+		entries: make(map[K]memoryEntry[V]),
+	}
+}
+
+// get returns not expired value by key.
+func (s *memoryStore[K, V]) get(key K) (V, bool) {
+	entry, ok := s.lookup(key)
+	return entry.value, ok
+}
+
+// lookup returns not expired entry by key.
+func (s *memoryStore[K, V]) lookup(key K) (memoryEntry[V], bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.lookupLocked(key)
+}
+
+func (s *memoryStore[K, V]) lookupLocked(key K) (memoryEntry[V], bool) {
+	entry, ok := s.entries[key]
+	if !ok {
+		return memoryEntry[V]{}, false
+	}
+
+	if entry.expired(time.Now()) {
+		delete(s.entries, key)
+		return memoryEntry[V]{}, false
+	}
+
+	return entry, true
+}
+
+func (s *memoryStore[K, V]) set(key K, value V, expiresAt time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.entries[key] = memoryEntry[V]{value: value, expiresAt: expiresAt}
+}
+
+// setIfAbsent sets value only if there is no not expired entry by key.
+func (s *memoryStore[K, V]) setIfAbsent(key K, value V, expiresAt time.Time) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.lookupLocked(key); ok {
+		return false
+	}
+
+	s.entries[key] = memoryEntry[V]{value: value, expiresAt: expiresAt}
+	return true
+}
+
+func (s *memoryStore[K, V]) delete(key K) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.entries, key)
+}
+
+// update atomically applies fn to not expired value. fn returns new value,
+// its expiration date and false if entry must be removed.
+func (s *memoryStore[K, V]) update(key K, fn func(V, time.Time) (V, time.Time, bool)) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry, ok := s.lookupLocked(key)
+	if !ok {
+		return false
+	}
+
+	value, expiresAt, keep := fn(entry.value, entry.expiresAt)
+	if !keep {
+		delete(s.entries, key)
+		return true
+	}
+
+	s.entries[key] = memoryEntry[V]{value: value, expiresAt: expiresAt}
+	return true
+}
+
+// values returns all not expired values.
+func (s *memoryStore[K, V]) values() map[K]V {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	res := make(map[K]V, len(s.entries))
+	for key, entry := range s.entries {
+		if !entry.expired(now) {
+			res[key] = entry.value
+		}
+	}
+
+	return res
+}
+
+// sweep removes expired entries.
+func (s *memoryStore[K, V]) sweep() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	for key, entry := range s.entries {
+		if entry.expired(now) {
+			delete(s.entries, key)
+		}
+	}
+}
+
+// runSweeper periodically removes expired entries until ctx done.
+func (s *memoryStore[K, V]) runSweeper(ctx context.Context, period time.Duration) {
+	ticker := time.NewTicker(period)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.sweep()
+		}
+	}
+}
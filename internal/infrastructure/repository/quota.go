@@ -19,15 +19,17 @@ type redisQuotaRecord struct {
 
 // RedisQuotaRepository implementation of domain interface of quota repository.
 type RedisQuotaRepository struct {
-	client *redis.Client
-	config config.QuotaConfig
+	client    redis.UniversalClient
+	config    config.QuotaConfig
+	keyPrefix string
 }
 
-// NewRedisQuotaRepository constructor.
-func NewRedisQuotaRepository(c *redis.Client, cfg config.QuotaConfig) *RedisQuotaRepository {
+// NewRedisQuotaRepository constructor. Quota keys are prefixed with keyPrefix.
+func NewRedisQuotaRepository(c redis.UniversalClient, cfg config.QuotaConfig, keyPrefix string) *RedisQuotaRepository {
 	return &RedisQuotaRepository{
-		client: c,
-		config: cfg,
+		client:    c,
+		config:    cfg,
+		keyPrefix: keyPrefix,
 	}
 }
 
@@ -35,7 +37,7 @@ func NewRedisQuotaRepository(c *redis.Client, cfg config.QuotaConfig) *RedisQuot
 func (r *RedisQuotaRepository) GetByID(ctx context.Context, id objectvalue.QuotaSourceIP) (aggregate.Quota, error) {
 	var quota redisQuotaRecord
 
-	exists, err := r.exists(ctx, string(id))
+	exists, err := r.exists(ctx, r.key(id))
 	if err != nil {
 		return aggregate.Quota{}, err
 	}
@@ -44,7 +46,7 @@ func (r *RedisQuotaRepository) GetByID(ctx context.Context, id objectvalue.Quota
 		return aggregate.Quota{}, domainerrors.ErrQuotaNotFound
 	}
 
-	err = r.client.HGetAll(ctx, string(id)).Scan(&quota)
+	err = r.client.HGetAll(ctx, r.key(id)).Scan(&quota)
 	if err != nil {
 		return aggregate.Quota{}, fmt.Errorf("failure get quota for ip '%s': %w", id, err)
 	}
@@ -61,7 +63,7 @@ func (r *RedisQuotaRepository) SetByID(ctx context.Context, id objectvalue.Quota
 	   redis.call("EXPIRE", KEYS[1], ARGV[3])
 	   return 1
    `
-	err := r.client.Eval(ctx, script, []string{string(id)}, "value", q.Value(), int(r.config.QuotaResetPeriod().Seconds())).Err()
+	err := r.client.Eval(ctx, script, []string{r.key(id)}, "value", q.Value(), int(r.config.QuotaResetPeriod().Seconds())).Err()
 	if err != nil {
 		return fmt.Errorf("fail to set new quota key: %w", err)
 	}
@@ -77,3 +79,7 @@ func (r *RedisQuotaRepository) exists(ctx context.Context, key string) (bool, er
 
 	return keysNumber > 0, nil
 }
+
+func (r *RedisQuotaRepository) key(id objectvalue.QuotaSourceIP) string {
+	return r.keyPrefix + string(id)
+}
@@ -1,15 +1,10 @@
 package repository
 
 import (
-	"bytes"
-	"compress/gzip"
 	"context"
-	"crypto/rand"
 	"errors"
 	"fmt"
 	"io"
-	"math/big"
-	"sync"
 	"time"
 
 	"github.com/redis/go-redis/v9"
@@ -18,111 +13,307 @@ import (
 	"github.com/thek4n/paste.thek4n.ru/internal/domain/config"
 	"github.com/thek4n/paste.thek4n.ru/internal/domain/domainerrors"
 	"github.com/thek4n/paste.thek4n.ru/internal/domain/objectvalue"
+	"github.com/thek4n/paste.thek4n.ru/pkg/bodycodec"
 )
 
-var bufferPool = sync.Pool{
-	New: func() any {
-		return new(bytes.Buffer)
-	},
-}
+// migrateExpirationScript lua snippet converts relative "ttl" field written
+// by previous versions to absolute "expires_at" in unix milliseconds.
+// Remaining lifetime is taken from key expiration, so migration is lazy
+// and performed on reading.
+const migrateExpirationScript = `
+	if redis.call("HEXISTS", KEYS[1], "ttl") == 1 then
+		local pttl = redis.call("PTTL", KEYS[1])
+		if pttl > 0 then
+			local now = redis.call("TIME")
+			local nowMs = tonumber(now[1]) * 1000 + math.floor(tonumber(now[2]) / 1000)
+			redis.call("HSET", KEYS[1], "expires_at", nowMs + pttl)
+		end
+		redis.call("HDEL", KEYS[1], "ttl")
+	end
+`
+
+// recordFieldsScript lua snippet defines function returning flat HGETALL
+// reply of record without click stats, which are got separately.
+const recordFieldsScript = `
+	local function recordFields(withoutBody)
+		local fields = redis.call("HGETALL", KEYS[1])
+		for i = #fields - 1, 1, -2 do
+			if fields[i] == "click_stats" or (withoutBody and fields[i] == "body") then
+				table.remove(fields, i + 1)
+				table.remove(fields, i)
+			end
+		end
+		return fields
+	end
+`
 
 type redisKeyRecord struct {
-	Body      []byte        `redis:"body"`
-	TTL       time.Duration `redis:"ttl"`
-	Clicks    uint32        `redis:"clicks"`
-	Countdown uint8         `redis:"countdown"`
-	Eternal   bool          `redis:"eternal"`
-	URL       bool          `redis:"url"`
+	Body       []byte        `redis:"body"`
+	Encoding   string        `redis:"encoding"`
+	BodySize   int64         `redis:"body_size"`
+	ExpiresAt  int64         `redis:"expires_at"`
+	SlidingTTL time.Duration `redis:"sliding_ttl"`
+	Clicks     uint32        `redis:"clicks"`
+	Countdown  uint8         `redis:"countdown"`
+	Eternal    bool          `redis:"eternal"`
+	URL        bool          `redis:"url"`
+
+	CreatedAt    int64  `redis:"created_at"`
+	ContentType  string `redis:"content_type"`
+	Filename     string `redis:"filename"`
+	SourceIPHash string `redis:"source_ip_hash"`
+	APIKeyID     string `redis:"apikey_id"`
+
+	OwnerTokenHash string `redis:"owner_token_hash"`
+	PasswordHash   string `redis:"password_hash"`
+	Encrypted      bool   `redis:"encrypted"`
+	Reveal         bool   `redis:"reveal"`
+	// Files JSON encoded entries of bundle record.
+	Files string `redis:"files"`
+
+	RedirectStatus int  `redis:"redirect_status"`
+	ForwardQuery   bool `redis:"forward_query"`
+	ForwardPath    bool `redis:"forward_path"`
 }
 
 // RedisRecordRepository redis implementation of domain interface.
 type RedisRecordRepository struct {
-	client *redis.Client
-	config config.CachingConfig
+	client        redis.UniversalClient
+	config        config.CachingConfig
+	keyPrefix     string
+	keyGenerators map[objectvalue.KeyStyle]KeyGenerator
 }
 
-// NewRedisRecordRepository constructor.
-func NewRedisRecordRepository(c *redis.Client, cfg config.CachingConfig) *RedisRecordRepository {
-	return &RedisRecordRepository{
-		client: c,
-		config: cfg,
+// NewRedisRecordRepository constructor. Record keys are prefixed with
+// keyPrefix to share keyspace with other repositories.
+func NewRedisRecordRepository(c redis.UniversalClient, cfg config.CachingConfig, keyPrefix string) *RedisRecordRepository {
+	r := &RedisRecordRepository{
+		client:    c,
+		config:    cfg,
+		keyPrefix: keyPrefix,
 	}
+	r.keyGenerators = newKeyGenerators(cfg, r.nextSequence)
+
+	return r
 }
 
 // GetByKey fetch Record from redis db.
-func (r *RedisRecordRepository) GetByKey(ctx context.Context, key objectvalue.RecordKey) (aggregate.Record, error) {
-	exists, err := r.exists(ctx, key)
+func (r *RedisRecordRepository) GetByKey(
+	ctx context.Context,
+	key objectvalue.RecordKey,
+	acceptedEncodings ...objectvalue.BodyEncoding,
+) (aggregate.Record, error) {
+	record, err := r.getFields(ctx, key, false)
 	if err != nil {
-		return aggregate.Record{}, fmt.Errorf("fail to check record existing by key '%s': %w", key, err)
-	}
-	if !exists {
-		return aggregate.Record{}, domainerrors.ErrRecordNotFound
+		return aggregate.Record{}, err
 	}
 
-	var record redisKeyRecord
-	err = r.client.HGetAll(ctx, string(key)).Scan(&record)
+	return r.toRecord(key, record, acceptedEncodings)
+}
+
+// InfoByKey returns record without consuming it and without getting body.
+func (r *RedisRecordRepository) InfoByKey(ctx context.Context, key objectvalue.RecordKey) (aggregate.Record, error) {
+	record, err := r.getFields(ctx, key, true)
 	if err != nil {
-		return aggregate.Record{}, fmt.Errorf("fail to get record by key '%s': %w", key, err)
+		return aggregate.Record{}, err
 	}
 
-	if isCompressed(record.Body) {
-		decompressedBody, err := decompress(record.Body, r.config.MaxBodySize())
-		if err != nil {
-			return aggregate.Record{}, fmt.Errorf("fail to decompress compressed body: %w", err)
-		}
+	return r.toRecord(key, record, nil)
+}
 
-		record.Body = decompressedBody
+// getFields gets stored fields of record, body is omitted if withoutBody.
+func (r *RedisRecordRepository) getFields(ctx context.Context, key objectvalue.RecordKey, withoutBody bool) (redisKeyRecord, error) {
+	script := recordFieldsScript + `
+		if redis.call("EXISTS", KEYS[1]) == 0 or redis.call("HEXISTS", KEYS[1], "reserved") == 1 then
+			return false
+		end
+	` + migrateExpirationScript + `
+		return recordFields(ARGV[1] == "1")
+	`
+	res, err := r.client.Eval(ctx, script, []string{r.key(key)}, withoutBody).Slice()
+	if errors.Is(err, redis.Nil) {
+		return redisKeyRecord{}, domainerrors.ErrRecordNotFound
+	}
+	if err != nil {
+		return redisKeyRecord{}, fmt.Errorf("fail to get record by key '%s': %w", key, err)
 	}
 
-	res := aggregate.NewRecord(
-		string(key),
-		objectvalue.NewExpirationDateFromTTL(record.TTL),
-		record.Countdown,
-		record.Eternal,
-		record.Clicks,
-		record.Body,
-		record.URL,
-	)
+	record, err := scanRecord(res)
+	if err != nil {
+		return redisKeyRecord{}, fmt.Errorf("fail to scan record by key '%s': %w", key, err)
+	}
+
+	return record, nil
+}
 
-	return res, nil
+// EncodeBody reads body up to limit compressing it like SetByKey does.
+func (r *RedisRecordRepository) EncodeBody(body io.Reader, limit int64) (objectvalue.EncodedBody, error) {
+	return readEncodedBody(r.config, body, limit)
 }
 
 // SetByKey writes Record to redis db.
 func (r *RedisRecordRepository) SetByKey(ctx context.Context, key objectvalue.RecordKey, record aggregate.Record) error {
+	expirationDate := record.ExpirationDate()
+	metadata := record.Metadata()
 	rec := &redisKeyRecord{
-		URL:       record.URL(),
-		Clicks:    record.Clicks(),
-		Countdown: record.DisposableCounter(),
-		Eternal:   record.DisposableCounterEternal(),
-		TTL:       record.TTL(),
-		Body:      record.RGetBody(),
+		URL:          record.URL(),
+		Clicks:       record.Clicks(),
+		Countdown:    record.DisposableCounter(),
+		Eternal:      record.DisposableCounterEternal(),
+		SlidingTTL:   expirationDate.SlidingTTL(),
+		CreatedAt:    unixMilli(metadata.CreatedAt()),
+		ContentType:  metadata.ContentType(),
+		Filename:     metadata.Filename(),
+		SourceIPHash: metadata.SourceIPHash(),
+		APIKeyID:     metadata.APIKeyID(),
+
+		OwnerTokenHash: record.OwnerTokenHash(),
+		PasswordHash:   record.PasswordHash(),
+		Encrypted:      record.Encrypted(),
+		Reveal:         record.Reveal(),
+
+		RedirectStatus: int(record.Redirect().Status()),
+		ForwardQuery:   record.Redirect().ForwardQuery(),
+		ForwardPath:    record.Redirect().ForwardPath(),
 	}
 
-	if len(rec.Body) > int(r.config.CompressThresholdBytes()) {
-		compressedBody, err := compress(rec.Body)
-		if err != nil {
-			return fmt.Errorf("failed to compress: %w", err)
-		}
-
-		rec.Body = compressedBody
+	if !expirationDate.Eternal() {
+		rec.ExpiresAt = expirationDate.Date().UnixMilli()
 	}
 
-	err := r.client.HSet(ctx, string(key), rec).Err()
+	files, err := encodeBundleFiles(record.Files())
 	if err != nil {
-		return fmt.Errorf("failed to set key: %w", err)
+		return err
 	}
+	rec.Files = files
 
-	if !record.ExpirationDateEternal() {
-		ttl := record.TTL()
-		err := r.client.Expire(ctx, string(key), ttl).Err()
-		if err != nil {
-			return fmt.Errorf("failed to set expire for key '%s': %w", key, err)
+	body, encoding, err := encodeBody(r.config, record.RGetBody(), record.BodyEncoding())
+	if err != nil {
+		return err
+	}
+	rec.Body = body
+	rec.Encoding = string(encoding)
+	rec.BodySize = record.BodySize()
+
+	_, err = r.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.HSet(ctx, r.key(key), rec)
+		pipe.HDel(ctx, r.key(key), "ttl", "reserved")
+
+		if expirationDate.Eternal() {
+			pipe.Persist(ctx, r.key(key))
+		} else {
+			pipe.PExpireAt(ctx, r.key(key), expirationDate.Date())
 		}
+
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to set key '%s': %w", key, err)
 	}
 
 	return nil
 }
 
+// ConsumeByKey atomically decreases disposable counter, increases clicks
+// if countClick and removes record if its counter exhausted.
+func (r *RedisRecordRepository) ConsumeByKey(
+	ctx context.Context,
+	key objectvalue.RecordKey,
+	countClick bool,
+	acceptedEncodings ...objectvalue.BodyEncoding,
+) (aggregate.Record, error) {
+	// lua script because we need atomic execution
+	script := recordFieldsScript + `
+		if redis.call("EXISTS", KEYS[1]) == 0 or redis.call("HEXISTS", KEYS[1], "reserved") == 1 then
+			return false
+		end
+	` + migrateExpirationScript + `
+		local eternal = redis.call("HGET", KEYS[1], "eternal") == "1"
+		local countdown = tonumber(redis.call("HGET", KEYS[1], "countdown")) or 0
+		if not eternal then
+			if countdown < 1 then
+				return 0
+			end
+			countdown = countdown - 1
+			redis.call("HSET", KEYS[1], "countdown", countdown)
+		end
+
+		if ARGV[1] == "1" then
+			redis.call("HINCRBY", KEYS[1], "clicks", 1)
+		end
+
+		local expiresAt = tonumber(redis.call("HGET", KEYS[1], "expires_at")) or 0
+		local slidingTTL = tonumber(redis.call("HGET", KEYS[1], "sliding_ttl")) or 0
+		if expiresAt > 0 and slidingTTL > 0 then
+			local now = redis.call("TIME")
+			local nowMs = tonumber(now[1]) * 1000 + math.floor(tonumber(now[2]) / 1000)
+			expiresAt = nowMs + math.floor(slidingTTL / 1000000)
+			redis.call("HSET", KEYS[1], "expires_at", expiresAt)
+			redis.call("PEXPIREAT", KEYS[1], expiresAt)
+		end
+
+		local record = recordFields()
+
+		if not eternal and countdown < 1 then
+			redis.call("DEL", KEYS[1])
+		end
+
+		return record
+	`
+	res, err := r.client.Eval(ctx, script, []string{r.key(key)}, countClick).Result()
+	if errors.Is(err, redis.Nil) {
+		return aggregate.Record{}, domainerrors.ErrRecordNotFound
+	}
+	if err != nil {
+		return aggregate.Record{}, fmt.Errorf("fail to consume record by key '%s': %w", key, err)
+	}
+
+	fields, ok := res.([]any)
+	if !ok {
+		return aggregate.Record{}, domainerrors.ErrRecordCounterExhausted
+	}
+
+	record, err := scanRecord(fields)
+	if err != nil {
+		return aggregate.Record{}, fmt.Errorf("fail to scan consumed record by key '%s': %w", key, err)
+	}
+
+	return r.toRecord(key, record, acceptedEncodings)
+}
+
+// AccessByKey returns password hash, reveal flag, bundle files, path
+// forwarding and reads limit of record. Reserved key is not found.
+func (r *RedisRecordRepository) AccessByKey(ctx context.Context, key objectvalue.RecordKey) (objectvalue.RecordAccess, error) {
+	script := `
+		if redis.call("EXISTS", KEYS[1]) == 0 or redis.call("HEXISTS", KEYS[1], "reserved") == 1 then
+			return false
+		end
+		return redis.call("HMGET", KEYS[1], "password_hash", "reveal", "files", "url", "forward_path", "eternal")
+	`
+	res, err := r.client.Eval(ctx, script, []string{r.key(key)}).Slice()
+	if errors.Is(err, redis.Nil) {
+		return objectvalue.RecordAccess{}, domainerrors.ErrRecordNotFound
+	}
+	if err != nil {
+		return objectvalue.RecordAccess{}, fmt.Errorf("fail to get access by key '%s': %w", key, err)
+	}
+
+	// missing fields are nil
+	hash, _ := res[0].(string)
+	reveal, _ := res[1].(string)
+	encodedFiles, _ := res[2].(string)
+	url, _ := res[3].(string)
+	forwardPath, _ := res[4].(string)
+	eternal, _ := res[5].(string)
+
+	files, err := decodeBundleFiles(encodedFiles)
+	if err != nil {
+		return objectvalue.RecordAccess{}, fmt.Errorf("fail to get access by key '%s': %w", key, err)
+	}
+
+	return objectvalue.NewRecordAccess(hash, reveal == "1", files, url == "1" && forwardPath == "1", eternal != "1"), nil
+}
+
 // Exists returns is record with this key exists.
 func (r *RedisRecordRepository) Exists(ctx context.Context, key objectvalue.RecordKey) (bool, error) {
 	return r.exists(ctx, key)
@@ -130,7 +321,7 @@ func (r *RedisRecordRepository) Exists(ctx context.Context, key objectvalue.Reco
 
 // Exists returns is record with this key exists.
 func (r *RedisRecordRepository) exists(ctx context.Context, key objectvalue.RecordKey) (bool, error) {
-	keysNumber, err := r.client.Exists(ctx, string(key)).Uint64()
+	keysNumber, err := r.client.Exists(ctx, r.key(key)).Uint64()
 	if err != nil {
 		return false, fmt.Errorf("fail to check exists for key '%s': %w", key, err)
 	}
@@ -138,112 +329,307 @@ func (r *RedisRecordRepository) exists(ctx context.Context, key objectvalue.Reco
 	return keysNumber > 0, nil
 }
 
-// GenerateUniqueKey Generates unique key with minimum length of minLength
-// using charset. Increases minLength if was attemptsToIncreaseMinLength
-// attempts generate unique key. Returns error if database error or context
-// done or maxLength reached.
-func (r *RedisRecordRepository) GenerateUniqueKey(
-	ctx context.Context,
-	minLength, maxLength uint8,
-) (objectvalue.RecordKey, error) {
-	var err error
-	var key string
-	currentAttemptsCountdown := r.config.AttemptsToIncreaseKeyMinLength()
-
-	// initial true for start cycle
-	exists := true
-	for exists {
-		select {
-		case <-ctx.Done():
-			return "", fmt.Errorf("timeout")
-		default:
-		}
+// UpdateByKey rewrites body, bundle files, password hash and settings of
+// record keeping its clicks. Reserved key is not updated.
+func (r *RedisRecordRepository) UpdateByKey(ctx context.Context, key objectvalue.RecordKey, record aggregate.Record) error {
+	expirationDate := record.ExpirationDate()
+
+	var expiresAt int64
+	if !expirationDate.Eternal() {
+		expiresAt = expirationDate.Date().UnixMilli()
+	}
+
+	files, err := encodeBundleFiles(record.Files())
+	if err != nil {
+		return err
+	}
 
-		key, err = generateKey(minLength, r.config.KeysCharset())
+	body, encoding, err := encodeBody(r.config, record.RGetBody(), record.BodyEncoding())
+	if err != nil {
+		return err
+	}
+
+	// lua script because we need atomic execution
+	script := `
+		if redis.call("EXISTS", KEYS[1]) == 0 or redis.call("HEXISTS", KEYS[1], "reserved") == 1 then
+			return 0
+		end
+		redis.call("HSET", KEYS[1],
+			"body", ARGV[1],
+			"encoding", ARGV[2],
+			"expires_at", ARGV[3],
+			"sliding_ttl", ARGV[4],
+			"countdown", ARGV[5],
+			"eternal", ARGV[6],
+			"url", ARGV[7],
+			"password_hash", ARGV[8],
+			"files", ARGV[9],
+			"encrypted", ARGV[10],
+			"body_size", ARGV[11])
+		redis.call("HDEL", KEYS[1], "ttl")
+		if tonumber(ARGV[3]) == 0 then
+			redis.call("PERSIST", KEYS[1])
+		else
+			redis.call("PEXPIREAT", KEYS[1], ARGV[3])
+		end
+		return 1
+	`
+	updated, err := r.client.Eval(ctx, script, []string{r.key(key)},
+		body,
+		string(encoding),
+		expiresAt,
+		int64(expirationDate.SlidingTTL()),
+		record.DisposableCounter(),
+		record.DisposableCounterEternal(),
+		record.URL(),
+		record.PasswordHash(),
+		files,
+		record.Encrypted(),
+		record.BodySize(),
+	).Bool()
+	if err != nil {
+		return fmt.Errorf("fail to update record by key '%s': %w", key, err)
+	}
+
+	if !updated {
+		return domainerrors.ErrRecordNotFound
+	}
+
+	return nil
+}
+
+// DeleteByKey removes record. Reserved key is not removed.
+func (r *RedisRecordRepository) DeleteByKey(ctx context.Context, key objectvalue.RecordKey) error {
+	// lua script because we need atomic execution
+	script := `
+		if redis.call("EXISTS", KEYS[1]) == 0 or redis.call("HEXISTS", KEYS[1], "reserved") == 1 then
+			return 0
+		end
+		return redis.call("DEL", KEYS[1])
+	`
+	deleted, err := r.client.Eval(ctx, script, []string{r.key(key)}).Bool()
+	if err != nil {
+		return fmt.Errorf("fail to delete record by key '%s': %w", key, err)
+	}
+
+	if !deleted {
+		return domainerrors.ErrRecordNotFound
+	}
+
+	return nil
+}
+
+// GetClickStats returns click stats stored in "click_stats" field of
+// record. Reserved key is not found.
+func (r *RedisRecordRepository) GetClickStats(ctx context.Context, key objectvalue.RecordKey) (aggregate.ClickStats, error) {
+	data, err := r.clickStats(ctx, key)
+	if err != nil {
+		return aggregate.ClickStats{}, err
+	}
+
+	return decodeClickStats(data)
+}
+
+// UpdateClickStats atomically applies update to click stats of record.
+// Stats are written only if they were not changed since reading,
+// otherwise update is applied again. Reserved key is not updated.
+func (r *RedisRecordRepository) UpdateClickStats(
+	ctx context.Context,
+	key objectvalue.RecordKey,
+	update func(*aggregate.ClickStats),
+) error {
+	// lua script because we need atomic execution
+	script := `
+		if redis.call("EXISTS", KEYS[1]) == 0 or redis.call("HEXISTS", KEYS[1], "reserved") == 1 then
+			return -1
+		end
+		if (redis.call("HGET", KEYS[1], "click_stats") or "") ~= ARGV[1] then
+			return 0
+		end
+		redis.call("HSET", KEYS[1], "click_stats", ARGV[2])
+		return 1
+	`
+
+	for range clickStatsUpdateAttempts {
+		data, err := r.clickStats(ctx, key)
 		if err != nil {
-			return "", fmt.Errorf("fail generate key: %w", err)
+			return err
 		}
-		exists, err = r.Exists(ctx, objectvalue.RecordKey(key))
+
+		updated, err := updateEncodedClickStats(data, update)
 		if err != nil {
-			return "", fmt.Errorf("fail to check is key '%s' exists: %w", key, err)
+			return err
 		}
-		currentAttemptsCountdown--
 
-		if currentAttemptsCountdown < 1 {
-			minLength++
-			currentAttemptsCountdown = r.config.AttemptsToIncreaseKeyMinLength()
+		res, err := r.client.Eval(ctx, script, []string{r.key(key)}, data, updated).Int()
+		if err != nil {
+			return fmt.Errorf("fail to update click stats by key '%s': %w", key, err)
 		}
 
-		if minLength > maxLength {
-			return "", fmt.Errorf("max key length reached")
+		switch res {
+		case -1:
+			return domainerrors.ErrRecordNotFound
+		case 1:
+			return nil
 		}
 	}
 
-	return objectvalue.RecordKey(key), nil
+	return fmt.Errorf("fail to update click stats by key '%s': changed concurrently", key)
 }
 
-// generateKey generate new random key with specified length using charset.
-func generateKey(length uint8, charset string) (string, error) {
-	result := make([]byte, length)
-	charsetLen := len(charset)
-
-	for i := range length {
-		nBig, err := rand.Int(rand.Reader, big.NewInt(int64(charsetLen)))
-		if err != nil {
-			return "", fmt.Errorf("failure generate random number: %w", err)
-		}
-		result[i] = charset[nBig.Int64()]
+func (r *RedisRecordRepository) clickStats(ctx context.Context, key objectvalue.RecordKey) (string, error) {
+	script := `
+		if redis.call("EXISTS", KEYS[1]) == 0 or redis.call("HEXISTS", KEYS[1], "reserved") == 1 then
+			return false
+		end
+		return redis.call("HGET", KEYS[1], "click_stats") or ""
+	`
+	data, err := r.client.Eval(ctx, script, []string{r.key(key)}).Text()
+	if errors.Is(err, redis.Nil) {
+		return "", domainerrors.ErrRecordNotFound
+	}
+	if err != nil {
+		return "", fmt.Errorf("fail to get click stats by key '%s': %w", key, err)
 	}
 
-	return string(result), nil
+	return data, nil
 }
 
-func compress(data []byte) ([]byte, error) {
-	buf := bufferPool.Get().(*bytes.Buffer)
-	defer bufferPool.Put(buf)
-	buf.Reset()
+// ReserveKey atomically reserves key if it does not exist. Reservation is
+// hash with only "reserved" field, that SetByKey removes.
+func (r *RedisRecordRepository) ReserveKey(ctx context.Context, key objectvalue.RecordKey) (bool, error) {
+	// lua script because we need atomic execution
+	script := `
+		if redis.call("EXISTS", KEYS[1]) == 1 then
+			return 0
+		end
+		redis.call("HSET", KEYS[1], "reserved", 1)
+		redis.call("PEXPIRE", KEYS[1], ARGV[1])
+		return 1
+	`
+	reserved, err := r.client.Eval(ctx, script, []string{r.key(key)}, r.config.KeyReservationTTL().Milliseconds()).Bool()
+	if err != nil {
+		return false, fmt.Errorf("fail to reserve key '%s': %w", key, err)
+	}
+
+	return reserved, nil
+}
 
-	buf.Grow(len(data) / 2)
+// GenerateUniqueKey Generates and reserves unique key of style with
+// minimum length of minLength. Increases minLength if was
+// attemptsToIncreaseMinLength attempts generate unique key. Returns error
+// if database error or context done or maxLength reached.
+func (r *RedisRecordRepository) GenerateUniqueKey(
+	ctx context.Context,
+	style objectvalue.KeyStyle,
+	minLength, maxLength uint8,
+) (objectvalue.RecordKey, error) {
+	return generateUniqueKey(ctx, r.config, r.keyGenerators, style, minLength, maxLength, r.ReserveKey)
+}
 
-	gz, err := gzip.NewWriterLevel(buf, gzip.BestCompression)
+// nextSequence increments sequence of sequential keys. Sequence key
+// contains char not allowed in record keys, so it never clashes with them.
+func (r *RedisRecordRepository) nextSequence(ctx context.Context) (uint64, error) {
+	n, err := r.client.Incr(ctx, r.keyPrefix+"_sequence").Uint64()
 	if err != nil {
-		return nil, fmt.Errorf("failed to create gzip writer: %w", err)
+		return 0, fmt.Errorf("fail to increment keys sequence: %w", err)
 	}
 
-	if _, err := gz.Write(data); err != nil {
-		return nil, fmt.Errorf("failed to write data: %w", err)
+	return n, nil
+}
+
+func (r *RedisRecordRepository) key(key objectvalue.RecordKey) string {
+	return r.keyPrefix + string(key)
+}
+
+// scanRecord scans flat HGETALL reply returned by lua script.
+func scanRecord(fields []any) (redisKeyRecord, error) {
+	values := make(map[string]string, len(fields)/2)
+	for i := 0; i+1 < len(fields); i += 2 {
+		field, _ := fields[i].(string)
+		value, _ := fields[i+1].(string)
+		values[field] = value
 	}
 
-	if err := gz.Close(); err != nil {
-		return nil, fmt.Errorf("failed to close gzip writer: %w", err)
+	var record redisKeyRecord
+	err := redis.NewMapStringStringResult(values, nil).Scan(&record)
+	if err != nil {
+		return redisKeyRecord{}, fmt.Errorf("fail to scan record: %w", err)
 	}
 
-	return buf.Bytes(), nil
+	// records written by previous versions have no encoding field
+	// and are compressed with gzip detected by magic bytes
+	if _, ok := values["encoding"]; !ok && isGzip(record.Body) {
+		record.Encoding = bodycodec.Gzip
+	}
+
+	return record, nil
 }
 
-func decompress(data []byte, limit int64) ([]byte, error) {
-	gz, err := gzip.NewReader(bytes.NewReader(data))
+func (r *RedisRecordRepository) toRecord(
+	key objectvalue.RecordKey,
+	record redisKeyRecord,
+	acceptedEncodings []objectvalue.BodyEncoding,
+) (aggregate.Record, error) {
+	body, encoding, err := decodeBody(r.config, record.Body, objectvalue.BodyEncoding(record.Encoding), acceptedEncodings)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create gzip reader: %w", err)
+		return aggregate.Record{}, err
 	}
 
-	buf := bufferPool.Get().(*bytes.Buffer)
-	defer bufferPool.Put(buf)
-	buf.Reset()
-	buf.Grow(len(data) * 2)
-
-	_, err = io.CopyN(buf, gz, limit)
-	if !errors.Is(err, io.EOF) && err != nil {
-		return nil, fmt.Errorf("failed to decompress data: %w", err)
+	files, err := decodeBundleFiles(record.Files)
+	if err != nil {
+		return aggregate.Record{}, err
 	}
 
-	if err := gz.Close(); err != nil {
-		return nil, fmt.Errorf("failed to close gzip reader: %w", err)
-	}
+	rec := aggregate.NewRecord(
+		string(key),
+		objectvalue.NewExpirationDate(expiresAt(record.ExpiresAt), record.SlidingTTL),
+		record.Countdown,
+		record.Eternal,
+		record.Clicks,
+		nil,
+		record.URL,
+	)
+	rec.SetEncodedBody(body, encoding)
+	rec.SetBodySize(record.BodySize)
+	rec.SetMetadata(objectvalue.NewRecordMetadata(
+		fromUnixMilli(record.CreatedAt),
+		record.ContentType,
+		record.Filename,
+		record.SourceIPHash,
+		record.APIKeyID,
+	))
+	rec.SetOwnerTokenHash(record.OwnerTokenHash)
+	rec.SetPasswordHash(record.PasswordHash)
+	rec.SetEncrypted(record.Encrypted)
+	rec.SetReveal(record.Reveal)
+	rec.SetFiles(files)
+	rec.SetRedirect(objectvalue.NewRedirect(
+		objectvalue.RedirectStatus(record.RedirectStatus),
+		record.ForwardQuery,
+		record.ForwardPath,
+	))
+
+	return rec, nil
+}
 
-	return buf.Bytes(), nil
+// expiresAt converts stored unix milliseconds to time. Zero means eternal.
+func expiresAt(unixMilli int64) time.Time {
+	return fromUnixMilli(unixMilli)
 }
 
-func isCompressed(data []byte) bool {
-	return len(data) >= 2 && data[0] == 0x1f && data[1] == 0x8b
+// fromUnixMilli converts stored unix milliseconds to time. Zero is converted to zero time.
+func fromUnixMilli(unixMilli int64) time.Time {
+	if unixMilli == 0 {
+		return time.Time{}
+	}
+	return time.UnixMilli(unixMilli)
+}
+
+// unixMilli converts time to stored unix milliseconds. Zero time is stored as zero.
+func unixMilli(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.UnixMilli()
 }
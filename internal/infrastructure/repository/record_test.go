@@ -0,0 +1,170 @@
+//go:build integration
+
+package repository
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/thek4n/paste.thek4n.ru/internal/domain/aggregate"
+	"github.com/thek4n/paste.thek4n.ru/internal/domain/config"
+	"github.com/thek4n/paste.thek4n.ru/internal/domain/domainerrors"
+	"github.com/thek4n/paste.thek4n.ru/internal/domain/objectvalue"
+)
+
+func TestRedisRecordRepository(t *testing.T) {
+	ctx := context.Background()
+	client := redis.NewClient(&redis.Options{
+		Addr: fmt.Sprintf("%s:%d", getRedisHost(), 6379),
+		DB:   4,
+	})
+	require.NoError(t, client.FlushDB(ctx).Err())
+
+	repo := NewRedisRecordRepository(client, config.DefaultCachingConfig{}, "record:")
+
+	t.Run("info is got without body and with stored body size", func(t *testing.T) {
+		record := aggregate.NewRecord("info", objectvalue.NewExpirationDateFromTTL(time.Hour), 2, false, 0, bytes.Repeat([]byte("a"), 4096), false)
+		record.SetBodySize(4096)
+		record.SetMetadata(objectvalue.NewRecordMetadata(time.Now(), "text/plain", "", "", ""))
+
+		require.NoError(t, repo.SetByKey(ctx, "info", record))
+
+		got, err := repo.InfoByKey(ctx, "info")
+		require.NoError(t, err)
+		assert.Empty(t, got.RGetBody())
+		assert.Equal(t, int64(4096), got.BodySize())
+		assert.Equal(t, uint8(2), got.DisposableCounter())
+		assert.Equal(t, "text/plain", got.Metadata().ContentType())
+
+		updated := aggregate.NewRecord("info", objectvalue.NewExpirationDateFromTTL(time.Hour), 2, false, 0, []byte("new body"), false)
+		updated.SetBodySize(8)
+		require.NoError(t, repo.UpdateByKey(ctx, "info", updated))
+
+		got, err = repo.GetByKey(ctx, "info")
+		require.NoError(t, err)
+		assert.Equal(t, int64(8), got.BodySize())
+
+		_, err = repo.InfoByKey(ctx, "missing")
+		assert.ErrorIs(t, err, domainerrors.ErrRecordNotFound)
+	})
+
+	t.Run("concurrent reservations of same key succeed once and reservation is not readable", func(t *testing.T) {
+		const writers = 50
+
+		var reservedTimes atomic.Int32
+		var wg sync.WaitGroup
+		for range writers {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				reserved, err := repo.ReserveKey(ctx, "racy")
+				assert.NoError(t, err)
+				if reserved {
+					reservedTimes.Add(1)
+				}
+			}()
+		}
+		wg.Wait()
+
+		assert.Equal(t, int32(1), reservedTimes.Load())
+
+		_, err := repo.GetByKey(ctx, "racy")
+		assert.ErrorIs(t, err, domainerrors.ErrRecordNotFound)
+
+		_, err = repo.ConsumeByKey(ctx, "racy", true)
+		assert.ErrorIs(t, err, domainerrors.ErrRecordNotFound)
+
+		pttl, err := client.PTTL(ctx, "record:racy").Result()
+		require.NoError(t, err)
+		assert.Positive(t, pttl)
+
+		record := aggregate.NewRecord("racy", objectvalue.NewExpirationDateFromTTL(0), 0, true, 0, []byte("body"), false)
+		require.NoError(t, repo.SetByKey(ctx, "racy", record))
+
+		got, err := repo.ConsumeByKey(ctx, "racy", true)
+		require.NoError(t, err)
+		assert.Equal(t, []byte("body"), got.RGetBody())
+
+		reservedExists, err := client.HExists(ctx, "record:racy", "reserved").Result()
+		require.NoError(t, err)
+		assert.False(t, reservedExists)
+	})
+
+	t.Run("record metadata is stored", func(t *testing.T) {
+		createdAt := time.UnixMilli(time.Now().UnixMilli())
+		record := aggregate.NewRecord("meta", objectvalue.NewExpirationDateFromTTL(time.Hour), 0, true, 0, []byte("{}"), false)
+		record.SetMetadata(objectvalue.NewRecordMetadata(createdAt, "application/json", "data.json", "hash", "apikey-id"))
+
+		require.NoError(t, repo.SetByKey(ctx, "meta", record))
+
+		got, err := repo.ConsumeByKey(ctx, "meta", true)
+		require.NoError(t, err)
+		assert.Equal(t, record.Metadata(), got.Metadata())
+	})
+
+	t.Run("update rewrites record keeping clicks and metadata", func(t *testing.T) {
+		record := aggregate.NewRecord("updated", objectvalue.NewExpirationDateFromTTL(time.Hour), 3, false, 0, []byte("body"), false)
+		record.SetMetadata(objectvalue.NewRecordMetadata(time.UnixMilli(time.Now().UnixMilli()), "text/plain", "", "hash", ""))
+		require.NoError(t, repo.SetByKey(ctx, "updated", record))
+
+		_, err := repo.ConsumeByKey(ctx, "updated", true)
+		require.NoError(t, err)
+
+		update := aggregate.NewRecord("updated", objectvalue.NewExpirationDateFromTTL(0), 0, true, 0, []byte("https://example.com/"), true)
+		require.NoError(t, repo.UpdateByKey(ctx, "updated", update))
+
+		got, err := repo.GetByKey(ctx, "updated")
+		require.NoError(t, err)
+		assert.Equal(t, []byte("https://example.com/"), got.RGetBody())
+		assert.True(t, got.URL())
+		assert.True(t, got.DisposableCounterEternal())
+		assert.True(t, got.ExpirationDateEternal())
+		assert.Equal(t, uint32(1), got.Clicks())
+		assert.Equal(t, record.Metadata(), got.Metadata())
+
+		pttl, err := client.PTTL(ctx, "record:updated").Result()
+		require.NoError(t, err)
+		assert.Equal(t, time.Duration(-1), pttl)
+
+		assert.ErrorIs(t, repo.UpdateByKey(ctx, "missing", update), domainerrors.ErrRecordNotFound)
+
+		reserved, err := repo.ReserveKey(ctx, "reserved-update")
+		require.NoError(t, err)
+		require.True(t, reserved)
+		assert.ErrorIs(t, repo.UpdateByKey(ctx, "reserved-update", update), domainerrors.ErrRecordNotFound)
+	})
+
+	t.Run("delete removes record but not reservation", func(t *testing.T) {
+		record := aggregate.NewRecord("deleted", objectvalue.NewExpirationDateFromTTL(time.Hour), 0, true, 0, []byte("body"), false)
+		require.NoError(t, repo.SetByKey(ctx, "deleted", record))
+
+		require.NoError(t, repo.DeleteByKey(ctx, "deleted"))
+		assert.ErrorIs(t, repo.DeleteByKey(ctx, "deleted"), domainerrors.ErrRecordNotFound)
+
+		reserved, err := repo.ReserveKey(ctx, "reserved")
+		require.NoError(t, err)
+		require.True(t, reserved)
+		assert.ErrorIs(t, repo.DeleteByKey(ctx, "reserved"), domainerrors.ErrRecordNotFound)
+	})
+
+	t.Run("sequential keys are numbered by shared sequence", func(t *testing.T) {
+		first, err := repo.GenerateUniqueKey(ctx, objectvalue.KeyStyleSequential, 3, 20)
+		require.NoError(t, err)
+
+		second, err := NewRedisRecordRepository(client, config.DefaultCachingConfig{}, "record:").
+			GenerateUniqueKey(ctx, objectvalue.KeyStyleSequential, 3, 20)
+		require.NoError(t, err)
+
+		assert.Equal(t, objectvalue.RecordKey("001"), first)
+		assert.Equal(t, objectvalue.RecordKey("002"), second)
+	})
+}
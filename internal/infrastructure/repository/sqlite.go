@@ -0,0 +1,165 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	// pure go sqlite driver.
+	_ "modernc.org/sqlite"
+)
+
+// sqliteMigrations schema migrations of sqlite storage. Applied migrations
+// number stored in user_version pragma, so new migrations must be only appended.
+// Tables records, quotas and apikeys replace redis databases 0, 1 and 2.
+var sqliteMigrations = []string{
+	`
+	CREATE TABLE records (
+		key            TEXT PRIMARY KEY,
+		body           BLOB NOT NULL,
+		expires_at     INTEGER NOT NULL DEFAULT 0,
+		sliding_ttl_ms INTEGER NOT NULL DEFAULT 0,
+		clicks         INTEGER NOT NULL DEFAULT 0,
+		countdown      INTEGER NOT NULL DEFAULT 0,
+		eternal        INTEGER NOT NULL DEFAULT 0,
+		url            INTEGER NOT NULL DEFAULT 0
+	);
+	CREATE INDEX records_expires_at ON records (expires_at) WHERE expires_at > 0;
+
+	CREATE TABLE quotas (
+		source_ip  TEXT PRIMARY KEY,
+		value      INTEGER NOT NULL,
+		expires_at INTEGER NOT NULL
+	);
+	CREATE INDEX quotas_expires_at ON quotas (expires_at);
+
+	CREATE TABLE apikeys (
+		key   TEXT PRIMARY KEY,
+		id    TEXT NOT NULL,
+		valid INTEGER NOT NULL
+	);
+	`,
+	// body encoding was detected by gzip magic bytes before
+	`
+	ALTER TABLE records ADD COLUMN body_encoding TEXT NOT NULL DEFAULT '';
+	UPDATE records SET body_encoding = 'gzip' WHERE substr(body, 1, 2) = x'1f8b';
+	`,
+	// placeholders of keys reserved by ReserveKey
+	`
+	ALTER TABLE records ADD COLUMN reserved INTEGER NOT NULL DEFAULT 0;
+	`,
+	// counters of sequential keys
+	`
+	CREATE TABLE sequences (
+		name  TEXT PRIMARY KEY,
+		value INTEGER NOT NULL
+	);
+	`,
+	// record metadata
+	`
+	ALTER TABLE records ADD COLUMN created_at INTEGER NOT NULL DEFAULT 0;
+	ALTER TABLE records ADD COLUMN content_type TEXT NOT NULL DEFAULT '';
+	ALTER TABLE records ADD COLUMN filename TEXT NOT NULL DEFAULT '';
+	ALTER TABLE records ADD COLUMN source_ip_hash TEXT NOT NULL DEFAULT '';
+	ALTER TABLE records ADD COLUMN apikey_id TEXT NOT NULL DEFAULT '';
+	`,
+	// hash of record owner token
+	`
+	ALTER TABLE records ADD COLUMN owner_token_hash TEXT NOT NULL DEFAULT '';
+	`,
+	// password hash of protected record
+	`
+	ALTER TABLE records ADD COLUMN password_hash TEXT NOT NULL DEFAULT '';
+	`,
+	// body of record encrypted by client
+	`
+	ALTER TABLE records ADD COLUMN encrypted INTEGER NOT NULL DEFAULT 0;
+	`,
+	// reading of record requires explicit confirmation
+	`
+	ALTER TABLE records ADD COLUMN reveal INTEGER NOT NULL DEFAULT 0;
+	`,
+	// JSON encoded entries of bundle record
+	`
+	ALTER TABLE records ADD COLUMN files TEXT NOT NULL DEFAULT '';
+	`,
+	// redirect of url record, zero status is default one
+	`
+	ALTER TABLE records ADD COLUMN redirect_status INTEGER NOT NULL DEFAULT 0;
+	ALTER TABLE records ADD COLUMN forward_query INTEGER NOT NULL DEFAULT 0;
+	ALTER TABLE records ADD COLUMN forward_path INTEGER NOT NULL DEFAULT 0;
+	`,
+	// JSON encoded click stats of record
+	`
+	ALTER TABLE records ADD COLUMN click_stats TEXT NOT NULL DEFAULT '';
+	`,
+	// size of plain body, zero for records cached before it was stored
+	`
+	ALTER TABLE records ADD COLUMN body_size INTEGER NOT NULL DEFAULT 0;
+	`,
+}
+
+// OpenSQLite opens sqlite database by path and applies schema migrations.
+func OpenSQLite(ctx context.Context, path string) (*sql.DB, error) {
+	dsn := fmt.Sprintf("file:%s?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)&_pragma=synchronous(NORMAL)", path)
+
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("fail to open sqlite database '%s': %w", path, err)
+	}
+
+	if err := migrateSQLite(ctx, db); err != nil {
+		_ = db.Close()
+		return nil, err
+	}
+
+	return db, nil
+}
+
+func migrateSQLite(ctx context.Context, db *sql.DB) error {
+	var version int
+	if err := db.QueryRowContext(ctx, "PRAGMA user_version").Scan(&version); err != nil {
+		return fmt.Errorf("fail to get sqlite schema version: %w", err)
+	}
+
+	for i := version; i < len(sqliteMigrations); i++ {
+		tx, err := db.BeginTx(ctx, nil)
+		if err != nil {
+			return fmt.Errorf("fail to begin sqlite migration %d: %w", i+1, err)
+		}
+
+		if _, err := tx.ExecContext(ctx, sqliteMigrations[i]); err != nil {
+			_ = tx.Rollback()
+			return fmt.Errorf("fail to apply sqlite migration %d: %w", i+1, err)
+		}
+
+		// pragma does not support placeholders
+		if _, err := tx.ExecContext(ctx, fmt.Sprintf("PRAGMA user_version = %d", i+1)); err != nil {
+			_ = tx.Rollback()
+			return fmt.Errorf("fail to set sqlite schema version %d: %w", i+1, err)
+		}
+
+		if err := tx.Commit(); err != nil {
+			return fmt.Errorf("fail to commit sqlite migration %d: %w", i+1, err)
+		}
+	}
+
+	return nil
+}
+
+// runSQLiteSweeper periodically executes query with current unix
+// milliseconds as argument until ctx done.
+func runSQLiteSweeper(ctx context.Context, db *sql.DB, period time.Duration, query string) {
+	ticker := time.NewTicker(period)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			_, _ = db.ExecContext(ctx, query, time.Now().UnixMilli())
+		}
+	}
+}
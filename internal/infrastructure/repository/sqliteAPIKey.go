@@ -0,0 +1,121 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/thek4n/paste.thek4n.ru/internal/domain/aggregate"
+	"github.com/thek4n/paste.thek4n.ru/internal/domain/domainerrors"
+	"github.com/thek4n/paste.thek4n.ru/internal/domain/objectvalue"
+)
+
+// SQLiteAPIKeyRepository sqlite implementation of both readonly
+// and write domain interfaces for apikeys.
+type SQLiteAPIKeyRepository struct {
+	db *sql.DB
+}
+
+// NewSQLiteAPIKeyRepository constructor.
+func NewSQLiteAPIKeyRepository(db *sql.DB) *SQLiteAPIKeyRepository {
+	return &SQLiteAPIKeyRepository{
+		db: db,
+	}
+}
+
+// GetByID fetch APIKey from sqlite db.
+func (r *SQLiteAPIKeyRepository) GetByID(ctx context.Context, key string) (aggregate.APIKey, error) {
+	var id string
+	var valid bool
+
+	err := r.db.QueryRowContext(ctx, `
+		SELECT id, valid FROM apikeys WHERE key = ?
+	`, key).Scan(&id, &valid)
+	if errors.Is(err, sql.ErrNoRows) {
+		return aggregate.APIKey{}, fmt.Errorf("failure get record for key '%s': %w", key, domainerrors.ErrAPIKeyNotFound)
+	}
+	if err != nil {
+		return aggregate.APIKey{}, fmt.Errorf("failure get record for key '%s': %w", key, err)
+	}
+
+	rid, err := objectvalue.NewAPIKeyID(id)
+	if err != nil {
+		return aggregate.APIKey{}, fmt.Errorf("fail to parse apikey record id for key '%s': %w", key, err)
+	}
+
+	return aggregate.NewAPIKey(rid, key, valid), nil
+}
+
+// GetAll fetch all APIKeys from sqlite db.
+func (r *SQLiteAPIKeyRepository) GetAll(ctx context.Context) ([]aggregate.APIKey, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT key, id, valid FROM apikeys ORDER BY rowid
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("fail to query apikeys: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var apikeys []aggregate.APIKey
+	for rows.Next() {
+		var key, id string
+		var valid bool
+
+		if err := rows.Scan(&key, &id, &valid); err != nil {
+			return nil, fmt.Errorf("fail to scan apikey record: %w", err)
+		}
+
+		rid, err := objectvalue.NewAPIKeyID(id)
+		if err != nil {
+			return nil, fmt.Errorf("fail to parse apikey record id for key: %w", err)
+		}
+
+		apikeys = append(apikeys, aggregate.NewAPIKey(rid, key, valid))
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("fail to iterate apikeys: %w", err)
+	}
+
+	return apikeys, nil
+}
+
+// Exists checks is key exists.
+func (r *SQLiteAPIKeyRepository) Exists(ctx context.Context, key string) (bool, error) {
+	var exists bool
+
+	err := r.db.QueryRowContext(ctx, `
+		SELECT EXISTS (SELECT 1 FROM apikeys WHERE key = ?)
+	`, key).Scan(&exists)
+	if err != nil {
+		return false, fmt.Errorf("failure checking is key exists: %w", err)
+	}
+
+	return exists, nil
+}
+
+// SetByID write apikey to sqlite db.
+func (r *SQLiteAPIKeyRepository) SetByID(ctx context.Context, key string, apikey aggregate.APIKey) error {
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO apikeys (key, id, valid) VALUES (?, ?, ?)
+		ON CONFLICT (key) DO UPDATE SET id = excluded.id, valid = excluded.valid
+	`, key, apikey.PublicID().String(), apikey.Valid())
+	if err != nil {
+		return fmt.Errorf("failure set apikey for key '%s': %w", key, err)
+	}
+
+	return nil
+}
+
+// RemoveByID removes apikey from sqlite db.
+func (r *SQLiteAPIKeyRepository) RemoveByID(ctx context.Context, key string) error {
+	_, err := r.db.ExecContext(ctx, `
+		DELETE FROM apikeys WHERE key = ?
+	`, key)
+	if err != nil {
+		return fmt.Errorf("failure remove apikey by key '%s': %w", key, err)
+	}
+
+	return nil
+}
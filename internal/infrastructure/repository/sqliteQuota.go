@@ -0,0 +1,67 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/thek4n/paste.thek4n.ru/internal/domain/aggregate"
+	"github.com/thek4n/paste.thek4n.ru/internal/domain/config"
+	"github.com/thek4n/paste.thek4n.ru/internal/domain/domainerrors"
+	"github.com/thek4n/paste.thek4n.ru/internal/domain/objectvalue"
+)
+
+// SQLiteQuotaRepository sqlite implementation of domain interface of quota repository.
+type SQLiteQuotaRepository struct {
+	db     *sql.DB
+	config config.QuotaConfig
+}
+
+// NewSQLiteQuotaRepository constructor.
+func NewSQLiteQuotaRepository(db *sql.DB, cfg config.QuotaConfig) *SQLiteQuotaRepository {
+	return &SQLiteQuotaRepository{
+		db:     db,
+		config: cfg,
+	}
+}
+
+// GetByID get Quota aggregate from db.
+func (r *SQLiteQuotaRepository) GetByID(ctx context.Context, id objectvalue.QuotaSourceIP) (aggregate.Quota, error) {
+	var value uint32
+
+	err := r.db.QueryRowContext(ctx, `
+		SELECT value FROM quotas WHERE source_ip = ? AND expires_at > ?
+	`, string(id), time.Now().UnixMilli()).Scan(&value)
+	if errors.Is(err, sql.ErrNoRows) {
+		return aggregate.Quota{}, domainerrors.ErrQuotaNotFound
+	}
+	if err != nil {
+		return aggregate.Quota{}, fmt.Errorf("failure get quota for ip '%s': %w", id, err)
+	}
+
+	return aggregate.NewQuota(id, value), nil
+}
+
+// SetByID write quota to db.
+func (r *SQLiteQuotaRepository) SetByID(ctx context.Context, id objectvalue.QuotaSourceIP, q aggregate.Quota) error {
+	expiresAt := time.Now().Add(r.config.QuotaResetPeriod()).UnixMilli()
+
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO quotas (source_ip, value, expires_at) VALUES (?, ?, ?)
+		ON CONFLICT (source_ip) DO UPDATE SET value = excluded.value, expires_at = excluded.expires_at
+	`, string(id), q.Value(), expiresAt)
+	if err != nil {
+		return fmt.Errorf("fail to set new quota key: %w", err)
+	}
+
+	return nil
+}
+
+// RunSweeper periodically removes expired quotas until ctx done.
+func (r *SQLiteQuotaRepository) RunSweeper(ctx context.Context, period time.Duration) {
+	runSQLiteSweeper(ctx, r.db, period, `
+		DELETE FROM quotas WHERE expires_at <= ?
+	`)
+}
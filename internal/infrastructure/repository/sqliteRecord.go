@@ -0,0 +1,568 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/thek4n/paste.thek4n.ru/internal/domain/aggregate"
+	"github.com/thek4n/paste.thek4n.ru/internal/domain/config"
+	"github.com/thek4n/paste.thek4n.ru/internal/domain/domainerrors"
+	"github.com/thek4n/paste.thek4n.ru/internal/domain/objectvalue"
+)
+
+type sqliteKeyRecord struct {
+	Body         []byte
+	BodyEncoding string
+	BodySize     int64
+	ExpiresAt    int64
+	SlidingTTLMs int64
+	Clicks       uint32
+	Countdown    uint8
+	Eternal      bool
+	URL          bool
+	CreatedAt    int64
+	ContentType  string
+	Filename     string
+	SourceIPHash string
+	APIKeyID     string
+
+	OwnerTokenHash string
+	PasswordHash   string
+	Encrypted      bool
+	Reveal         bool
+	Files          string
+
+	RedirectStatus int
+	ForwardQuery   bool
+	ForwardPath    bool
+}
+
+// sqliteRecordColumns columns scanned by scanRecord.
+const sqliteRecordColumns = `body, body_encoding, ` + sqliteRecordFieldColumns
+
+// sqliteRecordFieldColumns columns scanned by scanRecord after body and its encoding.
+const sqliteRecordFieldColumns = `
+	body_size, expires_at, sliding_ttl_ms, clicks, countdown, eternal, url,
+	created_at, content_type, filename, source_ip_hash, apikey_id, owner_token_hash, password_hash, encrypted,
+	reveal, files, redirect_status, forward_query, forward_path
+`
+
+// SQLiteRecordRepository sqlite implementation of domain interface.
+type SQLiteRecordRepository struct {
+	db            *sql.DB
+	config        config.CachingConfig
+	keyGenerators map[objectvalue.KeyStyle]KeyGenerator
+}
+
+// NewSQLiteRecordRepository constructor.
+func NewSQLiteRecordRepository(db *sql.DB, cfg config.CachingConfig) *SQLiteRecordRepository {
+	r := &SQLiteRecordRepository{
+		db:     db,
+		config: cfg,
+	}
+	r.keyGenerators = newKeyGenerators(cfg, r.nextSequence)
+
+	return r
+}
+
+// GetByKey fetch Record from sqlite db.
+func (r *SQLiteRecordRepository) GetByKey(
+	ctx context.Context,
+	key objectvalue.RecordKey,
+	acceptedEncodings ...objectvalue.BodyEncoding,
+) (aggregate.Record, error) {
+	row := r.db.QueryRowContext(ctx, `
+		SELECT `+sqliteRecordColumns+`
+		FROM records
+		WHERE key = ? AND NOT reserved AND (expires_at = 0 OR expires_at > ?)
+	`, string(key), time.Now().UnixMilli())
+
+	return r.scanRecord(key, row, acceptedEncodings)
+}
+
+// InfoByKey returns record without consuming it and without selecting body.
+func (r *SQLiteRecordRepository) InfoByKey(ctx context.Context, key objectvalue.RecordKey) (aggregate.Record, error) {
+	row := r.db.QueryRowContext(ctx, `
+		SELECT x'', '', `+sqliteRecordFieldColumns+`
+		FROM records
+		WHERE key = ? AND NOT reserved AND (expires_at = 0 OR expires_at > ?)
+	`, string(key), time.Now().UnixMilli())
+
+	return r.scanRecord(key, row, nil)
+}
+
+// EncodeBody reads body up to limit compressing it like SetByKey does.
+func (r *SQLiteRecordRepository) EncodeBody(body io.Reader, limit int64) (objectvalue.EncodedBody, error) {
+	return readEncodedBody(r.config, body, limit)
+}
+
+// SetByKey writes Record to sqlite db.
+func (r *SQLiteRecordRepository) SetByKey(ctx context.Context, key objectvalue.RecordKey, record aggregate.Record) error {
+	expirationDate := record.ExpirationDate()
+	metadata := record.Metadata()
+	body, encoding, err := encodeBody(r.config, record.RGetBody(), record.BodyEncoding())
+	if err != nil {
+		return err
+	}
+
+	var expiresAt int64
+	if !expirationDate.Eternal() {
+		expiresAt = expirationDate.Date().UnixMilli()
+	}
+
+	files, err := encodeBundleFiles(record.Files())
+	if err != nil {
+		return err
+	}
+
+	_, err = r.db.ExecContext(ctx, `
+		INSERT INTO records (
+			key, body, body_encoding, body_size, expires_at, sliding_ttl_ms, clicks, countdown, eternal, url,
+			created_at, content_type, filename, source_ip_hash, apikey_id, owner_token_hash, password_hash, encrypted,
+			reveal, files, redirect_status, forward_query, forward_path, reserved
+		)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, 0)
+		ON CONFLICT (key) DO UPDATE SET
+			reserved = 0,
+			body = excluded.body,
+			body_encoding = excluded.body_encoding,
+			body_size = excluded.body_size,
+			expires_at = excluded.expires_at,
+			sliding_ttl_ms = excluded.sliding_ttl_ms,
+			clicks = excluded.clicks,
+			countdown = excluded.countdown,
+			eternal = excluded.eternal,
+			url = excluded.url,
+			created_at = excluded.created_at,
+			content_type = excluded.content_type,
+			filename = excluded.filename,
+			source_ip_hash = excluded.source_ip_hash,
+			apikey_id = excluded.apikey_id,
+			owner_token_hash = excluded.owner_token_hash,
+			password_hash = excluded.password_hash,
+			encrypted = excluded.encrypted,
+			reveal = excluded.reveal,
+			files = excluded.files,
+			redirect_status = excluded.redirect_status,
+			forward_query = excluded.forward_query,
+			forward_path = excluded.forward_path,
+			click_stats = ''
+	`,
+		string(key),
+		body,
+		string(encoding),
+		record.BodySize(),
+		expiresAt,
+		expirationDate.SlidingTTL().Milliseconds(),
+		record.Clicks(),
+		record.DisposableCounter(),
+		record.DisposableCounterEternal(),
+		record.URL(),
+		unixMilli(metadata.CreatedAt()),
+		metadata.ContentType(),
+		metadata.Filename(),
+		metadata.SourceIPHash(),
+		metadata.APIKeyID(),
+		record.OwnerTokenHash(),
+		record.PasswordHash(),
+		record.Encrypted(),
+		record.Reveal(),
+		files,
+		int(record.Redirect().Status()),
+		record.Redirect().ForwardQuery(),
+		record.Redirect().ForwardPath(),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to set key '%s': %w", key, err)
+	}
+
+	return nil
+}
+
+// ConsumeByKey atomically decreases disposable counter, increases clicks
+// if countClick and removes record if its counter exhausted.
+func (r *SQLiteRecordRepository) ConsumeByKey(
+	ctx context.Context,
+	key objectvalue.RecordKey,
+	countClick bool,
+	acceptedEncodings ...objectvalue.BodyEncoding,
+) (aggregate.Record, error) {
+	now := time.Now().UnixMilli()
+
+	// single statement because we need atomic execution
+	row := r.db.QueryRowContext(ctx, `
+		UPDATE records SET
+			countdown = CASE WHEN eternal THEN countdown ELSE countdown - 1 END,
+			clicks = CASE WHEN ? THEN clicks + 1 ELSE clicks END,
+			expires_at = CASE
+				WHEN expires_at > 0 AND sliding_ttl_ms > 0 THEN ? + sliding_ttl_ms
+				ELSE expires_at
+			END
+		WHERE key = ?
+			AND NOT reserved
+			AND (eternal OR countdown > 0)
+			AND (expires_at = 0 OR expires_at > ?)
+		RETURNING `+sqliteRecordColumns+`
+	`, countClick, now, string(key), now)
+
+	record, err := r.scanRecord(key, row, acceptedEncodings)
+	if err != nil {
+		return aggregate.Record{}, err
+	}
+
+	if record.CounterExhausted() {
+		_, err := r.db.ExecContext(ctx, `
+			DELETE FROM records WHERE key = ? AND NOT eternal AND countdown < 1
+		`, string(key))
+		if err != nil {
+			return aggregate.Record{}, fmt.Errorf("fail to remove exhausted record by key '%s': %w", key, err)
+		}
+	}
+
+	return record, nil
+}
+
+// AccessByKey returns password hash, reveal flag, bundle files, path
+// forwarding and reads limit of record. Reserved key is not found.
+func (r *SQLiteRecordRepository) AccessByKey(ctx context.Context, key objectvalue.RecordKey) (objectvalue.RecordAccess, error) {
+	var hash string
+	var reveal bool
+	var encodedFiles string
+	var forwardPath bool
+	var eternal bool
+
+	err := r.db.QueryRowContext(ctx, `
+		SELECT password_hash, reveal, files, url AND forward_path, eternal
+		FROM records
+		WHERE key = ? AND NOT reserved AND (expires_at = 0 OR expires_at > ?)
+	`, string(key), time.Now().UnixMilli()).Scan(&hash, &reveal, &encodedFiles, &forwardPath, &eternal)
+	if errors.Is(err, sql.ErrNoRows) {
+		return objectvalue.RecordAccess{}, domainerrors.ErrRecordNotFound
+	}
+	if err != nil {
+		return objectvalue.RecordAccess{}, fmt.Errorf("fail to get access by key '%s': %w", key, err)
+	}
+
+	files, err := decodeBundleFiles(encodedFiles)
+	if err != nil {
+		return objectvalue.RecordAccess{}, fmt.Errorf("fail to get access by key '%s': %w", key, err)
+	}
+
+	return objectvalue.NewRecordAccess(hash, reveal, files, forwardPath, !eternal), nil
+}
+
+// Exists returns is record with this key exists.
+func (r *SQLiteRecordRepository) Exists(ctx context.Context, key objectvalue.RecordKey) (bool, error) {
+	var exists bool
+
+	err := r.db.QueryRowContext(ctx, `
+		SELECT EXISTS (SELECT 1 FROM records WHERE key = ? AND (expires_at = 0 OR expires_at > ?))
+	`, string(key), time.Now().UnixMilli()).Scan(&exists)
+	if err != nil {
+		return false, fmt.Errorf("fail to check exists for key '%s': %w", key, err)
+	}
+
+	return exists, nil
+}
+
+// UpdateByKey rewrites body, bundle files, password hash and settings of
+// record keeping its clicks. Reserved key is not updated.
+func (r *SQLiteRecordRepository) UpdateByKey(ctx context.Context, key objectvalue.RecordKey, record aggregate.Record) error {
+	expirationDate := record.ExpirationDate()
+	body, encoding, err := encodeBody(r.config, record.RGetBody(), record.BodyEncoding())
+	if err != nil {
+		return err
+	}
+
+	files, err := encodeBundleFiles(record.Files())
+	if err != nil {
+		return err
+	}
+
+	var expiresAt int64
+	if !expirationDate.Eternal() {
+		expiresAt = expirationDate.Date().UnixMilli()
+	}
+
+	res, err := r.db.ExecContext(ctx, `
+		UPDATE records SET
+			body = ?,
+			body_encoding = ?,
+			body_size = ?,
+			expires_at = ?,
+			sliding_ttl_ms = ?,
+			countdown = ?,
+			eternal = ?,
+			url = ?,
+			password_hash = ?,
+			encrypted = ?,
+			files = ?
+		WHERE key = ? AND NOT reserved AND (expires_at = 0 OR expires_at > ?)
+	`,
+		body,
+		string(encoding),
+		record.BodySize(),
+		expiresAt,
+		expirationDate.SlidingTTL().Milliseconds(),
+		record.DisposableCounter(),
+		record.DisposableCounterEternal(),
+		record.URL(),
+		record.PasswordHash(),
+		record.Encrypted(),
+		files,
+		string(key),
+		time.Now().UnixMilli(),
+	)
+	if err != nil {
+		return fmt.Errorf("fail to update record by key '%s': %w", key, err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("fail to update record by key '%s': %w", key, err)
+	}
+
+	if affected == 0 {
+		return domainerrors.ErrRecordNotFound
+	}
+
+	return nil
+}
+
+// DeleteByKey removes record. Reserved key is not removed.
+func (r *SQLiteRecordRepository) DeleteByKey(ctx context.Context, key objectvalue.RecordKey) error {
+	res, err := r.db.ExecContext(ctx, `
+		DELETE FROM records WHERE key = ? AND NOT reserved AND (expires_at = 0 OR expires_at > ?)
+	`, string(key), time.Now().UnixMilli())
+	if err != nil {
+		return fmt.Errorf("fail to delete record by key '%s': %w", key, err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("fail to delete record by key '%s': %w", key, err)
+	}
+
+	if affected == 0 {
+		return domainerrors.ErrRecordNotFound
+	}
+
+	return nil
+}
+
+// GetClickStats returns click stats of record. Reserved key is not found.
+func (r *SQLiteRecordRepository) GetClickStats(ctx context.Context, key objectvalue.RecordKey) (aggregate.ClickStats, error) {
+	data, err := r.clickStats(ctx, key)
+	if err != nil {
+		return aggregate.ClickStats{}, err
+	}
+
+	return decodeClickStats(data)
+}
+
+// UpdateClickStats atomically applies update to click stats of record.
+// Stats are written only if they were not changed since reading,
+// otherwise update is applied again. Reserved key is not updated.
+func (r *SQLiteRecordRepository) UpdateClickStats(
+	ctx context.Context,
+	key objectvalue.RecordKey,
+	update func(*aggregate.ClickStats),
+) error {
+	for range clickStatsUpdateAttempts {
+		data, err := r.clickStats(ctx, key)
+		if err != nil {
+			return err
+		}
+
+		updated, err := updateEncodedClickStats(data, update)
+		if err != nil {
+			return err
+		}
+
+		res, err := r.db.ExecContext(ctx, `
+			UPDATE records SET click_stats = ?
+			WHERE key = ? AND NOT reserved AND click_stats = ?
+		`, updated, string(key), data)
+		if err != nil {
+			return fmt.Errorf("fail to update click stats by key '%s': %w", key, err)
+		}
+
+		affected, err := res.RowsAffected()
+		if err != nil {
+			return fmt.Errorf("fail to update click stats by key '%s': %w", key, err)
+		}
+
+		if affected > 0 {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("fail to update click stats by key '%s': changed concurrently", key)
+}
+
+func (r *SQLiteRecordRepository) clickStats(ctx context.Context, key objectvalue.RecordKey) (string, error) {
+	var data string
+
+	err := r.db.QueryRowContext(ctx, `
+		SELECT click_stats
+		FROM records
+		WHERE key = ? AND NOT reserved AND (expires_at = 0 OR expires_at > ?)
+	`, string(key), time.Now().UnixMilli()).Scan(&data)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", domainerrors.ErrRecordNotFound
+	}
+	if err != nil {
+		return "", fmt.Errorf("fail to get click stats by key '%s': %w", key, err)
+	}
+
+	return data, nil
+}
+
+// ReserveKey atomically reserves key if it does not exist. Expired not
+// swept record is replaced by reservation.
+func (r *SQLiteRecordRepository) ReserveKey(ctx context.Context, key objectvalue.RecordKey) (bool, error) {
+	now := time.Now()
+
+	res, err := r.db.ExecContext(ctx, `
+		INSERT INTO records (key, body, expires_at, reserved)
+		VALUES (?, x'', ?, 1)
+		ON CONFLICT (key) DO UPDATE SET
+			body = excluded.body,
+			body_encoding = '',
+			expires_at = excluded.expires_at,
+			sliding_ttl_ms = 0,
+			clicks = 0,
+			countdown = 0,
+			eternal = 0,
+			url = 0,
+			click_stats = '',
+			reserved = 1
+		WHERE records.expires_at > 0 AND records.expires_at <= ?
+	`, string(key), now.Add(r.config.KeyReservationTTL()).UnixMilli(), now.UnixMilli())
+	if err != nil {
+		return false, fmt.Errorf("fail to reserve key '%s': %w", key, err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("fail to reserve key '%s': %w", key, err)
+	}
+
+	return affected == 1, nil
+}
+
+// GenerateUniqueKey Generates and reserves unique key.
+// See RedisRecordRepository.GenerateUniqueKey.
+func (r *SQLiteRecordRepository) GenerateUniqueKey(
+	ctx context.Context,
+	style objectvalue.KeyStyle,
+	minLength, maxLength uint8,
+) (objectvalue.RecordKey, error) {
+	return generateUniqueKey(ctx, r.config, r.keyGenerators, style, minLength, maxLength, r.ReserveKey)
+}
+
+// nextSequence increments sequence of sequential keys.
+func (r *SQLiteRecordRepository) nextSequence(ctx context.Context) (uint64, error) {
+	var n uint64
+	err := r.db.QueryRowContext(ctx, `
+		INSERT INTO sequences (name, value) VALUES ('records', 1)
+		ON CONFLICT (name) DO UPDATE SET value = value + 1
+		RETURNING value
+	`).Scan(&n)
+	if err != nil {
+		return 0, fmt.Errorf("fail to increment keys sequence: %w", err)
+	}
+
+	return n, nil
+}
+
+// RunSweeper periodically removes expired records until ctx done.
+func (r *SQLiteRecordRepository) RunSweeper(ctx context.Context, period time.Duration) {
+	runSQLiteSweeper(ctx, r.db, period, `
+		DELETE FROM records WHERE expires_at > 0 AND expires_at <= ?
+	`)
+}
+
+func (r *SQLiteRecordRepository) scanRecord(
+	key objectvalue.RecordKey,
+	row *sql.Row,
+	acceptedEncodings []objectvalue.BodyEncoding,
+) (aggregate.Record, error) {
+	var rec sqliteKeyRecord
+
+	err := row.Scan(
+		&rec.Body,
+		&rec.BodyEncoding,
+		&rec.BodySize,
+		&rec.ExpiresAt,
+		&rec.SlidingTTLMs,
+		&rec.Clicks,
+		&rec.Countdown,
+		&rec.Eternal,
+		&rec.URL,
+		&rec.CreatedAt,
+		&rec.ContentType,
+		&rec.Filename,
+		&rec.SourceIPHash,
+		&rec.APIKeyID,
+		&rec.OwnerTokenHash,
+		&rec.PasswordHash,
+		&rec.Encrypted,
+		&rec.Reveal,
+		&rec.Files,
+		&rec.RedirectStatus,
+		&rec.ForwardQuery,
+		&rec.ForwardPath,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return aggregate.Record{}, domainerrors.ErrRecordNotFound
+	}
+	if err != nil {
+		return aggregate.Record{}, fmt.Errorf("fail to get record by key '%s': %w", key, err)
+	}
+
+	body, encoding, err := decodeBody(r.config, rec.Body, objectvalue.BodyEncoding(rec.BodyEncoding), acceptedEncodings)
+	if err != nil {
+		return aggregate.Record{}, err
+	}
+
+	files, err := decodeBundleFiles(rec.Files)
+	if err != nil {
+		return aggregate.Record{}, err
+	}
+
+	record := aggregate.NewRecord(
+		string(key),
+		objectvalue.NewExpirationDate(expiresAt(rec.ExpiresAt), time.Duration(rec.SlidingTTLMs)*time.Millisecond),
+		rec.Countdown,
+		rec.Eternal,
+		rec.Clicks,
+		nil,
+		rec.URL,
+	)
+	record.SetEncodedBody(body, encoding)
+	record.SetBodySize(rec.BodySize)
+	record.SetMetadata(objectvalue.NewRecordMetadata(
+		fromUnixMilli(rec.CreatedAt),
+		rec.ContentType,
+		rec.Filename,
+		rec.SourceIPHash,
+		rec.APIKeyID,
+	))
+	record.SetOwnerTokenHash(rec.OwnerTokenHash)
+	record.SetPasswordHash(rec.PasswordHash)
+	record.SetEncrypted(rec.Encrypted)
+	record.SetReveal(rec.Reveal)
+	record.SetFiles(files)
+	record.SetRedirect(objectvalue.NewRedirect(
+		objectvalue.RedirectStatus(rec.RedirectStatus),
+		rec.ForwardQuery,
+		rec.ForwardPath,
+	))
+
+	return record, nil
+}
@@ -0,0 +1,538 @@
+//go:build unit
+
+package repository
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/thek4n/paste.thek4n.ru/internal/domain/aggregate"
+	"github.com/thek4n/paste.thek4n.ru/internal/domain/config"
+	"github.com/thek4n/paste.thek4n.ru/internal/domain/domainerrors"
+	"github.com/thek4n/paste.thek4n.ru/internal/domain/objectvalue"
+)
+
+func openTestSQLite(t *testing.T) *sql.DB {
+	t.Helper()
+
+	db, err := OpenSQLite(context.Background(), filepath.Join(t.TempDir(), "paste.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = db.Close() })
+
+	return db
+}
+
+func TestOpenSQLite(t *testing.T) {
+	t.Run("reopening database does not apply migrations twice", func(t *testing.T) {
+		t.Parallel()
+
+		path := filepath.Join(t.TempDir(), "paste.db")
+
+		db, err := OpenSQLite(context.Background(), path)
+		require.NoError(t, err)
+		require.NoError(t, db.Close())
+
+		db, err = OpenSQLite(context.Background(), path)
+		require.NoError(t, err)
+		defer func() { _ = db.Close() }()
+
+		var version int
+		require.NoError(t, db.QueryRow("PRAGMA user_version").Scan(&version))
+		assert.Equal(t, len(sqliteMigrations), version)
+	})
+}
+
+func TestSQLiteRecordRepository(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("set and get returns same record", func(t *testing.T) {
+		t.Parallel()
+
+		repo := NewSQLiteRecordRepository(openTestSQLite(t), config.DefaultCachingConfig{})
+		expirationDate := objectvalue.NewExpirationDateFromTTL(time.Hour)
+		record := aggregate.NewRecord("key", expirationDate, 3, false, 2, []byte("body"), true)
+
+		require.NoError(t, repo.SetByKey(ctx, "key", record))
+
+		got, err := repo.GetByKey(ctx, "key")
+		require.NoError(t, err)
+		assert.Equal(t, []byte("body"), got.RGetBody())
+		assert.Equal(t, uint8(3), got.DisposableCounter())
+		assert.Equal(t, uint32(2), got.Clicks())
+		assert.True(t, got.URL())
+		assert.Equal(t, expirationDate.Date().UnixMilli(), got.ExpirationDate().Date().UnixMilli())
+	})
+
+	t.Run("record metadata is stored", func(t *testing.T) {
+		t.Parallel()
+
+		repo := NewSQLiteRecordRepository(openTestSQLite(t), config.DefaultCachingConfig{})
+		createdAt := time.UnixMilli(time.Now().UnixMilli())
+		record := aggregate.NewRecord("meta", objectvalue.NewExpirationDateFromTTL(time.Hour), 0, true, 0, []byte("{}"), false)
+		record.SetMetadata(objectvalue.NewRecordMetadata(createdAt, "application/json", "data.json", "hash", "apikey-id"))
+
+		require.NoError(t, repo.SetByKey(ctx, "meta", record))
+
+		got, err := repo.ConsumeByKey(ctx, "meta", true)
+		require.NoError(t, err)
+		assert.Equal(t, record.Metadata(), got.Metadata())
+	})
+
+	t.Run("info is got without body and with stored body size", func(t *testing.T) {
+		t.Parallel()
+
+		repo := NewSQLiteRecordRepository(openTestSQLite(t), config.DefaultCachingConfig{})
+
+		record := aggregate.NewRecord("info", objectvalue.NewExpirationDateFromTTL(time.Hour), 2, false, 0, bytes.Repeat([]byte("a"), 4096), false)
+		record.SetBodySize(4096)
+		record.SetMetadata(objectvalue.NewRecordMetadata(time.Now(), "text/plain", "", "", ""))
+
+		require.NoError(t, repo.SetByKey(ctx, "info", record))
+
+		got, err := repo.InfoByKey(ctx, "info")
+		require.NoError(t, err)
+		assert.Empty(t, got.RGetBody())
+		assert.Equal(t, int64(4096), got.BodySize())
+		assert.Equal(t, uint8(2), got.DisposableCounter())
+		assert.Equal(t, "text/plain", got.Metadata().ContentType())
+
+		updated := aggregate.NewRecord("info", objectvalue.NewExpirationDateFromTTL(time.Hour), 2, false, 0, []byte("new body"), false)
+		updated.SetBodySize(8)
+		require.NoError(t, repo.UpdateByKey(ctx, "info", updated))
+
+		got, err = repo.GetByKey(ctx, "info")
+		require.NoError(t, err)
+		assert.Equal(t, int64(8), got.BodySize())
+
+		_, err = repo.InfoByKey(ctx, "missing")
+		assert.ErrorIs(t, err, domainerrors.ErrRecordNotFound)
+	})
+
+	t.Run("encrypted flag is stored and rewritten on update", func(t *testing.T) {
+		t.Parallel()
+
+		repo := NewSQLiteRecordRepository(openTestSQLite(t), config.DefaultCachingConfig{})
+		record := aggregate.NewRecord("encrypted", objectvalue.NewExpirationDateFromTTL(time.Hour), 0, true, 0, []byte("ciphertext"), false)
+		record.SetEncrypted(true)
+
+		require.NoError(t, repo.SetByKey(ctx, "encrypted", record))
+
+		got, err := repo.GetByKey(ctx, "encrypted")
+		require.NoError(t, err)
+		assert.True(t, got.Encrypted())
+
+		updated := aggregate.NewRecord("encrypted", objectvalue.NewExpirationDateFromTTL(time.Hour), 0, true, 0, []byte("plain"), false)
+		require.NoError(t, repo.UpdateByKey(ctx, "encrypted", updated))
+
+		got, err = repo.ConsumeByKey(ctx, "encrypted", true)
+		require.NoError(t, err)
+		assert.False(t, got.Encrypted())
+		assert.Equal(t, []byte("plain"), got.RGetBody())
+	})
+
+	t.Run("bundle files are stored and given by access", func(t *testing.T) {
+		t.Parallel()
+
+		repo := NewSQLiteRecordRepository(openTestSQLite(t), config.DefaultCachingConfig{})
+		files := objectvalue.BundleFiles{
+			objectvalue.NewBundleFile("config.yaml", "application/yaml", 4),
+			objectvalue.NewBundleFile("logs/app.log", "", 3),
+		}
+		record := aggregate.NewRecord("bundle", objectvalue.NewExpirationDateFromTTL(time.Hour), 0, true, 0, []byte("confapp"), false)
+		record.SetFiles(files)
+
+		require.NoError(t, repo.SetByKey(ctx, "bundle", record))
+
+		access, err := repo.AccessByKey(ctx, "bundle")
+		require.NoError(t, err)
+		assert.Equal(t, files, access.Files())
+
+		got, err := repo.ConsumeByKey(ctx, "bundle", true)
+		require.NoError(t, err)
+		assert.Equal(t, files, got.Files())
+
+		file, content, ok := got.BundleFile("logs/app.log")
+		require.True(t, ok)
+		assert.Equal(t, "logs/app.log", file.Name())
+		assert.Equal(t, []byte("app"), content)
+
+		plain := aggregate.NewRecord("bundle", objectvalue.NewExpirationDateFromTTL(time.Hour), 0, true, 0, []byte("plain"), false)
+		require.NoError(t, repo.UpdateByKey(ctx, "bundle", plain))
+
+		access, err = repo.AccessByKey(ctx, "bundle")
+		require.NoError(t, err)
+		assert.Empty(t, access.Files())
+	})
+
+	t.Run("redirect is stored and path forwarding given by access", func(t *testing.T) {
+		t.Parallel()
+
+		repo := NewSQLiteRecordRepository(openTestSQLite(t), config.DefaultCachingConfig{})
+		redirect := objectvalue.NewRedirect(objectvalue.RedirectMovedPermanently, true, true)
+		record := aggregate.NewRecord("short", objectvalue.NewExpirationDateFromTTL(time.Hour), 0, true, 0, []byte("https://example.com/"), true)
+		record.SetRedirect(redirect)
+
+		require.NoError(t, repo.SetByKey(ctx, "short", record))
+
+		access, err := repo.AccessByKey(ctx, "short")
+		require.NoError(t, err)
+		assert.True(t, access.ForwardPath())
+
+		got, err := repo.GetByKey(ctx, "short")
+		require.NoError(t, err)
+		assert.Equal(t, redirect, got.Redirect())
+
+		plain := aggregate.NewRecord("short", objectvalue.NewExpirationDateFromTTL(time.Hour), 0, true, 0, []byte("plain"), false)
+		require.NoError(t, repo.UpdateByKey(ctx, "short", plain))
+
+		access, err = repo.AccessByKey(ctx, "short")
+		require.NoError(t, err)
+		assert.False(t, access.ForwardPath(), "record which is not url should not forward path")
+	})
+
+	t.Run("reads limit is given by access", func(t *testing.T) {
+		t.Parallel()
+
+		repo := NewSQLiteRecordRepository(openTestSQLite(t), config.DefaultCachingConfig{})
+		disposable := aggregate.NewRecord("disposable", objectvalue.NewExpirationDateFromTTL(time.Hour), 2, false, 0, []byte("body"), false)
+		eternal := aggregate.NewRecord("eternal", objectvalue.NewExpirationDateFromTTL(time.Hour), 0, true, 0, []byte("body"), false)
+		require.NoError(t, repo.SetByKey(ctx, "disposable", disposable))
+		require.NoError(t, repo.SetByKey(ctx, "eternal", eternal))
+
+		access, err := repo.AccessByKey(ctx, "disposable")
+		require.NoError(t, err)
+		assert.True(t, access.ReadsLimited())
+
+		access, err = repo.AccessByKey(ctx, "eternal")
+		require.NoError(t, err)
+		assert.False(t, access.ReadsLimited())
+	})
+
+	t.Run("click stats are updated concurrently and kept on update", func(t *testing.T) {
+		t.Parallel()
+
+		repo := NewSQLiteRecordRepository(openTestSQLite(t), config.DefaultCachingConfig{})
+		record := aggregate.NewRecord("stats", objectvalue.NewExpirationDateFromTTL(time.Hour), 0, true, 0, []byte("https://example.com/"), true)
+		require.NoError(t, repo.SetByKey(ctx, "stats", record))
+
+		stats, err := repo.GetClickStats(ctx, "stats")
+		require.NoError(t, err)
+		assert.Equal(t, uint32(0), stats.Clicks())
+
+		var wg sync.WaitGroup
+		for i := range 8 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				click := objectvalue.NewClick(time.Now(), "https://example.com/", "curl/8.0", "", strconv.Itoa(i))
+				assert.NoError(t, repo.UpdateClickStats(ctx, "stats", func(stats *aggregate.ClickStats) {
+					stats.AddClick(click, config.DefaultClickStatsConfig{})
+				}))
+			}()
+		}
+		wg.Wait()
+
+		require.NoError(t, repo.UpdateByKey(ctx, "stats", record))
+
+		stats, err = repo.GetClickStats(ctx, "stats")
+		require.NoError(t, err)
+		assert.Equal(t, uint32(8), stats.Clicks())
+		assert.Equal(t, map[string]uint32{"example.com": 8}, stats.Referrers())
+		assert.Equal(t, uint64(8), stats.UniqueVisitors())
+
+		got, err := repo.GetByKey(ctx, "stats")
+		require.NoError(t, err)
+		assert.Equal(t, []byte("https://example.com/"), got.RGetBody())
+
+		reserved, err := repo.ReserveKey(ctx, "reserved-stats")
+		require.NoError(t, err)
+		require.True(t, reserved)
+		_, err = repo.GetClickStats(ctx, "reserved-stats")
+		assert.ErrorIs(t, err, domainerrors.ErrRecordNotFound)
+		err = repo.UpdateClickStats(ctx, "missing", func(*aggregate.ClickStats) {})
+		assert.ErrorIs(t, err, domainerrors.ErrRecordNotFound)
+	})
+
+	t.Run("update rewrites record keeping clicks and metadata", func(t *testing.T) {
+		t.Parallel()
+
+		repo := NewSQLiteRecordRepository(openTestSQLite(t), config.DefaultCachingConfig{})
+		record := aggregate.NewRecord("updated", objectvalue.NewExpirationDateFromTTL(time.Hour), 3, false, 0, []byte("body"), false)
+		record.SetMetadata(objectvalue.NewRecordMetadata(time.UnixMilli(time.Now().UnixMilli()), "text/plain", "", "hash", ""))
+		require.NoError(t, repo.SetByKey(ctx, "updated", record))
+
+		_, err := repo.ConsumeByKey(ctx, "updated", true)
+		require.NoError(t, err)
+
+		update := aggregate.NewRecord("updated", objectvalue.NewExpirationDateFromTTL(0), 0, true, 0, []byte("https://example.com/"), true)
+		require.NoError(t, repo.UpdateByKey(ctx, "updated", update))
+
+		got, err := repo.GetByKey(ctx, "updated")
+		require.NoError(t, err)
+		assert.Equal(t, []byte("https://example.com/"), got.RGetBody())
+		assert.True(t, got.URL())
+		assert.True(t, got.DisposableCounterEternal())
+		assert.True(t, got.ExpirationDateEternal())
+		assert.Equal(t, uint32(1), got.Clicks())
+		assert.Equal(t, record.Metadata(), got.Metadata())
+
+		assert.ErrorIs(t, repo.UpdateByKey(ctx, "missing", update), domainerrors.ErrRecordNotFound)
+
+		reserved, err := repo.ReserveKey(ctx, "reserved-update")
+		require.NoError(t, err)
+		require.True(t, reserved)
+		assert.ErrorIs(t, repo.UpdateByKey(ctx, "reserved-update", update), domainerrors.ErrRecordNotFound)
+	})
+
+	t.Run("delete removes record but not reservation", func(t *testing.T) {
+		t.Parallel()
+
+		repo := NewSQLiteRecordRepository(openTestSQLite(t), config.DefaultCachingConfig{})
+		record := aggregate.NewRecord("deleted", objectvalue.NewExpirationDateFromTTL(time.Hour), 0, true, 0, []byte("body"), false)
+		require.NoError(t, repo.SetByKey(ctx, "deleted", record))
+
+		require.NoError(t, repo.DeleteByKey(ctx, "deleted"))
+		assert.ErrorIs(t, repo.DeleteByKey(ctx, "deleted"), domainerrors.ErrRecordNotFound)
+
+		reserved, err := repo.ReserveKey(ctx, "reserved")
+		require.NoError(t, err)
+		require.True(t, reserved)
+		assert.ErrorIs(t, repo.DeleteByKey(ctx, "reserved"), domainerrors.ErrRecordNotFound)
+
+		exists, err := repo.Exists(ctx, "reserved")
+		require.NoError(t, err)
+		assert.True(t, exists)
+	})
+
+	t.Run("large body is returned intact", func(t *testing.T) {
+		t.Parallel()
+
+		cfg := config.DefaultCachingConfig{}
+		repo := NewSQLiteRecordRepository(openTestSQLite(t), cfg)
+		body := bytes.Repeat([]byte("a"), int(cfg.CompressThresholdBytes())*2)
+		record := aggregate.NewRecord("big", objectvalue.NewExpirationDateFromTTL(0), 0, true, 0, body, false)
+
+		require.NoError(t, repo.SetByKey(ctx, "big", record))
+
+		got, err := repo.GetByKey(ctx, "big")
+		require.NoError(t, err)
+		assert.Equal(t, body, got.RGetBody())
+		assert.True(t, got.ExpirationDateEternal())
+	})
+
+	t.Run("body compressed with zstd is returned intact", func(t *testing.T) {
+		t.Parallel()
+
+		cfg := zstdCachingConfig{}
+		repo := NewSQLiteRecordRepository(openTestSQLite(t), cfg)
+		body := bytes.Repeat([]byte("a"), int(cfg.CompressThresholdBytes())*2)
+		record := aggregate.NewRecord("zstd", objectvalue.NewExpirationDateFromTTL(time.Hour), 0, true, 0, body, false)
+
+		require.NoError(t, repo.SetByKey(ctx, "zstd", record))
+
+		got, err := repo.ConsumeByKey(ctx, "zstd", true)
+		require.NoError(t, err)
+		assert.Equal(t, objectvalue.BodyEncodingIdentity, got.BodyEncoding())
+		assert.Equal(t, body, got.RGetBody())
+	})
+
+	t.Run("expired record is not found and swept", func(t *testing.T) {
+		t.Parallel()
+
+		db := openTestSQLite(t)
+		repo := NewSQLiteRecordRepository(db, config.DefaultCachingConfig{})
+		record := aggregate.NewRecord("expired", objectvalue.NewExpirationDateFromTTL(-time.Second), 0, true, 0, []byte("body"), false)
+
+		require.NoError(t, repo.SetByKey(ctx, "expired", record))
+
+		_, err := repo.GetByKey(ctx, "expired")
+		assert.True(t, errors.Is(err, domainerrors.ErrRecordNotFound))
+
+		sweepCtx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+		defer cancel()
+		repo.RunSweeper(sweepCtx, 10*time.Millisecond)
+
+		var rows int
+		require.NoError(t, db.QueryRow("SELECT count(*) FROM records").Scan(&rows))
+		assert.Zero(t, rows)
+	})
+
+	t.Run("concurrent consume serves disposable record exactly disposable times", func(t *testing.T) {
+		t.Parallel()
+
+		const disposable = 5
+		const readers = 50
+
+		repo := NewSQLiteRecordRepository(openTestSQLite(t), config.DefaultCachingConfig{})
+		record := aggregate.NewRecord("disposable", objectvalue.NewExpirationDateFromTTL(time.Hour), disposable, false, 0, []byte("body"), false)
+		require.NoError(t, repo.SetByKey(ctx, "disposable", record))
+
+		var served atomic.Int32
+		var wg sync.WaitGroup
+		for range readers {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				_, err := repo.ConsumeByKey(ctx, "disposable", true)
+				if err == nil {
+					served.Add(1)
+					return
+				}
+				assert.True(t, errors.Is(err, domainerrors.ErrRecordNotFound), err)
+			}()
+		}
+		wg.Wait()
+
+		assert.Equal(t, int32(disposable), served.Load())
+
+		exists, err := repo.Exists(ctx, "disposable")
+		require.NoError(t, err)
+		assert.False(t, exists)
+	})
+}
+
+func TestSQLiteRecordRepository_ReserveKey(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("reserved key is not readable until set", func(t *testing.T) {
+		t.Parallel()
+
+		repo := NewSQLiteRecordRepository(openTestSQLite(t), config.DefaultCachingConfig{})
+
+		reserved, err := repo.ReserveKey(ctx, "reserved")
+		require.NoError(t, err)
+		assert.True(t, reserved)
+
+		reserved, err = repo.ReserveKey(ctx, "reserved")
+		require.NoError(t, err)
+		assert.False(t, reserved)
+
+		_, err = repo.GetByKey(ctx, "reserved")
+		assert.ErrorIs(t, err, domainerrors.ErrRecordNotFound)
+
+		_, err = repo.ConsumeByKey(ctx, "reserved", true)
+		assert.ErrorIs(t, err, domainerrors.ErrRecordNotFound)
+
+		record := aggregate.NewRecord("reserved", objectvalue.NewExpirationDateFromTTL(time.Hour), 0, true, 0, []byte("body"), false)
+		require.NoError(t, repo.SetByKey(ctx, "reserved", record))
+
+		got, err := repo.ConsumeByKey(ctx, "reserved", true)
+		require.NoError(t, err)
+		assert.Equal(t, []byte("body"), got.RGetBody())
+	})
+
+	t.Run("expired not swept record can be reserved", func(t *testing.T) {
+		t.Parallel()
+
+		repo := NewSQLiteRecordRepository(openTestSQLite(t), config.DefaultCachingConfig{})
+		record := aggregate.NewRecord("expired", objectvalue.NewExpirationDateFromTTL(-time.Second), 0, true, 0, []byte("body"), false)
+		require.NoError(t, repo.SetByKey(ctx, "expired", record))
+
+		reserved, err := repo.ReserveKey(ctx, "expired")
+		require.NoError(t, err)
+		assert.True(t, reserved)
+	})
+
+	t.Run("concurrent reservations of same key succeed once", func(t *testing.T) {
+		t.Parallel()
+
+		const writers = 20
+
+		repo := NewSQLiteRecordRepository(openTestSQLite(t), config.DefaultCachingConfig{})
+
+		var reservedTimes atomic.Int32
+		var wg sync.WaitGroup
+		for range writers {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				reserved, err := repo.ReserveKey(ctx, "racy")
+				assert.NoError(t, err)
+				if reserved {
+					reservedTimes.Add(1)
+				}
+			}()
+		}
+		wg.Wait()
+
+		assert.Equal(t, int32(1), reservedTimes.Load())
+	})
+}
+
+func TestSQLiteRecordRepository_GenerateUniqueKey(t *testing.T) {
+	t.Run("sequential keys survive reopening database", func(t *testing.T) {
+		t.Parallel()
+
+		ctx := context.Background()
+		path := filepath.Join(t.TempDir(), "paste.db")
+
+		db, err := OpenSQLite(ctx, path)
+		require.NoError(t, err)
+		first, err := NewSQLiteRecordRepository(db, config.DefaultCachingConfig{}).GenerateUniqueKey(ctx, objectvalue.KeyStyleSequential, 3, 20)
+		require.NoError(t, err)
+		require.NoError(t, db.Close())
+
+		db, err = OpenSQLite(ctx, path)
+		require.NoError(t, err)
+		defer func() { _ = db.Close() }()
+		second, err := NewSQLiteRecordRepository(db, config.DefaultCachingConfig{}).GenerateUniqueKey(ctx, objectvalue.KeyStyleSequential, 3, 20)
+		require.NoError(t, err)
+
+		assert.Equal(t, objectvalue.RecordKey("001"), first)
+		assert.Equal(t, objectvalue.RecordKey("002"), second)
+	})
+}
+
+func TestSQLiteQuotaRepository(t *testing.T) {
+	t.Run("set quota can be got", func(t *testing.T) {
+		t.Parallel()
+
+		repo := NewSQLiteQuotaRepository(openTestSQLite(t), config.DefaultQuotaConfig{})
+
+		_, err := repo.GetByID(context.Background(), "127.0.0.1")
+		require.True(t, errors.Is(err, domainerrors.ErrQuotaNotFound))
+
+		require.NoError(t, repo.SetByID(context.Background(), "127.0.0.1", aggregate.NewQuota("127.0.0.1", 7)))
+
+		quota, err := repo.GetByID(context.Background(), "127.0.0.1")
+		require.NoError(t, err)
+		assert.Equal(t, uint32(7), quota.Value())
+	})
+}
+
+func TestSQLiteAPIKeyRepository(t *testing.T) {
+	t.Run("set, get and remove apikey", func(t *testing.T) {
+		t.Parallel()
+
+		ctx := context.Background()
+		repo := NewSQLiteAPIKeyRepository(openTestSQLite(t))
+		apikey := aggregate.NewAPIKey(objectvalue.NilAPIKeyID, "secret", true)
+
+		require.NoError(t, repo.SetByID(ctx, "secret", apikey))
+
+		got, err := repo.GetByID(ctx, "secret")
+		require.NoError(t, err)
+		assert.True(t, got.Valid())
+
+		all, err := repo.GetAll(ctx)
+		require.NoError(t, err)
+		assert.Len(t, all, 1)
+
+		require.NoError(t, repo.RemoveByID(ctx, "secret"))
+
+		exists, err := repo.Exists(ctx, "secret")
+		require.NoError(t, err)
+		assert.False(t, exists)
+	})
+}
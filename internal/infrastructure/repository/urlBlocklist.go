@@ -0,0 +1,166 @@
+package repository
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net/netip"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/thek4n/paste.thek4n.ru/internal/domain/logger"
+)
+
+// FileURLBlocklistRepository file implementation of domain interface of url
+// blocklist repository. File contains one domain, ip or cidr per line, blank
+// lines and lines starting with '#' are ignored. Domain blocks its subdomains
+// too. File is reloaded by RunReloader when it is changed.
+type FileURLBlocklistRepository struct {
+	path string
+
+	// modTime and size of last read file are used only by reload.
+	modTime time.Time
+	size    int64
+
+	mu       sync.RWMutex
+	domains  map[string]struct{}
+	prefixes []netip.Prefix
+}
+
+// NewFileURLBlocklistRepository constructor. Returns error if file can not
+// be read or is invalid.
+func NewFileURLBlocklistRepository(path string) (*FileURLBlocklistRepository, error) {
+	r := &FileURLBlocklistRepository{
+		path: path,
+	}
+
+	if err := r.reload(); err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// Blocked returns is host or one of its parent domains blocked. Ip host is
+// blocked if one of blocked cidrs contains it.
+func (r *FileURLBlocklistRepository) Blocked(_ context.Context, host string) (bool, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	host = strings.TrimSuffix(strings.ToLower(host), ".")
+
+	if addr, err := netip.ParseAddr(host); err == nil {
+		addr = addr.Unmap().WithZone("")
+		for _, prefix := range r.prefixes {
+			if prefix.Contains(addr) {
+				return true, nil
+			}
+		}
+		return false, nil
+	}
+
+	for domain := host; domain != ""; {
+		if _, exists := r.domains[domain]; exists {
+			return true, nil
+		}
+
+		_, parent, found := strings.Cut(domain, ".")
+		if !found {
+			break
+		}
+		domain = parent
+	}
+
+	return false, nil
+}
+
+// RunReloader periodically reloads changed file until ctx done. File that
+// can not be read or is invalid is logged and last read blocklist is kept.
+func (r *FileURLBlocklistRepository) RunReloader(ctx context.Context, period time.Duration, lgr logger.Logger) {
+	ticker := time.NewTicker(period)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := r.reload(); err != nil {
+				lgr.Error("Fail to reload url blocklist, previous one is kept", "path", r.path, "error", err)
+			}
+		}
+	}
+}
+
+// reload reads file again if it is changed since last read. Blocklist is
+// replaced only if file is read successfully. Must not be called concurrently.
+func (r *FileURLBlocklistRepository) reload() error {
+	info, err := os.Stat(r.path)
+	if err != nil {
+		return fmt.Errorf("fail to stat url blocklist: %w", err)
+	}
+
+	if info.ModTime().Equal(r.modTime) && info.Size() == r.size {
+		return nil
+	}
+
+	domains, prefixes, err := readURLBlocklist(r.path)
+	if err != nil {
+		return err
+	}
+	r.modTime = info.ModTime()
+	r.size = info.Size()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.domains = domains
+	r.prefixes = prefixes
+
+	return nil
+}
+
+func readURLBlocklist(path string) (map[string]struct{}, []netip.Prefix, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("fail to open url blocklist: %w", err)
+	}
+	defer file.Close()
+
+	domains := make(map[string]struct{})
+	var prefixes []netip.Prefix
+
+	scanner := bufio.NewScanner(file)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		if prefix, err := netip.ParsePrefix(line); err == nil {
+			prefixes = append(prefixes, prefix.Masked())
+			continue
+		}
+
+		if addr, err := netip.ParseAddr(line); err == nil {
+			addr = addr.Unmap().WithZone("")
+			prefixes = append(prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+			continue
+		}
+
+		domain := strings.TrimPrefix(strings.TrimPrefix(line, "*"), ".")
+		domain = strings.TrimSuffix(strings.ToLower(domain), ".")
+		if domain == "" || strings.ContainsAny(domain, "/:*@ \t") {
+			return nil, nil, fmt.Errorf("invalid url blocklist entry '%s' at line %d", line, lineNumber)
+		}
+		domains[domain] = struct{}{}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, nil, fmt.Errorf("fail to read url blocklist: %w", err)
+	}
+
+	return domains, prefixes, nil
+}
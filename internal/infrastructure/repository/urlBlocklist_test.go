@@ -0,0 +1,145 @@
+//go:build unit
+
+package repository
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// errorsCountingLogger counts logged errors.
+type errorsCountingLogger struct {
+	errors atomic.Int32
+}
+
+func (l *errorsCountingLogger) Debug(string, ...any) {}
+func (l *errorsCountingLogger) Info(string, ...any)  {}
+func (l *errorsCountingLogger) Warn(string, ...any)  {}
+func (l *errorsCountingLogger) Error(string, ...any) { l.errors.Add(1) }
+
+func TestFileURLBlocklistRepository(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	writeBlocklist := func(t *testing.T, path, content string) {
+		t.Helper()
+		require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	}
+
+	t.Run("blocks domains with subdomains, ips and cidrs", func(t *testing.T) {
+		t.Parallel()
+
+		path := filepath.Join(t.TempDir(), "blocklist")
+		writeBlocklist(t, path, "# phishing\n\nEvil.example.\n*.tracker.example\n10.0.0.0/8\n192.0.2.1\n2001:db8::/32\n")
+
+		blocklist, err := NewFileURLBlocklistRepository(path)
+		require.NoError(t, err)
+
+		tests := map[string]bool{
+			"evil.example":        true,
+			"www.evil.example":    true,
+			"notevil.example":     false,
+			"example":             false,
+			"a.b.tracker.example": true,
+			"tracker.example":     true,
+			"10.1.2.3":            true,
+			"11.0.0.1":            false,
+			"192.0.2.1":           true,
+			"192.0.2.2":           false,
+			"::ffff:10.0.0.1":     true,
+			"2001:db8::1":         true,
+			"2001:db9::1":         false,
+		}
+		for host, expected := range tests {
+			blocked, err := blocklist.Blocked(ctx, host)
+			require.NoError(t, err)
+			assert.Equal(t, expected, blocked, host)
+		}
+	})
+
+	t.Run("changed file is reloaded", func(t *testing.T) {
+		t.Parallel()
+
+		path := filepath.Join(t.TempDir(), "blocklist")
+		writeBlocklist(t, path, "evil.example\n")
+
+		blocklist, err := NewFileURLBlocklistRepository(path)
+		require.NoError(t, err)
+
+		reloaderCtx, cancel := context.WithCancel(ctx)
+		t.Cleanup(cancel)
+		go blocklist.RunReloader(reloaderCtx, 10*time.Millisecond, &errorsCountingLogger{})
+
+		writeBlocklist(t, path, "another.example\nbad.example\n")
+
+		require.Eventually(t, func() bool {
+			blocked, err := blocklist.Blocked(ctx, "bad.example")
+			require.NoError(t, err)
+			return blocked
+		}, time.Second, 10*time.Millisecond)
+
+		blocked, err := blocklist.Blocked(ctx, "evil.example")
+		require.NoError(t, err)
+		assert.False(t, blocked)
+	})
+
+	t.Run("invalid file replacing valid one is logged and previous one is kept", func(t *testing.T) {
+		t.Parallel()
+
+		path := filepath.Join(t.TempDir(), "blocklist")
+		writeBlocklist(t, path, "evil.example\n")
+
+		blocklist, err := NewFileURLBlocklistRepository(path)
+		require.NoError(t, err)
+
+		lgr := &errorsCountingLogger{}
+		reloaderCtx, cancel := context.WithCancel(ctx)
+		t.Cleanup(cancel)
+		go blocklist.RunReloader(reloaderCtx, 10*time.Millisecond, lgr)
+
+		writeBlocklist(t, path, "bad.example\nhttps://bad.example/\n")
+
+		require.Eventually(t, func() bool {
+			return lgr.errors.Load() > 0
+		}, time.Second, 10*time.Millisecond)
+
+		blocked, err := blocklist.Blocked(ctx, "evil.example")
+		require.NoError(t, err)
+		assert.True(t, blocked)
+
+		blocked, err = blocklist.Blocked(ctx, "bad.example")
+		require.NoError(t, err)
+		assert.False(t, blocked)
+
+		require.NoError(t, os.Remove(path))
+		errors := lgr.errors.Load()
+		require.Eventually(t, func() bool {
+			return lgr.errors.Load() > errors
+		}, time.Second, 10*time.Millisecond)
+
+		blocked, err = blocklist.Blocked(ctx, "evil.example")
+		require.NoError(t, err)
+		assert.True(t, blocked, "removed file keeps previous blocklist")
+	})
+
+	t.Run("invalid file is error", func(t *testing.T) {
+		t.Parallel()
+
+		path := filepath.Join(t.TempDir(), "blocklist")
+		writeBlocklist(t, path, "evil.example\nhttps://bad.example/\n")
+
+		_, err := NewFileURLBlocklistRepository(path)
+		require.Error(t, err)
+
+		_, err = NewFileURLBlocklistRepository(filepath.Join(t.TempDir(), "missing"))
+		require.Error(t, err)
+	})
+}
@@ -0,0 +1,288 @@
+package webhandlers
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"log/slog"
+	"mime"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+
+	"github.com/thek4n/paste.thek4n.ru/internal/application/service"
+	"github.com/thek4n/paste.thek4n.ru/internal/domain/domainerrors"
+	"github.com/thek4n/paste.thek4n.ru/internal/domain/objectvalue"
+	"github.com/thek4n/paste.thek4n.ru/pkg/bundlearchive"
+)
+
+// maxBundlePathLength max length of bundle file path in bytes.
+const maxBundlePathLength = 1024
+
+// reservedBundlePaths paths served by record routes, files with them
+// would be unreachable.
+var reservedBundlePaths = []string{objectvalue.BundleArchiveName, "clicks", "info", "stats", "view"}
+
+type bundlePage struct {
+	Title      string
+	ArchiveURL string
+	Files      []bundlePageFile
+}
+
+type bundlePageFile struct {
+	Name string
+	URL  string
+	Size int64
+}
+
+// newBundle returns files of bundle and its body concatenating their
+// contents. Files are taken from form with several files or from archive
+// uploaded as body.
+func (app *Handlers) newBundle(form cacheForm, body []byte, maxBodySize int64) (objectvalue.BundleFiles, []byte, error) {
+	formFiles := form.files
+
+	if len(formFiles) == 0 {
+		archiveFiles, err := bundlearchive.Read(body, maxBodySize, app.Config.MaxBundleFiles())
+		if errors.Is(err, bundlearchive.ErrTooLarge) {
+			return nil, nil, domainerrors.ErrBodyTooLarge
+		}
+		if errors.Is(err, bundlearchive.ErrUnknownFormat) {
+			return nil, nil, &cacheError{Message: "Bundle must be zip or tar archive", StatusCode: http.StatusBadRequest, Err: err}
+		}
+		if err != nil {
+			return nil, nil, &cacheError{Message: "Invalid bundle archive", StatusCode: http.StatusBadRequest, Err: err}
+		}
+
+		for _, file := range archiveFiles {
+			formFiles = append(formFiles, cacheFormFile{name: file.Name, content: file.Content})
+		}
+	}
+
+	if len(formFiles) == 0 {
+		return nil, nil, &cacheError{Message: "Bundle has no files", StatusCode: http.StatusBadRequest}
+	}
+
+	files := make(objectvalue.BundleFiles, 0, len(formFiles))
+	var bundleBody bytes.Buffer
+
+	for _, file := range formFiles {
+		if err := validateBundlePath(file.name); err != nil {
+			return nil, nil, &cacheError{Message: fmt.Sprintf("Invalid bundle file name '%s'", file.name), StatusCode: http.StatusBadRequest, Err: err}
+		}
+
+		contentType := ""
+		if file.contentType != "" && file.contentType != "application/octet-stream" {
+			var err error
+			contentType, err = normalizeContentType(file.contentType)
+			if err != nil {
+				return nil, nil, &cacheError{Message: fmt.Sprintf("Invalid content type of bundle file '%s'", file.name), StatusCode: http.StatusBadRequest, Err: err}
+			}
+		}
+
+		files = append(files, objectvalue.NewBundleFile(file.name, contentType, int64(len(file.content))))
+		bundleBody.Write(file.content)
+	}
+
+	return files, bundleBody.Bytes(), nil
+}
+
+// validateBundlePath checks that path of bundle file is relative and
+// has no empty, "." and ".." segments, so it is served under record URL.
+func validateBundlePath(name string) error {
+	if name == "" || len(name) > maxBundlePathLength || !utf8.ValidString(name) {
+		return fmt.Errorf("invalid path")
+	}
+
+	if slices.Contains(reservedBundlePaths, name) {
+		return fmt.Errorf("path '%s' is reserved", name)
+	}
+
+	for segment := range strings.SplitSeq(name, "/") {
+		if segment == "" || segment == "." || segment == ".." {
+			return fmt.Errorf("path contains empty or relative segment")
+		}
+	}
+
+	for _, char := range name {
+		if char == '\\' || unicode.IsControl(char) {
+			return fmt.Errorf("path contains illegal char")
+		}
+	}
+
+	return nil
+}
+
+// serveBundleListing answers with list of bundle files. Browsers get page
+// with links, other clients get URL of file per line.
+func serveBundleListing(w http.ResponseWriter, r *http.Request, key string, record service.GetBodyAnswer, logger *slog.Logger) {
+	baseURL := recordURL(r, objectvalue.RecordKey(key))
+
+	page := bundlePage{
+		Title:      key,
+		ArchiveURL: baseURL + objectvalue.BundleArchiveName,
+		Files:      make([]bundlePageFile, 0, len(record.Files)),
+	}
+	for _, file := range record.Files {
+		page.Files = append(page.Files, bundlePageFile{
+			Name: file.Name(),
+			URL:  baseURL + escapeBundlePath(file.Name()),
+			Size: file.Size(),
+		})
+	}
+
+	w.Header().Set("Vary", "Accept")
+
+	if acceptsHTML(r) {
+		writeTemplatePage(w, "bundle.tmpl", page, http.StatusOK, logger)
+		logger.Info(
+			"Listed bundle",
+		)
+		return
+	}
+
+	var buf bytes.Buffer
+	for _, file := range page.Files {
+		buf.WriteString(file.URL + "\n")
+	}
+
+	w.Header().Set("content-type", "text/plain; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	_, writeErr := w.Write(buf.Bytes())
+	if writeErr != nil {
+		logger.Error(
+			"Fail to answer",
+			"error", writeErr,
+			"answer_code", http.StatusInternalServerError,
+		)
+		return
+	}
+	logger.Info(
+		"Listed bundle",
+	)
+}
+
+// escapeBundlePath escapes segments of bundle file path for URL.
+func escapeBundlePath(name string) string {
+	segments := strings.Split(name, "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+	return strings.Join(segments, "/")
+}
+
+// GetArchive handle getting all files of bundle as zip archive. Archive
+// consumes bundle like getting of its single file.
+func (app *Handlers) GetArchive(w http.ResponseWriter, r *http.Request) {
+	remoteAddr := getClientIP(r)
+	requestUUID := uuid.NewString()
+
+	logger := app.Logger.With(
+		"source_ip", remoteAddr,
+		"request_id", requestUUID,
+	)
+
+	logger.Debug(
+		"Start getting bundle archive",
+	)
+
+	key := r.PathValue("key")
+
+	logger = logger.With(
+		"key", key,
+	)
+
+	if isLinkPreview(r) && app.servePreview(w, key, logger) {
+		return
+	}
+
+	app.setServingHeaders(w)
+
+	password := getPassword(w, r)
+
+	record, err := app.getService.GetBody(objectvalue.RecordKey(key), objectvalue.GetRequestParams{
+		Password:  password,
+		Confirmed: r.Method == http.MethodPost,
+		Archive:   true,
+		Click:     getClickParams(r),
+	})
+	if err != nil {
+		if handlePasswordError(w, r, err, password, logger) {
+			return
+		}
+		if handleRevealError(w, r, err, logger) {
+			return
+		}
+		if errors.Is(err, domainerrors.ErrRecordNotFound) || errors.Is(err, domainerrors.ErrRecordCounterExhausted) || errors.Is(err, domainerrors.ErrRecordExpired) {
+			w.WriteHeader(http.StatusNotFound)
+
+			_, writeErr := w.Write([]byte("404 Not Found"))
+			if writeErr != nil {
+				logger.Error(
+					"Fail to answer on getting bundle archive",
+					"error", writeErr,
+					"answer_code", http.StatusInternalServerError,
+				)
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			return
+		}
+		logger.Error(
+			"Fail to get bundle archive",
+			"error", err,
+			"answer_code", http.StatusInternalServerError,
+		)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	archiveFiles := make([]bundlearchive.File, 0, len(record.Files))
+	var offset int64
+	for _, file := range record.Files {
+		end := offset + file.Size()
+		if end > int64(len(record.Body)) {
+			logger.Error(
+				"Bundle file is out of body",
+				"file", file.Name(),
+				"answer_code", http.StatusInternalServerError,
+			)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		archiveFiles = append(archiveFiles, bundlearchive.File{Name: file.Name(), Content: record.Body[offset:end]})
+		offset = end
+	}
+
+	var buf bytes.Buffer
+	if err := bundlearchive.WriteZip(&buf, archiveFiles); err != nil {
+		logger.Error(
+			"Fail to write bundle archive",
+			"error", err,
+			"answer_code", http.StatusInternalServerError,
+		)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	setRecordStateHeaders(w, record)
+	w.Header().Set("content-type", "application/zip")
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": key + ".zip"}))
+	w.WriteHeader(http.StatusOK)
+	_, writeErr := w.Write(buf.Bytes())
+	if writeErr != nil {
+		logger.Error(
+			"Fail to answer",
+			"error", writeErr,
+			"answer_code", http.StatusInternalServerError,
+		)
+		return
+	}
+	logger.Info(
+		"Got bundle archive",
+	)
+}
@@ -1,29 +1,45 @@
 package webhandlers
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
-	"io"
 	"log/slog"
 	"math"
+	"mime"
 	"net/http"
 	"net/url"
 	"strconv"
 	"strings"
 	"time"
+	"unicode"
+	"unicode/utf8"
 
 	"github.com/google/uuid"
 
+	"github.com/thek4n/paste.thek4n.ru/internal/application/service"
 	"github.com/thek4n/paste.thek4n.ru/internal/domain/domainerrors"
 	"github.com/thek4n/paste.thek4n.ru/internal/domain/objectvalue"
 )
 
+// maxFilenameLength max length of filename in bytes, common for file systems.
+const maxFilenameLength = 255
+
 type cacheRequestParams struct {
 	APIKey       string
 	RequestedKey string
 	TTL          time.Duration
 	Length       int
 	Disposable   int
+	KeyStyle     objectvalue.KeyStyle
+	ContentType  string
+	Filename     string
 	IsURL        bool
+	Sliding      bool
+	Encrypted    bool
+	Reveal       bool
+	Bundle       bool
+	Redirect     objectvalue.Redirect
 }
 
 type cacheRequestAPIKey struct {
@@ -41,7 +57,10 @@ type cacheRequest struct {
 }
 
 type cacheError struct {
-	Message    string
+	Message string
+	// Code is stable machine readable error code. If empty, it is
+	// derived from StatusCode.
+	Code       errorCode
 	Err        error
 	StatusCode int
 }
@@ -63,26 +82,100 @@ func (app *Handlers) Cache(w http.ResponseWriter, r *http.Request) {
 	logger := app.Logger.With("source_ip", req.SourceIP, "request_id", req.ID)
 	logger.Debug("Start caching key")
 
-	req.Params, err = app.parseAndValidateRequestParams(r.URL.Query())
+	query := r.URL.Query()
+
+	// form is read before options are validated, because options may be
+	// its fields. Apikey raising body size limit must be passed in query.
+	var form cacheForm
+	isForm := isFormRequest(r)
+	if isForm {
+		formMaxBodySize, err := app.cacheService.MaxBodySize(query.Get("apikey"))
+		if err != nil {
+			handleCacheError(w, r, err, logger)
+			return
+		}
+
+		form, err = readCacheForm(w, r, formMaxBodySize)
+		if err != nil {
+			handleCacheError(w, r, err, logger)
+			return
+		}
+
+		query = form.mergeOptions(query)
+	}
+
+	req.Params, err = app.parseAndValidateRequestParams(query)
 	if err != nil {
-		handleCacheError(w, err, logger)
+		handleCacheError(w, r, err, logger)
 		return
 	}
 
-	body, err := readRequestBody(r)
+	if req.Params.ContentType == "" {
+		req.Params.ContentType = getRequestContentType(r)
+	}
+
+	maxBodySize, err := app.cacheService.MaxBodySize(req.Params.APIKey)
 	if err != nil {
-		handleCacheError(w, err, logger)
+		handleCacheError(w, r, err, logger)
 		return
 	}
 
+	password := r.Header.Get(passwordHeader)
+	if password == "" {
+		password = form.options.Get(formPasswordField)
+	}
+
+	var body []byte
+	bodyEncoding := objectvalue.BodyEncodingIdentity
+	var bodyLen int64
+	switch {
+	case isForm:
+		body = form.content
+		if int64(len(body)) > maxBodySize {
+			handleCacheError(w, r, domainerrors.ErrBodyTooLarge, logger)
+			return
+		}
+
+	// url is checked, protected body is encrypted and bundle is split
+	// whole, so they are read plain
+	case req.Params.IsURL || req.Params.Bundle || password != "":
+		body, err = readRequestBody(w, r, maxBodySize)
+		if err != nil {
+			handleCacheError(w, r, err, logger)
+			return
+		}
+
+	default:
+		encoded, err := app.readEncodedRequestBody(r, maxBodySize)
+		if err != nil {
+			handleCacheError(w, r, err, logger)
+			return
+		}
+		body, bodyEncoding, bodyLen = encoded.Data(), encoded.Encoding(), encoded.Size()
+	}
+
+	// form with several files is bundle regardless of parameter
+	var files objectvalue.BundleFiles
+	if req.Params.Bundle || len(form.files) > 0 {
+		files, body, err = app.newBundle(form, body, maxBodySize)
+		if err != nil {
+			handleCacheError(w, r, err, logger)
+			return
+		}
+	}
+
+	if bodyEncoding == objectvalue.BodyEncodingIdentity {
+		bodyLen = int64(len(body))
+	}
+
 	if req.Params.IsURL && !validateURL(string(body)) {
-		handleCacheError(w, &cacheError{Message: "Invalid 'url'", StatusCode: http.StatusBadRequest}, logger)
+		handleCacheError(w, r, &cacheError{Message: "Invalid 'url'", StatusCode: http.StatusBadRequest}, logger)
 		return
 	}
 
 	paramsDisposable := req.Params.Disposable
 	if paramsDisposable < 0 || paramsDisposable > math.MaxUint8 {
-		handleCacheError(w, &cacheError{Message: fmt.Sprintf("disposable counter more then %d or less then 0", math.MaxUint8), StatusCode: http.StatusBadRequest}, logger)
+		handleCacheError(w, r, &cacheError{Message: fmt.Sprintf("disposable counter more then %d or less then 0", math.MaxUint8), StatusCode: http.StatusBadRequest}, logger)
 		return
 	}
 
@@ -90,7 +183,7 @@ func (app *Handlers) Cache(w http.ResponseWriter, r *http.Request) {
 
 	paramsLength := req.Params.Length
 	if paramsLength < 0 || paramsLength > math.MaxUint8 {
-		handleCacheError(w, &cacheError{Message: fmt.Sprintf("requested key length more then %d or less then 0", math.MaxUint8), StatusCode: http.StatusBadRequest}, logger)
+		handleCacheError(w, r, &cacheError{Message: fmt.Sprintf("requested key length more then %d or less then 0", math.MaxUint8), StatusCode: http.StatusBadRequest}, logger)
 		return
 	}
 
@@ -102,29 +195,47 @@ func (app *Handlers) Cache(w http.ResponseWriter, r *http.Request) {
 		SourceIP:           req.SourceIP,
 		Body:               body,
 		TTL:                req.Params.TTL,
-		BodyLen:            int64(len(body)),
+		BodyLen:            bodyLen,
+		BodyEncoding:       bodyEncoding,
 		RequestedKeyLength: paramsLengthChecked,
+		KeyStyle:           req.Params.KeyStyle,
+		ContentType:        req.Params.ContentType,
+		Filename:           req.Params.Filename,
+		Password:           password,
 		Disposable:         paramsDisposableChecked,
 		IsURL:              req.Params.IsURL,
+		Sliding:            req.Params.Sliding,
+		Encrypted:          req.Params.Encrypted,
+		Reveal:             req.Params.Reveal,
+		Files:              files,
+		Redirect:           req.Params.Redirect,
+		Host:               r.Host,
 	}
 
-	recordkey, err := app.cacheService.Serve(params)
+	answer, err := app.cacheService.Serve(params)
 	if err != nil {
-		handleCacheError(w, err, logger)
+		handleCacheError(w, r, err, logger)
 		return
 	}
 
-	if err := sendSuccessResponse(w, r, string(recordkey)); err != nil {
-		handleCacheError(w, err, logger)
+	if isForm && acceptsHTML(r) {
+		sendCreatedPage(w, r, answer, logger)
+	} else if err := sendSuccessResponse(w, r, answer); err != nil {
+		handleCacheError(w, r, err, logger)
 		return
 	}
 
 	logger.Info("Set key",
-		"key", string(recordkey),
-		"body_size", len(req.Body),
+		"key", string(answer.Key),
+		"body_size", bodyLen,
 		"ttl", req.Params.TTL,
 		"disposable", req.Params.Disposable,
 		"isURL", req.Params.IsURL,
+		"encrypted", req.Params.Encrypted,
+		"reveal", req.Params.Reveal,
+		"bundle_files", len(files),
+		"redirect_status", int(req.Params.Redirect.Status()),
+		"content_type", req.Params.ContentType,
 	)
 }
 
@@ -152,6 +263,66 @@ func (app *Handlers) parseAndValidateRequestParams(urlQuery url.Values) (cacheRe
 		return p, &cacheError{Message: "Invalid 'url' parameter", StatusCode: http.StatusBadRequest}
 	}
 
+	p.Sliding, err = getSliding(urlQuery)
+	if err != nil {
+		return p, &cacheError{Message: "Invalid 'sliding' parameter", StatusCode: http.StatusBadRequest}
+	}
+
+	p.Encrypted, err = getEncrypted(urlQuery)
+	if err != nil {
+		return p, &cacheError{Message: "Invalid 'encrypted' parameter", StatusCode: http.StatusBadRequest}
+	}
+
+	if p.IsURL && p.Encrypted {
+		return p, &cacheError{Message: "Encrypted url is not supported", StatusCode: http.StatusBadRequest}
+	}
+
+	redirectStatus, err := getRedirectStatus(urlQuery)
+	if err != nil {
+		return p, &cacheError{Message: "Invalid 'redirect' parameter", StatusCode: http.StatusBadRequest}
+	}
+
+	forwardQuery, err := getForward(urlQuery, "forwardquery")
+	if err != nil {
+		return p, &cacheError{Message: "Invalid 'forwardquery' parameter", StatusCode: http.StatusBadRequest}
+	}
+
+	forwardPath, err := getForward(urlQuery, "forwardpath")
+	if err != nil {
+		return p, &cacheError{Message: "Invalid 'forwardpath' parameter", StatusCode: http.StatusBadRequest}
+	}
+
+	p.Redirect = objectvalue.NewRedirect(redirectStatus, forwardQuery, forwardPath)
+
+	if !p.IsURL && p.Redirect != (objectvalue.Redirect{}) {
+		return p, &cacheError{Message: "Redirect parameters are supported only for url", StatusCode: http.StatusBadRequest}
+	}
+
+	p.Reveal, err = getReveal(urlQuery)
+	if err != nil {
+		return p, &cacheError{Message: "Invalid 'reveal' parameter", StatusCode: http.StatusBadRequest}
+	}
+
+	p.Bundle, err = getBundle(urlQuery)
+	if err != nil {
+		return p, &cacheError{Message: "Invalid 'bundle' parameter", StatusCode: http.StatusBadRequest}
+	}
+
+	p.KeyStyle, err = getKeyStyle(urlQuery)
+	if err != nil {
+		return p, &cacheError{Message: "Invalid 'keystyle' parameter", StatusCode: http.StatusBadRequest}
+	}
+
+	p.ContentType, err = getContentType(urlQuery)
+	if err != nil {
+		return p, &cacheError{Message: "Invalid 'type' parameter", StatusCode: http.StatusBadRequest}
+	}
+
+	p.Filename, err = getFilename(urlQuery)
+	if err != nil {
+		return p, &cacheError{Message: "Invalid 'filename' parameter", StatusCode: http.StatusBadRequest}
+	}
+
 	p.RequestedKey, err = app.getRequestedKey(urlQuery)
 	if err != nil {
 		return p, &cacheError{Message: err.Error(), StatusCode: http.StatusBadRequest}
@@ -162,9 +333,33 @@ func (app *Handlers) parseAndValidateRequestParams(urlQuery url.Values) (cacheRe
 	return p, nil
 }
 
-func readRequestBody(r *http.Request) ([]byte, error) {
-	body, err := io.ReadAll(r.Body)
-	if err != nil && err != io.EOF {
+// readEncodedRequestBody reads body up to limit compressing it while
+// reading, so only compressed body is held in memory. Reading stops as
+// soon as limit exceeded.
+func (app *Handlers) readEncodedRequestBody(r *http.Request, limit int64) (objectvalue.EncodedBody, error) {
+	if r.ContentLength > limit {
+		return objectvalue.EncodedBody{}, domainerrors.ErrBodyTooLarge
+	}
+
+	return app.cacheService.ReadBody(r.Body, limit)
+}
+
+// readRequestBody reads plain body up to limit. Reading stops as soon as
+// limit exceeded, so client can not make server buffer arbitrary large body.
+func readRequestBody(w http.ResponseWriter, r *http.Request, limit int64) ([]byte, error) {
+	if r.ContentLength > limit {
+		return nil, domainerrors.ErrBodyTooLarge
+	}
+
+	buf := bytes.NewBuffer(make([]byte, 0, max(r.ContentLength, 0)+bytes.MinRead))
+
+	_, err := buf.ReadFrom(http.MaxBytesReader(w, r.Body, limit))
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			return nil, domainerrors.ErrBodyTooLarge
+		}
+
 		return nil, &cacheError{
 			Message:    "Failed to read body",
 			StatusCode: http.StatusInternalServerError,
@@ -172,16 +367,44 @@ func readRequestBody(r *http.Request) ([]byte, error) {
 		}
 	}
 
-	return body, nil
+	return buf.Bytes(), nil
+}
+
+type createdResponse struct {
+	Key string `json:"key"`
+	URL string `json:"url"`
+	// ExpiresAt is omitted if record is eternal.
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	Eternal   bool       `json:"eternal"`
+	// RemainingReads is omitted if reads are not limited.
+	RemainingReads *uint8 `json:"remaining_reads,omitempty"`
+	KeyLength      int    `json:"key_length"`
+	Privileged     bool   `json:"privileged"`
+	OwnerToken     string `json:"owner_token"`
 }
 
-func sendSuccessResponse(w http.ResponseWriter, r *http.Request, key string) error {
+// sendSuccessResponse answers with URL of created record. Clients accepting
+// json get createdResponse.
+func sendSuccessResponse(w http.ResponseWriter, r *http.Request, answer service.CacheAnswer) error {
 	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Expose-Headers", ownerTokenHeader)
+	w.Header().Set(ownerTokenHeader, string(answer.OwnerToken))
+
+	if acceptsJSON(r) {
+		if err := sendJSONResponse(w, newCreatedResponse(r, answer), http.StatusCreated); err != nil {
+			return &cacheError{
+				Message:    "Failed to send response",
+				StatusCode: http.StatusInternalServerError,
+				Err:        err,
+			}
+		}
+		return nil
+	}
+
 	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
 	w.WriteHeader(http.StatusCreated)
 
-	proto := detectProto(r)
-	if _, err := fmt.Fprintf(w, "%s://%s/%s/", proto, r.Host, key); err != nil {
+	if _, err := fmt.Fprint(w, recordURL(r, answer.Key)); err != nil {
 		return &cacheError{
 			Message:    "Failed to send response",
 			StatusCode: http.StatusInternalServerError,
@@ -192,11 +415,59 @@ func sendSuccessResponse(w http.ResponseWriter, r *http.Request, key string) err
 	return nil
 }
 
-func handleCacheError(w http.ResponseWriter, err error, logger *slog.Logger) {
+func newCreatedResponse(r *http.Request, answer service.CacheAnswer) createdResponse {
+	resp := createdResponse{
+		Key:        string(answer.Key),
+		URL:        recordURL(r, answer.Key),
+		Eternal:    answer.ExpiresAt.IsZero(),
+		KeyLength:  len(answer.Key),
+		Privileged: answer.Privileged,
+		OwnerToken: string(answer.OwnerToken),
+	}
+	if !answer.ExpiresAt.IsZero() {
+		expiresAt := answer.ExpiresAt.UTC()
+		resp.ExpiresAt = &expiresAt
+	}
+	if answer.ReadsLimited {
+		resp.RemainingReads = &answer.RemainingReads
+	}
+
+	return resp
+}
+
+type createdPage struct {
+	URL        string
+	OwnerToken string
+}
+
+// sendCreatedPage answers browser form post with page linking created
+// record. Page is shown instead of redirect, so record with limited reads
+// is not consumed by its author.
+func sendCreatedPage(w http.ResponseWriter, r *http.Request, answer service.CacheAnswer, logger *slog.Logger) {
+	page := createdPage{
+		URL:        recordURL(r, answer.Key),
+		OwnerToken: string(answer.OwnerToken),
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set(ownerTokenHeader, page.OwnerToken)
+	writeTemplatePage(w, "created.tmpl", page, http.StatusCreated, logger)
+}
+
+// recordURL returns absolute URL of record.
+func recordURL(r *http.Request, key objectvalue.RecordKey) string {
+	return fmt.Sprintf("%s://%s/%s/", detectProto(r), r.Host, key)
+}
+
+// handleCacheError answers with error. Domain errors are mapped to status
+// and stable error code. Clients accepting json get problem details
+// (RFC 9457), others get plain text message.
+func handleCacheError(w http.ResponseWriter, r *http.Request, err error, logger *slog.Logger) {
 	switch err {
 	case domainerrors.ErrQuotaExhausted:
 		err = &cacheError{
 			Message:    "Quota exhausted",
+			Code:       errorCodeQuotaExhausted,
 			StatusCode: http.StatusForbidden,
 			Err:        err,
 		}
@@ -204,6 +475,7 @@ func handleCacheError(w http.ResponseWriter, err error, logger *slog.Logger) {
 	case domainerrors.ErrBodyTooLarge:
 		err = &cacheError{
 			Message:    "Body too large",
+			Code:       errorCodeBodyTooLarge,
 			StatusCode: http.StatusRequestEntityTooLarge,
 			Err:        err,
 		}
@@ -211,6 +483,7 @@ func handleCacheError(w http.ResponseWriter, err error, logger *slog.Logger) {
 	case domainerrors.ErrRequestedKeyExists:
 		err = &cacheError{
 			Message:    "Requested key exists",
+			Code:       errorCodeKeyExists,
 			StatusCode: http.StatusConflict,
 			Err:        err,
 		}
@@ -218,6 +491,7 @@ func handleCacheError(w http.ResponseWriter, err error, logger *slog.Logger) {
 	case domainerrors.ErrInvalidTTL:
 		err = &cacheError{
 			Message:    "Invalid TTL",
+			Code:       errorCodeInvalidTTL,
 			StatusCode: http.StatusBadRequest,
 			Err:        err,
 		}
@@ -225,6 +499,7 @@ func handleCacheError(w http.ResponseWriter, err error, logger *slog.Logger) {
 	case domainerrors.ErrInvalidRequestedKeyLength:
 		err = &cacheError{
 			Message:    "Invalid key length",
+			Code:       errorCodeInvalidKeyLength,
 			StatusCode: http.StatusBadRequest,
 			Err:        err,
 		}
@@ -232,13 +507,79 @@ func handleCacheError(w http.ResponseWriter, err error, logger *slog.Logger) {
 	case domainerrors.ErrInvalidRequestedKey:
 		err = &cacheError{
 			Message:    "Invalid requested key",
+			Code:       errorCodeInvalidKey,
 			StatusCode: http.StatusBadRequest,
 			Err:        err,
 		}
 
+	case domainerrors.ErrKeyStyleNotAllowed:
+		err = &cacheError{
+			Message:    "Key style is not allowed",
+			Code:       errorCodeKeyStyleNotAllowed,
+			StatusCode: http.StatusForbidden,
+			Err:        err,
+		}
+
+	case domainerrors.ErrInvalidURL:
+		err = &cacheError{
+			Message:    "Invalid 'url'",
+			Code:       errorCodeInvalidURL,
+			StatusCode: http.StatusBadRequest,
+			Err:        err,
+		}
+
+	case domainerrors.ErrURLNotAllowed:
+		err = &cacheError{
+			Message:    "Url is not allowed",
+			Code:       errorCodeURLNotAllowed,
+			StatusCode: http.StatusUnprocessableEntity,
+			Err:        err,
+		}
+
+	case domainerrors.ErrInvalidBundle:
+		err = &cacheError{
+			Message:    "Invalid bundle",
+			Code:       errorCodeInvalidBundle,
+			StatusCode: http.StatusBadRequest,
+			Err:        err,
+		}
+
+	case domainerrors.ErrNotOwner:
+		err = &cacheError{
+			Message:    "Forbidden",
+			Code:       errorCodeNotOwner,
+			StatusCode: http.StatusForbidden,
+			Err:        err,
+		}
+
+	case domainerrors.ErrWrongPassword:
+		err = &cacheError{
+			Message:    "Wrong password",
+			Code:       errorCodeWrongPassword,
+			StatusCode: http.StatusUnauthorized,
+			Err:        err,
+		}
+
+	case domainerrors.ErrPasswordAttemptsExhausted:
+		err = &cacheError{
+			Message:    "Too many password attempts",
+			Code:       errorCodePasswordAttemptsExhausted,
+			StatusCode: http.StatusTooManyRequests,
+			Err:        err,
+		}
+
+	case domainerrors.ErrRevealNotConfirmed:
+		err = &cacheError{
+			Message:    "Reading must be confirmed",
+			Code:       errorCodeRevealNotConfirmed,
+			StatusCode: http.StatusPreconditionRequired,
+			Err:        err,
+		}
+
 	case domainerrors.ErrNonAuthorized:
 		err = &cacheError{
 			Message:    "Unauthorized",
+			Code:       errorCodeUnauthorized,
 			StatusCode: http.StatusUnauthorized,
 			Err:        err,
 		}
@@ -246,6 +587,7 @@ func handleCacheError(w http.ResponseWriter, err error, logger *slog.Logger) {
 	case domainerrors.ErrRecordNotFound:
 		err = &cacheError{
 			Message:    "Not found",
+			Code:       errorCodeNotFound,
 			StatusCode: http.StatusNotFound,
 			Err:        err,
 		}
@@ -253,6 +595,7 @@ func handleCacheError(w http.ResponseWriter, err error, logger *slog.Logger) {
 	case domainerrors.ErrRecordCounterExhausted:
 		err = &cacheError{
 			Message:    "Not found",
+			Code:       errorCodeNotFound,
 			StatusCode: http.StatusNotFound,
 			Err:        err,
 		}
@@ -260,6 +603,7 @@ func handleCacheError(w http.ResponseWriter, err error, logger *slog.Logger) {
 	case domainerrors.ErrRecordExpired:
 		err = &cacheError{
 			Message:    "Not found",
+			Code:       errorCodeNotFound,
 			StatusCode: http.StatusNotFound,
 			Err:        err,
 		}
@@ -267,6 +611,7 @@ func handleCacheError(w http.ResponseWriter, err error, logger *slog.Logger) {
 	case domainerrors.ErrAPIKeyNotFound:
 		err = &cacheError{
 			Message:    "Forbidden",
+			Code:       errorCodeAPIKeyNotFound,
 			StatusCode: http.StatusUnauthorized,
 			Err:        err,
 		}
@@ -274,6 +619,7 @@ func handleCacheError(w http.ResponseWriter, err error, logger *slog.Logger) {
 	case domainerrors.ErrQuotaNotFound:
 		err = &cacheError{
 			Message:    "Internal server error",
+			Code:       errorCodeInternal,
 			StatusCode: http.StatusInternalServerError,
 			Err:        err,
 		}
@@ -281,6 +627,7 @@ func handleCacheError(w http.ResponseWriter, err error, logger *slog.Logger) {
 	case domainerrors.ErrAPIKeyInvalid:
 		err = &cacheError{
 			Message:    "Unauthorized",
+			Code:       errorCodeAPIKeyInvalid,
 			StatusCode: http.StatusUnauthorized,
 			Err:        err,
 		}
@@ -290,6 +637,7 @@ func handleCacheError(w http.ResponseWriter, err error, logger *slog.Logger) {
 	if !ok {
 		cacheErr = &cacheError{
 			Message:    "Internal server error",
+			Code:       errorCodeInternal,
 			StatusCode: http.StatusInternalServerError,
 			Err:        err,
 		}
@@ -299,6 +647,16 @@ func handleCacheError(w http.ResponseWriter, err error, logger *slog.Logger) {
 		)
 	}
 
+	if acceptsJSON(r) {
+		if err := sendProblemResponse(w, r, cacheErr); err != nil {
+			logger.Error("Error on answer error",
+				"error", err,
+				"answer_code", http.StatusInternalServerError,
+			)
+		}
+		return
+	}
+
 	w.WriteHeader(cacheErr.StatusCode)
 	if cacheErr.Message != "" {
 		if _, err := fmt.Fprint(w, cacheErr.Message); err != nil {
@@ -403,6 +761,86 @@ func (app *Handlers) getLength(v url.Values) (int, error) {
 	return length, nil
 }
 
+func getKeyStyle(v url.Values) (objectvalue.KeyStyle, error) {
+	keyStyle := objectvalue.KeyStyle(v.Get("keystyle"))
+
+	if keyStyle == "" {
+		return "", nil
+	}
+
+	if !keyStyle.Valid() {
+		return "", fmt.Errorf("unknown key style '%s'", keyStyle)
+	}
+
+	return keyStyle, nil
+}
+
+func getContentType(v url.Values) (string, error) {
+	contentType := v.Get("type")
+
+	if contentType == "" {
+		return "", nil
+	}
+
+	return normalizeContentType(contentType)
+}
+
+// getRequestContentType returns declared Content-Type of request body.
+// Form content types are ignored, because clients like curl send them
+// by default for any data.
+func getRequestContentType(r *http.Request) string {
+	contentType, err := normalizeContentType(r.Header.Get("Content-Type"))
+	if err != nil {
+		return ""
+	}
+
+	mediaType, _, _ := strings.Cut(contentType, ";")
+	switch mediaType {
+	case "application/x-www-form-urlencoded", "multipart/form-data":
+		return ""
+	}
+
+	return contentType
+}
+
+// normalizeContentType validates media type and formats it in canonical form.
+func normalizeContentType(contentType string) (string, error) {
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return "", fmt.Errorf("fail to parse media type: %w", err)
+	}
+
+	if !strings.Contains(mediaType, "/") {
+		return "", fmt.Errorf("media type '%s' has no subtype", mediaType)
+	}
+
+	return mime.FormatMediaType(mediaType, params), nil
+}
+
+func getFilename(v url.Values) (string, error) {
+	filename := v.Get("filename")
+
+	if filename == "" {
+		return "", nil
+	}
+
+	if len(filename) > maxFilenameLength {
+		return "", fmt.Errorf("filename length more then %d", maxFilenameLength)
+	}
+
+	if !utf8.ValidString(filename) || filename == "." || filename == ".." {
+		return "", fmt.Errorf("invalid filename")
+	}
+
+	for _, char := range filename {
+		if char == '/' || char == '\\' || unicode.IsControl(char) {
+			return "", fmt.Errorf("filename contains illegal char")
+		}
+	}
+
+	return filename, nil
+}
+
 func getURL(v url.Values) (bool, error) {
 	URLQuery := v.Get("url")
 
@@ -421,6 +859,117 @@ func getURL(v url.Values) (bool, error) {
 	return false, fmt.Errorf("URL argument can be only 'true' or 'false'")
 }
 
+func getSliding(v url.Values) (bool, error) {
+	slidingQuery := v.Get("sliding")
+
+	if slidingQuery == "" {
+		return false, nil
+	}
+
+	if slidingQuery == "true" {
+		return true, nil
+	}
+
+	if slidingQuery == "false" {
+		return false, nil
+	}
+
+	return false, fmt.Errorf("sliding argument can be only 'true' or 'false'")
+}
+
+func getEncrypted(v url.Values) (bool, error) {
+	encryptedQuery := v.Get("encrypted")
+
+	if encryptedQuery == "" {
+		return false, nil
+	}
+
+	if encryptedQuery == "true" {
+		return true, nil
+	}
+
+	if encryptedQuery == "false" {
+		return false, nil
+	}
+
+	return false, fmt.Errorf("encrypted argument can be only 'true' or 'false'")
+}
+
+func getReveal(v url.Values) (bool, error) {
+	revealQuery := v.Get("reveal")
+
+	if revealQuery == "" {
+		return false, nil
+	}
+
+	if revealQuery == "true" {
+		return true, nil
+	}
+
+	if revealQuery == "false" {
+		return false, nil
+	}
+
+	return false, fmt.Errorf("reveal argument can be only 'true' or 'false'")
+}
+
+func getBundle(v url.Values) (bool, error) {
+	bundleQuery := v.Get("bundle")
+
+	if bundleQuery == "" {
+		return false, nil
+	}
+
+	if bundleQuery == "true" {
+		return true, nil
+	}
+
+	if bundleQuery == "false" {
+		return false, nil
+	}
+
+	return false, fmt.Errorf("bundle argument can be only 'true' or 'false'")
+}
+
+func getRedirectStatus(v url.Values) (objectvalue.RedirectStatus, error) {
+	redirectQuery := v.Get("redirect")
+
+	if redirectQuery == "" {
+		return 0, nil
+	}
+
+	code, err := strconv.Atoi(redirectQuery)
+	if err != nil {
+		return 0, fmt.Errorf("fail to parse redirect status: %w", err)
+	}
+
+	status := objectvalue.RedirectStatus(code)
+	if !status.Valid() {
+		return 0, fmt.Errorf("redirect argument can be only 301, 302, 303, 307 or 308")
+	}
+
+	return status, nil
+}
+
+// getForward parses forwarding parameter with name.
+func getForward(v url.Values, name string) (bool, error) {
+	value := v.Get(name)
+
+	if value == "" {
+		return false, nil
+	}
+
+	if value == "true" {
+		return true, nil
+	}
+
+	if value == "false" {
+		return false, nil
+	}
+
+	return false, fmt.Errorf("%s argument can be only 'true' or 'false'", name)
+}
+
 func validateURL(str string) bool {
 	u, err := url.Parse(str)
 	return err == nil && u.Scheme != "" && u.Host != ""
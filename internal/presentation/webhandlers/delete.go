@@ -0,0 +1,57 @@
+package webhandlers
+
+import (
+	"net/http"
+
+	"github.com/google/uuid"
+
+	"github.com/thek4n/paste.thek4n.ru/internal/domain/objectvalue"
+)
+
+// ownerTokenHeader header with owner token returned on caching
+// and accepted on managing record.
+const ownerTokenHeader = "X-Owner-Token"
+
+// Delete handle removing key by its owner. Owner is authorized by owner
+// token in header or "token" parameter, or by apikey created record.
+func (app *Handlers) Delete(w http.ResponseWriter, r *http.Request) {
+	remoteAddr := getClientIP(r)
+	requestUUID := uuid.NewString()
+
+	key := r.PathValue("key")
+
+	logger := app.Logger.With(
+		"source_ip", remoteAddr,
+		"request_id", requestUUID,
+		"key", key,
+	)
+
+	logger.Debug(
+		"Start deleting key",
+	)
+
+	err := app.cacheService.Delete(
+		objectvalue.RecordKey(key),
+		getOwnerToken(r),
+		r.URL.Query().Get("apikey"),
+	)
+	if err != nil {
+		handleCacheError(w, r, err, logger)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+
+	logger.Info(
+		"Deleted key",
+	)
+}
+
+func getOwnerToken(r *http.Request) objectvalue.OwnerToken {
+	token := r.Header.Get(ownerTokenHeader)
+	if token == "" {
+		token = r.URL.Query().Get("token")
+	}
+
+	return objectvalue.OwnerToken(token)
+}
@@ -5,6 +5,9 @@ import (
 	"fmt"
 	"html/template"
 	"net/http"
+	"strings"
+
+	"github.com/thek4n/paste.thek4n.ru/internal/domain/objectvalue"
 )
 
 //go:embed docs/templates
@@ -121,7 +124,7 @@ func (app *Handlers) buildMainSection(baseURL string) section {
 				ID:              "create-record",
 				Method:          methodPost,
 				Path:            "/",
-				Description:     "Save body",
+				Description:     "Save body. Response header X-Owner-Token contains secret token to delete record. It is shown only once. With 'Accept: application/json' header record description is returned as json and errors as problem details (RFC 9457) with stable 'code' field.",
 				ResponseExample: fmt.Sprintf("%s/eoVbybwLnlc49q/", baseURL),
 				Parameters:      app.getCreateRecordParameters(),
 			},
@@ -129,18 +132,98 @@ func (app *Handlers) buildMainSection(baseURL string) section {
 				ID:              "get-record",
 				Method:          methodGet,
 				Path:            "/{key}",
-				Description:     "Get previously saved body with key. If key was saved as url - you will be redirected.",
+				Description:     "Get previously saved body with key. If key was saved as url - you will be redirected. Response headers X-Expires-At and X-Remaining-Reads contain expiration date and remaining gettings if limited. Body readable many times has ETag and Cache-Control headers and supports If-None-Match and Range requests. HEAD request returns headers without consuming record.",
 				ResponseExample: "body",
-				Parameters:      getKeyPathParameter(),
+				Parameters: append(getKeyPathParameter(), parameter{
+					Name:        downloadParameter,
+					Type:        "bool",
+					In:          inQuery,
+					Required:    false,
+					Description: "Get body as attachment to save it as file",
+					Default:     "",
+				}),
+			},
+			{
+				ID:              "get-bundle-file",
+				Method:          methodGet,
+				Path:            "/{key}/{file}",
+				Description:     "Get file of bundle by its path. Getting of record URL lists files of bundle without consuming it, getting of file counts as getting of whole bundle. Url saved with forwardpath redirects with this path appended.",
+				ResponseExample: "file content",
+				Parameters: append(getKeyPathParameter(), parameter{
+					Name:        "file",
+					Type:        "string",
+					In:          inPath,
+					Required:    true,
+					Description: "Path of file inside bundle",
+					Default:     "",
+				}),
+			},
+			{
+				ID:          "get-bundle-archive",
+				Method:      methodGet,
+				Path:        "/{key}/archive.zip",
+				Description: "Get all files of bundle as zip archive. Counts as getting of whole bundle.",
+				Parameters:  getKeyPathParameter(),
 			},
 			{
 				ID:              "get-record-clicks",
 				Method:          methodGet,
 				Path:            "/{key}/clicks",
-				Description:     "Get clicks count for key.",
+				Description:     "Get clicks count for key, bots are not counted.",
 				ResponseExample: "1",
 				Parameters:      getKeyPathParameter(),
 			},
+			{
+				ID:          "view-record",
+				Method:      methodGet,
+				Path:        "/{key}/view",
+				Description: "Get previously saved body rendered as HTML page with highlighted syntax, line numbers and raw and download buttons. Viewing counts as getting of disposable record.",
+				Parameters: append(getKeyPathParameter(), parameter{
+					Name:        "lang",
+					Type:        "string",
+					In:          inQuery,
+					Required:    false,
+					Description: "Language to highlight, e.g. go or python. Detected by filename, content type or content if not provided",
+					Default:     "",
+				}),
+			},
+			{
+				ID:              "get-record-info",
+				Method:          methodGet,
+				Path:            "/{key}/info",
+				Description:     "Get record description as json without consuming it. Disposable counter and clicks are not changed. expires_at, ttl_seconds and remaining_reads are omitted if not limited.",
+				ResponseExample: `{"expires_at":"2026-01-02T15:04:05Z","ttl_seconds":3600,"eternal":false,"remaining_reads":1,"clicks":0,"body_size":5,"url":false,"content_type":"text/plain"}`,
+				Parameters:      getKeyPathParameter(),
+			},
+			{
+				ID:              "get-record-stats",
+				Method:          methodGet,
+				Path:            "/{key}/stats/",
+				Description:     "Get click analytics of record as json. Requires apikey that saved record, clicks are recorded only for such records. Time series is kept in hourly buckets for a week. Clicks by bots are counted apart and HEAD requests are not counted.",
+				ResponseExample: `{"totals":{"clicks":2,"bot_clicks":1,"unique_visitors":2,"bucket_seconds":3600},"series":[{"start":"2026-01-02T15:00:00Z","clicks":2,"bot_clicks":1}],"breakdowns":{"referrers":{"direct":1,"example.com":1},"user_agents":{"bot":1,"browser":1,"cli":1},"countries":{"DE":1}}}`,
+				Parameters: append(getKeyPathParameter(), parameter{
+					Name:        "apikey",
+					Type:        "string",
+					In:          inQuery,
+					Required:    true,
+					Description: "Apikey that saved record",
+					Default:     "",
+				}),
+			},
+			{
+				ID:          "update-record",
+				Method:      methodPatch,
+				Path:        "/{key}/",
+				Description: "Update body and settings of record keeping its key and clicks. Only presented parameters are changed, empty body keeps current one. Requires owner token or apikey like deleting. Responses 204 No Content on success.",
+				Parameters:  app.getUpdateRecordParameters(),
+			},
+			{
+				ID:          "delete-record",
+				Method:      methodDelete,
+				Path:        "/{key}/",
+				Description: "Delete record. Requires owner token returned on saving or apikey that saved record. Responses 204 No Content on success.",
+				Parameters:  getDeleteRecordParameters(),
+			},
 		},
 	}
 }
@@ -198,9 +281,73 @@ func (app *Handlers) getCreateRecordParameters() []parameter {
 			Type:        "bool",
 			In:          inQuery,
 			Required:    false,
-			Description: "Is body url. If true after getting this key you will be redirected.",
+			Description: "Is body url. If true after getting this key you will be redirected. Variables {key}, {path} and {query} in url are replaced with key, path after key and query of request. Only http and https urls are allowed, urls to blocked hosts and to service itself are rejected with 422 and code url_not_allowed.",
+			Default:     "false",
+		},
+		{
+			Name:        "redirect",
+			Type:        "int",
+			In:          inQuery,
+			Required:    false,
+			Description: "Status of redirect to url: 301, 302, 303, 307 or 308. Only for url",
+			Default:     "303",
+		},
+		{
+			Name:        "forwardquery",
+			Type:        "bool",
+			In:          inQuery,
+			Required:    false,
+			Description: "If true query of request is appended to url on redirect. Only for url",
+			Default:     "false",
+		},
+		{
+			Name:        "forwardpath",
+			Type:        "bool",
+			In:          inQuery,
+			Required:    false,
+			Description: "If true path after key is appended to url on redirect, e.g. /{key}/rest redirects to url/rest. Otherwise such path is not found. Only for url",
+			Default:     "false",
+		},
+		{
+			Name:        "sliding",
+			Type:        "bool",
+			In:          inQuery,
+			Required:    false,
+			Description: "If true every getting of this key prolongs its lifetime by ttl. By default key expires after ttl since creation.",
 			Default:     "false",
 		},
+		{
+			Name:        "bundle",
+			Type:        "bool",
+			In:          inQuery,
+			Required:    false,
+			Description: fmt.Sprintf("Is body zip or tar archive to save as bundle of files. Multipart form with several file parts is bundle anyway. At most %d files", app.Config.MaxBundleFiles()),
+			Default:     "false",
+		},
+		{
+			Name:        "keystyle",
+			Type:        "string",
+			In:          inQuery,
+			Required:    false,
+			Description: fmt.Sprintf("Style of key to generate. Unprivileged styles: %s, privileged styles: %s", joinKeyStyles(app.Config.UnprivilegedKeyStyles()), joinKeyStyles(app.Config.PrivilegedKeyStyles())),
+			Default:     string(app.Config.DefaultKeyStyle()),
+		},
+		{
+			Name:        "type",
+			Type:        "string",
+			In:          inQuery,
+			Required:    false,
+			Description: "Content type of body returned on getting. By default request Content-Type header is used, except form types",
+			Default:     "",
+		},
+		{
+			Name:        "filename",
+			Type:        "string",
+			In:          inQuery,
+			Required:    false,
+			Description: "Filename returned in Content-Disposition header on getting",
+			Default:     "",
+		},
 		{
 			Name:        "key",
 			Type:        "string",
@@ -222,10 +369,94 @@ func (app *Handlers) getCreateRecordParameters() []parameter {
 			Type:        "string",
 			In:          inBody,
 			Required:    true,
-			Description: "Body to cache.",
+			Description: "Body to cache. Urlencoded or multipart form takes it from 'content' field or file part, other fields override query parameters.",
+			Default:     "",
+		},
+	}
+}
+
+// getUpdateRecordParameters returns parameters for the update record endpoint.
+func (app *Handlers) getUpdateRecordParameters() []parameter {
+	return append(getDeleteRecordParameters(),
+		parameter{
+			Name:        "ttl",
+			Type:        "time",
+			In:          inQuery,
+			Required:    false,
+			Description: "New TTL counted from now. Authorized apikeys can make key persist by providing ttl parameter as 0",
 			Default:     "",
 		},
+		parameter{
+			Name:        "sliding",
+			Type:        "bool",
+			In:          inQuery,
+			Required:    false,
+			Description: "If true every getting of this key prolongs its lifetime by new ttl. Requires ttl",
+			Default:     "false",
+		},
+		parameter{
+			Name:        "disposable",
+			Type:        "int",
+			In:          inQuery,
+			Required:    false,
+			Description: "Resets number of remaining gettings. 0 removes limit",
+			Default:     "",
+		},
+		parameter{
+			Name:        "url",
+			Type:        "bool",
+			In:          inQuery,
+			Required:    false,
+			Description: "Is body url. Url is checked by same policy as on saving. Switching it on password protected record requires new body and password",
+			Default:     "",
+		},
+		parameter{
+			Name:        "encrypted",
+			Type:        "bool",
+			In:          inQuery,
+			Required:    false,
+			Description: "Is new body ciphertext encrypted by client. New body without it is plain text",
+			Default:     "false",
+		},
+		parameter{
+			Name:        "body",
+			Type:        "string",
+			In:          inBody,
+			Required:    false,
+			Description: "New body. Empty body keeps current one",
+			Default:     "",
+		},
+	)
+}
+
+// getDeleteRecordParameters returns parameters for the delete record endpoint.
+func getDeleteRecordParameters() []parameter {
+	return append(getKeyPathParameter(),
+		parameter{
+			Name:        ownerTokenHeader,
+			Type:        "string",
+			In:          inHeader,
+			Required:    false,
+			Description: "Owner token returned on saving. Can be passed as 'token' query parameter",
+			Default:     "",
+		},
+		parameter{
+			Name:        "apikey",
+			Type:        "string",
+			In:          inQuery,
+			Required:    false,
+			Description: "Apikey that saved record",
+			Default:     "",
+		},
+	)
+}
+
+func joinKeyStyles(styles []objectvalue.KeyStyle) string {
+	names := make([]string, 0, len(styles))
+	for _, style := range styles {
+		names = append(names, string(style))
 	}
+	return strings.Join(names, ", ")
 }
 
 // getKeyPathParameter returns the common key path parameter.
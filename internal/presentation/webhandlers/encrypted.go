@@ -0,0 +1,83 @@
+package webhandlers
+
+import (
+	"bytes"
+	"encoding/base64"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/thek4n/paste.thek4n.ru/internal/application/service"
+	"github.com/thek4n/paste.thek4n.ru/internal/domain/objectvalue"
+)
+
+type decryptPage struct {
+	Title    string
+	Filename string
+	// Ciphertext base64 encoded body decrypted by page script with key
+	// from URL fragment.
+	Ciphertext string
+}
+
+// acceptsHTML returns is client a browser expecting HTML page.
+func acceptsHTML(r *http.Request) bool {
+	return strings.Contains(r.Header.Get("Accept"), "text/html")
+}
+
+// serveDecryptView answers with page decrypting body of encrypted record
+// in browser. Ciphertext is embedded, so record is not consumed again.
+func (app *Handlers) serveDecryptView(w http.ResponseWriter, key string, record service.GetBodyAnswer, logger *slog.Logger) {
+	body := record.Body
+	if record.BodyEncoding != objectvalue.BodyEncodingIdentity {
+		var err error
+		body, err = app.decodeBody(body, record.BodyEncoding)
+		if err != nil {
+			logger.Error(
+				"Fail to decode ciphertext",
+				"error", err,
+				"answer_code", http.StatusInternalServerError,
+			)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+	}
+
+	filename := record.Filename
+	if filename == "" {
+		filename = key
+	}
+
+	page := decryptPage{
+		Title:      filename,
+		Filename:   filename,
+		Ciphertext: base64.StdEncoding.EncodeToString(body),
+	}
+
+	var buf bytes.Buffer
+	if err := viewTpl.ExecuteTemplate(&buf, "decrypt.tmpl", page); err != nil {
+		logger.Error(
+			"Fail to execute decrypt template",
+			"error", err,
+			"answer_code", http.StatusInternalServerError,
+		)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	setRecordStateHeaders(w, record)
+	w.Header().Set("Vary", "Accept")
+	w.Header().Set("content-type", "text/html; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	_, writeErr := w.Write(buf.Bytes())
+	if writeErr != nil {
+		logger.Error(
+			"Fail to answer",
+			"error", writeErr,
+			"answer_code", http.StatusInternalServerError,
+		)
+		return
+	}
+	logger.Info(
+		"Served encrypted content",
+	)
+}
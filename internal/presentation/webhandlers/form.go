@@ -0,0 +1,228 @@
+package webhandlers
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+
+	"github.com/thek4n/paste.thek4n.ru/internal/domain/domainerrors"
+)
+
+// formContentField name of form field with content. First file part is
+// used as content if this field is empty.
+const formContentField = "content"
+
+// formPasswordField name of form field with password of protected record.
+const formPasswordField = "password"
+
+// maxFormOverhead allowance for form encoding and option fields.
+const maxFormOverhead = 64 << 10
+
+// maxFormOptionSize max size of form field other than content.
+const maxFormOptionSize = 4096
+
+// maxFormParts max number of parts of multipart form.
+const maxFormParts = 256
+
+// cacheForm content and options posted by form.
+type cacheForm struct {
+	// options are form fields other than content.
+	options url.Values
+	content []byte
+	// filename and contentType are taken from file part.
+	filename    string
+	contentType string
+	// files are file parts of form with several files. Content is
+	// empty then.
+	files []cacheFormFile
+}
+
+// cacheFormFile file part of multipart form.
+type cacheFormFile struct {
+	name        string
+	contentType string
+	content     []byte
+}
+
+// isFormRequest returns is request body urlencoded or multipart form.
+func isFormRequest(r *http.Request) bool {
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil {
+		return false
+	}
+
+	return mediaType == "application/x-www-form-urlencoded" || mediaType == "multipart/form-data"
+}
+
+// readCacheForm reads content and options of form. Content size is
+// limited by limit, form encoding may take up to maxFormOverhead more.
+// Urlencoded body without content field is content itself, because clients
+// like curl send any data as urlencoded form by default.
+func readCacheForm(w http.ResponseWriter, r *http.Request, limit int64) (cacheForm, error) {
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+
+	if mediaType == "multipart/form-data" {
+		r.Body = http.MaxBytesReader(w, r.Body, limit+maxFormOverhead)
+		return readMultipartForm(r, limit)
+	}
+
+	body, err := readRequestBody(w, r, limit+maxFormOverhead)
+	if err != nil {
+		return cacheForm{}, err
+	}
+
+	form := cacheForm{content: body}
+
+	options, err := url.ParseQuery(string(body))
+	if err == nil && options.Has(formContentField) {
+		form.content = []byte(options.Get(formContentField))
+		options.Del(formContentField)
+		form.options = options
+
+		if len(form.content) == 0 {
+			return cacheForm{}, errFormNoContent
+		}
+	}
+
+	if int64(len(form.content)) > limit {
+		return cacheForm{}, domainerrors.ErrBodyTooLarge
+	}
+
+	return form, nil
+}
+
+var errFormNoContent = &cacheError{
+	Message:    "Form has no 'content' field or file",
+	StatusCode: http.StatusBadRequest,
+}
+
+func readMultipartForm(r *http.Request, limit int64) (cacheForm, error) {
+	reader, err := r.MultipartReader()
+	if err != nil {
+		return cacheForm{}, &cacheError{Message: "Invalid form", StatusCode: http.StatusBadRequest, Err: err}
+	}
+
+	form := cacheForm{options: url.Values{}}
+	// size of content and files, they are limited together
+	var size int64
+
+	for parts := 0; ; parts++ {
+		part, err := reader.NextPart()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return cacheForm{}, formReadError(err)
+		}
+
+		if parts == maxFormParts {
+			return cacheForm{}, &cacheError{Message: "Too many form parts", StatusCode: http.StatusBadRequest}
+		}
+
+		name := part.FormName()
+		switch {
+		case part.FileName() != "":
+			content, err := readFormPart(part, limit-size)
+			if err != nil {
+				return cacheForm{}, err
+			}
+			size += int64(len(content))
+			form.files = append(form.files, cacheFormFile{
+				name:        part.FileName(),
+				contentType: part.Header.Get("Content-Type"),
+				content:     content,
+			})
+
+		case name == formContentField:
+			content, err := readFormPart(part, limit-size)
+			if err != nil {
+				return cacheForm{}, err
+			}
+			size += int64(len(content))
+			form.content = content
+
+		case name != "":
+			value, err := readFormPart(part, maxFormOptionSize)
+			if err != nil {
+				return cacheForm{}, err
+			}
+			form.options.Add(name, string(value))
+		}
+
+		_ = part.Close()
+	}
+
+	// content field wins over files
+	if len(form.content) > 0 {
+		form.files = nil
+	}
+
+	if len(form.files) == 1 {
+		file := form.files[0]
+		form.content = file.content
+		form.filename = file.name
+		form.contentType = file.contentType
+		form.files = nil
+	}
+
+	if len(form.content) == 0 && len(form.files) == 0 {
+		return cacheForm{}, errFormNoContent
+	}
+
+	return form, nil
+}
+
+// readFormPart reads part up to limit.
+func readFormPart(part *multipart.Part, limit int64) ([]byte, error) {
+	var buf bytes.Buffer
+
+	n, err := buf.ReadFrom(io.LimitReader(part, limit+1))
+	if err != nil {
+		return nil, formReadError(err)
+	}
+	if n > limit {
+		return nil, domainerrors.ErrBodyTooLarge
+	}
+
+	return buf.Bytes(), nil
+}
+
+func formReadError(err error) error {
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		return domainerrors.ErrBodyTooLarge
+	}
+
+	return &cacheError{Message: "Invalid form", StatusCode: http.StatusBadRequest, Err: err}
+}
+
+// mergeOptions returns query with options of form. Form fields override
+// query parameters with the same name. Filename and content type of file
+// part are used if they are not declared.
+func (f cacheForm) mergeOptions(query url.Values) url.Values {
+	merged := url.Values{}
+	for name, values := range query {
+		merged[name] = values
+	}
+	for name, values := range f.options {
+		if name != formPasswordField {
+			merged[name] = values
+		}
+	}
+
+	if f.filename != "" && merged.Get("filename") == "" {
+		merged.Set("filename", f.filename)
+	}
+
+	// browsers and curl send unknown files as application/octet-stream,
+	// content type is guessed by filename then
+	if f.contentType != "" && f.contentType != "application/octet-stream" && merged.Get("type") == "" {
+		merged.Set("type", f.contentType)
+	}
+
+	return merged
+}
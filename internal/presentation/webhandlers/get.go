@@ -1,26 +1,43 @@
 package webhandlers
 
 import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
 	"errors"
 	"fmt"
+	"io"
+	"log/slog"
+	"mime"
 	"net/http"
+	"path"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/google/uuid"
 
+	"github.com/thek4n/paste.thek4n.ru/internal/application/service"
 	"github.com/thek4n/paste.thek4n.ru/internal/domain/domainerrors"
 	"github.com/thek4n/paste.thek4n.ru/internal/domain/objectvalue"
+	"github.com/thek4n/paste.thek4n.ru/pkg/bodycodec"
 )
 
-const redirectBody = `<html><head>
-<title>303 See Other</title>
-</head><body>
-<h1>See Other</h1>
-<p>The document has moved <a href="%s">here</a>.</p>
-</body></html>`
+// recordMaxAge max-age of cacheable records. Owner can update or delete
+// any record, so caches revalidate it by ETag after max-age passes.
+const recordMaxAge = 10 * time.Minute
 
-// Get handle getting key.
+// downloadParameter query parameter getting body as attachment.
+const downloadParameter = "download"
+
+// Get handle getting key. Password of protected record is taken from
+// header or from form posted by password prompt. Browsers get page
+// decrypting encrypted record, other clients get its ciphertext.
+// Link previews get neutral page, so they do not consume limited reads.
+// Record requiring reveal is consumed only by POST request. File of bundle
+// is got by path after key, bundle itself is listed without consuming.
+// Url record forwarding path is got by path after key too.
+// Browsers are redirected to usercontent host if it is configured.
 func (app *Handlers) Get(w http.ResponseWriter, r *http.Request) {
 	remoteAddr := getClientIP(r)
 	requestUUID := uuid.NewString()
@@ -40,8 +57,32 @@ func (app *Handlers) Get(w http.ResponseWriter, r *http.Request) {
 		"key", key,
 	)
 
-	record, err := app.getService.GetBody(objectvalue.RecordKey(key))
+	if isLinkPreview(r) && app.servePreview(w, key, logger) {
+		return
+	}
+
+	if app.redirectToUserContent(w, r) {
+		return
+	}
+
+	app.setServingHeaders(w)
+
+	password := getPassword(w, r)
+
+	record, err := app.getService.GetBody(objectvalue.RecordKey(key), objectvalue.GetRequestParams{
+		Password:          password,
+		Confirmed:         r.Method == http.MethodPost,
+		AcceptedEncodings: acceptedEncodings(r),
+		File:              r.PathValue("path"),
+		Click:             getClickParams(r),
+	})
 	if err != nil {
+		if handlePasswordError(w, r, err, password, logger) {
+			return
+		}
+		if handleRevealError(w, r, err, logger) {
+			return
+		}
 		if errors.Is(err, domainerrors.ErrRecordNotFound) || errors.Is(err, domainerrors.ErrRecordCounterExhausted) || errors.Is(err, domainerrors.ErrRecordExpired) {
 			w.WriteHeader(http.StatusNotFound)
 
@@ -66,12 +107,58 @@ func (app *Handlers) Get(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if len(record.Files) > 0 {
+		serveBundleListing(w, r, key, record, logger)
+		return
+	}
+
+	if record.IsURL && record.BodyEncoding != objectvalue.BodyEncodingIdentity {
+		record.Body, err = app.decodeBody(record.Body, record.BodyEncoding)
+		if err != nil {
+			logger.Error(
+				"Fail to decode url",
+				"error", err,
+				"answer_code", http.StatusInternalServerError,
+			)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+	}
+
+	if record.Encrypted && acceptsHTML(r) {
+		app.serveDecryptView(w, key, record, logger)
+		return
+	}
+
+	setRecordStateHeaders(w, record)
+
 	if record.IsURL {
-		answer := make([]byte, 0)
-		answer = fmt.Appendf(answer, redirectBody, string(record.Body))
-		w.Header().Set("content-type", http.DetectContentType(answer))
-		http.Redirect(w, r, strings.TrimSpace(string(record.Body)), http.StatusSeeOther)
-		_, writeErr := w.Write(answer)
+		serveRedirect(w, r, key, record, logger)
+		return
+	}
+
+	app.writeRecordBody(w, r, record, logger)
+}
+
+// writeRecordBody answers with body of record. Record which can be read
+// again is answered with ETag and Cache-Control, and conditional and range
+// requests are served from its body. Other records are never cached.
+// Active content is served as plain text and is not allowed to run scripts.
+func (app *Handlers) writeRecordBody(w http.ResponseWriter, r *http.Request, record service.GetBodyAnswer, logger *slog.Logger) {
+	w.Header().Set("Vary", "Accept, Accept-Encoding")
+	w.Header().Set("content-type", app.servedContentType(record))
+	w.Header().Set("Content-Security-Policy", app.ServingConfig.ContentSecurityPolicy())
+	if record.BodyEncoding != objectvalue.BodyEncodingIdentity {
+		w.Header().Set("Content-Encoding", string(record.BodyEncoding))
+	}
+	if disposition := contentDisposition(r, record); disposition != "" {
+		w.Header().Set("Content-Disposition", disposition)
+	}
+
+	if !cacheableRecord(record) {
+		w.Header().Set("Cache-Control", "no-store")
+		w.WriteHeader(http.StatusOK)
+		_, writeErr := w.Write(record.Body)
 		if writeErr != nil {
 			logger.Error(
 				"Fail to answer",
@@ -82,28 +169,64 @@ func (app *Handlers) Get(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 		logger.Info(
-			"Redirect to url",
+			"Got content",
 		)
 		return
 	}
 
-	w.Header().Set("content-type", http.DetectContentType(record.Body))
-	w.WriteHeader(http.StatusOK)
-	_, writeErr := w.Write(record.Body)
-	if writeErr != nil {
-		logger.Error(
-			"Fail to answer",
-			"error", writeErr,
-			"answer_code", http.StatusInternalServerError,
-		)
-		w.WriteHeader(http.StatusInternalServerError)
-		return
-	}
+	w.Header().Set("ETag", recordETag(record))
+	w.Header().Set("Cache-Control", recordCacheControl(record))
+	http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(record.Body))
 	logger.Info(
 		"Got content",
 	)
 }
 
+// contentDisposition returns Content-Disposition of body. Body requested with
+// download parameter is attachment named by filename or key.
+func contentDisposition(r *http.Request, record service.GetBodyAnswer) string {
+	if r.URL.Query().Has(downloadParameter) {
+		filename := record.Filename
+		if filename == "" {
+			filename = r.PathValue("key")
+		}
+		return mime.FormatMediaType("attachment", map[string]string{"filename": filename})
+	}
+
+	if record.Filename != "" && !record.Encrypted {
+		return mime.FormatMediaType("inline", map[string]string{"filename": record.Filename})
+	}
+
+	return ""
+}
+
+// cacheableRecord returns can body of record be stored by caches. Body of
+// record with limited reads, protected by password or requiring reveal
+// must be got from server every time.
+func cacheableRecord(record service.GetBodyAnswer) bool {
+	return !record.ReadsLimited && !record.Protected && !record.Reveal && !record.IsURL
+}
+
+// recordETag returns strong ETag of body. Body encoded differently has
+// other ETag.
+func recordETag(record service.GetBodyAnswer) string {
+	sum := sha256.Sum256(record.Body)
+	return `"` + base64.RawURLEncoding.EncodeToString(sum[:]) + `"`
+}
+
+// recordCacheControl returns Cache-Control of cacheable record. Record is
+// cached for recordMaxAge but not after it expires, then it must be
+// revalidated. Updated or deleted record may be served by caches until
+// max-age passes.
+func recordCacheControl(record service.GetBodyAnswer) string {
+	maxAge := recordMaxAge
+	if !record.ExpiresAt.IsZero() {
+		maxAge = max(min(time.Until(record.ExpiresAt), maxAge), 0)
+	}
+
+	return fmt.Sprintf("public, max-age=%d, must-revalidate", int64(maxAge.Seconds()))
+}
+
 // GetClicks handle getting clicks for key request.
 func (app *Handlers) GetClicks(w http.ResponseWriter, r *http.Request) {
 	remoteAddr := getClientIP(r)
@@ -168,3 +291,81 @@ func (app *Handlers) GetClicks(w http.ResponseWriter, r *http.Request) {
 		"Got clicks",
 	)
 }
+
+// acceptedEncodings returns supported encodings listed in Accept-Encoding header.
+// Stored bodies in these encodings are served as is.
+func acceptedEncodings(r *http.Request) []objectvalue.BodyEncoding {
+	var encodings []objectvalue.BodyEncoding
+
+	for _, header := range r.Header.Values("Accept-Encoding") {
+		for coding := range strings.SplitSeq(header, ",") {
+			name, params, _ := strings.Cut(coding, ";")
+			name = strings.ToLower(strings.TrimSpace(name))
+
+			if q, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+				if weight, err := strconv.ParseFloat(q, 64); err == nil && weight == 0 {
+					continue
+				}
+			}
+
+			if _, err := bodycodec.Get(name); err == nil {
+				encodings = append(encodings, objectvalue.BodyEncoding(name))
+			}
+		}
+	}
+
+	return encodings
+}
+
+// recordContentType returns content type declared on caching, guessed
+// by filename extension or detected by content. Ciphertext of encrypted
+// record is binary regardless of declared type.
+func recordContentType(record service.GetBodyAnswer) string {
+	if record.Encrypted {
+		return "application/octet-stream"
+	}
+
+	if record.ContentType != "" {
+		return record.ContentType
+	}
+
+	if contentType := mime.TypeByExtension(path.Ext(record.Filename)); contentType != "" {
+		return contentType
+	}
+
+	if record.BodyEncoding != objectvalue.BodyEncodingIdentity {
+		return detectEncodedContentType(record.Body, record.BodyEncoding)
+	}
+
+	return http.DetectContentType(record.Body)
+}
+
+// detectEncodedContentType detects content type by decoding only first bytes of body.
+func detectEncodedContentType(body []byte, encoding objectvalue.BodyEncoding) string {
+	const sniffLen = 512
+
+	codec, err := bodycodec.Get(string(encoding))
+	if err != nil {
+		return "application/octet-stream"
+	}
+
+	r, err := codec.NewReader(bytes.NewReader(body))
+	if err != nil {
+		return "application/octet-stream"
+	}
+	defer func() { _ = r.Close() }()
+
+	head := make([]byte, sniffLen)
+	n, _ := io.ReadFull(r, head)
+
+	return http.DetectContentType(head[:n])
+}
+
+func (app *Handlers) decodeBody(body []byte, encoding objectvalue.BodyEncoding) ([]byte, error) {
+	codec, err := bodycodec.Get(string(encoding))
+	if err != nil {
+		return nil, err
+	}
+
+	return bodycodec.Decode(codec, body, app.Config.PrivilegedMaxBodySize())
+}
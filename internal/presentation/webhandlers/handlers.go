@@ -14,29 +14,38 @@ import (
 // Handlers struct contains repositories and provides handlers.
 type Handlers struct {
 	Config             config.CacheValidationConfig
+	ServingConfig      config.ServingConfig
 	Version            string
 	Logger             slog.Logger
 	getService         *service.GetService
 	cacheService       *service.CacheService
+	updateService      *service.UpdateService
+	clickStatsService  *service.ClickStatsService
 	HealthcheckEnabled bool
 }
 
 // NewHandlers constructor.
 func NewHandlers(
 	cfg config.CacheValidationConfig,
+	servingCfg config.ServingConfig,
 	version string,
 	healthcheckEnabled bool,
 	logger slog.Logger,
 	getService *service.GetService,
 	cacheService *service.CacheService,
+	updateService *service.UpdateService,
+	clickStatsService *service.ClickStatsService,
 ) *Handlers {
 	return &Handlers{
 		Config:             cfg,
+		ServingConfig:      servingCfg,
 		Version:            version,
 		HealthcheckEnabled: healthcheckEnabled,
 		Logger:             logger,
 		getService:         getService,
 		cacheService:       cacheService,
+		updateService:      updateService,
+		clickStatsService:  clickStatsService,
 	}
 }
 
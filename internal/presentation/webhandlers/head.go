@@ -0,0 +1,84 @@
+package webhandlers
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/google/uuid"
+
+	"github.com/thek4n/paste.thek4n.ru/internal/domain/domainerrors"
+	"github.com/thek4n/paste.thek4n.ru/internal/domain/objectvalue"
+)
+
+// Head handle HEAD request of record, bundle file or archive. Record is
+// not consumed, so headers of record with limited reads can be checked.
+// Content headers are answered only for records readable without password,
+// reveal and redirect, the same as Get answers them.
+func (app *Handlers) Head(w http.ResponseWriter, r *http.Request) {
+	remoteAddr := getClientIP(r)
+	requestUUID := uuid.NewString()
+
+	logger := app.Logger.With(
+		"source_ip", remoteAddr,
+		"request_id", requestUUID,
+	)
+
+	logger.Debug(
+		"Start getting key head",
+	)
+
+	key := r.PathValue("key")
+
+	logger = logger.With(
+		"key", key,
+	)
+
+	app.setServingHeaders(w)
+
+	file := r.PathValue("path")
+	archive := file == "" && strings.HasSuffix(r.URL.Path, "/"+objectvalue.BundleArchiveName)
+
+	record, err := app.getService.Head(objectvalue.RecordKey(key), objectvalue.GetRequestParams{
+		AcceptedEncodings: acceptedEncodings(r),
+		File:              file,
+		Archive:           archive,
+	})
+	if err != nil {
+		if errors.Is(err, domainerrors.ErrRecordNotFound) || errors.Is(err, domainerrors.ErrRecordCounterExhausted) || errors.Is(err, domainerrors.ErrRecordExpired) {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		logger.Error(
+			"Fail to get key head",
+			"error", err,
+			"answer_code", http.StatusInternalServerError,
+		)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	setRecordStateHeaders(w, record)
+
+	switch {
+	case record.Protected || archive:
+		w.Header().Set("Cache-Control", "no-store")
+		w.WriteHeader(http.StatusOK)
+
+	case len(record.Files) > 0:
+		serveBundleListing(w, r, key, record, logger)
+		return
+
+	case record.Reveal || record.IsURL || record.Encrypted && acceptsHTML(r):
+		w.Header().Set("Cache-Control", "no-store")
+		w.WriteHeader(http.StatusOK)
+
+	default:
+		app.writeRecordBody(w, r, record, logger)
+		return
+	}
+
+	logger.Info(
+		"Got head",
+	)
+}
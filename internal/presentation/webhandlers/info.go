@@ -0,0 +1,152 @@
+package webhandlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/thek4n/paste.thek4n.ru/internal/application/service"
+	"github.com/thek4n/paste.thek4n.ru/internal/domain/domainerrors"
+	"github.com/thek4n/paste.thek4n.ru/internal/domain/objectvalue"
+)
+
+type infoResponse struct {
+	// ExpiresAt and TTLSeconds are omitted if record is eternal.
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	TTLSeconds *int64     `json:"ttl_seconds,omitempty"`
+	Eternal    bool       `json:"eternal"`
+	// RemainingReads is omitted if reads are not limited.
+	RemainingReads *uint8 `json:"remaining_reads,omitempty"`
+	Clicks         uint32 `json:"clicks"`
+	BodySize       int64  `json:"body_size"`
+	URL            bool   `json:"url"`
+	Encrypted      bool   `json:"encrypted"`
+	Reveal         bool   `json:"reveal"`
+	ContentType    string `json:"content_type,omitempty"`
+	Filename       string `json:"filename,omitempty"`
+	// Files are omitted if record is not bundle.
+	Files []infoFile `json:"files,omitempty"`
+	// Redirect is omitted if record is not url.
+	Redirect *infoRedirect `json:"redirect,omitempty"`
+}
+
+type infoRedirect struct {
+	Status       int  `json:"status"`
+	ForwardQuery bool `json:"forward_query"`
+	ForwardPath  bool `json:"forward_path"`
+}
+
+type infoFile struct {
+	Name        string `json:"name"`
+	Size        int64  `json:"size"`
+	ContentType string `json:"content_type,omitempty"`
+}
+
+// GetInfo handle getting record description without consuming it.
+func (app *Handlers) GetInfo(w http.ResponseWriter, r *http.Request) {
+	remoteAddr := getClientIP(r)
+	requestUUID := uuid.NewString()
+
+	logger := app.Logger.With(
+		"source_ip", remoteAddr,
+		"request_id", requestUUID,
+	)
+
+	logger.Debug(
+		"Start getting key info",
+	)
+
+	key := r.PathValue("key")
+
+	logger = logger.With(
+		"key", key,
+	)
+
+	info, err := app.getService.GetInfo(objectvalue.RecordKey(key))
+	if err != nil {
+		if errors.Is(err, domainerrors.ErrRecordNotFound) || errors.Is(err, domainerrors.ErrRecordCounterExhausted) || errors.Is(err, domainerrors.ErrRecordExpired) {
+			w.WriteHeader(http.StatusNotFound)
+
+			_, writeErr := fmt.Fprint(w, "404 Not Found")
+			if writeErr != nil {
+				logger.Error(
+					"Fail to answer",
+					"error", writeErr,
+					"answer_code", http.StatusInternalServerError,
+				)
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			return
+		}
+		logger.Error(
+			"Fail to get key info",
+			"error", err,
+			"answer_code", http.StatusInternalServerError,
+		)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	resp := infoResponse{
+		Eternal:     info.ExpiresAt.IsZero(),
+		Clicks:      info.Clicks,
+		BodySize:    info.BodySize,
+		URL:         info.IsURL,
+		Encrypted:   info.Encrypted,
+		Reveal:      info.Reveal,
+		ContentType: info.ContentType,
+		Filename:    info.Filename,
+	}
+	if !info.ExpiresAt.IsZero() {
+		expiresAt := info.ExpiresAt.UTC()
+		ttl := int64(time.Until(expiresAt).Seconds())
+		resp.ExpiresAt = &expiresAt
+		resp.TTLSeconds = &ttl
+	}
+	if info.ReadsLimited {
+		resp.RemainingReads = &info.RemainingReads
+	}
+	if info.IsURL {
+		resp.Redirect = &infoRedirect{
+			Status:       int(info.Redirect.Status()),
+			ForwardQuery: info.Redirect.ForwardQuery(),
+			ForwardPath:  info.Redirect.ForwardPath(),
+		}
+	}
+	for _, file := range info.Files {
+		resp.Files = append(resp.Files, infoFile{
+			Name:        file.Name(),
+			Size:        file.Size(),
+			ContentType: file.ContentType(),
+		})
+	}
+
+	if err := sendJSONResponse(w, resp, http.StatusOK); err != nil {
+		logger.Error(
+			"Fail to answer",
+			"error", err,
+			"answer_code", http.StatusOK,
+		)
+		return
+	}
+	logger.Info(
+		"Got info",
+	)
+}
+
+// setRecordStateHeaders sets X-Expires-At and X-Remaining-Reads headers
+// for record. Headers are omitted for eternal record and unlimited reads.
+func setRecordStateHeaders(w http.ResponseWriter, record service.GetBodyAnswer) {
+	if !record.ExpiresAt.IsZero() {
+		w.Header().Set("X-Expires-At", record.ExpiresAt.UTC().Format(time.RFC3339))
+	}
+
+	if record.ReadsLimited {
+		w.Header().Set("X-Remaining-Reads", strconv.Itoa(int(record.RemainingReads)))
+	}
+}
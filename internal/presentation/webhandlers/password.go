@@ -0,0 +1,84 @@
+package webhandlers
+
+import (
+	"bytes"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/thek4n/paste.thek4n.ru/internal/domain/domainerrors"
+)
+
+// passwordHeader header with password of protected record.
+const passwordHeader = "X-Paste-Password"
+
+// maxPasswordFormSize limits form posted by password prompt.
+const maxPasswordFormSize = 4096
+
+type passwordPrompt struct {
+	Wrong bool
+}
+
+// getPassword returns password from header or from form posted by password prompt.
+func getPassword(w http.ResponseWriter, r *http.Request) string {
+	if password := r.Header.Get(passwordHeader); password != "" {
+		return password
+	}
+
+	if r.Method != http.MethodPost {
+		return ""
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxPasswordFormSize)
+
+	return r.PostFormValue("password")
+}
+
+// handlePasswordError answers on wrong or missing password of protected
+// record and returns true. Browsers get HTML prompt posting password back.
+// Returns false if err is not password error.
+func handlePasswordError(w http.ResponseWriter, r *http.Request, err error, password string, logger *slog.Logger) bool {
+	if errors.Is(err, domainerrors.ErrPasswordAttemptsExhausted) {
+		logger.Warn(
+			"Password attempts exhausted",
+			"answer_code", http.StatusTooManyRequests,
+		)
+		w.WriteHeader(http.StatusTooManyRequests)
+		_, _ = w.Write([]byte("429 Too Many Requests"))
+		return true
+	}
+
+	if !errors.Is(err, domainerrors.ErrWrongPassword) {
+		return false
+	}
+
+	if password != "" {
+		logger.Warn(
+			"Wrong password",
+			"answer_code", http.StatusUnauthorized,
+		)
+	}
+
+	if !acceptsHTML(r) {
+		w.WriteHeader(http.StatusUnauthorized)
+		_, _ = w.Write([]byte("401 Unauthorized"))
+		return true
+	}
+
+	var buf bytes.Buffer
+	if err := viewTpl.ExecuteTemplate(&buf, "password.tmpl", passwordPrompt{Wrong: password != ""}); err != nil {
+		logger.Error(
+			"Fail to execute password template",
+			"error", err,
+			"answer_code", http.StatusInternalServerError,
+		)
+		w.WriteHeader(http.StatusInternalServerError)
+		return true
+	}
+
+	w.Header().Set("content-type", "text/html; charset=utf-8")
+	w.WriteHeader(http.StatusUnauthorized)
+	_, _ = w.Write(buf.Bytes())
+
+	return true
+}
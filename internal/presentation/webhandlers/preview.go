@@ -0,0 +1,128 @@
+package webhandlers
+
+import (
+	"bytes"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/thek4n/paste.thek4n.ru/internal/domain/domainerrors"
+	"github.com/thek4n/paste.thek4n.ru/internal/domain/objectvalue"
+)
+
+// previewUserAgents lowercase substrings of user agents of chat unfurlers,
+// social networks and mail scanners fetching links before human opens them.
+var previewUserAgents = []string{
+	"slackbot",
+	"slack-imgproxy",
+	"telegrambot",
+	"discordbot",
+	"whatsapp",
+	"twitterbot",
+	"facebookexternalhit",
+	"facebot",
+	"linkedinbot",
+	"skypeuripreview",
+	"mattermost-bot",
+	"redditbot",
+	"vkshare",
+	"viber",
+	"pinterestbot",
+	"embedly",
+	"iframely",
+	"bitlybot",
+	"google-pagerenderer",
+	"microsoftpreview",
+	"bingpreview",
+	"yahoomailproxy",
+	"googleimageproxy",
+}
+
+// isLinkPreview returns is request made by link preview bot. HEAD requests
+// are treated as previews too, they are used by scanners to check links.
+func isLinkPreview(r *http.Request) bool {
+	if r.Method == http.MethodHead {
+		return true
+	}
+
+	userAgent := strings.ToLower(r.UserAgent())
+	for _, preview := range previewUserAgents {
+		if strings.Contains(userAgent, preview) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// servePreview answers link preview with neutral page without consuming
+// record and returns true. Returns false if preview may read record,
+// because its reads are not limited and reveal is not required. Body of
+// record is not loaded to decide it.
+func (app *Handlers) servePreview(w http.ResponseWriter, key string, logger *slog.Logger) bool {
+	access, err := app.getService.GetAccess(objectvalue.RecordKey(key))
+	if err != nil {
+		if errors.Is(err, domainerrors.ErrRecordNotFound) {
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte("404 Not Found"))
+			return true
+		}
+		logger.Error(
+			"Fail to get key access for preview",
+			"error", err,
+			"answer_code", http.StatusInternalServerError,
+		)
+		w.WriteHeader(http.StatusInternalServerError)
+		return true
+	}
+
+	if !access.ReadsLimited() && !access.Reveal() {
+		return false
+	}
+
+	writeTemplatePage(w, "preview.tmpl", nil, http.StatusOK, logger)
+	logger.Info(
+		"Served link preview",
+	)
+
+	return true
+}
+
+// handleRevealError answers with page confirming reading of record by
+// POST request and returns true. Returns false if err is not reveal error.
+func handleRevealError(w http.ResponseWriter, r *http.Request, err error, logger *slog.Logger) bool {
+	if !errors.Is(err, domainerrors.ErrRevealNotConfirmed) {
+		return false
+	}
+
+	if !acceptsHTML(r) {
+		w.WriteHeader(http.StatusPreconditionRequired)
+		_, _ = w.Write([]byte("428 Precondition Required"))
+		return true
+	}
+
+	writeTemplatePage(w, "reveal.tmpl", nil, http.StatusOK, logger)
+
+	return true
+}
+
+// writeTemplatePage answers with HTML page executed from view template.
+func writeTemplatePage(w http.ResponseWriter, name string, data any, statusCode int, logger *slog.Logger) {
+	var buf bytes.Buffer
+	if err := viewTpl.ExecuteTemplate(&buf, name, data); err != nil {
+		logger.Error(
+			"Fail to execute template",
+			"template", name,
+			"error", err,
+			"answer_code", http.StatusInternalServerError,
+		)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("content-type", "text/html; charset=utf-8")
+	w.Header().Set("X-Robots-Tag", "noindex")
+	w.WriteHeader(statusCode)
+	_, _ = w.Write(buf.Bytes())
+}
@@ -0,0 +1,102 @@
+package webhandlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// errorCode stable machine readable code of error answered to client.
+// Codes are part of API, so existing ones must not be changed.
+type errorCode string
+
+// Error codes.
+const (
+	errorCodeInvalidRequest            errorCode = "invalid_request"
+	errorCodeInternal                  errorCode = "internal_error"
+	errorCodeQuotaExhausted            errorCode = "quota_exhausted"
+	errorCodeBodyTooLarge              errorCode = "body_too_large"
+	errorCodeKeyExists                 errorCode = "key_exists"
+	errorCodeInvalidTTL                errorCode = "invalid_ttl"
+	errorCodeInvalidKeyLength          errorCode = "invalid_key_length"
+	errorCodeInvalidKey                errorCode = "invalid_key"
+	errorCodeKeyStyleNotAllowed        errorCode = "key_style_not_allowed"
+	errorCodeInvalidURL                errorCode = "invalid_url"
+	errorCodeURLNotAllowed             errorCode = "url_not_allowed"
+	errorCodeInvalidBundle             errorCode = "invalid_bundle"
+	errorCodeUnauthorized              errorCode = "unauthorized"
+	errorCodeForbidden                 errorCode = "forbidden"
+	errorCodeNotOwner                  errorCode = "not_owner"
+	errorCodeWrongPassword             errorCode = "wrong_password"
+	errorCodePasswordAttemptsExhausted errorCode = "password_attempts_exhausted"
+	errorCodeRevealNotConfirmed        errorCode = "reveal_not_confirmed"
+	// errorCodeNotFound is common for missing, expired and exhausted
+	// records, so client can not tell whether record existed.
+	errorCodeNotFound       errorCode = "not_found"
+	errorCodeAPIKeyNotFound errorCode = "apikey_not_found"
+	errorCodeAPIKeyInvalid  errorCode = "apikey_invalid"
+)
+
+// problemContentType media type of problem details, RFC 9457.
+const problemContentType = "application/problem+json"
+
+// problemResponse problem details, RFC 9457, with error code extension.
+type problemResponse struct {
+	Type     string    `json:"type"`
+	Title    string    `json:"title"`
+	Status   int       `json:"status"`
+	Detail   string    `json:"detail,omitempty"`
+	Instance string    `json:"instance,omitempty"`
+	Code     errorCode `json:"code"`
+}
+
+// acceptsJSON returns is client asked for json answer.
+func acceptsJSON(r *http.Request) bool {
+	accept := r.Header.Get("Accept")
+	return strings.Contains(accept, "application/json") || strings.Contains(accept, problemContentType)
+}
+
+// sendProblemResponse answers with problem details of error.
+func sendProblemResponse(w http.ResponseWriter, r *http.Request, cacheErr *cacheError) error {
+	code := cacheErr.Code
+	if code == "" {
+		code = errorCodeFromStatus(cacheErr.StatusCode)
+	}
+
+	resp := problemResponse{
+		Type:     "about:blank",
+		Title:    http.StatusText(cacheErr.StatusCode),
+		Status:   cacheErr.StatusCode,
+		Detail:   cacheErr.Message,
+		Instance: r.URL.Path,
+		Code:     code,
+	}
+
+	w.Header().Set("Content-Type", problemContentType)
+	w.WriteHeader(cacheErr.StatusCode)
+
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		return fmt.Errorf("failed to encode problem: %w", err)
+	}
+	return nil
+}
+
+// errorCodeFromStatus returns code of error without own code.
+func errorCodeFromStatus(statusCode int) errorCode {
+	switch statusCode {
+	case http.StatusRequestEntityTooLarge:
+		return errorCodeBodyTooLarge
+	case http.StatusUnauthorized:
+		return errorCodeUnauthorized
+	case http.StatusForbidden:
+		return errorCodeForbidden
+	case http.StatusNotFound:
+		return errorCodeNotFound
+	}
+
+	if statusCode >= http.StatusInternalServerError {
+		return errorCodeInternal
+	}
+	return errorCodeInvalidRequest
+}
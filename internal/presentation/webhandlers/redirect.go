@@ -0,0 +1,62 @@
+package webhandlers
+
+import (
+	"fmt"
+	"html"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/thek4n/paste.thek4n.ru/internal/application/service"
+	"github.com/thek4n/paste.thek4n.ru/internal/domain/objectvalue"
+)
+
+const redirectBody = `<html><head>
+<title>%[1]d %[2]s</title>
+</head><body>
+<h1>%[2]s</h1>
+<p>The document has moved <a href="%[3]s">here</a>.</p>
+</body></html>`
+
+// serveRedirect redirects to url of record with status chosen by its
+// creator. Query and path suffix of request are forwarded if record
+// forwards them.
+func serveRedirect(w http.ResponseWriter, r *http.Request, key string, record service.GetBodyAnswer, logger *slog.Logger) {
+	status := int(record.Redirect.Status())
+	target := record.Redirect.Target(
+		string(record.Body),
+		objectvalue.RecordKey(key),
+		escapePathSuffix(r.PathValue("path")),
+		r.URL.RawQuery,
+	)
+
+	answer := make([]byte, 0)
+	answer = fmt.Appendf(answer, redirectBody, status, http.StatusText(status), html.EscapeString(target))
+	w.Header().Set("content-type", http.DetectContentType(answer))
+	http.Redirect(w, r, target, status)
+	_, writeErr := w.Write(answer)
+	if writeErr != nil {
+		logger.Error(
+			"Fail to answer",
+			"error", writeErr,
+			"answer_code", http.StatusInternalServerError,
+		)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	logger.Info(
+		"Redirect to url",
+		"answer_code", status,
+	)
+}
+
+// escapePathSuffix escapes every segment of unescaped path suffix.
+func escapePathSuffix(suffix string) string {
+	segments := strings.Split(suffix, "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+
+	return strings.Join(segments, "/")
+}
@@ -0,0 +1,113 @@
+package webhandlers
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"io/fs"
+	"mime"
+	"net/http"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/thek4n/paste.thek4n.ru/internal/application/service"
+)
+
+// plainTextContentType content type of downgraded active content.
+const plainTextContentType = "text/plain; charset=utf-8"
+
+// inlineScriptRe matches inline script of template.
+var inlineScriptRe = regexp.MustCompile(`(?s)<script>(.*?)</script>`)
+
+// pageScriptSources CSP sources allowing inline scripts of view templates.
+var pageScriptSources = mustScriptSources(viewTemplatesFS, "view/templates/*.tmpl")
+
+// setServingHeaders sets headers protecting readers of untrusted content.
+// Browser does not guess content type and record URL is not sent to linked
+// sites. Pages rendered from record content, like view, bundle listing and
+// password prompt, run only their own scripts. Raw body overrides policy.
+func (app *Handlers) setServingHeaders(w http.ResponseWriter) {
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("Referrer-Policy", app.ServingConfig.ReferrerPolicy())
+	w.Header().Set("Content-Security-Policy", app.ServingConfig.PageContentSecurityPolicy()+"; script-src "+pageScriptSources)
+}
+
+// mustScriptSources returns CSP hash sources of inline scripts of templates
+// matching pattern. Scripts must not contain template actions and comments,
+// which are removed on rendering, so rendered script is equal to hashed one.
+func mustScriptSources(templates fs.FS, pattern string) string {
+	names, err := fs.Glob(templates, pattern)
+	if err != nil {
+		panic(err)
+	}
+
+	var sources []string
+	for _, name := range names {
+		content, err := fs.ReadFile(templates, name)
+		if err != nil {
+			panic(err)
+		}
+
+		for _, match := range inlineScriptRe.FindAllSubmatch(content, -1) {
+			sum := sha256.Sum256(match[1])
+			sources = append(sources, "'sha256-"+base64.StdEncoding.EncodeToString(sum[:])+"'")
+		}
+	}
+
+	if len(sources) == 0 {
+		return "'none'"
+	}
+
+	return strings.Join(sources, " ")
+}
+
+// servedContentType returns content type body of record is served with.
+// Active content is served as plain text unless its type was declared by
+// privileged creator, so it is not rendered on our origin.
+func (app *Handlers) servedContentType(record service.GetBodyAnswer) string {
+	contentType := recordContentType(record)
+
+	if record.Privileged && record.ContentType != "" {
+		return contentType
+	}
+
+	if isActiveContentType(app.ServingConfig.ActiveContentTypes(), contentType) {
+		return plainTextContentType
+	}
+
+	return contentType
+}
+
+// isActiveContentType returns is content type one of active types or xml
+// based type.
+func isActiveContentType(activeTypes []string, contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		// unparsable type can not be checked
+		return true
+	}
+
+	return slices.Contains(activeTypes, mediaType) || strings.HasSuffix(mediaType, "+xml")
+}
+
+// redirectToUserContent redirects browser to usercontent host, so content
+// is rendered on origin which has no access to main host. Other clients do
+// not render content and are served from any host. Returns true if redirected.
+func (app *Handlers) redirectToUserContent(w http.ResponseWriter, r *http.Request) bool {
+	host := app.ServingConfig.UserContentHost()
+	if host == "" || r.Host == host || !acceptsHTML(r) {
+		return false
+	}
+
+	target := url.URL{
+		Scheme:   detectProto(r),
+		Host:     host,
+		Path:     r.URL.Path,
+		RawQuery: r.URL.RawQuery,
+	}
+
+	// 307 keeps method, so posted password and reveal confirmation are not lost
+	http.Redirect(w, r, target.String(), http.StatusTemporaryRedirect)
+	return true
+}
@@ -0,0 +1,106 @@
+package webhandlers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/thek4n/paste.thek4n.ru/internal/domain/objectvalue"
+)
+
+type statsResponse struct {
+	Totals     statsTotals     `json:"totals"`
+	Series     []statsBucket   `json:"series"`
+	Breakdowns statsBreakdowns `json:"breakdowns"`
+}
+
+type statsTotals struct {
+	// Clicks are human clicks, bot clicks are counted apart.
+	Clicks         uint32 `json:"clicks"`
+	BotClicks      uint32 `json:"bot_clicks"`
+	UniqueVisitors uint64 `json:"unique_visitors"`
+	BucketSeconds  int64  `json:"bucket_seconds"`
+}
+
+type statsBucket struct {
+	Start     time.Time `json:"start"`
+	Clicks    uint32    `json:"clicks"`
+	BotClicks uint32    `json:"bot_clicks"`
+}
+
+type statsBreakdowns struct {
+	Referrers  map[string]uint32 `json:"referrers"`
+	UserAgents map[string]uint32 `json:"user_agents"`
+	Countries  map[string]uint32 `json:"countries"`
+}
+
+// GetStats handle getting click analytics of record by apikey that saved it.
+func (app *Handlers) GetStats(w http.ResponseWriter, r *http.Request) {
+	remoteAddr := getClientIP(r)
+	requestUUID := uuid.NewString()
+
+	key := r.PathValue("key")
+
+	logger := app.Logger.With(
+		"source_ip", remoteAddr,
+		"request_id", requestUUID,
+		"key", key,
+	)
+
+	logger.Debug(
+		"Start getting key stats",
+	)
+
+	answer, err := app.clickStatsService.GetStats(objectvalue.RecordKey(key), r.URL.Query().Get("apikey"))
+	if err != nil {
+		handleCacheError(w, r, err, logger)
+		return
+	}
+
+	resp := statsResponse{
+		Totals: statsTotals{
+			Clicks:         answer.Stats.Clicks(),
+			BotClicks:      answer.Stats.BotClicks(),
+			UniqueVisitors: answer.Stats.UniqueVisitors(),
+			BucketSeconds:  int64(answer.BucketSize.Seconds()),
+		},
+		Series: make([]statsBucket, 0),
+		Breakdowns: statsBreakdowns{
+			Referrers:  answer.Stats.Referrers(),
+			UserAgents: make(map[string]uint32),
+			Countries:  answer.Stats.Countries(),
+		},
+	}
+	for _, bucket := range answer.Stats.Buckets() {
+		resp.Series = append(resp.Series, statsBucket{
+			Start:     bucket.Start().UTC(),
+			Clicks:    bucket.Clicks(),
+			BotClicks: bucket.BotClicks(),
+		})
+	}
+	for class, clicks := range answer.Stats.UserAgents() {
+		resp.Breakdowns.UserAgents[string(class)] = clicks
+	}
+
+	if err := sendJSONResponse(w, resp, http.StatusOK); err != nil {
+		logger.Error(
+			"Fail to answer",
+			"error", err,
+			"answer_code", http.StatusOK,
+		)
+		return
+	}
+	logger.Info(
+		"Got stats",
+	)
+}
+
+// getClickParams returns click of consuming request for click analytics.
+func getClickParams(r *http.Request) objectvalue.ClickRequestParams {
+	return objectvalue.ClickRequestParams{
+		SourceIP:  getClientIP(r),
+		Referrer:  r.Referer(),
+		UserAgent: r.UserAgent(),
+	}
+}
@@ -0,0 +1,114 @@
+package webhandlers
+
+import (
+	"net/http"
+	"net/url"
+
+	"github.com/google/uuid"
+
+	"github.com/thek4n/paste.thek4n.ru/internal/domain/objectvalue"
+)
+
+// Update handle changing record body and settings by its owner. Owner is
+// authorized like in Delete. Only presented parameters are changed and
+// empty body keeps current one.
+func (app *Handlers) Update(w http.ResponseWriter, r *http.Request) {
+	remoteAddr := getClientIP(r)
+	requestUUID := uuid.NewString()
+
+	key := r.PathValue("key")
+
+	logger := app.Logger.With(
+		"source_ip", remoteAddr,
+		"request_id", requestUUID,
+		"key", key,
+	)
+
+	logger.Debug(
+		"Start updating key",
+	)
+
+	params, err := app.parseUpdateRequestParams(r.URL.Query())
+	if err != nil {
+		handleCacheError(w, r, err, logger)
+		return
+	}
+	params.OwnerToken = getOwnerToken(r)
+	params.Password = r.Header.Get(passwordHeader)
+	params.Host = r.Host
+
+	maxBodySize, err := app.cacheService.MaxBodySize(params.APIKey)
+	if err != nil {
+		handleCacheError(w, r, err, logger)
+		return
+	}
+
+	body, err := readRequestBody(w, r, maxBodySize)
+	if err != nil {
+		handleCacheError(w, r, err, logger)
+		return
+	}
+
+	if len(body) > 0 {
+		params.Body = body
+		params.BodyLen = int64(len(body))
+	}
+
+	err = app.updateService.Update(objectvalue.RecordKey(key), params)
+	if err != nil {
+		handleCacheError(w, r, err, logger)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+
+	logger.Info(
+		"Updated key",
+		"body_size", len(body),
+	)
+}
+
+func (app *Handlers) parseUpdateRequestParams(urlQuery url.Values) (objectvalue.UpdateRequestParams, error) {
+	p := objectvalue.UpdateRequestParams{}
+	var err error
+
+	if urlQuery.Has("ttl") {
+		ttl, err := app.getTTL(urlQuery)
+		if err != nil {
+			return p, &cacheError{Message: "Invalid 'ttl' parameter", StatusCode: http.StatusBadRequest}
+		}
+		p.TTL = &ttl
+	}
+
+	if urlQuery.Has("disposable") {
+		disposable, err := getDisposable(urlQuery)
+		if err != nil {
+			return p, &cacheError{Message: "Invalid 'disposable' parameter", StatusCode: http.StatusBadRequest}
+		}
+		disposableChecked := uint8(disposable)
+		p.Disposable = &disposableChecked
+	}
+
+	if urlQuery.Has("url") {
+		isURL, err := getURL(urlQuery)
+		if err != nil {
+			return p, &cacheError{Message: "Invalid 'url' parameter", StatusCode: http.StatusBadRequest}
+		}
+		p.IsURL = &isURL
+	}
+
+	p.Encrypted, err = getEncrypted(urlQuery)
+	if err != nil {
+		return p, &cacheError{Message: "Invalid 'encrypted' parameter", StatusCode: http.StatusBadRequest}
+	}
+
+	sliding, err := getSliding(urlQuery)
+	if err != nil || (sliding && p.TTL == nil) {
+		return p, &cacheError{Message: "Invalid 'sliding' parameter", StatusCode: http.StatusBadRequest}
+	}
+	p.Sliding = sliding
+
+	p.APIKey = urlQuery.Get("apikey")
+
+	return p, nil
+}
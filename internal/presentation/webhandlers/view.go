@@ -0,0 +1,260 @@
+package webhandlers
+
+import (
+	"bytes"
+	"embed"
+	"errors"
+	"fmt"
+	"html/template"
+	"mime"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/alecthomas/chroma/v2"
+	chromahtml "github.com/alecthomas/chroma/v2/formatters/html"
+	"github.com/alecthomas/chroma/v2/lexers"
+	"github.com/alecthomas/chroma/v2/styles"
+	"github.com/google/uuid"
+
+	"github.com/thek4n/paste.thek4n.ru/internal/application/service"
+	"github.com/thek4n/paste.thek4n.ru/internal/domain/domainerrors"
+	"github.com/thek4n/paste.thek4n.ru/internal/domain/objectvalue"
+)
+
+// maxHighlightSize is body size above which view is rendered without highlighting.
+const maxHighlightSize = 1 << 20
+
+//go:embed view/templates
+var viewTemplatesFS embed.FS
+
+var viewTpl = template.Must(template.ParseFS(viewTemplatesFS, "view/templates/*.tmpl"))
+
+var (
+	viewStyle     = styles.Get("github")
+	viewFormatter = chromahtml.New(
+		chromahtml.WithClasses(true),
+		chromahtml.WithLineNumbers(true),
+		chromahtml.LineNumbersInTable(true),
+		chromahtml.WithLinkableLineNumbers(true, "L"),
+		chromahtml.TabWidth(4),
+	)
+	viewCSS = mustViewCSS()
+)
+
+type viewPage struct {
+	Title       string
+	Language    string
+	Notice      string
+	Filename    string
+	RawURL      string
+	DownloadURL string
+	CSS         template.CSS
+	Code        template.HTML
+}
+
+// View handle getting key rendered as HTML page with highlighted syntax.
+// Viewing consumes record like Get. Encrypted record is decrypted by page
+// in browser. Browsers are redirected to usercontent host if it is configured.
+func (app *Handlers) View(w http.ResponseWriter, r *http.Request) {
+	remoteAddr := getClientIP(r)
+	requestUUID := uuid.NewString()
+
+	logger := app.Logger.With(
+		"source_ip", remoteAddr,
+		"request_id", requestUUID,
+	)
+
+	logger.Debug(
+		"Start viewing key",
+	)
+
+	key := r.PathValue("key")
+
+	logger = logger.With(
+		"key", key,
+	)
+
+	if isLinkPreview(r) && app.servePreview(w, key, logger) {
+		return
+	}
+
+	if app.redirectToUserContent(w, r) {
+		return
+	}
+
+	app.setServingHeaders(w)
+
+	password := getPassword(w, r)
+
+	record, err := app.getService.GetBody(objectvalue.RecordKey(key), objectvalue.GetRequestParams{
+		Password:  password,
+		Confirmed: r.Method == http.MethodPost,
+		Click:     getClickParams(r),
+	})
+	if err != nil {
+		if handlePasswordError(w, r, err, password, logger) {
+			return
+		}
+		if handleRevealError(w, r, err, logger) {
+			return
+		}
+		if errors.Is(err, domainerrors.ErrRecordNotFound) || errors.Is(err, domainerrors.ErrRecordCounterExhausted) || errors.Is(err, domainerrors.ErrRecordExpired) {
+			w.WriteHeader(http.StatusNotFound)
+
+			_, writeErr := w.Write([]byte("404 Not Found"))
+			if writeErr != nil {
+				logger.Error(
+					"Fail to answer on viewing key",
+					"error", writeErr,
+					"answer_code", http.StatusInternalServerError,
+				)
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			return
+		}
+		logger.Error(
+			"Fail to view key",
+			"error", err,
+			"answer_code", http.StatusInternalServerError,
+		)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	if len(record.Files) > 0 {
+		serveBundleListing(w, r, key, record, logger)
+		return
+	}
+
+	if record.Encrypted {
+		app.serveDecryptView(w, key, record, logger)
+		return
+	}
+
+	page, err := buildViewPage(key, record, r.URL.Query().Get("lang"))
+	if err != nil {
+		logger.Error(
+			"Fail to render view",
+			"error", err,
+			"answer_code", http.StatusInternalServerError,
+		)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	var buf bytes.Buffer
+	if err := viewTpl.ExecuteTemplate(&buf, "view.tmpl", page); err != nil {
+		logger.Error(
+			"Fail to execute view template",
+			"error", err,
+			"answer_code", http.StatusInternalServerError,
+		)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	setRecordStateHeaders(w, record)
+	w.Header().Set("content-type", "text/html; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	_, writeErr := w.Write(buf.Bytes())
+	if writeErr != nil {
+		logger.Error(
+			"Fail to answer",
+			"error", writeErr,
+			"answer_code", http.StatusInternalServerError,
+		)
+		return
+	}
+	logger.Info(
+		"Viewed content",
+		"language", page.Language,
+	)
+}
+
+// buildViewPage highlights record body. Raw and download links are
+// omitted if record has no remaining reads.
+func buildViewPage(key string, record service.GetBodyAnswer, lang string) (viewPage, error) {
+	filename := record.Filename
+	if filename == "" {
+		filename = key
+	}
+
+	contentType := recordContentType(record)
+	page := viewPage{
+		Title:    filename,
+		Filename: filename,
+		CSS:      viewCSS,
+	}
+
+	if !record.ReadsLimited || record.RemainingReads > 0 {
+		page.RawURL = "/" + key + "/"
+		page.DownloadURL = page.RawURL + "?" + downloadParameter + "=1"
+	}
+
+	if !utf8.Valid(record.Body) {
+		page.Language = "Binary"
+		page.Notice = fmt.Sprintf("%d bytes", len(record.Body))
+		page.Code = template.HTML("<pre>Binary content can not be viewed, download it instead.</pre>") //nolint:gosec // constant html
+		return page, nil
+	}
+
+	text := string(record.Body)
+	lexer := detectLexer(lang, record.Filename, contentType, text)
+	if len(record.Body) > maxHighlightSize {
+		lexer = lexers.Fallback
+		page.Notice = "too large to highlight"
+	}
+	lexer = chroma.Coalesce(lexer)
+	page.Language = lexer.Config().Name
+
+	iterator, err := lexer.Tokenise(nil, text)
+	if err != nil {
+		return viewPage{}, fmt.Errorf("fail to tokenise body: %w", err)
+	}
+
+	var code strings.Builder
+	if err := viewFormatter.Format(&code, viewStyle, iterator); err != nil {
+		return viewPage{}, fmt.Errorf("fail to format body: %w", err)
+	}
+	page.Code = template.HTML(code.String()) //nolint:gosec // formatter escapes body
+
+	return page, nil
+}
+
+// detectLexer returns lexer by explicit language name, filename, content type
+// or analysing text in this order.
+func detectLexer(lang, filename, contentType, text string) chroma.Lexer {
+	if lang != "" {
+		if lexer := lexers.Get(lang); lexer != nil {
+			return lexer
+		}
+	}
+
+	if filename != "" {
+		if lexer := lexers.Match(filename); lexer != nil {
+			return lexer
+		}
+	}
+
+	if mediaType, _, err := mime.ParseMediaType(contentType); err == nil && mediaType != "text/plain" {
+		if lexer := lexers.MatchMimeType(mediaType); lexer != nil {
+			return lexer
+		}
+	}
+
+	if lexer := lexers.Analyse(text); lexer != nil {
+		return lexer
+	}
+
+	return lexers.Fallback
+}
+
+func mustViewCSS() template.CSS {
+	var css strings.Builder
+	if err := viewFormatter.WriteCSS(&css, viewStyle); err != nil {
+		panic(err)
+	}
+	return template.CSS(css.String()) //nolint:gosec // generated by formatter
+}
@@ -0,0 +1,89 @@
+// Package bodycodec contains codecs compressing stored record bodies.
+// Codec names are equal to HTTP content-coding tokens, so encoded body
+// can be served as is with Content-Encoding header.
+package bodycodec
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Codec names.
+const (
+	Gzip = "gzip"
+	Zstd = "zstd"
+)
+
+// ErrUnknownCodec returned by Get for unsupported codec name.
+var ErrUnknownCodec = errors.New("unknown codec")
+
+// ErrTooLarge returned by Decode if decompressed data exceeds limit.
+var ErrTooLarge = errors.New("decompressed data too large")
+
+// Codec compresses and decompresses bodies.
+type Codec interface {
+	// Name returns codec name.
+	Name() string
+
+	// Encode compresses data into newly allocated slice.
+	Encode(data []byte) ([]byte, error)
+
+	// NewWriter returns writer compressing into w. Data is flushed to w
+	// on Close.
+	NewWriter(w io.Writer) (io.WriteCloser, error)
+
+	// NewReader returns reader decompressing r.
+	NewReader(r io.Reader) (io.ReadCloser, error)
+
+	// decodedSize returns expected decompressed size of data or 0 if unknown.
+	decodedSize(data []byte) int64
+}
+
+// Get returns codec by name.
+func Get(name string) (Codec, error) {
+	switch name {
+	case Gzip:
+		return gzipCodec{}, nil
+	case Zstd:
+		return zstdCodec{}, nil
+	}
+
+	return nil, fmt.Errorf("%w: %q", ErrUnknownCodec, name)
+}
+
+// Decode decompresses data up to limit bytes, larger data is ErrTooLarge.
+// Output is allocated once when codec knows decompressed size.
+func Decode(c Codec, data []byte, limit int64) ([]byte, error) {
+	r, err := c.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create %s reader: %w", c.Name(), err)
+	}
+
+	buf := bytes.NewBuffer(make([]byte, 0, min(c.decodedSize(data), limit)+bytes.MinRead))
+
+	_, err = io.CopyN(buf, r, limit)
+	if err != nil && !errors.Is(err, io.EOF) {
+		_ = r.Close()
+		return nil, fmt.Errorf("failed to decompress data: %w", err)
+	}
+
+	// data decompressed up to limit is whole only if it ends here,
+	// reading to end also verifies checksum of gzip trailer
+	n, err := io.CopyN(io.Discard, r, 1)
+	if err != nil && !errors.Is(err, io.EOF) {
+		_ = r.Close()
+		return nil, fmt.Errorf("failed to decompress data: %w", err)
+	}
+	if n > 0 {
+		_ = r.Close()
+		return nil, ErrTooLarge
+	}
+
+	if err := r.Close(); err != nil {
+		return nil, fmt.Errorf("failed to close %s reader: %w", c.Name(), err)
+	}
+
+	return buf.Bytes(), nil
+}
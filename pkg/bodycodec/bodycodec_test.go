@@ -0,0 +1,77 @@
+//go:build unit
+
+package bodycodec
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCodecs(t *testing.T) {
+	body := bytes.Repeat([]byte("paste body "), 1000)
+
+	for _, name := range []string{Gzip, Zstd} {
+		t.Run(name+" round trip returns same body", func(t *testing.T) {
+			t.Parallel()
+
+			codec, err := Get(name)
+			require.NoError(t, err)
+			assert.Equal(t, name, codec.Name())
+
+			encoded, err := codec.Encode(body)
+			require.NoError(t, err)
+			assert.Less(t, len(encoded), len(body))
+			assert.Equal(t, int64(len(body)), codec.decodedSize(encoded))
+
+			decoded, err := Decode(codec, encoded, int64(len(body)))
+			require.NoError(t, err)
+			assert.Equal(t, body, decoded)
+		})
+
+		t.Run(name+" writer output is decoded to same body", func(t *testing.T) {
+			t.Parallel()
+
+			codec, err := Get(name)
+			require.NoError(t, err)
+
+			var encoded bytes.Buffer
+			w, err := codec.NewWriter(&encoded)
+			require.NoError(t, err)
+			_, err = io.Copy(w, bytes.NewReader(body))
+			require.NoError(t, err)
+			require.NoError(t, w.Close())
+			assert.Less(t, encoded.Len(), len(body))
+
+			decoded, err := Decode(codec, encoded.Bytes(), int64(len(body)))
+			require.NoError(t, err)
+			assert.Equal(t, body, decoded)
+		})
+
+		t.Run(name+" decode of data larger than limit is error", func(t *testing.T) {
+			t.Parallel()
+
+			codec, err := Get(name)
+			require.NoError(t, err)
+
+			encoded, err := codec.Encode(body)
+			require.NoError(t, err)
+
+			_, err = Decode(codec, encoded, 10)
+			require.ErrorIs(t, err, ErrTooLarge)
+
+			_, err = Decode(codec, encoded, int64(len(body))-1)
+			require.ErrorIs(t, err, ErrTooLarge)
+		})
+	}
+
+	t.Run("unknown codec returns error", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := Get("br")
+		assert.ErrorIs(t, err, ErrUnknownCodec)
+	})
+}
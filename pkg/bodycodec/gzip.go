@@ -0,0 +1,53 @@
+package bodycodec
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+type gzipCodec struct{}
+
+func (gzipCodec) Name() string {
+	return Gzip
+}
+
+// Encode compresses data. Output buffer is not pooled because it is
+// handed over to caller and may be as large as privileged body.
+func (gzipCodec) Encode(data []byte) ([]byte, error) {
+	buf := bytes.NewBuffer(make([]byte, 0, len(data)/4+bytes.MinRead))
+
+	gz, err := gzip.NewWriterLevel(buf, gzip.BestCompression)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create gzip writer: %w", err)
+	}
+
+	if _, err := gz.Write(data); err != nil {
+		return nil, fmt.Errorf("failed to write data: %w", err)
+	}
+
+	if err := gz.Close(); err != nil {
+		return nil, fmt.Errorf("failed to close gzip writer: %w", err)
+	}
+
+	return buf.Bytes(), nil
+}
+
+func (gzipCodec) NewWriter(w io.Writer) (io.WriteCloser, error) {
+	return gzip.NewWriterLevel(w, gzip.BestCompression)
+}
+
+func (gzipCodec) NewReader(r io.Reader) (io.ReadCloser, error) {
+	return gzip.NewReader(r)
+}
+
+// decodedSize reads ISIZE field of gzip trailer, that is uncompressed size modulo 2^32.
+func (gzipCodec) decodedSize(data []byte) int64 {
+	if len(data) < 4 {
+		return 0
+	}
+
+	return int64(binary.LittleEndian.Uint32(data[len(data)-4:]))
+}
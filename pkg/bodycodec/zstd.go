@@ -0,0 +1,65 @@
+package bodycodec
+
+import (
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// zstdEncoder is shared because EncodeAll is safe for concurrent use
+// and encoder allocation is expensive.
+var zstdEncoder = sync.OnceValues(func() (*zstd.Encoder, error) {
+	return zstd.NewWriter(nil, zstd.WithEncoderLevel(zstd.SpeedBetterCompression))
+})
+
+type zstdCodec struct{}
+
+func (zstdCodec) Name() string {
+	return Zstd
+}
+
+func (zstdCodec) Encode(data []byte) ([]byte, error) {
+	enc, err := zstdEncoder()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create zstd writer: %w", err)
+	}
+
+	return enc.EncodeAll(data, make([]byte, 0, len(data)/4+zstd.HeaderMaxSize)), nil
+}
+
+// NewWriter returns encoder of its own, shared encoder can not stream
+// concurrently.
+func (zstdCodec) NewWriter(w io.Writer) (io.WriteCloser, error) {
+	return zstd.NewWriter(w, zstd.WithEncoderLevel(zstd.SpeedBetterCompression), zstd.WithEncoderConcurrency(1))
+}
+
+func (zstdCodec) NewReader(r io.Reader) (io.ReadCloser, error) {
+	dec, err := zstd.NewReader(r, zstd.WithDecoderConcurrency(1))
+	if err != nil {
+		return nil, err
+	}
+
+	return zstdReadCloser{dec}, nil
+}
+
+// decodedSize reads frame content size from zstd frame header.
+func (zstdCodec) decodedSize(data []byte) int64 {
+	var header zstd.Header
+	if err := header.Decode(data); err != nil || !header.HasFCS {
+		return 0
+	}
+
+	return int64(header.FrameContentSize)
+}
+
+// zstdReadCloser adapts zstd decoder, which Close does not return error.
+type zstdReadCloser struct {
+	*zstd.Decoder
+}
+
+func (r zstdReadCloser) Close() error {
+	r.Decoder.Close()
+	return nil
+}
@@ -0,0 +1,185 @@
+// Package bundlearchive reads files of uploaded zip, tar and gzip
+// compressed tar archives and writes files to zip archive. Reading is
+// limited by total size and number of files, so archive bomb can not make
+// server buffer arbitrary large content.
+package bundlearchive
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// ErrUnknownFormat returned if data is not zip, tar or gzip compressed tar.
+var ErrUnknownFormat = errors.New("unknown archive format")
+
+// ErrTooLarge returned if files contents exceed size limit.
+var ErrTooLarge = errors.New("archive files too large")
+
+// ErrTooManyFiles returned if archive has more files than limit.
+var ErrTooManyFiles = errors.New("too many files in archive")
+
+// File of archive.
+type File struct {
+	// Name slash separated path of file inside archive.
+	Name    string
+	Content []byte
+}
+
+// Read returns regular files of archive detected by magic bytes.
+// Directories, links and other special entries are skipped. Leading "./"
+// is trimmed from names.
+func Read(data []byte, maxSize int64, maxFiles int) ([]File, error) {
+	switch {
+	case isZip(data):
+		return readZip(data, maxSize, maxFiles)
+
+	case isGzip(data):
+		gz, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, fmt.Errorf("fail to read gzip: %w", err)
+		}
+		defer func() { _ = gz.Close() }()
+
+		return readTar(gz, maxSize, maxFiles)
+
+	case isTar(data):
+		return readTar(bytes.NewReader(data), maxSize, maxFiles)
+	}
+
+	return nil, ErrUnknownFormat
+}
+
+// WriteZip writes files to zip archive.
+func WriteZip(w io.Writer, files []File) error {
+	zw := zip.NewWriter(w)
+
+	for _, file := range files {
+		fw, err := zw.Create(file.Name)
+		if err != nil {
+			return fmt.Errorf("fail to add '%s' to zip: %w", file.Name, err)
+		}
+
+		if _, err := fw.Write(file.Content); err != nil {
+			return fmt.Errorf("fail to write '%s' to zip: %w", file.Name, err)
+		}
+	}
+
+	if err := zw.Close(); err != nil {
+		return fmt.Errorf("fail to write zip: %w", err)
+	}
+
+	return nil
+}
+
+func readZip(data []byte, maxSize int64, maxFiles int) ([]File, error) {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("fail to read zip: %w", err)
+	}
+
+	files := make([]File, 0, min(len(zr.File), maxFiles))
+	var size int64
+
+	for _, entry := range zr.File {
+		if !entry.Mode().IsRegular() {
+			continue
+		}
+
+		if len(files) == maxFiles {
+			return nil, ErrTooManyFiles
+		}
+
+		rc, err := entry.Open()
+		if err != nil {
+			return nil, fmt.Errorf("fail to open '%s' in zip: %w", entry.Name, err)
+		}
+
+		// declared sizes of zip entries are not trusted
+		content, err := readLimited(rc, maxSize-size)
+		_ = rc.Close()
+		if err != nil {
+			return nil, err
+		}
+
+		size += int64(len(content))
+		files = append(files, File{Name: normalizeName(entry.Name), Content: content})
+	}
+
+	return files, nil
+}
+
+func readTar(r io.Reader, maxSize int64, maxFiles int) ([]File, error) {
+	tr := tar.NewReader(r)
+
+	var files []File
+	var size int64
+
+	for {
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("fail to read tar: %w", err)
+		}
+
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+
+		if len(files) == maxFiles {
+			return nil, ErrTooManyFiles
+		}
+
+		content, err := readLimited(tr, maxSize-size)
+		if err != nil {
+			return nil, err
+		}
+
+		size += int64(len(content))
+		files = append(files, File{Name: normalizeName(header.Name), Content: content})
+	}
+
+	return files, nil
+}
+
+// readLimited reads r returning ErrTooLarge if it has more than limit bytes.
+func readLimited(r io.Reader, limit int64) ([]byte, error) {
+	var buf bytes.Buffer
+
+	n, err := buf.ReadFrom(io.LimitReader(r, limit+1))
+	if err != nil {
+		return nil, fmt.Errorf("fail to read archive file: %w", err)
+	}
+	if n > limit {
+		return nil, ErrTooLarge
+	}
+
+	return buf.Bytes(), nil
+}
+
+func normalizeName(name string) string {
+	for strings.HasPrefix(name, "./") {
+		name = strings.TrimPrefix(name, "./")
+	}
+	return name
+}
+
+func isZip(data []byte) bool {
+	return bytes.HasPrefix(data, []byte("PK\x03\x04")) || bytes.HasPrefix(data, []byte("PK\x05\x06"))
+}
+
+func isGzip(data []byte) bool {
+	return len(data) >= 2 && data[0] == 0x1f && data[1] == 0x8b
+}
+
+// isTar detects ustar and gnu tar by magic at offset 257.
+func isTar(data []byte) bool {
+	return len(data) >= 262 && bytes.Equal(data[257:262], []byte("ustar"))
+}
@@ -0,0 +1,90 @@
+//go:build unit
+
+package bundlearchive
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRead(t *testing.T) {
+	files := []File{
+		{Name: "config.yaml", Content: []byte("key: value")},
+		{Name: "logs/app.log", Content: []byte("started")},
+	}
+
+	var zipData bytes.Buffer
+	require.NoError(t, WriteZip(&zipData, files))
+
+	tarData := makeTar(t, files)
+
+	var tgzData bytes.Buffer
+	gz := gzip.NewWriter(&tgzData)
+	_, err := gz.Write(tarData)
+	require.NoError(t, err)
+	require.NoError(t, gz.Close())
+
+	archives := map[string][]byte{
+		"zip":    zipData.Bytes(),
+		"tar":    tarData,
+		"tar.gz": tgzData.Bytes(),
+	}
+
+	for format, data := range archives {
+		t.Run(format+" files are read", func(t *testing.T) {
+			t.Parallel()
+
+			got, err := Read(data, 1024, 10)
+			require.NoError(t, err)
+			assert.Equal(t, files, got)
+		})
+
+		t.Run(format+" too large files return error", func(t *testing.T) {
+			t.Parallel()
+
+			_, err := Read(data, 12, 10)
+			assert.ErrorIs(t, err, ErrTooLarge)
+		})
+
+		t.Run(format+" too many files return error", func(t *testing.T) {
+			t.Parallel()
+
+			_, err := Read(data, 1024, 1)
+			assert.ErrorIs(t, err, ErrTooManyFiles)
+		})
+	}
+
+	t.Run("not archive returns error", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := Read([]byte("plain text"), 1024, 10)
+		assert.ErrorIs(t, err, ErrUnknownFormat)
+	})
+}
+
+func makeTar(t *testing.T, files []File) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+
+	require.NoError(t, tw.WriteHeader(&tar.Header{Name: "./logs/", Typeflag: tar.TypeDir, Mode: 0o755}))
+	for _, file := range files {
+		require.NoError(t, tw.WriteHeader(&tar.Header{
+			Name:     "./" + file.Name,
+			Typeflag: tar.TypeReg,
+			Mode:     0o644,
+			Size:     int64(len(file.Content)),
+		}))
+		_, err := tw.Write(file.Content)
+		require.NoError(t, err)
+	}
+	require.NoError(t, tw.Close())
+
+	return buf.Bytes()
+}
@@ -0,0 +1,102 @@
+// Package e2ecrypt encrypts record bodies on client side. Random key is
+// carried in URL fragment, which browsers never send to server, so server
+// stores only ciphertext it can not decrypt. Ciphertext is AES-256-GCM
+// nonce followed by sealed body, the format decrypted by browser view with
+// WebCrypto.
+package e2ecrypt
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+)
+
+// ErrInvalidKey returned if key can not be parsed.
+var ErrInvalidKey = errors.New("invalid key")
+
+// keyLen AES-256 key length.
+const keyLen = 32
+
+// Key encrypts and decrypts bodies.
+type Key []byte
+
+// NewKey generates random key.
+func NewKey() (Key, error) {
+	key := make(Key, keyLen)
+	if _, err := rand.Read(key); err != nil {
+		return nil, fmt.Errorf("fail to generate key: %w", err)
+	}
+
+	return key, nil
+}
+
+// ParseKey parses key formatted by Key.String.
+func ParseKey(s string) (Key, error) {
+	key, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil || len(key) != keyLen {
+		return nil, ErrInvalidKey
+	}
+
+	return key, nil
+}
+
+// String formats key as unpadded base64url to put it in URL fragment.
+func (key Key) String() string {
+	return base64.RawURLEncoding.EncodeToString(key)
+}
+
+// Seal encrypts plaintext with random nonce.
+func (key Key) Seal(plaintext []byte) ([]byte, error) {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(plaintext)+aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("fail to generate nonce: %w", err)
+	}
+
+	return aead.Seal(nonce, nonce, plaintext, nil), nil
+}
+
+// Open decrypts ciphertext returned by Seal.
+func (key Key) Open(ciphertext []byte) ([]byte, error) {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(ciphertext) < aead.NonceSize() {
+		return nil, errors.New("ciphertext too short")
+	}
+
+	nonce, sealed := ciphertext[:aead.NonceSize()], ciphertext[aead.NonceSize():]
+	plaintext, err := aead.Open(nil, nonce, sealed, nil)
+	if err != nil {
+		return nil, fmt.Errorf("fail to decrypt: %w", err)
+	}
+
+	return plaintext, nil
+}
+
+func newAEAD(key Key) (cipher.AEAD, error) {
+	if len(key) != keyLen {
+		return nil, ErrInvalidKey
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("fail to create cipher: %w", err)
+	}
+
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("fail to create gcm: %w", err)
+	}
+
+	return aead, nil
+}
@@ -0,0 +1,71 @@
+//go:build unit
+
+package e2ecrypt
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKeySealOpen(t *testing.T) {
+	t.Parallel()
+
+	plaintext := []byte("secret body")
+
+	key, err := NewKey()
+	require.NoError(t, err)
+
+	ciphertext, err := key.Seal(plaintext)
+	require.NoError(t, err)
+	assert.NotContains(t, string(ciphertext), "secret")
+
+	t.Run("same key decrypts body", func(t *testing.T) {
+		t.Parallel()
+
+		got, err := key.Open(ciphertext)
+		require.NoError(t, err)
+		assert.Equal(t, plaintext, got)
+	})
+
+	t.Run("parsed key decrypts body", func(t *testing.T) {
+		t.Parallel()
+
+		parsed, err := ParseKey(key.String())
+		require.NoError(t, err)
+
+		got, err := parsed.Open(ciphertext)
+		require.NoError(t, err)
+		assert.Equal(t, plaintext, got)
+	})
+
+	t.Run("other key is rejected", func(t *testing.T) {
+		t.Parallel()
+
+		other, err := NewKey()
+		require.NoError(t, err)
+
+		_, err = other.Open(ciphertext)
+		assert.Error(t, err)
+	})
+
+	t.Run("tampered ciphertext is rejected", func(t *testing.T) {
+		t.Parallel()
+
+		tampered := append([]byte(nil), ciphertext...)
+		tampered[len(tampered)-1] ^= 1
+
+		_, err := key.Open(tampered)
+		assert.Error(t, err)
+	})
+}
+
+func TestParseKey(t *testing.T) {
+	t.Parallel()
+
+	for _, s := range []string{"", "not base64!", "c2hvcnQ"} {
+		_, err := ParseKey(s)
+		assert.ErrorIs(t, err, ErrInvalidKey, s)
+	}
+}
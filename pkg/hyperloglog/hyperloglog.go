@@ -0,0 +1,107 @@
+// Package hyperloglog estimates number of distinct values in constant
+// memory. Sketch of 1 KiB estimates cardinality with about 3% error.
+package hyperloglog
+
+import (
+	"errors"
+	"hash/fnv"
+	"math"
+	"math/bits"
+)
+
+// precision number of hash bits choosing register.
+const precision = 10
+
+// Size length of serialized sketch in bytes.
+const Size = 1 << precision
+
+// ErrInvalidSketch returned by FromBytes for serialized sketch of wrong size.
+var ErrInvalidSketch = errors.New("invalid sketch")
+
+// Sketch HyperLogLog registers. Zero value is not usable, use New.
+type Sketch struct {
+	registers []uint8
+}
+
+// New returns empty sketch.
+func New() *Sketch {
+	return &Sketch{registers: make([]uint8, Size)}
+}
+
+// FromBytes restores sketch serialized by Bytes. Empty data is empty sketch.
+func FromBytes(data []byte) (*Sketch, error) {
+	if len(data) == 0 {
+		return New(), nil
+	}
+
+	if len(data) != Size {
+		return nil, ErrInvalidSketch
+	}
+
+	return &Sketch{registers: append([]uint8(nil), data...)}, nil
+}
+
+// Bytes returns copy of registers.
+func (s *Sketch) Bytes() []byte {
+	return append([]byte(nil), s.registers...)
+}
+
+// Add adds value to sketch.
+func (s *Sketch) Add(value []byte) {
+	h := hash(value)
+	index := h >> (64 - precision)
+	// sentinel bit bounds rank if remaining bits are zeros
+	rank := uint8(bits.LeadingZeros64(h<<precision|1<<(precision-1))) + 1
+
+	if rank > s.registers[index] {
+		s.registers[index] = rank
+	}
+}
+
+// Merge adds all values of other sketch to sketch.
+func (s *Sketch) Merge(other *Sketch) {
+	for i, rank := range other.registers {
+		if rank > s.registers[i] {
+			s.registers[i] = rank
+		}
+	}
+}
+
+// Estimate returns estimated number of distinct added values.
+func (s *Sketch) Estimate() uint64 {
+	m := float64(Size)
+	alpha := 0.7213 / (1 + 1.079/m)
+
+	sum := 0.0
+	zeros := 0
+	for _, rank := range s.registers {
+		sum += math.Ldexp(1, -int(rank))
+		if rank == 0 {
+			zeros++
+		}
+	}
+
+	estimate := alpha * m * m / sum
+	// linear counting is more accurate for small cardinalities
+	if estimate <= 2.5*m && zeros > 0 {
+		estimate = m * math.Log(m/float64(zeros))
+	}
+
+	return uint64(math.Round(estimate))
+}
+
+// hash returns well mixed 64 bit hash of value.
+func hash(value []byte) uint64 {
+	h := fnv.New64a()
+	_, _ = h.Write(value)
+
+	// splitmix64 finalizer spreads fnv bits over whole hash
+	x := h.Sum64()
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+
+	return x
+}
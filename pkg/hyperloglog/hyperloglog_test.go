@@ -0,0 +1,71 @@
+//go:build unit
+
+package hyperloglog
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSketch(t *testing.T) {
+	t.Run("empty sketch estimates zero", func(t *testing.T) {
+		t.Parallel()
+
+		assert.Equal(t, uint64(0), New().Estimate())
+	})
+
+	t.Run("repeated values are counted once", func(t *testing.T) {
+		t.Parallel()
+
+		s := New()
+		for range 100 {
+			s.Add([]byte("visitor"))
+		}
+		s.Add([]byte("another visitor"))
+
+		assert.Equal(t, uint64(2), s.Estimate())
+	})
+
+	for _, n := range []int{100, 10000, 200000} {
+		t.Run("estimate is close to cardinality "+strconv.Itoa(n), func(t *testing.T) {
+			t.Parallel()
+
+			s := New()
+			for i := range n {
+				s.Add([]byte("visitor-" + strconv.Itoa(i)))
+			}
+
+			assert.InEpsilon(t, n, s.Estimate(), 0.1)
+		})
+	}
+
+	t.Run("merged sketch counts union", func(t *testing.T) {
+		t.Parallel()
+
+		a, b := New(), New()
+		for i := range 1000 {
+			a.Add([]byte(strconv.Itoa(i)))
+			b.Add([]byte(strconv.Itoa(i + 500)))
+		}
+		a.Merge(b)
+
+		assert.InEpsilon(t, 1500, a.Estimate(), 0.1)
+	})
+
+	t.Run("serialized sketch is restored", func(t *testing.T) {
+		t.Parallel()
+
+		s := New()
+		s.Add([]byte("visitor"))
+
+		restored, err := FromBytes(s.Bytes())
+		require.NoError(t, err)
+		assert.Equal(t, s.Estimate(), restored.Estimate())
+
+		_, err = FromBytes([]byte{1, 2, 3})
+		assert.ErrorIs(t, err, ErrInvalidSketch)
+	})
+}
@@ -0,0 +1,207 @@
+// Package passwordcrypt protects record bodies with password. Only slow
+// argon2id hash of password is stored and body is encrypted with key
+// derived from the same argon2id output, so neither password nor body can
+// be recovered from stored data without guessing password.
+package passwordcrypt
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hkdf"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+)
+
+// ErrWrongPassword returned if password does not match hash.
+var ErrWrongPassword = errors.New("wrong password")
+
+// ErrInvalidHash returned if hash can not be parsed.
+var ErrInvalidHash = errors.New("invalid password hash")
+
+// Argon2id parameters of new hashes. Parameters are stored in hash,
+// so they can be changed without breaking existing hashes.
+const (
+	argonTime    = 2
+	argonMemory  = 19 * 1024
+	argonThreads = 1
+	saltLen      = 16
+	keyLen       = 32
+)
+
+type params struct {
+	salt    []byte
+	time    uint32
+	memory  uint32
+	threads uint8
+}
+
+// Seal encrypts plaintext with key derived from password. Returns hash
+// in PHC string format to store next to ciphertext.
+func Seal(password string, plaintext []byte) (string, []byte, error) {
+	p := params{
+		salt:    make([]byte, saltLen),
+		time:    argonTime,
+		memory:  argonMemory,
+		threads: argonThreads,
+	}
+	if _, err := rand.Read(p.salt); err != nil {
+		return "", nil, fmt.Errorf("fail to generate salt: %w", err)
+	}
+
+	verifier, key, err := deriveKeys(password, p)
+	if err != nil {
+		return "", nil, err
+	}
+
+	aead, err := newAEAD(key)
+	if err != nil {
+		return "", nil, err
+	}
+
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(plaintext)+aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", nil, fmt.Errorf("fail to generate nonce: %w", err)
+	}
+
+	return p.format(verifier), aead.Seal(nonce, nonce, plaintext, nil), nil
+}
+
+// Key decrypts ciphertexts sealed with password. Deriving it is slow,
+// so it can be kept to open ciphertext without repeating password check.
+type Key []byte
+
+// Verify returns key derived from password or ErrWrongPassword if password
+// does not match hash.
+func Verify(hash, password string) (Key, error) {
+	return verify(hash, password)
+}
+
+// Open checks password against hash and decrypts ciphertext returned by Seal.
+func Open(hash, password string, ciphertext []byte) ([]byte, error) {
+	key, err := verify(hash, password)
+	if err != nil {
+		return nil, err
+	}
+
+	return key.Open(ciphertext)
+}
+
+// Open decrypts ciphertext returned by Seal with password of key.
+func (key Key) Open(ciphertext []byte) ([]byte, error) {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(ciphertext) < aead.NonceSize() {
+		return nil, errors.New("ciphertext too short")
+	}
+
+	nonce, sealed := ciphertext[:aead.NonceSize()], ciphertext[aead.NonceSize():]
+	plaintext, err := aead.Open(nil, nonce, sealed, nil)
+	if err != nil {
+		return nil, fmt.Errorf("fail to decrypt: %w", err)
+	}
+
+	return plaintext, nil
+}
+
+// verify returns encryption key if password matches hash.
+func verify(hash, password string) (Key, error) {
+	p, expected, err := parse(hash)
+	if err != nil {
+		return nil, err
+	}
+
+	verifier, key, err := deriveKeys(password, p)
+	if err != nil {
+		return nil, err
+	}
+
+	if subtle.ConstantTimeCompare(verifier, expected) != 1 {
+		return nil, ErrWrongPassword
+	}
+
+	return Key(key), nil
+}
+
+// deriveKeys returns password verifier and encryption key expanded from
+// single argon2id output, so stored verifier does not reveal key.
+func deriveKeys(password string, p params) ([]byte, []byte, error) {
+	secret := argon2.IDKey([]byte(password), p.salt, p.time, p.memory, p.threads, keyLen)
+
+	verifier, err := hkdf.Expand(sha256.New, secret, "verifier", keyLen)
+	if err != nil {
+		return nil, nil, fmt.Errorf("fail to derive verifier: %w", err)
+	}
+
+	key, err := hkdf.Expand(sha256.New, secret, "encryption key", keyLen)
+	if err != nil {
+		return nil, nil, fmt.Errorf("fail to derive key: %w", err)
+	}
+
+	return verifier, key, nil
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("fail to create cipher: %w", err)
+	}
+
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("fail to create gcm: %w", err)
+	}
+
+	return aead, nil
+}
+
+func (p params) format(verifier []byte) string {
+	return fmt.Sprintf(
+		"$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version,
+		p.memory,
+		p.time,
+		p.threads,
+		base64.RawStdEncoding.EncodeToString(p.salt),
+		base64.RawStdEncoding.EncodeToString(verifier),
+	)
+}
+
+func parse(hash string) (params, []byte, error) {
+	parts := strings.Split(hash, "$")
+	if len(parts) != 6 || parts[1] != "argon2id" {
+		return params{}, nil, ErrInvalidHash
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return params{}, nil, ErrInvalidHash
+	}
+
+	var p params
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &p.memory, &p.time, &p.threads); err != nil {
+		return params{}, nil, ErrInvalidHash
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return params{}, nil, ErrInvalidHash
+	}
+	p.salt = salt
+
+	verifier, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil || len(verifier) != keyLen {
+		return params{}, nil, ErrInvalidHash
+	}
+
+	return p, verifier, nil
+}
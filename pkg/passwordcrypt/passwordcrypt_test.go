@@ -0,0 +1,61 @@
+//go:build unit
+
+package passwordcrypt
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSealOpen(t *testing.T) {
+	t.Parallel()
+
+	plaintext := []byte("secret body")
+
+	hash, ciphertext, err := Seal("password", plaintext)
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(hash, "$argon2id$v=19$"))
+	assert.NotContains(t, string(ciphertext), "secret")
+
+	t.Run("right password decrypts body", func(t *testing.T) {
+		t.Parallel()
+
+		got, err := Open(hash, "password", ciphertext)
+		require.NoError(t, err)
+		assert.Equal(t, plaintext, got)
+
+		key, err := Verify(hash, "password")
+		require.NoError(t, err)
+		got, err = key.Open(ciphertext)
+		require.NoError(t, err)
+		assert.Equal(t, plaintext, got)
+	})
+
+	t.Run("wrong password is rejected", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := Open(hash, "wrong", ciphertext)
+		require.ErrorIs(t, err, ErrWrongPassword)
+		_, err = Verify(hash, "")
+		assert.ErrorIs(t, err, ErrWrongPassword)
+	})
+
+	t.Run("malformed hash is rejected", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := Verify("$2a$10$bcrypt", "password")
+		assert.ErrorIs(t, err, ErrInvalidHash)
+	})
+
+	t.Run("same password seals with different salt", func(t *testing.T) {
+		t.Parallel()
+
+		otherHash, otherCiphertext, err := Seal("password", plaintext)
+		require.NoError(t, err)
+		assert.NotEqual(t, hash, otherHash)
+		assert.NotEqual(t, ciphertext, otherCiphertext)
+	})
+}